@@ -15,6 +15,64 @@
 /*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;
 /*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;
 
+--
+-- Table structure for table `api_key`
+--
+
+DROP TABLE IF EXISTS `api_key`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `api_key` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `name` varchar(64) NOT NULL,
+  `prefix` char(8) NOT NULL,
+  `key_hash` char(64) NOT NULL,
+  `scopes` varchar(255) NOT NULL,
+  `expires_at` datetime DEFAULT NULL,
+  `last_used_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `api_key_hash_uindex` (`key_hash`),
+  KEY `api_key_user_fk` (`user_id`),
+  CONSTRAINT `api_key_user_fk` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `api_key`
+--
+
+LOCK TABLES `api_key` WRITE;
+/*!40000 ALTER TABLE `api_key` DISABLE KEYS */;
+/*!40000 ALTER TABLE `api_key` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `diet_attribute`
+--
+
+DROP TABLE IF EXISTS `diet_attribute`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `diet_attribute` (
+  `ingredient` varchar(128) NOT NULL,
+  `attribute` varchar(32) NOT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`ingredient`,`attribute`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `diet_attribute`
+--
+
+LOCK TABLES `diet_attribute` WRITE;
+/*!40000 ALTER TABLE `diet_attribute` DISABLE KEYS */;
+/*!40000 ALTER TABLE `diet_attribute` ENABLE KEYS */;
+UNLOCK TABLES;
+
 --
 -- Table structure for table `ingredient`
 --
@@ -28,10 +86,12 @@ CREATE TABLE `ingredient` (
   `name` varchar(128) NOT NULL,
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `deleted_at` datetime DEFAULT NULL,
   PRIMARY KEY (`id`),
   KEY `ingredient_name_index` (`name`),
+  KEY `ingredient_deleted_at_index` (`deleted_at`),
   KEY `ingredient_recipe_fk` (`recipe_id`),
-  CONSTRAINT `ingredient_recipe_fk` FOREIGN KEY (`recipe_id`) REFERENCES `recipe` (`id`) ON DELETE CASCADE
+  CONSTRAINT `ingredient_recipe_fk` FOREIGN KEY (`recipe_id`) REFERENCES `recipe` (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -44,6 +104,123 @@ LOCK TABLES `ingredient` WRITE;
 /*!40000 ALTER TABLE `ingredient` ENABLE KEYS */;
 UNLOCK TABLES;
 
+--
+-- Table structure for table `ingredient_translation`
+--
+
+DROP TABLE IF EXISTS `ingredient_translation`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `ingredient_translation` (
+  `ingredient_id` bigint(20) NOT NULL,
+  `locale` varchar(16) NOT NULL,
+  `name` varchar(128) NOT NULL,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`ingredient_id`,`locale`),
+  CONSTRAINT `ingredient_translation_ingredient_fk` FOREIGN KEY (`ingredient_id`) REFERENCES `ingredient` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `ingredient_translation`
+--
+
+LOCK TABLES `ingredient_translation` WRITE;
+/*!40000 ALTER TABLE `ingredient_translation` DISABLE KEYS */;
+/*!40000 ALTER TABLE `ingredient_translation` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `nutrient`
+--
+
+DROP TABLE IF EXISTS `nutrient`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `nutrient` (
+  `ingredient` varchar(128) NOT NULL,
+  `calories` decimal(8,2) NOT NULL DEFAULT '0.00',
+  `protein` decimal(8,2) NOT NULL DEFAULT '0.00',
+  `fat` decimal(8,2) NOT NULL DEFAULT '0.00',
+  `carbs` decimal(8,2) NOT NULL DEFAULT '0.00',
+  `fiber` decimal(8,2) NOT NULL DEFAULT '0.00',
+  `sodium` decimal(8,2) NOT NULL DEFAULT '0.00',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`ingredient`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `nutrient`
+--
+
+LOCK TABLES `nutrient` WRITE;
+/*!40000 ALTER TABLE `nutrient` DISABLE KEYS */;
+/*!40000 ALTER TABLE `nutrient` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `oidc_login`
+--
+
+DROP TABLE IF EXISTS `oidc_login`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `oidc_login` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `provider` varchar(50) NOT NULL,
+  `state_hash` char(64) NOT NULL,
+  `nonce` varchar(64) NOT NULL,
+  `verifier` varchar(128) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `oidc_login_state_uindex` (`state_hash`),
+  KEY `oidc_login_expires_index` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `oidc_login`
+--
+
+LOCK TABLES `oidc_login` WRITE;
+/*!40000 ALTER TABLE `oidc_login` DISABLE KEYS */;
+/*!40000 ALTER TABLE `oidc_login` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `password_reset`
+--
+
+DROP TABLE IF EXISTS `password_reset`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `password_reset` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `password_reset_hash_uindex` (`token_hash`),
+  KEY `password_reset_user_fk` (`user_id`),
+  CONSTRAINT `password_reset_user_fk` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `password_reset`
+--
+
+LOCK TABLES `password_reset` WRITE;
+/*!40000 ALTER TABLE `password_reset` DISABLE KEYS */;
+/*!40000 ALTER TABLE `password_reset` ENABLE KEYS */;
+UNLOCK TABLES;
+
 --
 -- Table structure for table `recipe`
 --
@@ -56,10 +233,30 @@ CREATE TABLE `recipe` (
   `title` varchar(256) NOT NULL,
   `thumbnail` varchar(1024) DEFAULT NULL,
   `url` varchar(1024) DEFAULT NULL,
+  `views` bigint(20) NOT NULL DEFAULT '0',
+  `user_id` bigint(20) DEFAULT NULL,
+  `status` varchar(16) NOT NULL DEFAULT 'published',
+  `visibility` varchar(16) NOT NULL DEFAULT 'public',
+  `locale` varchar(16) NOT NULL DEFAULT 'en',
+  `prep_time` int(10) unsigned NOT NULL DEFAULT '0',
+  `cook_time` int(10) unsigned NOT NULL DEFAULT '0',
+  `total_time` int(10) unsigned NOT NULL DEFAULT '0',
+  `servings` int(10) unsigned NOT NULL DEFAULT '0',
+  `yield` varchar(64) NOT NULL DEFAULT '',
+  `difficulty` varchar(16) NOT NULL DEFAULT '',
+  `calories` double DEFAULT NULL,
+  `review_note` varchar(512) NOT NULL DEFAULT '',
   `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `deleted_at` datetime DEFAULT NULL,
   PRIMARY KEY (`id`),
-  UNIQUE KEY `recipe_title_uindex` (`title`)
+  UNIQUE KEY `recipe_title_uindex` (`title`),
+  KEY `recipe_status_index` (`status`),
+  KEY `recipe_total_time_index` (`total_time`),
+  KEY `recipe_calories_index` (`calories`),
+  KEY `recipe_deleted_at_index` (`deleted_at`),
+  KEY `recipe_user_fk` (`user_id`),
+  CONSTRAINT `recipe_user_fk` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -72,6 +269,318 @@ LOCK TABLES `recipe` WRITE;
 /*!40000 ALTER TABLE `recipe` ENABLE KEYS */;
 UNLOCK TABLES;
 
+--
+-- Table structure for table `recipe_diet`
+--
+
+DROP TABLE IF EXISTS `recipe_diet`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `recipe_diet` (
+  `recipe_id` bigint(20) NOT NULL,
+  `diet` varchar(16) NOT NULL,
+  `automatic` tinyint(1) NOT NULL DEFAULT '0',
+  `override` tinyint(1) DEFAULT NULL,
+  `override_user_id` bigint(20) DEFAULT NULL,
+  `override_note` varchar(256) NOT NULL DEFAULT '',
+  `overridden_at` datetime DEFAULT NULL,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`recipe_id`,`diet`),
+  KEY `recipe_diet_diet_idx` (`diet`),
+  KEY `recipe_diet_user_fk` (`override_user_id`),
+  CONSTRAINT `recipe_diet_recipe_fk` FOREIGN KEY (`recipe_id`) REFERENCES `recipe` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `recipe_diet_user_fk` FOREIGN KEY (`override_user_id`) REFERENCES `user` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `recipe_diet`
+--
+
+LOCK TABLES `recipe_diet` WRITE;
+/*!40000 ALTER TABLE `recipe_diet` DISABLE KEYS */;
+/*!40000 ALTER TABLE `recipe_diet` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `recipe_share`
+--
+
+DROP TABLE IF EXISTS `recipe_share`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `recipe_share` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `recipe_id` bigint(20) NOT NULL,
+  `user_id` bigint(20) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `recipe_share_recipe_id_index` (`recipe_id`),
+  CONSTRAINT `recipe_share_recipe_fk` FOREIGN KEY (`recipe_id`) REFERENCES `recipe` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `recipe_share`
+--
+
+LOCK TABLES `recipe_share` WRITE;
+/*!40000 ALTER TABLE `recipe_share` DISABLE KEYS */;
+/*!40000 ALTER TABLE `recipe_share` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `recipe_tag`
+--
+
+DROP TABLE IF EXISTS `recipe_tag`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `recipe_tag` (
+  `recipe_id` bigint(20) NOT NULL,
+  `tag_id` bigint(20) NOT NULL,
+  PRIMARY KEY (`recipe_id`,`tag_id`),
+  KEY `recipe_tag_tag_fk` (`tag_id`),
+  CONSTRAINT `recipe_tag_recipe_fk` FOREIGN KEY (`recipe_id`) REFERENCES `recipe` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `recipe_tag_tag_fk` FOREIGN KEY (`tag_id`) REFERENCES `tag` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `recipe_tag`
+--
+
+LOCK TABLES `recipe_tag` WRITE;
+/*!40000 ALTER TABLE `recipe_tag` DISABLE KEYS */;
+/*!40000 ALTER TABLE `recipe_tag` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `recipe_translation`
+--
+
+DROP TABLE IF EXISTS `recipe_translation`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `recipe_translation` (
+  `recipe_id` bigint(20) NOT NULL,
+  `locale` varchar(16) NOT NULL,
+  `title` varchar(256) NOT NULL,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`recipe_id`,`locale`),
+  KEY `recipe_translation_title_index` (`title`),
+  CONSTRAINT `recipe_translation_recipe_fk` FOREIGN KEY (`recipe_id`) REFERENCES `recipe` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `recipe_translation`
+--
+
+LOCK TABLES `recipe_translation` WRITE;
+/*!40000 ALTER TABLE `recipe_translation` DISABLE KEYS */;
+/*!40000 ALTER TABLE `recipe_translation` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `recipe_view`
+--
+
+DROP TABLE IF EXISTS `recipe_view`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `recipe_view` (
+  `recipe_id` bigint(20) NOT NULL,
+  `day` date NOT NULL,
+  `views` bigint(20) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`recipe_id`,`day`),
+  KEY `recipe_view_day_index` (`day`),
+  CONSTRAINT `recipe_view_recipe_fk` FOREIGN KEY (`recipe_id`) REFERENCES `recipe` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `recipe_view`
+--
+
+LOCK TABLES `recipe_view` WRITE;
+/*!40000 ALTER TABLE `recipe_view` DISABLE KEYS */;
+/*!40000 ALTER TABLE `recipe_view` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `refresh_token`
+--
+
+DROP TABLE IF EXISTS `refresh_token`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `refresh_token` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `family` char(32) NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `access_jti` char(32) NOT NULL,
+  `access_expires_at` datetime NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `refresh_token_hash_uindex` (`token_hash`),
+  KEY `refresh_token_family_index` (`family`),
+  KEY `refresh_token_user_fk` (`user_id`),
+  CONSTRAINT `refresh_token_user_fk` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `refresh_token`
+--
+
+LOCK TABLES `refresh_token` WRITE;
+/*!40000 ALTER TABLE `refresh_token` DISABLE KEYS */;
+/*!40000 ALTER TABLE `refresh_token` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `revoked_token`
+--
+
+DROP TABLE IF EXISTS `revoked_token`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `revoked_token` (
+  `jti` char(32) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`jti`),
+  KEY `revoked_token_expires_at_index` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `revoked_token`
+--
+
+LOCK TABLES `revoked_token` WRITE;
+/*!40000 ALTER TABLE `revoked_token` DISABLE KEYS */;
+/*!40000 ALTER TABLE `revoked_token` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `substitution`
+--
+
+DROP TABLE IF EXISTS `substitution`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `substitution` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `ingredient` varchar(128) NOT NULL,
+  `notes` varchar(512) NOT NULL DEFAULT '',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `substitution_ingredient_index` (`ingredient`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `substitution`
+--
+
+LOCK TABLES `substitution` WRITE;
+/*!40000 ALTER TABLE `substitution` DISABLE KEYS */;
+/*!40000 ALTER TABLE `substitution` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `substitution_ingredient`
+--
+
+DROP TABLE IF EXISTS `substitution_ingredient`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `substitution_ingredient` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `substitution_id` bigint(20) NOT NULL,
+  `name` varchar(128) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `substitution_ingredient_substitution_fk` (`substitution_id`),
+  CONSTRAINT `substitution_ingredient_substitution_fk` FOREIGN KEY (`substitution_id`) REFERENCES `substitution` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `substitution_ingredient`
+--
+
+LOCK TABLES `substitution_ingredient` WRITE;
+/*!40000 ALTER TABLE `substitution_ingredient` DISABLE KEYS */;
+/*!40000 ALTER TABLE `substitution_ingredient` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `tag`
+--
+
+DROP TABLE IF EXISTS `tag`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `tag` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `kind` varchar(16) NOT NULL,
+  `name` varchar(64) NOT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `tag_kind_name_uindex` (`kind`,`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `tag`
+--
+
+LOCK TABLES `tag` WRITE;
+/*!40000 ALTER TABLE `tag` DISABLE KEYS */;
+/*!40000 ALTER TABLE `tag` ENABLE KEYS */;
+UNLOCK TABLES;
+
+--
+-- Table structure for table `taxonomy`
+--
+
+DROP TABLE IF EXISTS `taxonomy`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `taxonomy` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `parent_id` bigint(20) DEFAULT NULL,
+  `name` varchar(128) NOT NULL,
+  `allergen` varchar(64) NOT NULL DEFAULT '',
+  `aisle` varchar(64) NOT NULL DEFAULT '',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `taxonomy_name_uindex` (`name`),
+  KEY `taxonomy_parent_fk` (`parent_id`),
+  CONSTRAINT `taxonomy_parent_fk` FOREIGN KEY (`parent_id`) REFERENCES `taxonomy` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `taxonomy`
+--
+
+LOCK TABLES `taxonomy` WRITE;
+/*!40000 ALTER TABLE `taxonomy` DISABLE KEYS */;
+/*!40000 ALTER TABLE `taxonomy` ENABLE KEYS */;
+UNLOCK TABLES;
+
 --
 -- Table structure for table `user`
 --
@@ -86,11 +595,20 @@ CREATE TABLE `user` (
   `fullName` varchar(128) DEFAULT NULL,
   `email` varchar(128) NOT NULL,
   `active` tinyint(1) DEFAULT '1',
+  `role` varchar(16) NOT NULL DEFAULT 'user',
+  `email_verified_at` datetime DEFAULT NULL,
+  `pending_email` varchar(128) DEFAULT NULL,
+  `preferences` json DEFAULT NULL,
+  `erase_at` datetime DEFAULT NULL,
+  `locked_until` datetime DEFAULT NULL,
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `deleted_at` datetime DEFAULT NULL,
   PRIMARY KEY (`id`),
   UNIQUE KEY `user_email_uindex` (`email`),
-  UNIQUE KEY `user_username_uindex` (`username`)
+  UNIQUE KEY `user_username_uindex` (`username`),
+  KEY `user_deleted_at_index` (`deleted_at`),
+  KEY `user_erase_at_index` (`erase_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=latin1;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -102,6 +620,35 @@ LOCK TABLES `user` WRITE;
 /*!40000 ALTER TABLE `user` DISABLE KEYS */;
 /*!40000 ALTER TABLE `user` ENABLE KEYS */;
 UNLOCK TABLES;
+--
+-- Table structure for table `user_identity`
+--
+
+DROP TABLE IF EXISTS `user_identity`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `user_identity` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `user_id` bigint(20) NOT NULL,
+  `provider` varchar(50) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `email` varchar(255) NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `user_identity_subject_uindex` (`provider`,`subject`),
+  KEY `user_identity_user_fk` (`user_id`),
+  CONSTRAINT `user_identity_user_fk` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `user_identity`
+--
+
+LOCK TABLES `user_identity` WRITE;
+/*!40000 ALTER TABLE `user_identity` DISABLE KEYS */;
+/*!40000 ALTER TABLE `user_identity` ENABLE KEYS */;
+UNLOCK TABLES;
 /*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;
 
 /*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
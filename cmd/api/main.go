@@ -48,6 +48,17 @@ func main() {
 
 	// Initialize handlers
 	h := handler.NewHandler(db, cfg, log)
+	if err := h.LoadKeys(cfg.Token); err != nil {
+		log.Fatal(err)
+	}
+
+	// Start handler background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		h.Run(ctx)
+	}()
 
 	// Initialize API routes
 	r := handler.Routes(h)
@@ -69,10 +80,20 @@ func main() {
 		}
 	}()
 
-	// Keep application open, close on termination signal
+	// Keep application open, reload the token signing keys on hangup and close on termination signal
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	<-sigs
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := <-sigs; sig == syscall.SIGHUP; sig = <-sigs {
+		reloaded, err := config.New("config")
+		if err == nil {
+			err = h.LoadKeys(reloaded.Token)
+		}
+		if err != nil {
+			log.Errorf("Failed to reload token signing keys, %s", err)
+			continue
+		}
+		log.Println("Reloaded token signing keys")
+	}
 
 	// Gracefully Shutdown server
 	log.Println("Application received a termination signal. Shutting down.")
@@ -80,4 +101,11 @@ func main() {
 	if err := s.Shutdown(context.Background()); err != nil {
 		log.Fatalf("Failed to gracefully shutdown http server, %s", err)
 	}
+
+	// Stop background jobs, wait for a flush in progress to finish and flush any buffered data
+	cancel()
+	<-stopped
+	if err := h.Close(); err != nil {
+		log.Errorf("Failed to flush handler data, %s", err)
+	}
 }
@@ -0,0 +1,113 @@
+package backoff
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	failures int
+	last     time.Time
+}
+
+// Tracker is a concurrency safe counter of failed attempts that tells callers how long to back off. Keys get free
+// failures, after that each attempt waits a delay that starts at base and doubles with every failure up to max. Keys
+// are forgotten once window has passed since their last failure.
+type Tracker struct {
+	mu        sync.Mutex
+	free      int
+	base      time.Duration
+	max       time.Duration
+	window    time.Duration
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+// New creates a new tracker, a base delay of zero never delays attempts but failures are still counted
+func New(free int, base time.Duration, max time.Duration, window time.Duration) *Tracker {
+	return &Tracker{
+		free:    free,
+		base:    base,
+		max:     max,
+		window:  window,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Wait returns the time left until key can attempt again at the given time, zero when it can attempt now
+func (t *Tracker) Wait(key string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e := t.entry(key, now)
+	if e == nil {
+		return 0
+	}
+
+	if wait := e.last.Add(t.delay(e.failures)).Sub(now); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
+
+// Fail records a failed attempt of key at the given time, returns the number of failures key has
+func (t *Tracker) Fail(key string, now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Expired keys are dropped once per window so the tracker only holds keys that failed recently
+	if now.Sub(t.lastSweep) > t.window {
+		for k, e := range t.entries {
+			if now.Sub(e.last) > t.window {
+				delete(t.entries, k)
+			}
+		}
+		t.lastSweep = now
+	}
+
+	e := t.entry(key, now)
+	if e == nil {
+		e = &entry{}
+		t.entries[key] = e
+	}
+	e.failures++
+	e.last = now
+
+	return e.failures
+}
+
+// Reset forgets the failures of key
+func (t *Tracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, key)
+}
+
+// entry returns the entry of key, nil when key has no failures within the window
+func (t *Tracker) entry(key string, now time.Time) *entry {
+	e, ok := t.entries[key]
+	if !ok || now.Sub(e.last) > t.window {
+		return nil
+	}
+
+	return e
+}
+
+// delay returns the delay after the given number of failures
+func (t *Tracker) delay(failures int) time.Duration {
+	if failures <= t.free || t.base <= 0 {
+		return 0
+	}
+
+	delay := t.base
+	for i := t.free + 1; i < failures && delay < t.max; i++ {
+		delay *= 2
+	}
+	if delay > t.max {
+		delay = t.max
+	}
+
+	return delay
+}
@@ -0,0 +1,80 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/backoff"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Should not delay free failures", func(t *testing.T) {
+		tr := backoff.New(2, time.Second, time.Minute, time.Hour)
+
+		for i := 1; i <= 2; i++ {
+			if n := tr.Fail("a", now); n != i {
+				t.Fatalf("Expected %d failures got %d", i, n)
+			}
+			if wait := tr.Wait("a", now); wait != 0 {
+				t.Fatalf("Expected no delay after %d failures got %s", i, wait)
+			}
+		}
+	})
+
+	t.Run("Should double the delay up to the max", func(t *testing.T) {
+		tr := backoff.New(1, time.Second, 5*time.Second, time.Hour)
+		tr.Fail("a", now)
+
+		for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+			tr.Fail("a", now)
+			if wait := tr.Wait("a", now); wait != expected {
+				t.Fatalf("Expected a delay of %s got %s", expected, wait)
+			}
+		}
+
+		if wait := tr.Wait("a", now.Add(3*time.Second)); wait != 2*time.Second {
+			t.Fatalf("Expected the delay to count from the last failure got %s", wait)
+		}
+		if wait := tr.Wait("b", now); wait != 0 {
+			t.Fatalf("Expected other keys to be tracked separately got %s", wait)
+		}
+	})
+
+	t.Run("Should forget failures after the window", func(t *testing.T) {
+		tr := backoff.New(0, time.Second, time.Minute, time.Minute)
+		tr.Fail("a", now)
+		tr.Fail("a", now)
+
+		later := now.Add(2 * time.Minute)
+		if wait := tr.Wait("a", later); wait != 0 {
+			t.Fatalf("Expected no delay got %s", wait)
+		}
+		if n := tr.Fail("a", later); n != 1 {
+			t.Fatalf("Expected failures to start over got %d", n)
+		}
+	})
+
+	t.Run("Should reset failures", func(t *testing.T) {
+		tr := backoff.New(0, time.Second, time.Minute, time.Hour)
+		tr.Fail("a", now)
+		tr.Reset("a")
+
+		if wait := tr.Wait("a", now); wait != 0 {
+			t.Fatalf("Expected no delay got %s", wait)
+		}
+	})
+
+	t.Run("Should count failures without delaying when disabled", func(t *testing.T) {
+		tr := backoff.New(0, 0, time.Minute, time.Hour)
+		tr.Fail("a", now)
+
+		if n := tr.Fail("a", now); n != 2 {
+			t.Fatalf("Expected 2 failures got %d", n)
+		}
+		if wait := tr.Wait("a", now); wait != 0 {
+			t.Fatalf("Expected no delay got %s", wait)
+		}
+	})
+}
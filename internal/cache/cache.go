@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type item struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Cache is a concurrency safe in memory key value store, values expire after the configured time to live
+type Cache struct {
+	mu    sync.RWMutex
+	items map[string]item
+	ttl   time.Duration
+}
+
+// New creates a new cache, values expire after ttl unless they are stored using SetWithTTL
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		items: make(map[string]item),
+		ttl:   ttl,
+	}
+}
+
+// Get a value, the second return value reports whether a non expired value was found
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	i, ok := c.items[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(i.expires) {
+		return nil, false
+	}
+
+	return i.value, true
+}
+
+// Set a value using the default time to live
+func (c *Cache) Set(key string, value interface{}) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL sets a value that expires after ttl
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[key] = item{value: value, expires: time.Now().Add(ttl)}
+}
+
+// Delete a value
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+}
+
+// Purge removes all expired values
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k := range c.items {
+		if now.After(c.items[k].expires) {
+			delete(c.items, k)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/cache"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("Should get a stored value", func(t *testing.T) {
+		c := cache.New(time.Minute)
+		c.Set("key", 1)
+
+		v, ok := c.Get("key")
+		if !ok || v.(int) != 1 {
+			t.Fatalf("Expected to get value 1 got %v", v)
+		}
+	})
+
+	t.Run("Should not get an expired value", func(t *testing.T) {
+		c := cache.New(time.Minute)
+		c.SetWithTTL("key", 1, -time.Second)
+
+		if _, ok := c.Get("key"); ok {
+			t.Fatal("Expected value to be expired")
+		}
+
+		c.Purge()
+		if _, ok := c.Get("key"); ok {
+			t.Fatal("Expected value to be purged")
+		}
+	})
+
+	t.Run("Should not get a deleted value", func(t *testing.T) {
+		c := cache.New(time.Minute)
+		c.Set("key", 1)
+		c.Delete("key")
+
+		if _, ok := c.Get("key"); ok {
+			t.Fatal("Expected value to be deleted")
+		}
+	})
+
+	t.Run("Should be safe for concurrent use", func(t *testing.T) {
+		c := cache.New(time.Minute)
+		done := make(chan struct{})
+
+		for i := 0; i < 10; i++ {
+			go func(i int) {
+				c.Set("key", i)
+				c.Get("key")
+				done <- struct{}{}
+			}(i)
+		}
+		for i := 0; i < 10; i++ {
+			<-done
+		}
+	})
+}
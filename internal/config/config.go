@@ -9,11 +9,24 @@ import (
 
 // Config object
 type Config struct {
-	APP      APP
-	Server   Server
-	Database Database
-	Logger   Logger
-	Token    Token
+	APP        APP
+	Server     Server
+	Database   Database
+	Logger     Logger
+	Token      Token
+	Admin      Admin
+	Cache      Cache
+	Views      Views
+	Duplicates Duplicates
+	Trash      Trash
+	RateLimit  RateLimit
+	Login      Login
+	Locale     Locale
+	Mail       Mail
+	Verify     Verify
+	Reset      Reset
+	Account    Account
+	OIDC       OIDC
 }
 
 // APP holds general app configuration values
@@ -54,9 +67,180 @@ type Logger struct {
 }
 
 // Token holds configuration for tokens
+// TTL is the lifetime of access tokens, keep it short since refresh tokens renew them (minutes)
+// RefreshTTL is the lifetime of refresh tokens (hours)
+// ShareTTL is the default lifetime of recipe share links (hours)
+// Keys are the PEM key files tokens are signed and verified with, the key with the SigningKey id signs new tokens.
+// Keys without a private part only verify, use them to publish a key before signing with it. Without keys tokens are
+// signed with the HS256 Secret, tokens without a key id keep verifying with it while the Secret is set
 type Token struct {
-	Secret string
-	TTL    int64 // Minutes
+	Secret     string
+	TTL        int64
+	RefreshTTL int64
+	ShareTTL   int64
+	SigningKey string
+	Keys       []SigningKey
+}
+
+// SigningKey is a PEM key file of Algorithm (RS256, ES256 or EdDSA), ID is the kid of the key
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	File      string
+}
+
+// Cache holds the configuration for caching
+// TTL is the time values are kept in the in memory cache (seconds)
+// MaxAge is the max-age directive sent to clients for cacheable responses (seconds)
+type Cache struct {
+	TTL    int64
+	MaxAge int64
+}
+
+// Views holds the configuration for recipe view counting
+// FlushInterval is how often buffered views are written to the database (seconds)
+// BufferSize is the number of buffered recipes that triggers an early flush
+// TrendingWindow is the number of days of views taken into account for trending recipes
+// TrendingHalfLife is the number of days after which a view weighs half for trending recipes, it must be positive
+type Views struct {
+	FlushInterval    int64
+	BufferSize       int
+	TrendingWindow   int
+	TrendingHalfLife float64
+}
+
+// Duplicates holds the configuration for near duplicate recipe detection
+// IngredientOverlap is the share of common ingredients (0-1) above which two recipes are considered duplicates
+// MinIngredients is the number of ingredients a recipe needs for the ingredient overlap rule to apply
+type Duplicates struct {
+	IngredientOverlap float64
+	MinIngredients    int
+}
+
+// Trash holds the configuration for deleted recipes and users
+// Retention is the number of days deleted rows are kept before they are purged, 0 keeps them forever
+// PurgeInterval is how often the trash and expired tokens are checked for rows to purge (seconds)
+type Trash struct {
+	Retention     int64
+	PurgeInterval int64
+}
+
+// RateLimit holds the configuration for request rate limiting of public routes
+// Window is the period requests are counted in (seconds)
+// Anonymous is the number of requests allowed per window for each ip address without a token, 0 disables the limit
+// Authenticated is the number of requests allowed per window for each signed in user, 0 disables the limit
+type RateLimit struct {
+	Window        int64
+	Anonymous     int
+	Authenticated int
+}
+
+// Login holds the configuration for sign in throttling and account lockout
+// Free is the number of failed sign ins of a username before its sign ins are delayed, IPFree the number for an ip
+// address, which is higher since many users can share an address
+// BaseDelay is the delay after the first failure past the free ones, it doubles with every further failure up to
+// MaxDelay (seconds), 0 disables the delays
+// Window is the time failures are remembered after the last one (seconds)
+// LockoutThreshold is the number of failed sign ins of a username that locks the account, 0 disables lockouts
+// LockoutDuration is the time an account stays locked unless an administrator unlocks it (minutes)
+type Login struct {
+	Free             int
+	IPFree           int
+	BaseDelay        int64
+	MaxDelay         int64
+	Window           int64
+	LockoutThreshold int
+	LockoutDuration  int64
+}
+
+// Locale holds the configuration for localized content
+// Default is the locale recipes are written in unless their author says otherwise, and the last locale of every
+// fallback chain
+// Supported is the list of locales recipes can be written in and translated to, the default locale is always supported
+type Locale struct {
+	Default   string
+	Supported []string
+}
+
+// Mail holds the configuration for outgoing email
+// Host and Port address the SMTP server, Username and Password authenticate with it when a username is set
+// From is the sender address of all emails
+type Mail struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Verify holds the configuration for email verification of new accounts
+// URL is the page of the client app verification links open, the signed token is added as token query parameter
+// ChangeURL is the page of the client app email change confirmation links open
+// TTL is the lifetime of verification and confirmation links (hours)
+// ResendWindow is the period verification emails are counted in (seconds)
+// ResendLimit is the number of verification emails allowed per window for each address, 0 disables the limit
+type Verify struct {
+	URL          string
+	ChangeURL    string
+	TTL          int64
+	ResendWindow int64
+	ResendLimit  int
+}
+
+// Reset holds the configuration for password resets
+// URL is the page of the client app reset links open, the reset token is added as token query parameter
+// TTL is the lifetime of reset links (minutes)
+// Window is the period reset emails are counted in (seconds)
+// Limit is the number of reset emails allowed per window for each address, 0 disables the limit
+type Reset struct {
+	URL    string
+	TTL    int64
+	Window int64
+	Limit  int
+}
+
+// Account holds the configuration for accounts deleted by their users
+// Grace is the number of days a deleted account can be restored before it is erased
+// Content is what happens to the recipes of an erased account, reassign or delete
+// ReassignTo is the id of the user reassigned recipes are given to, when zero they are left without an author
+type Account struct {
+	Grace      int64
+	Content    string
+	ReassignTo int64
+}
+
+// OIDC holds the OpenID Connect identity providers users can sign in with
+// StateTTL is the time a sign in started at a provider has to complete (minutes)
+type OIDC struct {
+	StateTTL  int64
+	Providers []Provider
+}
+
+// Provider is an OpenID Connect identity provider, Name is the provider segment of the sign in urls
+// AuthURL, TokenURL and JWKSURL are the authorization, token and key set endpoints of the provider
+// RedirectURL is the callback url registered with the provider, Issuer is the iss claim of its ID tokens
+// ClientSecret is only sent when set, public clients rely on PKCE alone
+// Scopes are requested together with openid, by default email and profile
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	JWKSURL      string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Admin holds the configuration for administrative access, roles are normally granted through the admin user endpoints
+// and these lists bootstrap a fresh install
+// Users is a list of user ids that have the admin role whatever their stored role is
+// Moderators is a list of user ids that have the moderator role whatever their stored role is
+// Users are listed by id since anyone can sign up, or sign in through a provider, with a username nobody holds yet
+type Admin struct {
+	Users      []int64
+	Moderators []int64
 }
 
 // New returns a new config, by default it looks for config files in the current working directory, if your config
@@ -75,6 +259,10 @@ func New(name string, path ...string) (*Config, error) {
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
+	// Trending scores divide by the half life, it can not be left out
+	v.SetDefault("views.trendingwindow", 7)
+	v.SetDefault("views.trendinghalflife", 2)
+
 	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config, %w", err)
 	}
@@ -83,5 +271,10 @@ func New(name string, path ...string) (*Config, error) {
 	if err := v.Unmarshal(&c); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config, %w", err)
 	}
+
+	if c.Views.TrendingHalfLife <= 0 {
+		return nil, fmt.Errorf("invalid config, views.trendingHalfLife must be positive got %g", c.Views.TrendingHalfLife)
+	}
+
 	return &c, nil
 }
@@ -52,4 +52,23 @@ func TestLoad(t *testing.T) {
 			t.Fatalf("Token TTL expected to have value %d got %d", 66, cfg.Token.TTL)
 		}
 	})
+	t.Run("Should default the trending half life", func(t *testing.T) {
+		cfg, err := config.New("valid", "testdata")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.Views.TrendingHalfLife <= 0 {
+			t.Fatalf("Expected a positive trending half life got %g", cfg.Views.TrendingHalfLife)
+		}
+	})
+
+	t.Run("Should reject a trending half life of zero", func(t *testing.T) {
+		os.Setenv("RECIPE_VIEWS_TRENDINGHALFLIFE", "0")
+		defer os.Unsetenv("RECIPE_VIEWS_TRENDINGHALFLIFE")
+
+		if _, err := config.New("valid", "testdata"); err == nil {
+			t.Fatal("Expected an error")
+		}
+	})
 }
@@ -0,0 +1,39 @@
+package database
+
+// API key scopes, each scope lets keys call a group of routes
+const (
+	ScopeRecipesRead  = "recipes:read"
+	ScopeRecipesWrite = "recipes:write"
+)
+
+// Scopes lists the supported API key scopes
+var Scopes = []string{ScopeRecipesRead, ScopeRecipesWrite}
+
+// APIKey entity, a personal key that authenticates scripts as its user. Keys are stored by the sha256 hash of their
+// value, Prefix holds the first characters of the value so users can tell their keys apart.
+type APIKey struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Prefix     string
+	Hash       string
+	Scopes     []string
+	ExpiresAt  string
+	LastUsedAt string
+	RevokedAt  string
+	CreatedAt  string
+}
+
+// APIKeys slice of API key entities
+type APIKeys []APIKey
+
+// HasScope reports whether the key was granted scope
+func (k APIKey) HasScope(scope string) bool {
+	for i := range k.Scopes {
+		if k.Scopes[i] == scope {
+			return true
+		}
+	}
+
+	return false
+}
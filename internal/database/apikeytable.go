@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const apiKeyColumns = "k.id, k.user_id, k.name, k.prefix, k.scopes, IFNULL(k.expires_at, ''), IFNULL(k.last_used_at, ''), " +
+	"IFNULL(k.revoked_at, ''), k.created_at"
+
+// APIKeyTable object
+type APIKeyTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewAPIKeyTable create an APIKeyTable object
+func NewAPIKeyTable(db *sql.DB) *APIKeyTable {
+	return &APIKeyTable{
+		db:   db,
+		name: "api_key k",
+	}
+}
+
+// Insert a new API key that expires at the given time, a zero expiresAt never expires. Returns inserted key id
+func (kt *APIKeyTable) Insert(k APIKey, expiresAt time.Time) (int64, error) {
+	var expires interface{}
+	if !expiresAt.IsZero() {
+		expires = expiresAt.UTC()
+	}
+
+	q := `INSERT INTO api_key (user_id, name, prefix, key_hash, scopes, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := kt.db.Exec(q, k.UserID, k.Name, k.Prefix, k.Hash, strings.Join(k.Scopes, ","), expires)
+	if err != nil {
+		return 0, fmt.Errorf("api key error, %w", err)
+	}
+
+	return res.LastInsertId()
+}
+
+// Get returns an API key of a user
+func (kt *APIKeyTable) Get(id uint64, userID int64) (*APIKey, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE k.id = ? AND k.user_id = ?`, apiKeyColumns, kt.name)
+
+	return scanAPIKey(kt.db.QueryRow(query, id, userID))
+}
+
+// Active returns the API key with the given hash when it is neither revoked nor expired at the given time
+func (kt *APIKeyTable) Active(hash string, now time.Time) (*APIKey, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s
+WHERE k.key_hash = ? AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > ?)`, apiKeyColumns, kt.name)
+
+	return scanAPIKey(kt.db.QueryRow(query, hash, now.UTC()))
+}
+
+// List the API keys of a user, most recent first
+func (kt *APIKeyTable) List(userID int64) (APIKeys, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE k.user_id = ? ORDER BY k.id DESC`, apiKeyColumns, kt.name)
+
+	rows, err := kt.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("api key error, %w", err)
+	}
+	defer rows.Close()
+
+	var keys APIKeys
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Revoke an API key of a user, returns ErrNoRows when the user has no such key or it was already revoked
+func (kt *APIKeyTable) Revoke(id uint64, userID int64, now time.Time) error {
+	q := `UPDATE api_key SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+	res, err := kt.db.Exec(q, now.UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("api key error, %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// RevokeUser revokes every active API key of a user and returns the number of keys revoked
+func (kt *APIKeyTable) RevokeUser(userID int64, now time.Time) (int64, error) {
+	q := `UPDATE api_key SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`
+	res, err := kt.db.Exec(q, now.UTC(), userID)
+	if err != nil {
+		return 0, fmt.Errorf("api key error, %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// Touch records that an API key was used, the time is only written when the recorded one is older than a minute so
+// busy keys do not update their row on every request
+func (kt *APIKeyTable) Touch(id int64, now time.Time) error {
+	q := `UPDATE api_key SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)`
+	if _, err := kt.db.Exec(q, now.UTC(), id, now.Add(-time.Minute).UTC()); err != nil {
+		return fmt.Errorf("api key error, %w", err)
+	}
+
+	return nil
+}
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(s scanner) (*APIKey, error) {
+	k := APIKey{}
+	var scopes string
+	if err := s.Scan(
+		&k.ID, &k.UserID, &k.Name, &k.Prefix, &scopes, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+
+	return &k, nil
+}
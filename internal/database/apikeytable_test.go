@@ -0,0 +1,106 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestAPIKeyTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	key := database.APIKey{UserID: 1, Name: "key", Prefix: "abcdefgh", Scopes: []string{database.ScopeRecipesRead}}
+
+	insert := func(hash string, expiresAt time.Time) int64 {
+		k := key
+		k.Hash = hash
+		id, err := db.APIKey.Insert(k, expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	t.Run("Should find active keys", func(t *testing.T) {
+		insert("key-hash1", time.Time{})
+
+		k, err := db.APIKey.Active("key-hash1", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.UserID != 1 || k.ExpiresAt != "" || !k.HasScope(database.ScopeRecipesRead) || k.HasScope(database.ScopeRecipesWrite) {
+			t.Fatalf("Expected a read only key without expiry got %+v", k)
+		}
+	})
+
+	t.Run("Should not find expired keys", func(t *testing.T) {
+		insert("key-hash2", now.Add(-time.Minute))
+
+		if _, err := db.APIKey.Active("key-hash2", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error got %v", err)
+		}
+	})
+
+	t.Run("Should not find revoked keys", func(t *testing.T) {
+		id := insert("key-hash3", now.Add(time.Hour))
+
+		if err := db.APIKey.Revoke(uint64(id), 2, now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error for the key of another user got %v", err)
+		}
+		if err := db.APIKey.Revoke(uint64(id), 1, now); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.APIKey.Active("key-hash3", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error got %v", err)
+		}
+	})
+
+	t.Run("Should record the last use", func(t *testing.T) {
+		k, err := db.APIKey.Active("key-hash1", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.APIKey.Touch(k.ID, now); err != nil {
+			t.Fatal(err)
+		}
+		if k, err = db.APIKey.Get(uint64(k.ID), 1); err != nil {
+			t.Fatal(err)
+		}
+		if k.LastUsedAt == "" {
+			t.Fatal("Expected the last use to be recorded")
+		}
+	})
+
+	t.Run("Should revoke every key of a user", func(t *testing.T) {
+		otherID, err := db.User.Insert(database.User{Username: "keyholder2", Password: "password", Email: "keyholder2@test.gr"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		other := key
+		other.UserID, other.Hash = otherID, "key-hash5"
+		if _, err := db.APIKey.Insert(other, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		insert("key-hash4", time.Time{})
+
+		n, err := db.APIKey.RevokeUser(1, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n < 2 {
+			t.Fatalf("Expected the active keys of the user to be revoked got %d", n)
+		}
+		if _, err := db.APIKey.Active("key-hash4", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error got %v", err)
+		}
+		if _, err := db.APIKey.Active("key-hash5", now); err != nil {
+			t.Fatalf("Expected the keys of other users to stay active got %v", err)
+		}
+	})
+}
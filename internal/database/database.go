@@ -10,10 +10,22 @@ import (
 )
 
 type Database struct {
-	Handle     *sql.DB
-	Recipe     *RecipeTable
-	Ingredient *IngredientTable
-	User       *UserTable
+	Handle       *sql.DB
+	Recipe       *RecipeTable
+	Ingredient   *IngredientTable
+	User         *UserTable
+	Taxonomy     *TaxonomyTable
+	Substitution *SubstitutionTable
+	Share        *ShareTable
+	Translation  *TranslationTable
+	Tag          *TagTable
+	Diet         *DietTable
+	Nutrient     *NutrientTable
+	Token        *TokenTable
+	Reset        *ResetTable
+	APIKey       *APIKeyTable
+	OIDC         *OIDCTable
+	Identity     *IdentityTable
 }
 
 func New(c config.Database) (*Database, error) {
@@ -37,9 +49,43 @@ func New(c config.Database) (*Database, error) {
 	}
 
 	return &Database{
-		Handle:     db,
-		Recipe:     NewRecipeTable(db),
-		Ingredient: NewIngredientTable(db),
-		User:       NewUserTable(db),
+		Handle:       db,
+		Recipe:       NewRecipeTable(db),
+		Ingredient:   NewIngredientTable(db),
+		User:         NewUserTable(db),
+		Taxonomy:     NewTaxonomyTable(db),
+		Substitution: NewSubstitutionTable(db),
+		Share:        NewShareTable(db),
+		Translation:  NewTranslationTable(db),
+		Tag:          NewTagTable(db),
+		Diet:         NewDietTable(db),
+		Nutrient:     NewNutrientTable(db),
+		Token:        NewTokenTable(db),
+		Reset:        NewResetTable(db),
+		APIKey:       NewAPIKeyTable(db),
+		OIDC:         NewOIDCTable(db),
+		Identity:     NewIdentityTable(db),
 	}, nil
 }
+
+// execer runs statements, implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// transaction runs fn inside a transaction, the transaction is rolled back when fn fails
+func transaction(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,95 @@
+package database
+
+// Diets recipes are classified against
+const (
+	DietVegan      = "vegan"
+	DietVegetarian = "vegetarian"
+	DietGlutenFree = "gluten-free"
+	DietKeto       = "keto"
+)
+
+// Ingredient attributes that rule diets out
+const (
+	DietAttrMeat   = "meat"
+	DietAttrFish   = "fish"
+	DietAttrDairy  = "dairy"
+	DietAttrEgg    = "egg"
+	DietAttrHoney  = "honey"
+	DietAttrGluten = "gluten"
+	DietAttrSugar  = "sugar"
+	DietAttrStarch = "starch"
+)
+
+// DietAttrs lists the supported ingredient attributes
+var DietAttrs = []string{
+	DietAttrMeat, DietAttrFish, DietAttrDairy, DietAttrEgg, DietAttrHoney, DietAttrGluten, DietAttrSugar, DietAttrStarch,
+}
+
+// Diets lists the supported diets in the order they are reported
+var Diets = []string{DietVegan, DietVegetarian, DietGlutenFree, DietKeto}
+
+// DietRules maps each diet to the ingredient attributes that rule it out
+var DietRules = map[string][]string{
+	DietVegan:      {DietAttrMeat, DietAttrFish, DietAttrDairy, DietAttrEgg, DietAttrHoney},
+	DietVegetarian: {DietAttrMeat, DietAttrFish},
+	DietGlutenFree: {DietAttrGluten},
+	DietKeto:       {DietAttrSugar, DietAttrStarch},
+}
+
+// DietAttributes maps ingredient names to the diet attributes they carry (pork > meat, flour > gluten)
+type DietAttributes map[string][]string
+
+// DietOverride a diet label set by the recipe author in place of the automatic classification, Automatic keeps
+// what the classification says so overrides stay visible
+type DietOverride struct {
+	RecipeID  int64
+	Diet      string
+	Label     bool
+	Automatic bool
+	UserID    int64
+	Note      string
+	CreatedAt string
+}
+
+// DietOverrides slice of diet override entities
+type DietOverrides []DietOverride
+
+// IsDiet reports whether diet is one of the supported diets
+func IsDiet(diet string) bool {
+	_, ok := DietRules[diet]
+	return ok
+}
+
+// Classify returns the diets that recipes made of ingredients fit. Ingredients inherit the attributes of their
+// taxonomy ancestors when tree is not nil, ingredients without attributes fit every diet.
+func (da DietAttributes) Classify(ingredients []string, tree *TaxonomyTree) []string {
+	found := make(map[string]struct{})
+	for i := range ingredients {
+		names := []string{normalizeName(ingredients[i])}
+		if tree != nil {
+			names = tree.Lineage(ingredients[i])
+		}
+
+		for _, name := range names {
+			for _, attr := range da[name] {
+				found[attr] = struct{}{}
+			}
+		}
+	}
+
+	diets := []string{}
+	for _, diet := range Diets {
+		fits := true
+		for _, attr := range DietRules[diet] {
+			if _, ok := found[attr]; ok {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			diets = append(diets, diet)
+		}
+	}
+
+	return diets
+}
@@ -0,0 +1,54 @@
+package database_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestDietAttributes_Classify(t *testing.T) {
+	attrs := database.DietAttributes{
+		"dairy":  {database.DietAttrDairy},
+		"eggs":   {database.DietAttrEgg},
+		"pork":   {database.DietAttrMeat},
+		"flour":  {database.DietAttrGluten, database.DietAttrStarch},
+		"honey":  {database.DietAttrHoney, database.DietAttrSugar},
+		"salmon": {database.DietAttrFish},
+	}
+	tree := database.NewTaxonomyTree(database.Taxonomies{
+		{ID: 1, Name: "dairy"},
+		{ID: 2, ParentID: 1, Name: "cheese"},
+		{ID: 3, ParentID: 2, Name: "feta"},
+	})
+
+	testData := []struct {
+		desc        string
+		ingredients []string
+		tree        *database.TaxonomyTree
+		expected    []string
+	}{
+		{"Should fit every diet", []string{"tomato", "olive oil"}, tree,
+			[]string{database.DietVegan, database.DietVegetarian, database.DietGlutenFree, database.DietKeto}},
+		{"Should inherit attributes from ancestors", []string{"tomato", "Feta"}, tree,
+			[]string{database.DietVegetarian, database.DietGlutenFree, database.DietKeto}},
+		{"Should not inherit attributes without a tree", []string{"tomato", "feta"}, nil,
+			[]string{database.DietVegan, database.DietVegetarian, database.DietGlutenFree, database.DietKeto}},
+		{"Should rule out vegetarian diets", []string{"pork", "salmon"}, tree,
+			[]string{database.DietGlutenFree, database.DietKeto}},
+		{"Should rule out gluten and keto", []string{"flour", "honey", "eggs"}, tree,
+			[]string{database.DietVegetarian}},
+		{"Should fit every diet without ingredients", nil, tree,
+			[]string{database.DietVegan, database.DietVegetarian, database.DietGlutenFree, database.DietKeto}},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if diets := attrs.Classify(tc.ingredients, tc.tree); !reflect.DeepEqual(diets, tc.expected) {
+				t.Fatalf("Expected diets %v got %v", tc.expected, diets)
+			}
+		})
+	}
+}
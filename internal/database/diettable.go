@@ -0,0 +1,287 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// DietTable object
+type DietTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewDietTable create a DietTable object
+func NewDietTable(db *sql.DB) *DietTable {
+	return &DietTable{
+		db:   db,
+		name: "diet_attribute d",
+	}
+}
+
+// Attributes returns the diet attributes of all ingredients that have any
+func (dt *DietTable) Attributes() (DietAttributes, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT d.ingredient, d.attribute FROM %s ORDER BY d.ingredient, d.attribute`, dt.name)
+
+	rows, err := dt.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("diet error, %w", err)
+	}
+	defer rows.Close()
+
+	attrs := DietAttributes{}
+	for rows.Next() {
+		var ingredient, attr string
+		if err := rows.Scan(&ingredient, &attr); err != nil {
+			return nil, err
+		}
+		attrs[ingredient] = append(attrs[ingredient], attr)
+	}
+
+	return attrs, rows.Err()
+}
+
+// Import replaces the attributes of the given ingredients and reclassifies all recipes, returns the number of
+// imported ingredients
+func (dt *DietTable) Import(attrs DietAttributes) (int, error) {
+	err := transaction(dt.db, func(tx *sql.Tx) error {
+		for ingredient, list := range attrs {
+			name := normalizeName(ingredient)
+			if _, err := tx.Exec(`DELETE FROM diet_attribute WHERE ingredient = ?`, name); err != nil {
+				return fmt.Errorf("diet error, %w", err)
+			}
+			if len(list) == 0 {
+				continue
+			}
+
+			var args []interface{}
+			for i := range list {
+				args = append(args, name, normalizeName(list[i]))
+			}
+			// nolint:gosec
+			q := fmt.Sprintf(`INSERT IGNORE INTO diet_attribute (ingredient, attribute) VALUES %s`,
+				strings.TrimSuffix(strings.Repeat("(?, ?),", len(list)), ","),
+			)
+			if _, err := tx.Exec(q, args...); err != nil {
+				return fmt.Errorf("diet error, %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(attrs), dt.Classify()
+}
+
+// Classify recomputes the automatic diet labels of recipes, of all recipes when no ids are given. Author overrides
+// are kept.
+func (dt *DietTable) Classify(recipeIDs ...int64) error {
+	attrs, tree, err := dietClassifier(dt.db)
+	if err != nil {
+		return err
+	}
+
+	var args []interface{}
+	query := `SELECT i.recipe_id, i.name FROM ingredient i`
+	if len(recipeIDs) > 0 {
+		query += fmt.Sprintf(" WHERE i.recipe_id IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(recipeIDs)), ","))
+		for i := range recipeIDs {
+			args = append(args, recipeIDs[i])
+		}
+	}
+
+	rows, err := dt.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("diet error, %w", err)
+	}
+	defer rows.Close()
+
+	ingredients := make(map[int64][]string)
+	for rows.Next() {
+		var recipeID int64
+		var name string
+		if err := rows.Scan(&recipeID, &name); err != nil {
+			return err
+		}
+		ingredients[recipeID] = append(ingredients[recipeID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return transaction(dt.db, func(tx *sql.Tx) error {
+		for recipeID := range ingredients {
+			if err := setRecipeDiets(tx, recipeID, attrs.Classify(ingredients[recipeID], tree)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Override records the diet label an author sets on a recipe in place of the automatic one
+func (dt *DietTable) Override(recipeID int64, o DietOverride) error {
+	q := `INSERT INTO recipe_diet (recipe_id, diet, override, override_user_id, override_note, overridden_at)
+VALUES (?, ?, ?, ?, ?, NOW())
+ON DUPLICATE KEY UPDATE override = VALUES(override), override_user_id = VALUES(override_user_id),
+override_note = VALUES(override_note), overridden_at = VALUES(overridden_at)`
+
+	if _, err := dt.db.Exec(q, recipeID, o.Diet, o.Label, nullID(o.UserID), o.Note); err != nil {
+		return fmt.Errorf("diet error, %w", err)
+	}
+
+	return nil
+}
+
+// ByUser lists the diet overrides set by a user, ordered by recipe
+func (dt *DietTable) ByUser(userID int64) (DietOverrides, error) {
+	q := `SELECT rd.recipe_id, rd.diet, rd.override, rd.automatic, rd.override_user_id, rd.override_note, rd.overridden_at
+FROM recipe_diet rd
+WHERE rd.override_user_id = ? AND rd.override IS NOT NULL
+ORDER BY rd.recipe_id, rd.diet`
+
+	rows, err := dt.db.Query(q, userID)
+	if err != nil {
+		return nil, fmt.Errorf("diet error, %w", err)
+	}
+	defer rows.Close()
+
+	var overrides DietOverrides
+	for rows.Next() {
+		o := DietOverride{}
+		if err := rows.Scan(&o.RecipeID, &o.Diet, &o.Label, &o.Automatic, &o.UserID, &o.Note, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+
+	return overrides, rows.Err()
+}
+
+// ClearOverride removes the author override of a recipe diet, the automatic label applies again
+func (dt *DietTable) ClearOverride(recipeID int64, diet string) error {
+	q := `UPDATE recipe_diet SET override = NULL, override_user_id = NULL, override_note = '', overridden_at = NULL
+WHERE recipe_id = ? AND diet = ? AND override IS NOT NULL`
+
+	res, err := dt.db.Exec(q, recipeID, diet)
+	if err != nil {
+		return fmt.Errorf("diet error, %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// dietClassifier loads what recipes are classified with, the attribute table and the ingredient taxonomy
+func dietClassifier(db *sql.DB) (DietAttributes, *TaxonomyTree, error) {
+	attrs, err := NewDietTable(db).Attributes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tree, err := NewTaxonomyTable(db).Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attrs, tree, nil
+}
+
+// setRecipeDiets stores the automatic labels of a recipe inside a transaction, diets not in fits are labeled false
+func setRecipeDiets(tx *sql.Tx, recipeID int64, fits []string) error {
+	var args []interface{}
+	for _, diet := range Diets {
+		automatic := false
+		for i := range fits {
+			if fits[i] == diet {
+				automatic = true
+			}
+		}
+		args = append(args, recipeID, diet, automatic)
+	}
+
+	// nolint:gosec
+	q := fmt.Sprintf(`INSERT INTO recipe_diet (recipe_id, diet, automatic) VALUES %s
+ON DUPLICATE KEY UPDATE automatic = VALUES(automatic)`,
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?),", len(Diets)), ","),
+	)
+	if _, err := tx.Exec(q, args...); err != nil {
+		return fmt.Errorf("diet error, %w", err)
+	}
+
+	return nil
+}
+
+// recipeDiets returns the effective diet labels and the author overrides of recipes by recipe id, in the order of
+// Diets
+func recipeDiets(db *sql.DB, recipeIDs []interface{}) (map[int64][]string, map[int64]DietOverrides, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT rd.recipe_id, rd.diet, rd.automatic, rd.override, rd.override_user_id, rd.override_note,
+rd.overridden_at
+FROM recipe_diet rd
+WHERE rd.recipe_id IN (%s)
+ORDER BY rd.recipe_id, FIELD(rd.diet, %s)`,
+		strings.TrimSuffix(strings.Repeat("?,", len(recipeIDs)), ","),
+		strings.TrimSuffix(strings.Repeat("?,", len(Diets)), ","),
+	)
+	args := append([]interface{}{}, recipeIDs...)
+	for i := range Diets {
+		args = append(args, Diets[i])
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("diet error, %w", err)
+	}
+	defer rows.Close()
+
+	diets := make(map[int64][]string)
+	overrides := make(map[int64]DietOverrides)
+	for rows.Next() {
+		var recipeID int64
+		var diet string
+		var automatic bool
+		var override sql.NullBool
+		var userID sql.NullInt64
+		var note string
+		var overriddenAt sql.NullString
+		if err := rows.Scan(&recipeID, &diet, &automatic, &override, &userID, &note, &overriddenAt); err != nil {
+			return nil, nil, err
+		}
+
+		label := automatic
+		if override.Valid {
+			label = override.Bool
+			overrides[recipeID] = append(overrides[recipeID], DietOverride{
+				RecipeID:  recipeID,
+				Diet:      diet,
+				Label:     override.Bool,
+				Automatic: automatic,
+				UserID:    userID.Int64,
+				Note:      note,
+				CreatedAt: overriddenAt.String,
+			})
+		}
+		if label {
+			diets[recipeID] = append(diets[recipeID], diet)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return diets, overrides, nil
+}
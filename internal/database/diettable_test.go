@@ -0,0 +1,118 @@
+package database_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestDietTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Diet.Import(database.DietAttributes{
+		"Halloumi": {database.DietAttrDairy},
+		"lamb":     {database.DietAttrMeat},
+		"pita":     {database.DietAttrGluten, database.DietAttrStarch},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, r := range []database.Recipe{
+		{Title: "Diet Grilled Halloumi", Ingredients: database.Ingredients{{Name: "halloumi"}, {Name: "mint"}}},
+		{Title: "Diet Lamb Gyros", Ingredients: database.Ingredients{{Name: "lamb"}, {Name: "pita"}}},
+		{Title: "Diet Horiatiki", Ingredients: database.Ingredients{{Name: "tomato"}, {Name: "olives"}}},
+	} {
+		r.UserID = 1
+		id, err := db.Recipe.Insert(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	// diets returns the ids of the recipes matching the diet filter
+	diets := func(diet string) []int64 {
+		recipes, _, err := db.Recipe.Paginate(1, &database.RecipeFilters{Term: "Diet ", Diet: diet})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []int64
+		for i := range recipes {
+			found = append(found, recipes[i].ID)
+		}
+		return found
+	}
+
+	recipe, err := db.Recipe.Get(uint64(ids[0]), database.Viewer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{database.DietVegetarian, database.DietGlutenFree, database.DietKeto}
+	if !reflect.DeepEqual(recipe.Diets, expected) {
+		t.Fatalf("Expected diets %v got %v", expected, recipe.Diets)
+	}
+	if found := diets(database.DietVegan); !reflect.DeepEqual(found, []int64{ids[2]}) {
+		t.Fatalf("Expected vegan recipes %v got %v", ids[2:], found)
+	}
+
+	t.Run("Should keep overrides when reclassifying", func(t *testing.T) {
+		override := database.DietOverride{Diet: database.DietVegan, Label: true, UserID: 1, Note: "vegan halloumi"}
+		if err := db.Diet.Override(ids[0], override); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Diet.Classify(ids[0]); err != nil {
+			t.Fatal(err)
+		}
+
+		recipe, err := db.Recipe.Get(uint64(ids[0]), database.Viewer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recipe.Diets) != 4 || recipe.Diets[0] != database.DietVegan {
+			t.Fatalf("Expected the override to label the recipe vegan got %v", recipe.Diets)
+		}
+		if len(recipe.DietOverrides) != 1 || recipe.DietOverrides[0].Automatic || !recipe.DietOverrides[0].Label ||
+			recipe.DietOverrides[0].Note != "vegan halloumi" || recipe.DietOverrides[0].CreatedAt == "" {
+			t.Fatalf("Expected the override to be recorded got %+v", recipe.DietOverrides)
+		}
+		if found := diets(database.DietVegan); !reflect.DeepEqual(found, []int64{ids[0], ids[2]}) {
+			t.Fatalf("Expected vegan recipes %v got %v", []int64{ids[0], ids[2]}, found)
+		}
+	})
+
+	t.Run("Should reclassify recipes after an import", func(t *testing.T) {
+		if _, err := db.Diet.Import(database.DietAttributes{"olives": {database.DietAttrFish}}); err != nil {
+			t.Fatal(err)
+		}
+		if found := diets(database.DietVegetarian); !reflect.DeepEqual(found, []int64{ids[0]}) {
+			t.Fatalf("Expected vegetarian recipes %v got %v", ids[:1], found)
+		}
+	})
+
+	t.Run("Should restore the automatic label", func(t *testing.T) {
+		if err := db.Diet.ClearOverride(ids[0], database.DietVegan); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Diet.ClearOverride(ids[0], database.DietVegan); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error got %v", err)
+		}
+		if found := diets(database.DietVegan); found != nil {
+			t.Fatalf("Expected no vegan recipes got %v", found)
+		}
+	})
+
+	attrs, err := db.Diet.Attributes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(attrs["pita"], []string{database.DietAttrGluten, database.DietAttrStarch}) ||
+		!reflect.DeepEqual(attrs["halloumi"], []string{database.DietAttrDairy}) {
+		t.Fatalf("Expected the imported attributes got %v", attrs)
+	}
+}
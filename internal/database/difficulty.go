@@ -0,0 +1,57 @@
+package database
+
+import "strings"
+
+// difficultTechniques are techniques that make a recipe harder, they are matched against the normalized title and
+// ingredient names of a recipe
+var difficultTechniques = []string{
+	"souffle", "flambe", "confit", "sous vide", "choux", "puff pastry", "laminated", "croissant", "macaron",
+	"wellington", "terrine", "consomme", "tempered", "caramel", "yeast", "gelatin", "phyllo", "filo",
+}
+
+// EstimateDifficulty estimates the difficulty of a recipe from its number of ingredients, its total time and the
+// techniques its title and ingredients mention. Recipes have no steps in this api, step counts are not considered.
+func EstimateDifficulty(recipe Recipe) string {
+	score := 0
+
+	switch n := len(recipe.Ingredients); {
+	case n >= 12:
+		score += 2
+	case n >= 8:
+		score++
+	}
+
+	total := recipe.TotalTime
+	if total == 0 {
+		total = recipe.PrepTime + recipe.CookTime
+	}
+	switch {
+	case total >= 120:
+		score += 2
+	case total >= 45:
+		score++
+	}
+
+	texts := []string{recipe.Title}
+	for i := range recipe.Ingredients {
+		texts = append(texts, recipe.Ingredients[i].Name)
+	}
+	text := " "
+	for i := range texts {
+		text += NormalizeTitle(texts[i]) + " "
+	}
+	for _, technique := range difficultTechniques {
+		if strings.Contains(text, " "+technique+" ") {
+			score += 2
+		}
+	}
+
+	switch {
+	case score <= 1:
+		return RecipeEasy
+	case score <= 3:
+		return RecipeMedium
+	default:
+		return RecipeHard
+	}
+}
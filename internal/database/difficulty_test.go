@@ -0,0 +1,43 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestEstimateDifficulty(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		recipe   database.Recipe
+		expected string
+	}{
+		{"Should rate a quick short recipe easy", database.Recipe{
+			Title: "Greek Salad", Ingredients: ingredients("tomato", "cucumber", "feta"), TotalTime: 10,
+		}, database.RecipeEasy},
+		{"Should rate a recipe without metadata easy", database.Recipe{Title: "Toast"}, database.RecipeEasy},
+		{"Should add prep and cook time", database.Recipe{
+			Title: "Moussaka", Ingredients: ingredients("eggplant", "beef", "onion", "tomato", "milk", "butter", "flour",
+				"nutmeg"), PrepTime: 60, CookTime: 90,
+		}, database.RecipeMedium},
+		{"Should weigh many ingredients", database.Recipe{
+			Title: "Stew", Ingredients: ingredients("a", "b", "c", "d", "e", "f", "g", "h"), TotalTime: 50,
+		}, database.RecipeMedium},
+		{"Should detect techniques in the title", database.Recipe{
+			Title: "Cheese Soufflé", Ingredients: ingredients("eggs", "cheese", "milk"), TotalTime: 40,
+		}, database.RecipeMedium},
+		{"Should detect techniques in ingredients", database.Recipe{
+			Title: "Beef Wellington", Ingredients: ingredients("beef", "puff pastry", "mushrooms"), TotalTime: 90,
+		}, database.RecipeHard},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if difficulty := database.EstimateDifficulty(tc.recipe); difficulty != tc.expected {
+				t.Fatalf("Expected %s got %s", tc.expected, difficulty)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package database
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/georlav/recipeapi/internal/fuzzy"
+)
+
+// Reasons a recipe is considered a near duplicate of another one
+const (
+	DuplicateTitle       = "title"
+	DuplicateURL         = "url"
+	DuplicateIngredients = "ingredients"
+)
+
+// titleSimilarity is the least similarity two title words at the same position must have for the titles to match, a
+// single typo in a long word passes but "beef" and "beet" are different words
+const titleSimilarity = 0.8
+
+// titleTokenLength is the number of leading letters of each title word used to find candidate duplicates
+const titleTokenLength = 3
+
+// titleStopWords are ignored when comparing recipe titles
+var titleStopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "in": {}, "of": {}, "the": {}, "with": {}, "recipe": {},
+}
+
+// Duplicate a recipe that looks like a near duplicate of another recipe
+type Duplicate struct {
+	Recipe            Recipe
+	Reasons           []string
+	IngredientOverlap float64
+}
+
+// Duplicates slice of duplicate objects
+type Duplicates []Duplicate
+
+// DuplicateRules thresholds used to detect near duplicate recipes. Recipes are near duplicates when their normalized
+// titles have the same words give or take a typo, when they point to the same canonical source url or when their
+// ingredient lists overlap by at least IngredientOverlap (jaccard similarity), the last rule only applies to recipes
+// that have at least MinIngredients ingredients.
+type DuplicateRules struct {
+	IngredientOverlap float64
+	MinIngredients    int
+}
+
+// Match returns the reasons a and b are considered near duplicates and their ingredient overlap, no reasons means
+// that the recipes are distinct
+func (dr DuplicateRules) Match(a, b Recipe) ([]string, float64) {
+	var reasons []string
+
+	if similarTitles(NormalizeTitle(a.Title), NormalizeTitle(b.Title)) {
+		reasons = append(reasons, DuplicateTitle)
+	}
+
+	if ua := CanonicalURL(a.URL); ua != "" && ua == CanonicalURL(b.URL) {
+		reasons = append(reasons, DuplicateURL)
+	}
+
+	overlap := IngredientOverlap(a.Ingredients, b.Ingredients)
+	if dr.IngredientOverlap > 0 && overlap >= dr.IngredientOverlap &&
+		len(a.Ingredients) >= dr.MinIngredients && len(b.Ingredients) >= dr.MinIngredients {
+		reasons = append(reasons, DuplicateIngredients)
+	}
+
+	return reasons, overlap
+}
+
+// Find returns the recipes from candidates that are near duplicates of recipe
+func (dr DuplicateRules) Find(recipe Recipe, candidates Recipes) Duplicates {
+	var duplicates Duplicates
+	for i := range candidates {
+		if candidates[i].ID != 0 && candidates[i].ID == recipe.ID {
+			continue
+		}
+
+		if reasons, overlap := dr.Match(recipe, candidates[i]); len(reasons) > 0 {
+			duplicates = append(duplicates, Duplicate{
+				Recipe:            candidates[i],
+				Reasons:           reasons,
+				IngredientOverlap: overlap,
+			})
+		}
+	}
+
+	return duplicates
+}
+
+// Clusters groups recipes that are near duplicates of each other, directly or through another recipe of the group.
+// Recipes without duplicates are not returned. Only the pairs returned by candidates are compared.
+func (dr DuplicateRules) Clusters(recipes Recipes) []Recipes {
+	parent := make([]int, len(recipes))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	for _, p := range dr.candidates(recipes) {
+		if reasons, _ := dr.Match(recipes[p[0]], recipes[p[1]]); len(reasons) > 0 {
+			parent[root(p[1])] = root(p[0])
+		}
+	}
+
+	groups := make(map[int]Recipes)
+	var order []int
+	for i := range recipes {
+		r := root(i)
+		if _, ok := groups[r]; !ok {
+			order = append(order, r)
+		}
+		groups[r] = append(groups[r], recipes[i])
+	}
+
+	var clusters []Recipes
+	for _, r := range order {
+		if len(groups[r]) > 1 {
+			clusters = append(clusters, groups[r])
+		}
+	}
+
+	return clusters
+}
+
+// candidates returns the index pairs of the recipes that may be near duplicates. Recipes are grouped by canonical url,
+// by the leading letters of their title words and by their rarest ingredients, only recipes sharing a group are paired.
+// A recipe pair overlapping by the rules shares at least one ingredient among the rarest len-shared+1 of either
+// recipe, so the common ingredients that would put every recipe in one group are never used.
+func (dr DuplicateRules) candidates(recipes Recipes) [][2]int {
+	groups := make(map[string][]int)
+	for i := range recipes {
+		if u := CanonicalURL(recipes[i].URL); u != "" {
+			groups["url:"+u] = append(groups["url:"+u], i)
+		}
+		for _, t := range titleTokens(recipes[i].Title) {
+			groups["title:"+t] = append(groups["title:"+t], i)
+		}
+	}
+
+	if dr.IngredientOverlap > 0 {
+		names := make([][]string, len(recipes))
+		frequency := make(map[string]int)
+		for i := range recipes {
+			names[i] = ingredientNames(recipes[i].Ingredients)
+			for _, n := range names[i] {
+				frequency[n]++
+			}
+		}
+
+		for i := range recipes {
+			if len(names[i]) == 0 || len(recipes[i].Ingredients) < dr.MinIngredients {
+				continue
+			}
+
+			sort.Slice(names[i], func(a, b int) bool {
+				na, nb := names[i][a], names[i][b]
+				if frequency[na] != frequency[nb] {
+					return frequency[na] < frequency[nb]
+				}
+				return na < nb
+			})
+
+			prefix := len(names[i]) - sharedIngredients(dr.IngredientOverlap, len(names[i])) + 1
+			if prefix < 1 {
+				prefix = 1
+			}
+			for _, n := range names[i][:prefix] {
+				groups["ingredient:"+n] = append(groups["ingredient:"+n], i)
+			}
+		}
+	}
+
+	seen := make(map[[2]int]struct{})
+	var pairs [][2]int
+	for _, group := range groups {
+		for a := range group {
+			for b := a + 1; b < len(group); b++ {
+				p := [2]int{group[a], group[b]}
+				if _, ok := seen[p]; !ok {
+					seen[p] = struct{}{}
+					pairs = append(pairs, p)
+				}
+			}
+		}
+	}
+
+	return pairs
+}
+
+// similarTitles reports whether two normalized titles have the same number of words and every word is at least
+// titleSimilarity similar to the word at the same position
+func similarTitles(a, b string) bool {
+	wa, wb := strings.Fields(a), strings.Fields(b)
+	if len(wa) == 0 || len(wa) != len(wb) {
+		return false
+	}
+
+	for i := range wa {
+		if similarity(wa[i], wb[i]) < titleSimilarity {
+			return false
+		}
+	}
+
+	return true
+}
+
+// similarity returns one minus the edit distance of a and b relative to the length of the longest, 1 means equal
+func similarity(a, b string) float64 {
+	longest := len([]rune(a))
+	if l := len([]rune(b)); l > longest {
+		longest = l
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(fuzzy.Distance(a, b))/float64(longest)
+}
+
+// titleTokens returns the distinct leading letters of the normalized title words, titles that match share at least
+// one of them unless every word has a typo near its start
+func titleTokens(title string) []string {
+	seen := make(map[string]struct{})
+	var tokens []string
+	for _, w := range strings.Fields(NormalizeTitle(title)) {
+		r := []rune(w)
+		if len(r) < titleTokenLength {
+			continue
+		}
+
+		t := string(r[:titleTokenLength])
+		if _, ok := seen[t]; !ok {
+			seen[t] = struct{}{}
+			tokens = append(tokens, t)
+		}
+	}
+
+	return tokens
+}
+
+// ingredientNames returns the distinct normalized names of ingredients
+func ingredientNames(ingredients Ingredients) []string {
+	seen := make(map[string]struct{})
+	var names []string
+	for i := range ingredients {
+		n := normalizeName(ingredients[i].Name)
+		if _, ok := seen[n]; !ok {
+			seen[n] = struct{}{}
+			names = append(names, n)
+		}
+	}
+
+	return names
+}
+
+// sharedIngredients returns the least number of ingredients a recipe with n distinct ingredients shares with any
+// recipe it overlaps by at least overlap
+func sharedIngredients(overlap float64, n int) int {
+	// The epsilon keeps products such as 0.8*5 from rounding up to the next integer
+	return int(math.Ceil(overlap*float64(n) - 1e-9))
+}
+
+// NormalizeTitle folds a recipe title and strips punctuation and stop words, "The Potato & Cheese Frittata!" becomes
+// "potato cheese frittata"
+func NormalizeTitle(title string) string {
+	words := strings.FieldsFunc(fuzzy.Fold(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	normalized := words[:0]
+	for _, w := range words {
+		if _, ok := titleStopWords[w]; !ok {
+			normalized = append(normalized, w)
+		}
+	}
+
+	return strings.Join(normalized, " ")
+}
+
+// CanonicalURL reduces a source url to its lower cased host and path, scheme, www prefix, query, fragment and
+// trailing slashes are dropped. Returns an empty string for urls without a host.
+func CanonicalURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	return host + strings.ToLower(strings.TrimRight(u.EscapedPath(), "/"))
+}
+
+// IngredientOverlap returns the jaccard similarity of two ingredient lists, 1 means same ingredients
+func IngredientOverlap(a, b Ingredients) float64 {
+	set := make(map[string]bool)
+	for i := range a {
+		set[normalizeName(a[i].Name)] = false
+	}
+
+	var shared int
+	union := len(set)
+	for i := range b {
+		name := normalizeName(b[i].Name)
+		seen, ok := set[name]
+		switch {
+		case !ok:
+			set[name] = true
+			union++
+		case !seen:
+			set[name] = true
+			shared++
+		}
+	}
+
+	if union == 0 {
+		return 0
+	}
+
+	return float64(shared) / float64(union)
+}
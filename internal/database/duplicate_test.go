@@ -0,0 +1,196 @@
+package database_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func ingredients(names ...string) database.Ingredients {
+	var ing database.Ingredients
+	for i := range names {
+		ing = append(ing, database.Ingredient{Name: names[i]})
+	}
+
+	return ing
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	testCases := []struct {
+		input  string
+		output string
+	}{
+		{"Potato and Cheese Frittata", "potato cheese frittata"},
+		{"The Potato & Cheese Frittata!", "potato cheese frittata"},
+		{"Crème Brûlée", "creme brulee"},
+		{"Isa's Cola de Mono", "isa s cola de mono"},
+		{"  ", ""},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.input, func(t *testing.T) {
+			if output := database.NormalizeTitle(tc.input); output != tc.output {
+				t.Fatalf("Expected %q got %q", tc.output, output)
+			}
+		})
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	testCases := []struct {
+		input  string
+		output string
+	}{
+		{"http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx", "allrecipes.com/recipe/ginger-champagne/detail.aspx"},
+		{"https://www.AllRecipes.com/Recipe/Ginger-Champagne/Detail.aspx/?utm=1#top", "allrecipes.com/recipe/ginger-champagne/detail.aspx"},
+		{"allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx", "allrecipes.com/recipe/ginger-champagne/detail.aspx"},
+		{"", ""},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.input, func(t *testing.T) {
+			if output := database.CanonicalURL(tc.input); output != tc.output {
+				t.Fatalf("Expected %q got %q", tc.output, output)
+			}
+		})
+	}
+}
+
+func TestIngredientOverlap(t *testing.T) {
+	testCases := []struct {
+		a, b   database.Ingredients
+		output float64
+	}{
+		{ingredients("eggs", "salt"), ingredients("Salt ", "eggs"), 1},
+		{ingredients("eggs", "salt", "milk"), ingredients("eggs", "salt", "flour"), 0.5},
+		{ingredients("eggs", "eggs"), ingredients("eggs", "salt"), 0.5},
+		{ingredients("eggs"), ingredients("salt"), 0},
+		{nil, nil, 0},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(fmt.Sprintf("%v %v", tc.a, tc.b), func(t *testing.T) {
+			if output := database.IngredientOverlap(tc.a, tc.b); output != tc.output {
+				t.Fatalf("Expected %f got %f", tc.output, output)
+			}
+		})
+	}
+}
+
+func TestDuplicateRules_Match(t *testing.T) {
+	rules := database.DuplicateRules{IngredientOverlap: 0.8, MinIngredients: 3}
+	recipe := database.Recipe{
+		Title:       "Ginger Champagne",
+		URL:         "http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx",
+		Ingredients: ingredients("champagne", "ginger", "ice", "vodka"),
+	}
+
+	testCases := []struct {
+		desc    string
+		input   database.Recipe
+		reasons []string
+	}{
+		{
+			"Should match a title with a typo",
+			database.Recipe{Title: "Ginger Champagne2", Ingredients: ingredients("eggs")},
+			[]string{database.DuplicateTitle},
+		},
+		{
+			"Should match the same source url",
+			database.Recipe{Title: "Champagne cocktail", URL: "https://www.allrecipes.com/recipe/ginger-champagne/detail.aspx/"},
+			[]string{database.DuplicateURL},
+		},
+		{
+			"Should match the same ingredients",
+			database.Recipe{Title: "Sparkling ginger", Ingredients: ingredients("vodka", "ice", "ginger", "champagne")},
+			[]string{database.DuplicateIngredients},
+		},
+		{
+			"Should match on every rule",
+			database.Recipe{Title: "ginger champagne", URL: recipe.URL, Ingredients: recipe.Ingredients},
+			[]string{database.DuplicateTitle, database.DuplicateURL, database.DuplicateIngredients},
+		},
+		{
+			"Should not match a distinct recipe",
+			database.Recipe{Title: "Irish Champ", URL: "http://allrecipes.com/Recipe/Irish-Champ/Detail.aspx",
+				Ingredients: ingredients("butter", "milk", "potato", "ginger")},
+			nil,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if reasons, _ := rules.Match(recipe, tc.input); !reflect.DeepEqual(reasons, tc.reasons) {
+				t.Fatalf("Expected reasons %v got %v", tc.reasons, reasons)
+			}
+		})
+	}
+
+	short := database.Recipe{Title: "Punch", Ingredients: ingredients("water", "sugar")}
+	if reasons, _ := rules.Match(short, database.Recipe{Title: "Lemonade", Ingredients: short.Ingredients}); reasons != nil {
+		t.Fatalf("Expected ingredient rule to skip recipes with few ingredients, got %v", reasons)
+	}
+
+	titles := []struct {
+		a, b  string
+		match bool
+	}{
+		{"Ginger Champagne", "Ginger Champange", true},
+		{"Chocolate Cake", "Chocolate Cakes", true},
+		{"Potato Salad", "Tomato Salad", false},
+		{"Beef Stew", "Beet Stew", false},
+		{"Ginger Champagne", "Champagne Ginger", false},
+		{"Shrimp Gumbo", "Shrimp Gumbo Soup", false},
+	}
+	for _, tc := range titles {
+		reasons, _ := rules.Match(database.Recipe{Title: tc.a}, database.Recipe{Title: tc.b})
+		if match := len(reasons) > 0; match != tc.match {
+			t.Fatalf("Expected match %t for titles %q and %q got %v", tc.match, tc.a, tc.b, reasons)
+		}
+	}
+}
+
+func TestDuplicateRules_Clusters(t *testing.T) {
+	rules := database.DuplicateRules{IngredientOverlap: 0.8, MinIngredients: 3}
+	recipes := database.Recipes{
+		{ID: 1, Title: "Ginger Champagne", URL: "http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx"},
+		{ID: 2, Title: "Irish Champ", URL: "http://allrecipes.com/Recipe/Irish-Champ/Detail.aspx"},
+		{ID: 3, Title: "Champagne with ginger", URL: "http://www.allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx"},
+		{ID: 4, Title: "Champagne and Ginger", URL: "http://example.com/champagne"},
+		{ID: 5, Title: "Shrimp Gumbo", URL: "http://allrecipes.com/Recipe/Shrimp-Gumbo/Detail.aspx"},
+		{ID: 6, Title: "Irish Champ", URL: "http://example.com/champ"},
+		{ID: 7, Title: "Beef Stew", Ingredients: ingredients("salt", "beef", "carrot", "onion", "wine")},
+		{ID: 8, Title: "Beet Stew", Ingredients: ingredients("salt", "beet", "carrot", "onion")},
+		{ID: 9, Title: "Sunday Roast", Ingredients: ingredients("salt", "beef", "carrot", "onion", "wine")},
+	}
+
+	clusters := rules.Clusters(recipes)
+	if len(clusters) != 3 {
+		t.Fatalf("Expected 3 clusters got %d, %+v", len(clusters), clusters)
+	}
+
+	expected := [][]int64{{1, 3, 4}, {2, 6}, {7, 9}}
+	for i := range expected {
+		var ids []int64
+		for _, r := range clusters[i] {
+			ids = append(ids, r.ID)
+		}
+		if !reflect.DeepEqual(ids, expected[i]) {
+			t.Fatalf("Expected cluster %v got %v", expected[i], ids)
+		}
+	}
+
+	if duplicates := rules.Find(recipes[0], recipes); len(duplicates) != 1 || duplicates[0].Recipe.ID != 3 {
+		t.Fatalf("Expected recipe 3 to be the only duplicate of recipe 1, got %+v", duplicates)
+	}
+}
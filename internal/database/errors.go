@@ -6,4 +6,8 @@ import (
 )
 
 var ErrDuplicateEntry = errors.New("already exists")
+var ErrEmailTaken = errors.New("email address is already used")
 var ErrNoRows = sql.ErrNoRows
+var ErrStatusTransition = errors.New("invalid status transition")
+var ErrTaxonomyCycle = errors.New("taxonomy node would be its own ancestor")
+var ErrTokenReused = errors.New("refresh token was already used")
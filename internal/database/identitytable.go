@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// IdentityTable object
+type IdentityTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewIdentityTable create an IdentityTable object
+func NewIdentityTable(db *sql.DB) *IdentityTable {
+	return &IdentityTable{
+		db:   db,
+		name: "user_identity i",
+	}
+}
+
+// Get the identity of provider with the given subject
+func (it *IdentityTable) Get(provider string, subject string) (*Identity, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT i.id, i.user_id, i.provider, i.subject, i.email, i.created_at FROM %s
+WHERE i.provider = ? AND i.subject = ?`, it.name)
+
+	i := Identity{}
+	if err := it.db.QueryRow(query, provider, subject).Scan(
+		&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// ByUser lists the identities linked to a user, oldest first
+func (it *IdentityTable) ByUser(userID int64) (Identities, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT i.id, i.user_id, i.provider, i.subject, i.email, i.created_at FROM %s
+WHERE i.user_id = ? ORDER BY i.id`, it.name)
+
+	rows, err := it.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities Identities
+	for rows.Next() {
+		i := Identity{}
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+
+	return identities, rows.Err()
+}
+
+// Link the identity to its user, returns ErrDuplicateEntry when the identity is already linked
+func (it *IdentityTable) Link(i Identity) (int64, error) {
+	return insertIdentity(it.db, i)
+}
+
+// InsertUser creates a user together with the identity they signed in with, returns the id of the user. Returns
+// ErrEmailTaken or ErrDuplicateEntry like UserTable.Insert.
+func (it *IdentityTable) InsertUser(u User, i Identity) (int64, error) {
+	var userID int64
+	err := transaction(it.db, func(tx *sql.Tx) error {
+		var err error
+		if userID, err = insertUser(tx, u); err != nil {
+			return err
+		}
+
+		i.UserID = userID
+		_, err = insertIdentity(tx, i)
+
+		return err
+	})
+
+	return userID, err
+}
+
+func insertIdentity(db execer, i Identity) (int64, error) {
+	q := `INSERT INTO user_identity (user_id, provider, subject, email) VALUES (?, ?, ?, ?)`
+	res, err := db.Exec(q, i.UserID, i.Provider, i.Subject, i.Email)
+	if err != nil {
+		return 0, duplicateError(err)
+	}
+
+	return res.LastInsertId()
+}
@@ -11,3 +11,12 @@ type Ingredient struct {
 
 // Ingredients slice or recipe ingredient entities
 type Ingredients []Ingredient
+
+// IngredientName a distinct ingredient name and the number of recipes that use it
+type IngredientName struct {
+	Name    string
+	Recipes int64
+}
+
+// IngredientNames slice of distinct ingredient names
+type IngredientNames []IngredientName
@@ -7,7 +7,7 @@ import (
 
 const ingredientColumns = "i.id, i.recipe_id, i.name, i.created_at, i.updated_at"
 
-// RecipeTable object
+// IngredientTable object
 type IngredientTable struct {
 	db   *sql.DB
 	name string
@@ -21,13 +21,15 @@ func NewIngredientTable(db *sql.DB) *IngredientTable {
 	}
 }
 
-// Get a recipe by id
-func (it *IngredientTable) Get(id uint64) (*Ingredient, error) {
+// Get an ingredient by id, ingredients of recipes the viewer can not read are not found
+func (it *IngredientTable) Get(id uint64, v Viewer) (*Ingredient, error) {
+	cond, args := v.visibility(false)
 	// nolint:gosec
-	query := fmt.Sprintf(`SELECT %s FROM ingredient i WHERE id = ?`, ingredientColumns)
+	query := fmt.Sprintf(`SELECT %s FROM %s JOIN recipe r ON r.id = i.recipe_id
+WHERE i.id = ? AND i.deleted_at IS NULL AND %s`, ingredientColumns, it.name, cond)
 
 	var i Ingredient
-	if err := it.db.QueryRow(query, id).Scan(
+	if err := it.db.QueryRow(query, append([]interface{}{id}, args...)...).Scan(
 		&i.ID, &i.RecipeID, &i.Name, &i.CreatedAt, &i.UpdatedAt,
 	); err != nil {
 		return nil, err
@@ -35,3 +37,35 @@ func (it *IngredientTable) Get(id uint64) (*Ingredient, error) {
 
 	return &i, nil
 }
+
+// Names returns the distinct ingredient names of the recipes the viewer lists ordered by the number of those recipes
+// that use them
+func (it *IngredientTable) Names(v Viewer) (IngredientNames, error) {
+	cond, args := v.visibility(true)
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT i.name, COUNT(DISTINCT i.recipe_id) AS recipes FROM %s
+JOIN recipe r ON r.id = i.recipe_id
+WHERE i.deleted_at IS NULL AND %s
+GROUP BY i.name
+ORDER BY recipes DESC, i.name`, it.name, cond)
+
+	rows, err := it.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names IngredientNames
+	for rows.Next() {
+		n := IngredientName{}
+		if err := rows.Scan(&n.Name, &n.Recipes); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
@@ -42,7 +42,7 @@ func TestIngredientTable_Get(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.desc, func(t *testing.T) {
-			ingredient, err := db.Ingredient.Get(tc.input)
+			ingredient, err := db.Ingredient.Get(tc.input, database.Viewer{})
 			if err != nil && !errors.Is(err, tc.error) {
 				t.Fatal(err)
 			}
@@ -55,3 +55,27 @@ func TestIngredientTable_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestIngredientTable_Names(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := db.Ingredient.Names(database.Viewer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) == 0 {
+		t.Fatal("Expected to have ingredient names")
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i].Recipes > names[i-1].Recipes {
+			t.Fatalf("Expected names to be ordered by popularity, %+v", names[i-1:i+1])
+		}
+		if names[i].Name == names[i-1].Name {
+			t.Fatalf("Expected names to be distinct, %s", names[i].Name)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Nutrient entity, the nutrition facts of 100g of a canonical ingredient. Calories are in kcal, sodium in mg and the
+// rest in grams.
+type Nutrient struct {
+	Ingredient string
+	Calories   float64
+	Protein    float64
+	Fat        float64
+	Carbs      float64
+	Fiber      float64
+	Sodium     float64
+	CreatedAt  string
+	UpdatedAt  string
+}
+
+// Nutrients slice of nutrient entities
+type Nutrients []Nutrient
+
+// NutrientIndex maps canonical ingredient names to their nutrition facts
+type NutrientIndex map[string]Nutrient
+
+// Match returns the nutrition facts of an ingredient, ingredients without their own facts use the ones of their
+// closest taxonomy ancestor when tree is not nil. Reports false when nothing matches.
+func (ni NutrientIndex) Match(name string, tree *TaxonomyTree) (Nutrient, bool) {
+	names := []string{normalizeName(name)}
+	if tree != nil {
+		names = tree.Lineage(name)
+	}
+
+	for i := range names {
+		if n, ok := ni[names[i]]; ok {
+			return n, true
+		}
+	}
+
+	return Nutrient{}, false
+}
+
+// unitGrams maps measuring units to their weight in grams, volumes are weighed as water
+var unitGrams = map[string]float64{
+	"g": 1, "gr": 1, "gram": 1, "grams": 1,
+	"kg": 1000, "kilo": 1000, "kilos": 1000, "kilogram": 1000, "kilograms": 1000,
+	"mg": 0.001,
+	"ml": 1, "l": 1000, "liter": 1000, "liters": 1000, "litre": 1000, "litres": 1000,
+	"cup": 240, "cups": 240,
+	"tbsp": 15, "tablespoon": 15, "tablespoons": 15,
+	"tsp": 5, "teaspoon": 5, "teaspoons": 5,
+	"oz": 28.35, "ounce": 28.35, "ounces": 28.35,
+	"lb": 453.6, "lbs": 453.6, "pound": 453.6, "pounds": 453.6,
+}
+
+// IngredientQuantity an ingredient line split into its amount, unit and name. Grams is the weight of the amount,
+// zero when the line has no amount or a unit that can not be weighed like "2 eggs".
+type IngredientQuantity struct {
+	Amount float64
+	Unit   string
+	Name   string
+	Grams  float64
+}
+
+// ParseQuantity splits an ingredient line like "200g flour", "1 1/2 cups of milk" or "2 eggs" into its quantity and
+// name, lines without a leading amount are returned as the name
+func ParseQuantity(line string) IngredientQuantity {
+	fields := strings.Fields(line)
+	q := IngredientQuantity{Name: strings.TrimSpace(line)}
+	if len(fields) == 0 {
+		return q
+	}
+
+	// The unit may be glued to the amount, 200g
+	number := strings.TrimRightFunc(fields[0], func(r rune) bool {
+		return !unicode.IsDigit(r)
+	})
+	amount, ok := parseAmount(number)
+	if !ok {
+		return q
+	}
+	rest := fields[1:]
+	if unit := strings.ToLower(strings.TrimPrefix(fields[0], number)); unit != "" {
+		rest = append([]string{unit}, rest...)
+	}
+
+	// Mixed numbers, 1 1/2
+	if len(rest) > 0 && strings.Contains(rest[0], "/") {
+		if fraction, ok := parseAmount(rest[0]); ok {
+			amount += fraction
+			rest = rest[1:]
+		}
+	}
+
+	if len(rest) > 0 {
+		unit := strings.TrimSuffix(strings.ToLower(rest[0]), ".")
+		if grams, ok := unitGrams[unit]; ok {
+			q.Unit = unit
+			q.Grams = amount * grams
+			rest = rest[1:]
+			if len(rest) > 1 && strings.ToLower(rest[0]) == "of" {
+				rest = rest[1:]
+			}
+		}
+	}
+	if len(rest) == 0 {
+		return q
+	}
+
+	q.Amount = amount
+	q.Name = strings.Join(rest, " ")
+
+	return q
+}
+
+// parseAmount parses a decimal or a fraction like 1/2
+func parseAmount(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	if i := strings.Index(s, "/"); i > 0 {
+		num, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, false
+		}
+		den, err := strconv.ParseFloat(s[i+1:], 64)
+		if err != nil || den == 0 {
+			return 0, false
+		}
+		return num / den, true
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// IngredientNutrition the share of a single ingredient line in the nutrition of a recipe. Per100g is nil when the
+// ingredient could not be matched, Total is nil when it could not be matched or weighed.
+type IngredientNutrition struct {
+	Line     string
+	Quantity IngredientQuantity
+	Per100g  *Nutrient
+	Total    *Nutrient
+}
+
+// RecipeNutrition the nutrition of a recipe computed from the quantities of its ingredients. Total sums the
+// ingredients that were matched and weighed, Complete reports whether that is all of them. PerServing is nil when
+// the recipe does not say how many it serves.
+type RecipeNutrition struct {
+	Ingredients []IngredientNutrition
+	Total       Nutrient
+	PerServing  *Nutrient
+	Complete    bool
+}
+
+// Recipe computes the nutrition of a recipe from its ingredient lines
+func (ni NutrientIndex) Recipe(lines []string, servings int64, tree *TaxonomyTree) RecipeNutrition {
+	rn := RecipeNutrition{Complete: len(lines) > 0}
+
+	for i := range lines {
+		in := IngredientNutrition{Line: lines[i], Quantity: ParseQuantity(lines[i])}
+		if n, ok := ni.Match(in.Quantity.Name, tree); ok {
+			in.Per100g = &n
+			if in.Quantity.Grams > 0 {
+				total := n.scale(in.Quantity.Grams)
+				in.Total = &total
+				rn.Total.add(total)
+			}
+		}
+		if in.Total == nil {
+			rn.Complete = false
+		}
+		rn.Ingredients = append(rn.Ingredients, in)
+	}
+
+	if servings > 0 {
+		perServing := rn.Total.scale(100 / float64(servings))
+		rn.PerServing = &perServing
+	}
+
+	return rn
+}
+
+// calories returns the calories of a serving, nil when they are not known for every ingredient
+func (rn RecipeNutrition) calories() interface{} {
+	if !rn.Complete || rn.PerServing == nil {
+		return nil
+	}
+
+	return rn.PerServing.Calories
+}
+
+// scale returns the facts of the given grams of the ingredient
+func (n Nutrient) scale(grams float64) Nutrient {
+	f := grams / 100
+	return Nutrient{
+		Ingredient: n.Ingredient,
+		Calories:   n.Calories * f,
+		Protein:    n.Protein * f,
+		Fat:        n.Fat * f,
+		Carbs:      n.Carbs * f,
+		Fiber:      n.Fiber * f,
+		Sodium:     n.Sodium * f,
+	}
+}
+
+// add sums the facts of o into n
+func (n *Nutrient) add(o Nutrient) {
+	n.Calories += o.Calories
+	n.Protein += o.Protein
+	n.Fat += o.Fat
+	n.Carbs += o.Carbs
+	n.Fiber += o.Fiber
+	n.Sodium += o.Sodium
+}
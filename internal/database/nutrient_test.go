@@ -0,0 +1,85 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestNutrientIndex_Match(t *testing.T) {
+	index := database.NutrientIndex{
+		"cheese":   {Ingredient: "cheese", Calories: 402},
+		"parmesan": {Ingredient: "parmesan", Calories: 392},
+	}
+	tree := database.NewTaxonomyTree(database.Taxonomies{
+		{ID: 1, Name: "dairy"},
+		{ID: 2, ParentID: 1, Name: "cheese"},
+		{ID: 3, ParentID: 2, Name: "parmesan"},
+		{ID: 4, ParentID: 2, Name: "feta"},
+	})
+
+	testData := []struct {
+		desc       string
+		ingredient string
+		tree       *database.TaxonomyTree
+		expected   string
+	}{
+		{"Should match an ingredient", "Parmesan", tree, "parmesan"},
+		{"Should match the closest ancestor", "feta", tree, "cheese"},
+		{"Should not match ancestors without a tree", "feta", nil, ""},
+		{"Should not match unknown ingredients", "dairy", tree, ""},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			n, ok := index.Match(tc.ingredient, tc.tree)
+			if ok != (tc.expected != "") || n.Ingredient != tc.expected {
+				t.Fatalf("Expected %s to match %q got %q", tc.ingredient, tc.expected, n.Ingredient)
+			}
+		})
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	testData := []struct {
+		desc     string
+		line     string
+		expected database.IngredientQuantity
+	}{
+		{"Should parse a unit glued to the amount", "200g flour", database.IngredientQuantity{
+			Amount: 200, Unit: "g", Name: "flour", Grams: 200}},
+		{"Should parse mixed numbers", "1 1/2 cups of milk", database.IngredientQuantity{
+			Amount: 1.5, Unit: "cups", Name: "milk", Grams: 360}},
+		{"Should not weigh countable ingredients", "2 eggs", database.IngredientQuantity{Amount: 2, Name: "eggs"}},
+		{"Should keep lines without an amount", "salt and pepper", database.IngredientQuantity{Name: "salt and pepper"}},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if q := database.ParseQuantity(tc.line); q != tc.expected {
+				t.Fatalf("Expected %q to parse to %+v got %+v", tc.line, tc.expected, q)
+			}
+		})
+	}
+}
+
+func TestNutrientIndex_Recipe(t *testing.T) {
+	index := database.NutrientIndex{
+		"flour": {Ingredient: "flour", Calories: 364, Protein: 10},
+		"milk":  {Ingredient: "milk", Calories: 42},
+	}
+
+	rn := index.Recipe([]string{"500g flour", "1 cup milk"}, 4, nil)
+	if !rn.Complete || rn.Total.Calories != 1920.8 || rn.PerServing == nil || rn.PerServing.Calories != 480.2 {
+		t.Fatalf("Expected 1920.8 calories and 480.2 per serving got %+v", rn)
+	}
+
+	rn = index.Recipe([]string{"500g flour", "2 eggs"}, 0, nil)
+	if rn.Complete || rn.PerServing != nil || rn.Ingredients[1].Per100g != nil {
+		t.Fatalf("Expected an incomplete recipe without servings got %+v", rn)
+	}
+}
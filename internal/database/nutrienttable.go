@@ -0,0 +1,165 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const nutrientColumns = "n.ingredient, n.calories, n.protein, n.fat, n.carbs, n.fiber, n.sodium, n.created_at, n.updated_at"
+
+// NutrientTable object
+type NutrientTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewNutrientTable create a NutrientTable object
+func NewNutrientTable(db *sql.DB) *NutrientTable {
+	return &NutrientTable{
+		db:   db,
+		name: "nutrient n",
+	}
+}
+
+// List all nutrients ordered by ingredient
+func (nt *NutrientTable) List() (Nutrients, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY n.ingredient`, nutrientColumns, nt.name)
+
+	rows, err := nt.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("nutrient error, %w", err)
+	}
+	defer rows.Close()
+
+	nutrients := Nutrients{}
+	for rows.Next() {
+		n := Nutrient{}
+		if err := rows.Scan(
+			&n.Ingredient, &n.Calories, &n.Protein, &n.Fat, &n.Carbs, &n.Fiber, &n.Sodium, &n.CreatedAt, &n.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		nutrients = append(nutrients, n)
+	}
+
+	return nutrients, rows.Err()
+}
+
+// Index returns all nutrients keyed by ingredient
+func (nt *NutrientTable) Index() (NutrientIndex, error) {
+	nutrients, err := nt.List()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(NutrientIndex, len(nutrients))
+	for i := range nutrients {
+		index[nutrients[i].Ingredient] = nutrients[i]
+	}
+
+	return index, nil
+}
+
+// Import inserts nutrients or replaces the facts of ingredients that already have some, returns the number of
+// imported nutrients
+func (nt *NutrientTable) Import(nutrients Nutrients) (int, error) {
+	if len(nutrients) == 0 {
+		return 0, nil
+	}
+
+	var args []interface{}
+	for i := range nutrients {
+		n := nutrients[i]
+		args = append(args, normalizeName(n.Ingredient), n.Calories, n.Protein, n.Fat, n.Carbs, n.Fiber, n.Sodium)
+	}
+
+	// nolint:gosec
+	q := fmt.Sprintf(`INSERT INTO nutrient (ingredient, calories, protein, fat, carbs, fiber, sodium) VALUES %s
+ON DUPLICATE KEY UPDATE calories = VALUES(calories), protein = VALUES(protein), fat = VALUES(fat),
+carbs = VALUES(carbs), fiber = VALUES(fiber), sodium = VALUES(sodium)`,
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?),", len(nutrients)), ","),
+	)
+
+	if _, err := nt.db.Exec(q, args...); err != nil {
+		return 0, fmt.Errorf("nutrient error, %w", err)
+	}
+
+	return len(nutrients), nt.Calculate()
+}
+
+// Calculate recomputes the calories per serving recipes are filtered by, of all recipes when no ids are given
+func (nt *NutrientTable) Calculate(recipeIDs ...int64) error {
+	index, tree, err := nutritionCalculator(nt.db)
+	if err != nil {
+		return err
+	}
+
+	var args []interface{}
+	query := `SELECT r.id, r.servings, i.name FROM recipe r JOIN ingredient i ON i.recipe_id = r.id
+WHERE i.deleted_at IS NULL`
+	if len(recipeIDs) > 0 {
+		query += fmt.Sprintf(" AND r.id IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(recipeIDs)), ","))
+		for i := range recipeIDs {
+			args = append(args, recipeIDs[i])
+		}
+	}
+
+	rows, err := nt.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("nutrient error, %w", err)
+	}
+	defer rows.Close()
+
+	servings := make(map[int64]int64)
+	ingredients := make(map[int64][]string)
+	for rows.Next() {
+		var recipeID, serves int64
+		var name string
+		if err := rows.Scan(&recipeID, &serves, &name); err != nil {
+			return err
+		}
+		servings[recipeID] = serves
+		ingredients[recipeID] = append(ingredients[recipeID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return transaction(nt.db, func(tx *sql.Tx) error {
+		for recipeID := range ingredients {
+			rn := index.Recipe(ingredients[recipeID], servings[recipeID], tree)
+			if err := setRecipeCalories(tx, recipeID, rn); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// nutritionCalculator loads what the nutrition of recipes is computed with, the nutrient index and the ingredient
+// taxonomy
+func nutritionCalculator(db *sql.DB) (NutrientIndex, *TaxonomyTree, error) {
+	index, err := NewNutrientTable(db).Index()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tree, err := NewTaxonomyTable(db).Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return index, tree, nil
+}
+
+// setRecipeCalories stores the calories per serving of a recipe, NULL when its nutrition is incomplete
+func setRecipeCalories(e execer, recipeID int64, rn RecipeNutrition) error {
+	if _, err := e.Exec(`UPDATE recipe SET calories = ? WHERE id = ?`, rn.calories(), recipeID); err != nil {
+		return fmt.Errorf("nutrient error, %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestNutrientTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total, err := db.Nutrient.Import(database.Nutrients{
+		{Ingredient: "Chickpeas", Calories: 364, Protein: 19.3, Fat: 6.04, Carbs: 60.65, Fiber: 17.4, Sodium: 24},
+		{Ingredient: "tahini", Calories: 595, Protein: 17, Fat: 53.76, Carbs: 21.19, Fiber: 9.3, Sodium: 115},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("Expected 2 imported nutrients got %d", total)
+	}
+
+	// Importing again replaces the facts
+	if _, err := db.Nutrient.Import(database.Nutrients{{Ingredient: "tahini", Calories: 570}}); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := db.Nutrient.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := index["chickpeas"]; n.Calories != 364 || n.Fiber != 17.4 {
+		t.Fatalf("Expected chickpeas facts got %+v", n)
+	}
+	if n := index["tahini"]; n.Calories != 570 || n.Fat != 0 {
+		t.Fatalf("Expected tahini facts to be replaced got %+v", n)
+	}
+}
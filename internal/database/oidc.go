@@ -0,0 +1,27 @@
+package database
+
+import "time"
+
+// OIDCLogin entity, a sign in started at an identity provider. It is stored by the sha256 hash of its state and
+// holds the nonce and PKCE code verifier the callback of the provider is checked with
+type OIDCLogin struct {
+	ID        int64
+	Provider  string
+	StateHash string
+	Nonce     string
+	Verifier  string
+	ExpiresAt time.Time
+}
+
+// Identity entity, links the subject of an identity provider to a user
+type Identity struct {
+	ID        int64
+	UserID    int64
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt string
+}
+
+// Identities slice of identity entities
+type Identities []Identity
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// OIDCTable object
+type OIDCTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewOIDCTable create an OIDCTable object
+func NewOIDCTable(db *sql.DB) *OIDCTable {
+	return &OIDCTable{
+		db:   db,
+		name: "oidc_login o",
+	}
+}
+
+// Insert a new sign in, returns inserted sign in id
+func (ot *OIDCTable) Insert(l OIDCLogin) (int64, error) {
+	q := `INSERT INTO oidc_login (provider, state_hash, nonce, verifier, expires_at) VALUES (?, ?, ?, ?, ?)`
+	res, err := ot.db.Exec(q, l.Provider, l.StateHash, l.Nonce, l.Verifier, l.ExpiresAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("oidc error, %w", err)
+	}
+
+	return res.LastInsertId()
+}
+
+// Take removes the sign in of provider with the given state hash and returns it, so each sign in completes once.
+// Unknown and expired sign ins and sign ins of another provider return ErrNoRows.
+func (ot *OIDCTable) Take(provider string, stateHash string, now time.Time) (*OIDCLogin, error) {
+	l := OIDCLogin{}
+	err := transaction(ot.db, func(tx *sql.Tx) error {
+		// nolint:gosec
+		query := fmt.Sprintf(`SELECT o.id, o.provider, o.state_hash, o.nonce, o.verifier FROM %s
+WHERE o.state_hash = ? AND o.provider = ? AND o.expires_at > ? FOR UPDATE`, ot.name)
+
+		if err := tx.QueryRow(query, stateHash, provider, now.UTC()).Scan(
+			&l.ID, &l.Provider, &l.StateHash, &l.Nonce, &l.Verifier,
+		); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM oidc_login WHERE id = ?`, l.ID); err != nil {
+			return fmt.Errorf("oidc error, %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// Purge removes expired sign ins, returns the number of removed rows
+func (ot *OIDCTable) Purge(now time.Time) (int64, error) {
+	res, err := ot.db.Exec(`DELETE FROM oidc_login WHERE expires_at <= ?`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("oidc error, %w", err)
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,125 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestOIDCTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	insert := func(hash string, expiresAt time.Time) {
+		if _, err := db.OIDC.Insert(database.OIDCLogin{
+			Provider:  "standin",
+			StateHash: hash,
+			Nonce:     "nonce",
+			Verifier:  "verifier",
+			ExpiresAt: expiresAt,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Should take a sign in once", func(t *testing.T) {
+		insert("state-hash1", now.Add(time.Minute))
+
+		if _, err := db.OIDC.Take("other", "state-hash1", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error for another provider got %v", err)
+		}
+
+		l, err := db.OIDC.Take("standin", "state-hash1", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Nonce != "nonce" || l.Verifier != "verifier" {
+			t.Fatalf("Expected the nonce and verifier of the sign in got %+v", l)
+		}
+
+		if _, err := db.OIDC.Take("standin", "state-hash1", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error got %v", err)
+		}
+	})
+
+	t.Run("Should not take expired sign ins", func(t *testing.T) {
+		insert("state-hash2", now.Add(-time.Minute))
+
+		if _, err := db.OIDC.Take("standin", "state-hash2", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error got %v", err)
+		}
+
+		n, err := db.OIDC.Purge(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Fatalf("Expected 1 purged sign in got %d", n)
+		}
+	})
+}
+
+func TestIdentityTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity := database.Identity{Provider: "standin", Subject: "sub-1", Email: "identity1@test.gr"}
+
+	t.Run("Should create a user with the identity", func(t *testing.T) {
+		userID, err := db.Identity.InsertUser(database.User{
+			Username: "identity1",
+			Password: "password",
+			Email:    "identity1@test.gr",
+			Active:   true,
+		}, identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		i, err := db.Identity.Get("standin", "sub-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i.UserID != userID {
+			t.Fatalf("Expected user %d got %d", userID, i.UserID)
+		}
+
+		u, err := db.User.Get(uint64(userID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.VerifiedAt == "" {
+			t.Fatal("Expected the email address of the user to count as verified")
+		}
+	})
+
+	t.Run("Should not link an identity twice", func(t *testing.T) {
+		identity.UserID = 1
+		if _, err := db.Identity.Link(identity); !errors.Is(err, database.ErrDuplicateEntry) {
+			t.Fatalf("Expected duplicate entry error got %v", err)
+		}
+	})
+
+	t.Run("Should not create the user when the identity is taken", func(t *testing.T) {
+		_, err := db.Identity.InsertUser(database.User{
+			Username: "identity2",
+			Password: "password",
+			Email:    "identity2@test.gr",
+			Active:   true,
+		}, identity)
+		if !errors.Is(err, database.ErrDuplicateEntry) {
+			t.Fatalf("Expected duplicate entry error got %v", err)
+		}
+
+		if _, err := db.User.GetByUsername("identity2"); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected the user to be rolled back got %v", err)
+		}
+	})
+}
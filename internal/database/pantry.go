@@ -0,0 +1,93 @@
+package database
+
+import (
+	"sort"
+	"strings"
+)
+
+// PantryMatch is a recipe found by a pantry search, Missing lists the ingredients that are neither in the pantry nor
+// covered by a substitution and Substitutions the substitutions that cover the rest
+type PantryMatch struct {
+	Recipe        Recipe
+	Missing       []string
+	Substitutions Substitutions
+}
+
+// PantryMatches slice of pantry matches
+type PantryMatches []PantryMatch
+
+// MatchPantry checks recipes against the ingredients of pantry, an ingredient the pantry lacks counts as covered when
+// one of subs can be made from the pantry. Recipes missing more than maxMissing ingredients are dropped, the others
+// are ordered by the number of missing ingredients and then by the number of substitutions they need
+func MatchPantry(recipes Recipes, pantry []string, subs Substitutions, maxMissing int) PantryMatches {
+	available := make(map[string]struct{}, len(pantry))
+	for i := range pantry {
+		available[IngredientKey(pantry[i])] = struct{}{}
+	}
+
+	matches := PantryMatches{}
+	for _, r := range recipes {
+		m := PantryMatch{Recipe: r}
+		seen := make(map[string]struct{})
+
+		for _, ing := range r.Ingredients {
+			key := IngredientKey(ing.Name)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			if _, ok := available[key]; ok {
+				continue
+			}
+			if s := subs.Cover(ing.Name, pantry); s != nil {
+				m.Substitutions = append(m.Substitutions, *s)
+				continue
+			}
+			m.Missing = append(m.Missing, ing.Name)
+		}
+
+		if len(m.Missing) <= maxMissing {
+			matches = append(matches, m)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if len(matches[i].Missing) != len(matches[j].Missing) {
+			return len(matches[i].Missing) < len(matches[j].Missing)
+		}
+		if len(matches[i].Substitutions) != len(matches[j].Substitutions) {
+			return len(matches[i].Substitutions) < len(matches[j].Substitutions)
+		}
+		return matches[i].Recipe.ID < matches[j].Recipe.ID
+	})
+
+	return matches
+}
+
+// pantryNames returns the names recipe ingredients may be written with for the items of pantry, the singular and
+// the common plurals of each item so candidate recipes can be found in SQL before they are matched by key
+func pantryNames(pantry []string) []string {
+	seen := make(map[string]struct{})
+	var names []string
+
+	for i := range pantry {
+		key := IngredientKey(pantry[i])
+		if key == "" {
+			continue
+		}
+
+		variants := []string{key, key + "s", key + "es"}
+		if strings.HasSuffix(key, "y") {
+			variants = append(variants, strings.TrimSuffix(key, "y")+"ies")
+		}
+		for _, v := range variants {
+			if _, ok := seen[v]; !ok {
+				seen[v] = struct{}{}
+				names = append(names, v)
+			}
+		}
+	}
+
+	return names
+}
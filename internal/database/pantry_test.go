@@ -0,0 +1,55 @@
+package database_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestMatchPantry(t *testing.T) {
+	recipes := database.Recipes{
+		{ID: 1, Ingredients: database.Ingredients{{Name: "buttermilk"}, {Name: "flour"}, {Name: "Eggs"}}},
+		{ID: 2, Ingredients: database.Ingredients{{Name: "flour"}, {Name: "egg"}}},
+		{ID: 3, Ingredients: database.Ingredients{{Name: "flour"}, {Name: "vodka"}, {Name: "salt"}}},
+		{ID: 4, Ingredients: database.Ingredients{{Name: "flour"}, {Name: "saffron"}, {Name: "salt"}}},
+	}
+	subs := database.Substitutions{
+		{ID: 1, Ingredient: "buttermilk", Substitutes: []string{"milk", "lemon juice"}},
+		{ID: 2, Ingredient: "vodka"},
+	}
+	pantry := []string{"Flour", "egg", "milk", "lemon juice"}
+
+	testCases := []struct {
+		desc       string
+		maxMissing int
+		ids        []int64
+	}{
+		{"Should find recipes covered by the pantry and substitutions", 0, []int64{2, 1}},
+		{"Should allow missing ingredients", 2, []int64{2, 1, 3, 4}},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			var ids []int64
+			for _, m := range database.MatchPantry(recipes, pantry, subs, tc.maxMissing) {
+				ids = append(ids, m.Recipe.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.ids) {
+				t.Fatalf("Expected recipes %v got %v", tc.ids, ids)
+			}
+		})
+	}
+
+	t.Run("Should report the substitutions used and the missing ingredients", func(t *testing.T) {
+		matches := database.MatchPantry(recipes, pantry, subs, 2)
+		if used := matches[1].Substitutions; len(used) != 1 || used[0].ID != 1 {
+			t.Fatalf("Expected recipe 1 to use substitution 1 got %+v", used)
+		}
+		if missing := matches[3].Missing; !reflect.DeepEqual(missing, []string{"saffron", "salt"}) {
+			t.Fatalf("Expected recipe 4 to miss saffron and salt got %v", missing)
+		}
+	})
+}
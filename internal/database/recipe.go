@@ -1,5 +1,40 @@
 package database
 
+// Recipe statuses, user submitted recipes start as drafts, are submitted for review and go live once a moderator
+// approves them. Published recipes can be archived (unpublished) by moderators.
+const (
+	RecipeDraft     = "draft"
+	RecipePending   = "pending"
+	RecipePublished = "published"
+	RecipeArchived  = "archived"
+)
+
+// Recipe visibility levels, public recipes are listed and searchable, unlisted recipes can only be read by id and
+// private recipes are only visible to their author and to holders of a share link
+const (
+	RecipePublic   = "public"
+	RecipeUnlisted = "unlisted"
+	RecipePrivate  = "private"
+)
+
+// Recipe difficulty levels
+const (
+	RecipeEasy   = "easy"
+	RecipeMedium = "medium"
+	RecipeHard   = "hard"
+)
+
+// DefaultLocale is the locale of recipes that were created without one
+const DefaultLocale = "en"
+
+// recipeTransitions maps each recipe status to the statuses it can be reached from
+var recipeTransitions = map[string][]string{
+	RecipePending:   {RecipeDraft},
+	RecipePublished: {RecipePending},
+	RecipeDraft:     {RecipePending, RecipeArchived},
+	RecipeArchived:  {RecipePublished},
+}
+
 // Recipe entity
 type Recipe struct {
 	ID          int64
@@ -7,9 +42,64 @@ type Recipe struct {
 	URL         string
 	Thumbnail   string
 	Ingredients Ingredients
-	CreatedAt   string
-	UpdatedAt   string
+	Tags        Tags
+	Views       int64
+	UserID      int64
+	Status      string
+	Visibility  string
+	Locale      string
+	// PrepTime, CookTime and TotalTime are in minutes, zero when unknown
+	PrepTime   int64
+	CookTime   int64
+	TotalTime  int64
+	Servings   int64
+	Yield      string
+	Difficulty string
+	ReviewNote string
+	CreatedAt  string
+	UpdatedAt  string
+	DeletedAt  string
+	// Diets are the effective diet labels, author overrides take precedence over the automatic classification
+	Diets         []string
+	DietOverrides DietOverrides
 }
 
 // Recipes slice or recipe entities
 type Recipes []Recipe
+
+// Viewer identifies who reads recipes, published public recipes are visible to everyone and unlisted ones to
+// everyone that knows their id. Recipes in any other status or visibility are only visible to their author, to
+// moderators and, when published, to holders of a share link. Deleted recipes are visible to no one. Moderator is only
+// set by the moderation endpoints, moderators read the rest of the api like any other user.
+type Viewer struct {
+	UserID         int64
+	Moderator      bool
+	SharedRecipeID int64
+}
+
+// visibility returns a where condition and its arguments that limit recipes to the ones visible to the viewer,
+// listing excludes unlisted recipes of other authors
+func (v Viewer) visibility(listing bool) (string, []interface{}) {
+	if v.Moderator {
+		return "r.deleted_at IS NULL", nil
+	}
+
+	cond := "r.status = ? AND r.visibility IN (?, ?)"
+	args := []interface{}{RecipePublished, RecipePublic, RecipeUnlisted}
+	if listing {
+		args[2] = RecipePublic
+	}
+
+	if v.UserID > 0 {
+		cond = "(" + cond + " OR r.user_id = ?)"
+		args = append(args, v.UserID)
+	}
+
+	// Share links open published recipes whatever their visibility, drafts and archived recipes stay hidden
+	if v.SharedRecipeID > 0 {
+		cond = "(" + cond + " OR (r.id = ? AND r.status = ?))"
+		args = append(args, v.SharedRecipeID, RecipePublished)
+	}
+
+	return "r.deleted_at IS NULL AND " + cond, args
+}
@@ -5,14 +5,29 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
-const recipeColumns = "r.id, r.title, r.thumbnail, r.url, r.created_at, r.updated_at"
+const recipeColumns = "r.id, r.title, r.thumbnail, r.url, r.views, r.user_id, r.status, r.review_note, r.created_at, " +
+	"r.updated_at, r.deleted_at, r.visibility, r.locale, r.prep_time, r.cook_time, r.total_time, r.servings, r.yield, " +
+	"r.difficulty"
 
-// RecipeFilters object
+// RecipeFilters object, recipes are limited to the ones visible to Viewer, Term also matches titles translated to
+// one of Locales. MaxTotalTime only matches recipes with a known total time. Recipes need all of Tags, or any of
+// them when AnyTag is set. Diet matches recipes whose effective label for that diet is set.
 type RecipeFilters struct {
-	Term        string
-	Ingredients []string
+	Term         string
+	Ingredients  []string
+	Viewer       Viewer
+	Locales      []string
+	MaxTotalTime uint64
+	Difficulty   string
+	Tags         Tags
+	AnyTag       bool
+	Diet         string
+	// MinCalories and MaxCalories bound the calories per serving, zero for no bound
+	MinCalories float64
+	MaxCalories float64
 }
 
 // RecipeTable object
@@ -31,19 +46,18 @@ func NewRecipeTable(db *sql.DB) *RecipeTable {
 	}
 }
 
-// Get a recipe by id
-func (rt *RecipeTable) Get(id uint64) (*Recipe, error) {
+// Get a recipe by id, recipes that are not visible to the viewer are reported as missing
+func (rt *RecipeTable) Get(id uint64, v Viewer) (*Recipe, error) {
+	cond, args := v.visibility(false)
 	// nolint:gosec
-	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, recipeColumns, rt.name)
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE r.id = ? AND %s`, recipeColumns, rt.name, cond)
 
-	var rcp Recipe
-	if err := rt.db.QueryRow(query, id).Scan(
-		&rcp.ID, &rcp.Title, &rcp.Thumbnail, &rcp.URL, &rcp.CreatedAt, &rcp.UpdatedAt,
-	); err != nil {
+	rcp, err := scanRecipe(rt.db.QueryRow(query, append([]interface{}{id}, args...)...))
+	if err != nil {
 		return nil, err
 	}
 
-	ri, err := rt.withIngredients(rcp)
+	ri, err := rt.withIngredients(*rcp)
 	if err != nil {
 		return nil, err
 	}
@@ -51,15 +65,65 @@ func (rt *RecipeTable) Get(id uint64) (*Recipe, error) {
 	return &ri[0], nil
 }
 
-// Paginate get paginated recipes
+// Paginate get paginated recipes, without filters only published recipes are returned
 func (rt *RecipeTable) Paginate(page uint64, filters *RecipeFilters) (Recipes, int64, error) {
-	var args []interface{}
+	query, args := rt.filter(filters)
+
+	return rt.page(page, query, args...)
+}
+
+// Facets counts the recipes matching filters by tag, facets are ordered by kind and by count within a kind
+func (rt *RecipeTable) Facets(filters *RecipeFilters) (Facets, error) {
+	filtered, args := rt.filter(filters)
 	// nolint:gosec
-	query := fmt.Sprintf(`SELECT DISTINCT %s FROM %s 
-JOIN ingredient i on r.id = i.recipe_id 
-WHERE 1=1`, recipeColumns, rt.name)
+	query := fmt.Sprintf(`SELECT t.kind, t.name, COUNT(DISTINCT rg.recipe_id) AS total FROM recipe_tag rg
+JOIN tag t ON t.id = rg.tag_id
+WHERE rg.recipe_id IN (SELECT f.id FROM (%s) f)
+GROUP BY t.id
+ORDER BY t.kind, total DESC, t.name`, filtered)
 
-	if filters != nil && filters.Term != "" {
+	rows, err := rt.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("facet error, %w", err)
+	}
+	defer rows.Close()
+
+	facets := Facets{}
+	for rows.Next() {
+		f := Facet{}
+		if err := rows.Scan(&f.Kind, &f.Name, &f.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, f)
+	}
+
+	return facets, rows.Err()
+}
+
+// filter builds the select query of the recipes matching filters and its arguments
+func (rt *RecipeTable) filter(filters *RecipeFilters) (string, []interface{}) {
+	var v Viewer
+	if filters != nil {
+		v = filters.Viewer
+	}
+	cond, args := v.visibility(true)
+
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT DISTINCT %s FROM %s
+JOIN ingredient i on r.id = i.recipe_id
+WHERE %s`, recipeColumns, rt.name, cond)
+
+	if filters != nil && filters.Term != "" && len(filters.Locales) > 0 {
+		query += fmt.Sprintf(` AND (r.title like ? OR r.id IN (
+SELECT rt.recipe_id FROM recipe_translation rt WHERE rt.locale IN (%s) AND rt.title like ?))`,
+			strings.TrimSuffix(strings.Repeat("?,", len(filters.Locales)), ","),
+		)
+		args = append(args, "%"+filters.Term+"%")
+		for i := range filters.Locales {
+			args = append(args, filters.Locales[i])
+		}
+		args = append(args, "%"+filters.Term+"%")
+	} else if filters != nil && filters.Term != "" {
 		query += " AND r.title like ?"
 		args = append(args, "%"+filters.Term+"%")
 	}
@@ -72,9 +136,330 @@ WHERE 1=1`, recipeColumns, rt.name)
 			args = append(args, filters.Ingredients[i])
 		}
 	}
+	if filters != nil && filters.MaxTotalTime > 0 {
+		query += " AND r.total_time > 0 AND r.total_time <= ?"
+		args = append(args, filters.MaxTotalTime)
+	}
+
+	if filters != nil && filters.Difficulty != "" {
+		query += " AND r.difficulty = ?"
+		args = append(args, filters.Difficulty)
+	}
+
+	// Recipes without complete nutrition facts have no calories and are left out
+	if filters != nil && filters.MinCalories > 0 {
+		query += " AND r.calories >= ?"
+		args = append(args, filters.MinCalories)
+	}
+	if filters != nil && filters.MaxCalories > 0 {
+		query += " AND r.calories <= ?"
+		args = append(args, filters.MaxCalories)
+	}
+
+	if filters != nil && filters.Diet != "" {
+		query += ` AND r.id IN (SELECT rd.recipe_id FROM recipe_diet rd
+WHERE rd.diet = ? AND COALESCE(rd.override, rd.automatic) = 1)`
+		args = append(args, filters.Diet)
+	}
+
+	if filters != nil && len(filters.Tags) > 0 {
+		query += fmt.Sprintf(` AND r.id IN (SELECT rg.recipe_id FROM recipe_tag rg
+JOIN tag t ON t.id = rg.tag_id
+WHERE %s
+GROUP BY rg.recipe_id`,
+			strings.TrimSuffix(strings.Repeat("(t.kind = ? AND t.name = ?) OR ", len(filters.Tags)), " OR "),
+		)
+		for i := range filters.Tags {
+			args = append(args, filters.Tags[i].Kind, filters.Tags[i].Name)
+		}
+		if !filters.AnyTag {
+			query += " HAVING COUNT(DISTINCT rg.tag_id) = ?"
+			args = append(args, len(filters.Tags))
+		}
+		query += ")"
+	}
 	query += " GROUP BY r.id"
 
-	// count all results before applying limits
+	return query, args
+}
+
+// List a page of the recipes visible to the viewer with their ingredients, oldest first
+func (rt *RecipeTable) List(v Viewer, page uint64) (Recipes, int64, error) {
+	cond, args := v.visibility(false)
+	// nolint:gosec
+	return rt.page(page, fmt.Sprintf(`SELECT %s FROM %s WHERE %s ORDER BY r.id`, recipeColumns, rt.name, cond), args...)
+}
+
+// UsingPantry lists the recipes visible to the viewer that use at least one of the items of pantry with all their
+// ingredients, the candidates of a pantry search. Recipes with the fewest ingredients outside the pantry come first,
+// at most limit recipes are returned
+func (rt *RecipeTable) UsingPantry(v Viewer, pantry []string, limit int) (Recipes, error) {
+	names := pantryNames(pantry)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	in := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+
+	cond, args := v.visibility(false)
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s
+INNER JOIN (
+	SELECT i.recipe_id, COUNT(DISTINCT i.name) - COUNT(DISTINCT IF(i.name IN (%s), i.name, NULL)) AS missing
+	FROM ingredient i
+	GROUP BY i.recipe_id
+	HAVING COUNT(DISTINCT IF(i.name IN (%s), i.name, NULL)) > 0
+) p ON p.recipe_id = r.id
+WHERE %s
+ORDER BY p.missing, r.id
+LIMIT ?`, recipeColumns, rt.name, in, in, cond)
+
+	pantryArgs := make([]interface{}, 0, len(names)*2+len(args)+1)
+	for n := 0; n < 2; n++ {
+		for i := range names {
+			pantryArgs = append(pantryArgs, names[i])
+		}
+	}
+	pantryArgs = append(pantryArgs, args...)
+
+	return rt.query(query, append(pantryArgs, limit)...)
+}
+
+// DuplicateCandidates lists the recipes visible to the viewer that may be near duplicates of recipe under rules with
+// their ingredients. Only recipes with the same source url, a title word starting with the same letters or enough
+// shared ingredients are loaded, they still have to be matched by the rules.
+func (rt *RecipeTable) DuplicateCandidates(recipe Recipe, v Viewer, rules DuplicateRules) (Recipes, error) {
+	var conds []string
+	var condArgs []interface{}
+
+	if u := CanonicalURL(recipe.URL); u != "" {
+		conds = append(conds, "r.url LIKE ?")
+		condArgs = append(condArgs, "%"+u+"%")
+	}
+	for _, t := range titleTokens(recipe.Title) {
+		conds = append(conds, "r.title LIKE ?")
+		condArgs = append(condArgs, "%"+t+"%")
+	}
+
+	names := ingredientNames(recipe.Ingredients)
+	if rules.IngredientOverlap > 0 && len(names) > 0 && len(recipe.Ingredients) >= rules.MinIngredients {
+		conds = append(conds, fmt.Sprintf(`r.id IN (SELECT i.recipe_id FROM ingredient i WHERE i.name IN (%s)
+GROUP BY i.recipe_id HAVING COUNT(DISTINCT i.name) >= ?)`, strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")))
+		for i := range names {
+			condArgs = append(condArgs, names[i])
+		}
+		condArgs = append(condArgs, sharedIngredients(rules.IngredientOverlap, len(names)))
+	}
+
+	if len(conds) == 0 {
+		return nil, nil
+	}
+
+	cond, args := v.visibility(false)
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s AND (%s) ORDER BY r.id`,
+		recipeColumns, rt.name, cond, strings.Join(conds, " OR "))
+
+	return rt.query(query, append(args, condArgs...)...)
+}
+
+// ByAuthor lists the recipes of an author that are not in the trash with their ingredients, whatever their status
+// and visibility
+func (rt *RecipeTable) ByAuthor(userID int64) (Recipes, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE r.user_id = ? AND r.deleted_at IS NULL ORDER BY r.id`, recipeColumns, rt.name)
+
+	return rt.query(query, userID)
+}
+
+// Popular get paginated recipes ordered by their total number of views
+func (rt *RecipeTable) Popular(page uint64) (Recipes, int64, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s
+WHERE r.status = ? AND r.visibility = ? AND r.deleted_at IS NULL AND r.views > 0
+ORDER BY r.views DESC, r.id`, recipeColumns, rt.name)
+	args := []interface{}{RecipePublished, RecipePublic}
+
+	return rt.page(page, query, args...)
+}
+
+// Trending get paginated recipes ordered by their time decayed popularity, only views of the last window days are
+// taken into account and a view loses half of its weight every halfLife days
+func (rt *RecipeTable) Trending(page uint64, now time.Time, window int, halfLife float64) (Recipes, int64, error) {
+	today := now.Format("2006-01-02")
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s
+JOIN (
+	SELECT rv.recipe_id, SUM(rv.views * POW(0.5, DATEDIFF(?, rv.day) / ?)) AS score
+	FROM recipe_view rv
+	WHERE rv.day > DATE_SUB(?, INTERVAL ? DAY)
+	GROUP BY rv.recipe_id
+) t ON t.recipe_id = r.id
+WHERE r.status = ? AND r.visibility = ? AND r.deleted_at IS NULL
+ORDER BY t.score DESC, r.id`, recipeColumns, rt.name)
+	args := []interface{}{today, halfLife, today, window, RecipePublished, RecipePublic}
+
+	return rt.page(page, query, args...)
+}
+
+// Queue get paginated recipes that wait for review, oldest submissions first
+func (rt *RecipeTable) Queue(page uint64) (Recipes, int64, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE r.status = ? AND r.deleted_at IS NULL ORDER BY r.id`, recipeColumns,
+		rt.name)
+	args := []interface{}{RecipePending}
+
+	return rt.page(page, query, args...)
+}
+
+// Transition moves a recipe to a new status and stores the review note, returns ErrStatusTransition when the
+// recipe current status does not lead to the requested one. When from is given the recipe must also be in one of
+// those statuses.
+func (rt *RecipeTable) Transition(id uint64, status string, note string, from ...string) error {
+	var current string
+	if err := rt.db.QueryRow(`SELECT status FROM recipe WHERE id = ? AND deleted_at IS NULL`, id).Scan(&current); err != nil {
+		return err
+	}
+
+	if !hasStatus(recipeTransitions[status], current) || (len(from) > 0 && !hasStatus(from, current)) {
+		return ErrStatusTransition
+	}
+
+	// Guard against concurrent transitions by matching the status that was read
+	q := `UPDATE recipe SET status = ?, review_note = ? WHERE id = ? AND status = ?`
+	res, err := rt.db.Exec(q, status, note, id, current)
+	if err != nil {
+		return fmt.Errorf("recipe error, %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("recipe error, %w", err)
+	}
+	if affected == 0 {
+		return ErrStatusTransition
+	}
+
+	return nil
+}
+
+// hasStatus reports whether status is one of statuses
+func hasStatus(statuses []string, status string) bool {
+	for i := range statuses {
+		if statuses[i] == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddViews adds views to recipes, views are added to the recipe totals and to the daily counters used by trending
+func (rt *RecipeTable) AddViews(views map[int64]int64, at time.Time) error {
+	day := at.Format("2006-01-02")
+
+	return transaction(rt.db, func(tx *sql.Tx) error {
+		for id, n := range views {
+			if _, err := tx.Exec(`UPDATE recipe SET views = views + ? WHERE id = ?`, n, id); err != nil {
+				return fmt.Errorf("views error, %w", err)
+			}
+
+			// Select from recipe so views of recipes deleted in the meantime are dropped instead of failing the batch
+			q := `INSERT INTO recipe_view (recipe_id, day, views) SELECT r.id, ?, ? FROM recipe r WHERE r.id = ?
+ON DUPLICATE KEY UPDATE recipe_view.views = recipe_view.views + VALUES(views)`
+			if _, err := tx.Exec(q, day, n, id); err != nil {
+				return fmt.Errorf("views error, %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// SetVisibility changes the visibility level of a recipe
+func (rt *RecipeTable) SetVisibility(id uint64, visibility string) error {
+	if _, err := rt.db.Exec(`UPDATE recipe SET visibility = ? WHERE id = ?`, visibility, id); err != nil {
+		return fmt.Errorf("recipe error, %w", err)
+	}
+
+	return nil
+}
+
+// Delete moves a recipe and its ingredients to the trash
+func (rt *RecipeTable) Delete(id uint64) error {
+	return rt.setDeleted(id, true)
+}
+
+// Restore brings a recipe and its ingredients back from the trash
+func (rt *RecipeTable) Restore(id uint64) error {
+	return rt.setDeleted(id, false)
+}
+
+// Trash get paginated deleted recipes, most recently deleted first
+func (rt *RecipeTable) Trash(page uint64) (Recipes, int64, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE r.deleted_at IS NOT NULL ORDER BY r.deleted_at DESC, r.id`,
+		recipeColumns, rt.name)
+
+	return rt.page(page, query)
+}
+
+// Purge permanently removes recipes that were deleted before the given time together with their ingredients,
+// returns the number of purged recipes
+func (rt *RecipeTable) Purge(before time.Time) (int64, error) {
+	iq := `DELETE i FROM ingredient i INNER JOIN recipe r ON r.id = i.recipe_id WHERE r.deleted_at < ?`
+
+	var purged int64
+	err := transaction(rt.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(iq, before.UTC()); err != nil {
+			return fmt.Errorf("ingredient error, %w", err)
+		}
+
+		res, err := tx.Exec(`DELETE FROM recipe WHERE deleted_at < ?`, before.UTC())
+		if err != nil {
+			return fmt.Errorf("recipe error, %w", err)
+		}
+		purged, err = res.RowsAffected()
+
+		return err
+	})
+
+	return purged, err
+}
+
+// setDeleted marks or unmarks a recipe and its ingredients as deleted, returns ErrNoRows when there is no recipe to
+// delete or restore
+func (rt *RecipeTable) setDeleted(id uint64, deleted bool) error {
+	rq := `UPDATE recipe SET deleted_at = UTC_TIMESTAMP() WHERE id = ? AND deleted_at IS NULL`
+	iq := `UPDATE ingredient SET deleted_at = UTC_TIMESTAMP() WHERE recipe_id = ?`
+	if !deleted {
+		rq = `UPDATE recipe SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+		iq = `UPDATE ingredient SET deleted_at = NULL WHERE recipe_id = ?`
+	}
+
+	return transaction(rt.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(rq, id)
+		if err != nil {
+			return fmt.Errorf("recipe error, %w", err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("recipe error, %w", err)
+		}
+		if affected == 0 {
+			return ErrNoRows
+		}
+
+		if _, err := tx.Exec(iq, id); err != nil {
+			return fmt.Errorf("ingredient error, %w", err)
+		}
+
+		return nil
+	})
+}
+
+// page counts the results of a recipe select query and returns the requested page
+func (rt *RecipeTable) page(page uint64, query string, args ...interface{}) (Recipes, int64, error) {
 	total, err := rt.countGroup(query, args)
 	if err != nil {
 		return nil, 0, err
@@ -84,39 +469,70 @@ WHERE 1=1`, recipeColumns, rt.name)
 		page--
 	}
 	query += ` LIMIT ?, ?`
-	args = append(args, rt.pageSize*page, rt.pageSize)
 
-	rows, err := rt.db.Query(query, args...)
+	recipes, err := rt.query(query, append(args, rt.pageSize*page, rt.pageSize)...)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	return recipes, total, nil
+}
+
+// query runs a recipe select query and returns the resulting recipes with their ingredients
+func (rt *RecipeTable) query(query string, args ...interface{}) (Recipes, error) {
+	rows, err := rt.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
 	var recipes Recipes
 	for rows.Next() {
-		r := Recipe{}
-		if err := rows.Scan(
-			&r.ID, &r.Title, &r.Thumbnail, &r.URL, &r.CreatedAt, &r.UpdatedAt,
-		); err != nil {
-			return nil, 0, err
+		r, err := scanRecipe(rows)
+		if err != nil {
+			return nil, err
 		}
 
-		recipes = append(recipes, r)
+		recipes = append(recipes, *r)
 	}
-	if rows.Err() != nil {
-		return recipes, 0, err
-	}
-
-	recipes, err = rt.withIngredients(recipes...)
-	if err != nil {
-		return nil, 0, err
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return recipes, total, nil
+	return rt.withIngredients(recipes...)
 }
 
 // Insert a new recipe, returns inserted recipe id
 func (rt *RecipeTable) Insert(recipe Recipe) (int64, error) {
-	rq := `INSERT INTO recipe (title, thumbnail, url) VALUES (?, ?, ?)`
+	rq := `INSERT INTO recipe (title, thumbnail, url, user_id, status, visibility, locale, prep_time, cook_time, total_time,
+servings, yield, difficulty) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if recipe.Status == "" {
+		recipe.Status = RecipePublished
+	}
+	if recipe.Visibility == "" {
+		recipe.Visibility = RecipePublic
+	}
+	if recipe.Locale == "" {
+		recipe.Locale = DefaultLocale
+	}
+	if recipe.TotalTime == 0 {
+		recipe.TotalTime = recipe.PrepTime + recipe.CookTime
+	}
+	if recipe.Difficulty == "" {
+		recipe.Difficulty = EstimateDifficulty(recipe)
+	}
+	attrs, tree, err := dietClassifier(rt.db)
+	if err != nil {
+		return 0, err
+	}
+	index, err := NewNutrientTable(rt.db).Index()
+	if err != nil {
+		return 0, err
+	}
+	var names []string
+	for i := range recipe.Ingredients {
+		names = append(names, recipe.Ingredients[i].Name)
+	}
 	// nolint:gosec
 	iq := fmt.Sprintf(`INSERT INTO ingredient (recipe_id, name) VALUES %s`,
 		strings.TrimSuffix(strings.Repeat("(?, ?),", len(recipe.Ingredients)), ","),
@@ -131,7 +547,11 @@ func (rt *RecipeTable) Insert(recipe Recipe) (int64, error) {
 	var rid int64
 	err = func() error {
 		// Insert recipe
-		res, err := tx.Exec(rq, recipe.Title, recipe.Thumbnail, recipe.URL)
+		res, err := tx.Exec(
+			rq, recipe.Title, recipe.Thumbnail, recipe.URL, nullID(recipe.UserID), recipe.Status, recipe.Visibility,
+			recipe.Locale, recipe.PrepTime, recipe.CookTime, recipe.TotalTime, recipe.Servings, recipe.Yield,
+			recipe.Difficulty,
+		)
 		if err != nil {
 			if strings.Contains(err.Error(), "Error 1062") {
 				return ErrDuplicateEntry
@@ -155,7 +575,15 @@ func (rt *RecipeTable) Insert(recipe Recipe) (int64, error) {
 			return fmt.Errorf("ingredient error, %w", err)
 		}
 
-		return nil
+		if err := setRecipeTags(tx, rid, recipe.Tags); err != nil {
+			return err
+		}
+
+		if err := setRecipeCalories(tx, rid, index.Recipe(names, recipe.Servings, tree)); err != nil {
+			return err
+		}
+
+		return setRecipeDiets(tx, rid, attrs.Classify(names, tree))
 	}()
 
 	// Check if any transaction failed to rollback
@@ -174,17 +602,19 @@ func (rt *RecipeTable) Insert(recipe Recipe) (int64, error) {
 	return rid, nil
 }
 
-// Get recipe ingredients
+// Get recipe ingredients, tags and diet labels
 func (rt *RecipeTable) withIngredients(recipes ...Recipe) (Recipes, error) {
 	if len(recipes) == 0 {
 		return recipes, nil
 	}
 
+	// Recipes in the trash keep the ingredients that were deleted together with them
 	var args []interface{}
 	// nolint:gosec
-	query := fmt.Sprintf(`select %s 
-FROM ingredient i 
-WHERE recipe_id IN (%s)`,
+	query := fmt.Sprintf(`select %s
+FROM ingredient i
+INNER JOIN recipe r ON r.id = i.recipe_id
+WHERE i.recipe_id IN (%s) AND (i.deleted_at IS NULL OR r.deleted_at IS NOT NULL)`,
 		ingredientColumns,
 		strings.TrimSuffix(strings.Repeat("?,", len(recipes)), ","),
 	)
@@ -213,6 +643,23 @@ WHERE recipe_id IN (%s)`,
 		return nil, err
 	}
 
+	tags, err := recipeTags(rt.db, args)
+	if err != nil {
+		return nil, err
+	}
+	for i := range recipes {
+		recipes[i].Tags = tags[recipes[i].ID]
+	}
+
+	diets, overrides, err := recipeDiets(rt.db, args)
+	if err != nil {
+		return nil, err
+	}
+	for i := range recipes {
+		recipes[i].Diets = diets[recipes[i].ID]
+		recipes[i].DietOverrides = overrides[recipes[i].ID]
+	}
+
 	return recipes, nil
 }
 
@@ -233,3 +680,20 @@ func (rt *RecipeTable) countGroup(q string, qArgs []interface{}) (int64, error)
 
 	return total, nil
 }
+
+func scanRecipe(s scanner) (*Recipe, error) {
+	var r Recipe
+	var userID sql.NullInt64
+	var deletedAt sql.NullString
+	if err := s.Scan(
+		&r.ID, &r.Title, &r.Thumbnail, &r.URL, &r.Views, &userID, &r.Status, &r.ReviewNote, &r.CreatedAt, &r.UpdatedAt,
+		&deletedAt, &r.Visibility, &r.Locale, &r.PrepTime, &r.CookTime, &r.TotalTime, &r.Servings, &r.Yield,
+		&r.Difficulty,
+	); err != nil {
+		return nil, err
+	}
+	r.UserID = userID.Int64
+	r.DeletedAt = deletedAt.String
+
+	return &r, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/georlav/recipeapi/internal/config"
 	"github.com/georlav/recipeapi/internal/database"
@@ -52,7 +53,7 @@ func TestNewRecipeTable_Get(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.desc, func(t *testing.T) {
-			recipe, err := db.Recipe.Get(tc.input)
+			recipe, err := db.Recipe.Get(tc.input, database.Viewer{})
 			if err != nil && !errors.Is(err, tc.error) {
 				t.Fatal(err)
 			}
@@ -123,6 +124,9 @@ func TestNewRecipeTable_Insert(t *testing.T) {
 			if err == nil && id == 0 {
 				t.Fatal("Recipe expected to have an id")
 			}
+			if _, err := db.Handle.Exec(`delete from ingredient where recipe_id = ?`, id); err != nil {
+				t.Fatal(err)
+			}
 			if _, err := db.Handle.Exec(`delete from recipe where id = ?`, id); err != nil {
 				t.Fatal(err)
 			}
@@ -194,3 +198,374 @@ func db() (*database.Database, error) {
 
 	return db, err
 }
+
+func TestRecipeTable_Timing(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quick, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Timed Quick Omelette",
+		Ingredients: database.Ingredients{{Name: "eggs"}, {Name: "butter"}},
+		PrepTime:    5,
+		CookTime:    10,
+		Servings:    2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Timed Slow Braise",
+		Ingredients: database.Ingredients{{Name: "beef"}, {Name: "wine"}},
+		TotalTime:   240,
+		Yield:       "1 pot",
+		Difficulty:  database.RecipeMedium,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := db.Handle.Exec(`delete from ingredient where recipe_id IN (?, ?)`, quick, slow); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Handle.Exec(`delete from recipe where id IN (?, ?)`, quick, slow); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	recipe, err := db.Recipe.Get(uint64(quick), database.Viewer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe.TotalTime != 15 || recipe.Servings != 2 || recipe.Difficulty != database.RecipeEasy {
+		t.Fatalf("Expected total time 15, 2 servings and an estimated easy difficulty got %+v", recipe)
+	}
+
+	testCases := []struct {
+		desc     string
+		filters  *database.RecipeFilters
+		expected []int64
+	}{
+		{"Should filter by total time", &database.RecipeFilters{Term: "Timed", MaxTotalTime: 30}, []int64{quick}},
+		{"Should include the limit", &database.RecipeFilters{Term: "Timed", MaxTotalTime: 240}, []int64{quick, slow}},
+		{"Should filter by difficulty", &database.RecipeFilters{Term: "Timed", Difficulty: database.RecipeMedium},
+			[]int64{slow}},
+		{"Should combine filters", &database.RecipeFilters{Term: "Timed", MaxTotalTime: 30,
+			Difficulty: database.RecipeMedium}, nil},
+		{"Should skip recipes without a total time", &database.RecipeFilters{Term: "Ginger", MaxTotalTime: 300}, nil},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			recipes, _, err := db.Recipe.Paginate(1, tc.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(recipes) != len(tc.expected) {
+				t.Fatalf("Expected %d recipes got %d", len(tc.expected), len(recipes))
+			}
+			for i := range recipes {
+				if recipes[i].ID != tc.expected[i] {
+					t.Fatalf("Expected recipes %v got %+v", tc.expected, recipes)
+				}
+			}
+		})
+	}
+}
+
+func TestRecipeTable_List(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipes, total, err := db.Recipe.List(database.Viewer{Moderator: true}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total < 22 {
+		t.Fatalf("Expected at least 22 recipes got %d", total)
+	}
+	if len(recipes) == 0 || recipes[0].ID != 1 {
+		t.Fatalf("Expected the page to start with the oldest recipe got %+v", recipes)
+	}
+	for i := range recipes {
+		if len(recipes[i].Ingredients) == 0 {
+			t.Fatalf("Expected recipe %d to have ingredients", recipes[i].ID)
+		}
+	}
+}
+
+func TestRecipeTable_Views(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2030, 1, 10, 12, 0, 0, 0, time.UTC)
+	views := []struct {
+		recipeID int64
+		views    int64
+		at       time.Time
+	}{
+		{3, 10, now.AddDate(0, 0, -6)},
+		{4, 4, now},
+		{5, 100, now.AddDate(0, 0, -30)},
+		{99999, 1, now},
+	}
+	for _, v := range views {
+		if err := db.Recipe.AddViews(map[int64]int64{v.recipeID: v.views}, v.at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		desc     string
+		paginate func() (database.Recipes, int64, error)
+		expected []int64
+	}{
+		{
+			"Should order recipes by total views",
+			func() (database.Recipes, int64, error) { return db.Recipe.Popular(1) },
+			[]int64{5, 3, 4},
+		},
+		{
+			"Should order recipes by decayed views within the window",
+			func() (database.Recipes, int64, error) { return db.Recipe.Trending(1, now, 7, 2) },
+			[]int64{4, 3},
+		},
+		{
+			"Should ignore views outside the window",
+			func() (database.Recipes, int64, error) { return db.Recipe.Trending(1, now.AddDate(0, 0, 7), 7, 2) },
+			[]int64{},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			recipes, total, err := tc.paginate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != int64(len(tc.expected)) {
+				t.Fatalf("Expected %d total results got %d", len(tc.expected), total)
+			}
+			for j := range tc.expected {
+				if recipes[j].ID != tc.expected[j] {
+					t.Fatalf("Expected recipe %d at position %d got %d", tc.expected[j], j, recipes[j].ID)
+				}
+			}
+		})
+	}
+
+	recipe, err := db.Recipe.Get(5, database.Viewer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe.Views != 100 {
+		t.Fatalf("Expected recipe to have 100 views got %d", recipe.Views)
+	}
+}
+
+func TestRecipeTable_Transition(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Draft Lemonade",
+		URL:         "http://example.com/draft-lemonade",
+		Ingredients: database.Ingredients{{Name: "lemon"}, {Name: "water"}, {Name: "sugar"}},
+		UserID:      1,
+		Status:      database.RecipeDraft,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	visibility := []struct {
+		desc    string
+		viewer  database.Viewer
+		visible bool
+	}{
+		{"Should hide drafts from anonymous users", database.Viewer{}, false},
+		{"Should hide drafts from other users", database.Viewer{UserID: 2}, false},
+		{"Should show drafts to their author", database.Viewer{UserID: 1}, true},
+		{"Should show drafts to moderators", database.Viewer{UserID: 2, Moderator: true}, true},
+	}
+
+	for i := range visibility {
+		tc := visibility[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := db.Recipe.Get(uint64(id), tc.viewer)
+			if tc.visible && err != nil {
+				t.Fatal(err)
+			}
+			if !tc.visible && !errors.Is(err, database.ErrNoRows) {
+				t.Fatalf("Expected no rows got %v", err)
+			}
+
+			_, total, err := db.Recipe.Paginate(1, &database.RecipeFilters{Term: "Draft Lemonade", Viewer: tc.viewer})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.visible != (total == 1) {
+				t.Fatalf("Expected visible %t got %d results", tc.visible, total)
+			}
+		})
+	}
+
+	transitions := []struct {
+		desc   string
+		id     uint64
+		status string
+		note   string
+		error  error
+	}{
+		{"Should not publish a draft", uint64(id), database.RecipePublished, "", database.ErrStatusTransition},
+		{"Should submit a draft", uint64(id), database.RecipePending, "", nil},
+		{"Should reject a pending recipe", uint64(id), database.RecipeDraft, "needs more sugar", nil},
+		{"Should resubmit a rejected recipe", uint64(id), database.RecipePending, "", nil},
+		{"Should approve a pending recipe", uint64(id), database.RecipePublished, "", nil},
+		{"Should archive a published recipe", uint64(id), database.RecipeArchived, "", nil},
+		{"Should not archive an archived recipe", uint64(id), database.RecipeArchived, "", database.ErrStatusTransition},
+		{"Should reopen an archived recipe as a draft", uint64(id), database.RecipeDraft, "", nil},
+		{"Should fail to move an unknown recipe", 99999, database.RecipePending, "", database.ErrNoRows},
+	}
+
+	for i := range transitions {
+		tc := transitions[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			err := db.Recipe.Transition(tc.id, tc.status, tc.note)
+			if !errors.Is(err, tc.error) {
+				t.Fatalf("Expected error %v got %v", tc.error, err)
+			}
+			if err != nil {
+				return
+			}
+
+			recipe, err := db.Recipe.Get(tc.id, database.Viewer{Moderator: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recipe.Status != tc.status || recipe.ReviewNote != tc.note {
+				t.Fatalf("Expected status %s (%s) got %s (%s)", tc.status, tc.note, recipe.Status, recipe.ReviewNote)
+			}
+
+			// Pending recipes are listed in the moderation queue
+			queue, _, err := db.Recipe.Queue(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var queued bool
+			for j := range queue {
+				queued = queued || queue[j].ID == id
+			}
+			if queued != (tc.status == database.RecipePending) {
+				t.Fatalf("Expected queued %t got %t", tc.status == database.RecipePending, queued)
+			}
+		})
+	}
+}
+
+func TestRecipeTable_Trash(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Trashed Porridge",
+		URL:         "http://example.com/trashed-porridge",
+		Ingredients: database.Ingredients{{Name: "trashed oats"}, {Name: "milk"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inTrash := func() bool {
+		recipes, _, err := db.Recipe.Trash(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range recipes {
+			if recipes[i].ID == id {
+				return recipes[i].DeletedAt != "" && len(recipes[i].Ingredients) == 2
+			}
+		}
+		return false
+	}
+
+	hasIngredient := func() bool {
+		names, err := db.Ingredient.Names(database.Viewer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range names {
+			if names[i].Name == "trashed oats" {
+				return true
+			}
+		}
+		return false
+	}
+
+	steps := []struct {
+		desc    string
+		action  func(id uint64) error
+		error   error
+		deleted bool
+	}{
+		{"Should delete a recipe", db.Recipe.Delete, nil, true},
+		{"Should fail to delete a deleted recipe", db.Recipe.Delete, database.ErrNoRows, true},
+		{"Should restore a deleted recipe", db.Recipe.Restore, nil, false},
+		{"Should fail to restore a recipe that is not deleted", db.Recipe.Restore, database.ErrNoRows, false},
+		{"Should delete a restored recipe", db.Recipe.Delete, nil, true},
+	}
+
+	for i := range steps {
+		tc := steps[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := tc.action(uint64(id)); !errors.Is(err, tc.error) {
+				t.Fatalf("Expected error %v got %v", tc.error, err)
+			}
+
+			_, err := db.Recipe.Get(uint64(id), database.Viewer{Moderator: true})
+			if tc.deleted != errors.Is(err, database.ErrNoRows) {
+				t.Fatalf("Expected deleted %t got error %v", tc.deleted, err)
+			}
+			if tc.deleted != inTrash() {
+				t.Fatalf("Expected recipe in trash %t", tc.deleted)
+			}
+			if tc.deleted == hasIngredient() {
+				t.Fatalf("Expected deleted ingredients to be hidden %t", tc.deleted)
+			}
+		})
+	}
+
+	if _, err := db.Recipe.Purge(time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if !inTrash() {
+		t.Fatal("Expected recently deleted recipe to be kept")
+	}
+
+	purged, err := db.Recipe.Purge(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged < 1 || inTrash() {
+		t.Fatalf("Expected recipe to be purged, purged %d", purged)
+	}
+	if err := db.Recipe.Restore(uint64(id)); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected purged recipe to be gone got %v", err)
+	}
+}
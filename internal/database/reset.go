@@ -0,0 +1,12 @@
+package database
+
+import "time"
+
+// PasswordReset entity, reset tokens are stored by the sha256 hash of their value and can be used once before they
+// expire
+type PasswordReset struct {
+	ID        int64
+	UserID    int64
+	Hash      string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ResetTable object
+type ResetTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewResetTable create a ResetTable object
+func NewResetTable(db *sql.DB) *ResetTable {
+	return &ResetTable{
+		db:   db,
+		name: "password_reset p",
+	}
+}
+
+// Insert a new reset token, unused tokens issued to the user before are discarded so only the latest link works.
+// Returns inserted token id
+func (rt *ResetTable) Insert(p PasswordReset) (int64, error) {
+	var id int64
+	err := transaction(rt.db, func(tx *sql.Tx) error {
+		q := `DELETE FROM password_reset WHERE user_id = ? AND used_at IS NULL`
+		if _, err := tx.Exec(q, p.UserID); err != nil {
+			return fmt.Errorf("reset error, %w", err)
+		}
+
+		res, err := tx.Exec(`INSERT INTO password_reset (user_id, token_hash, expires_at) VALUES (?, ?, ?)`,
+			p.UserID, p.Hash, p.ExpiresAt.UTC())
+		if err != nil {
+			return fmt.Errorf("reset error, %w", err)
+		}
+
+		id, err = res.LastInsertId()
+
+		return err
+	})
+
+	return id, err
+}
+
+// Redeem marks the reset token with the given hash as used and sets the password of its user, returns the id of the
+// user. Unknown, expired and used tokens and tokens of deleted users return ErrNoRows.
+func (rt *ResetTable) Redeem(hash string, password string, now time.Time) (int64, error) {
+	var userID int64
+	err := transaction(rt.db, func(tx *sql.Tx) error {
+		// nolint:gosec
+		query := fmt.Sprintf(`SELECT p.id, p.user_id FROM %s
+WHERE p.token_hash = ? AND p.used_at IS NULL AND p.expires_at > ? FOR UPDATE`, rt.name)
+
+		var id int64
+		if err := tx.QueryRow(query, hash, now.UTC()).Scan(&id, &userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`UPDATE password_reset SET used_at = ? WHERE id = ?`, now.UTC(), id); err != nil {
+			return fmt.Errorf("reset error, %w", err)
+		}
+
+		res, err := tx.Exec(`UPDATE user SET password = ? WHERE id = ? AND deleted_at IS NULL`, password, userID)
+		if err != nil {
+			return fmt.Errorf("reset error, %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNoRows
+		}
+
+		return nil
+	})
+
+	return userID, err
+}
+
+// Purge removes used and expired reset tokens, returns the number of removed rows
+func (rt *ResetTable) Purge(now time.Time) (int64, error) {
+	q := `DELETE FROM password_reset WHERE used_at IS NOT NULL OR expires_at <= ?`
+	res, err := rt.db.Exec(q, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("reset error, %w", err)
+	}
+
+	return res.RowsAffected()
+}
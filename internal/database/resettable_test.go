@@ -0,0 +1,82 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestResetTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := db.User.Insert(database.User{
+		Username: "reset1",
+		Password: "password",
+		Email:    "reset1@test.gr",
+		Active:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	insert := func(hash string, expiresAt time.Time) {
+		if _, err := db.Reset.Insert(database.PasswordReset{UserID: id, Hash: hash, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Should only keep the latest token of a user", func(t *testing.T) {
+		insert("reset-hash1", now.Add(time.Hour))
+		insert("reset-hash2", now.Add(time.Hour))
+
+		if _, err := db.Reset.Redeem("reset-hash1", "password1", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error for a replaced token got %v", err)
+		}
+	})
+
+	t.Run("Should redeem a token once", func(t *testing.T) {
+		userID, err := db.Reset.Redeem("reset-hash2", "password2", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if userID != id {
+			t.Fatalf("Expected user %d got %d", id, userID)
+		}
+
+		u, err := db.User.Get(uint64(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Password != "password2" {
+			t.Fatalf("Expected the password to change got %s", u.Password)
+		}
+
+		if _, err := db.Reset.Redeem("reset-hash2", "password3", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error for a used token got %v", err)
+		}
+	})
+
+	t.Run("Should not redeem expired tokens", func(t *testing.T) {
+		insert("reset-hash3", now.Add(-time.Minute))
+
+		if _, err := db.Reset.Redeem("reset-hash3", "password3", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error for an expired token got %v", err)
+		}
+	})
+
+	t.Run("Should purge used and expired tokens", func(t *testing.T) {
+		n, err := db.Reset.Purge(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 2 {
+			t.Fatalf("Expected 2 purged rows got %d", n)
+		}
+	})
+}
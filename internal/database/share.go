@@ -0,0 +1,14 @@
+package database
+
+// Share entity, a link that gives read access to a recipe without signing in until it expires or is revoked
+type Share struct {
+	ID        int64
+	RecipeID  int64
+	UserID    int64
+	ExpiresAt string
+	RevokedAt string
+	CreatedAt string
+}
+
+// Shares slice of share entities
+type Shares []Share
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const shareColumns = "s.id, s.recipe_id, s.user_id, s.expires_at, s.revoked_at, s.created_at"
+
+// ShareTable object
+type ShareTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewShareTable create a ShareTable object
+func NewShareTable(db *sql.DB) *ShareTable {
+	return &ShareTable{
+		db:   db,
+		name: "recipe_share s",
+	}
+}
+
+// Active returns a share of a recipe that is neither revoked nor expired at the given time
+func (st *ShareTable) Active(id uint64, recipeID uint64, now time.Time) (*Share, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE s.id = ? AND s.recipe_id = ? AND s.revoked_at IS NULL AND s.expires_at > ?`,
+		shareColumns, st.name)
+
+	return scanShare(st.db.QueryRow(query, id, recipeID, now.UTC()))
+}
+
+// List the shares of a recipe, most recent first
+func (st *ShareTable) List(recipeID uint64) (Shares, error) {
+	// nolint:gosec
+	return st.list(fmt.Sprintf(`SELECT %s FROM %s WHERE s.recipe_id = ? ORDER BY s.id DESC`, shareColumns, st.name), recipeID)
+}
+
+// ByUser lists the shares created by a user, most recent first
+func (st *ShareTable) ByUser(userID int64) (Shares, error) {
+	// nolint:gosec
+	return st.list(fmt.Sprintf(`SELECT %s FROM %s WHERE s.user_id = ? ORDER BY s.id DESC`, shareColumns, st.name), userID)
+}
+
+// list runs a share select query and returns the resulting shares
+func (st *ShareTable) list(query string, args ...interface{}) (Shares, error) {
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares Shares
+	for rows.Next() {
+		s, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// Insert a new share of a recipe that expires at the given time, returns inserted share id
+func (st *ShareTable) Insert(recipeID int64, userID int64, expiresAt time.Time) (int64, error) {
+	q := `INSERT INTO recipe_share (recipe_id, user_id, expires_at) VALUES (?, ?, ?)`
+	res, err := st.db.Exec(q, recipeID, userID, expiresAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("share error, %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("share error, %w", err)
+	}
+
+	return id, nil
+}
+
+// Revoke a share of a recipe, returns ErrNoRows when the recipe has no such share or it is already revoked
+func (st *ShareTable) Revoke(id uint64, recipeID uint64) error {
+	q := `UPDATE recipe_share SET revoked_at = UTC_TIMESTAMP() WHERE id = ? AND recipe_id = ? AND revoked_at IS NULL`
+	res, err := st.db.Exec(q, id, recipeID)
+	if err != nil {
+		return fmt.Errorf("share error, %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("share error, %w", err)
+	}
+	if affected == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+func scanShare(s scanner) (*Share, error) {
+	var sh Share
+	var revokedAt sql.NullString
+	if err := s.Scan(&sh.ID, &sh.RecipeID, &sh.UserID, &sh.ExpiresAt, &revokedAt, &sh.CreatedAt); err != nil {
+		return nil, err
+	}
+	sh.RevokedAt = revokedAt.String
+
+	return &sh, nil
+}
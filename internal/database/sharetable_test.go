@@ -0,0 +1,69 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestShareTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipeID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Shared Focaccia",
+		URL:         "http://example.com/shared-focaccia",
+		Ingredients: database.Ingredients{{Name: "flour"}, {Name: "olive oil"}},
+		UserID:      1,
+		Status:      database.RecipePublished,
+		Visibility:  database.RecipePrivate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Recipe.Get(uint64(recipeID), database.Viewer{UserID: 2}); err == nil {
+		t.Fatal("Expected private recipe to be hidden from other users")
+	}
+	if _, err := db.Recipe.Get(uint64(recipeID), database.Viewer{SharedRecipeID: recipeID}); err != nil {
+		t.Fatalf("Expected private recipe to be visible through a share link, %s", err)
+	}
+
+	now := time.Now()
+	id, err := db.Share.Insert(recipeID, 1, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Share.Active(uint64(id), uint64(recipeID), now); err != nil {
+		t.Fatalf("Expected share to be active, %s", err)
+	}
+	if _, err := db.Share.Active(uint64(id), uint64(recipeID)+1, now); err == nil {
+		t.Fatal("Expected share to only be active for its recipe")
+	}
+	if _, err := db.Share.Active(uint64(id), uint64(recipeID), now.Add(2*time.Hour)); err == nil {
+		t.Fatal("Expected share to expire")
+	}
+
+	if err := db.Share.Revoke(uint64(id), uint64(recipeID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Share.Revoke(uint64(id), uint64(recipeID)); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected no rows error got %v", err)
+	}
+	if _, err := db.Share.Active(uint64(id), uint64(recipeID), now); err == nil {
+		t.Fatal("Expected revoked share to be inactive")
+	}
+
+	shares, err := db.Share.List(uint64(recipeID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || shares[0].RevokedAt == "" {
+		t.Fatalf("Expected a single revoked share got %+v", shares)
+	}
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/georlav/recipeapi/internal/fuzzy"
+)
+
+// Substitution entity, a replacement for an ingredient made of zero or more substitutes, a substitution without
+// substitutes means that the ingredient can be omitted
+type Substitution struct {
+	ID          int64
+	Ingredient  string
+	Substitutes []string
+	Notes       string
+	CreatedAt   string
+	UpdatedAt   string
+}
+
+// Substitutions slice of substitution entities
+type Substitutions []Substitution
+
+// For returns the substitutions of ingredient, names are compared by their IngredientKey
+func (s Substitutions) For(ingredient string) Substitutions {
+	key := IngredientKey(ingredient)
+
+	var subs Substitutions
+	for i := range s {
+		if IngredientKey(s[i].Ingredient) == key {
+			subs = append(subs, s[i])
+		}
+	}
+
+	return subs
+}
+
+// Cover returns the substitution that can replace ingredient using only what is available in pantry, substitutions
+// that use pantry ingredients are preferred over omitting the ingredient, returns nil when nothing fits
+func (s Substitutions) Cover(ingredient string, pantry []string) *Substitution {
+	available := make(map[string]struct{}, len(pantry))
+	for i := range pantry {
+		available[IngredientKey(pantry[i])] = struct{}{}
+	}
+
+	var omit *Substitution
+	for i := range s {
+		if IngredientKey(s[i].Ingredient) != IngredientKey(ingredient) {
+			continue
+		}
+		if len(s[i].Substitutes) == 0 {
+			if omit == nil {
+				omit = &s[i]
+			}
+			continue
+		}
+
+		covered := true
+		for _, sub := range s[i].Substitutes {
+			if _, ok := available[IngredientKey(sub)]; !ok {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return &s[i]
+		}
+	}
+
+	return omit
+}
+
+// IngredientKey normalizes an ingredient name for matching, it folds case and diacritics, collapses spaces and
+// reduces the last word to its singular so "Fresh  Tomatoes" matches "fresh tomato"
+func IngredientKey(name string) string {
+	words := strings.Fields(fuzzy.Fold(name))
+	if len(words) == 0 {
+		return ""
+	}
+	words[len(words)-1] = singular(words[len(words)-1])
+
+	return strings.Join(words, " ")
+}
+
+// singular strips the common English plural endings of word, it only needs to map both forms of a word to the same
+// key, not to produce the correct singular
+func singular(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case len(word) > 4 && (strings.HasSuffix(word, "oes") || strings.HasSuffix(word, "ches") ||
+		strings.HasSuffix(word, "shes") || strings.HasSuffix(word, "xes")):
+		return strings.TrimSuffix(word, "es")
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return strings.TrimSuffix(word, "s")
+	}
+
+	return word
+}
@@ -0,0 +1,84 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestSubstitutions_Cover(t *testing.T) {
+	subs := database.Substitutions{
+		{ID: 1, Ingredient: "buttermilk", Substitutes: []string{"milk", "lemon juice"}},
+		{ID: 2, Ingredient: "buttermilk", Substitutes: []string{"yogurt"}},
+		{ID: 3, Ingredient: "vodka"},
+		{ID: 4, Ingredient: "vodka", Substitutes: []string{"sparkling water"}},
+	}
+
+	testCases := []struct {
+		desc       string
+		ingredient string
+		pantry     []string
+		output     int64
+	}{
+		{"Should cover using all substitutes", "buttermilk", []string{"Milk", "lemon juice", "eggs"}, 1},
+		{"Should cover using the second substitution", "buttermilk", []string{"yogurt"}, 2},
+		{"Should not cover when a substitute is missing", "buttermilk", []string{"milk"}, 0},
+		{"Should prefer pantry substitutes over omitting", "vodka", []string{"sparkling water"}, 4},
+		{"Should fall back to omitting", "vodka", nil, 3},
+		{"Should not cover unknown ingredients", "eggs", []string{"milk"}, 0},
+		{"Should match names written differently", "Buttermilk", []string{"milks", "Lemon  Juice"}, 1},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			s := subs.Cover(tc.ingredient, tc.pantry)
+			if tc.output == 0 && s != nil {
+				t.Fatalf("Expected no substitution got %+v", s)
+			}
+			if tc.output != 0 && (s == nil || s.ID != tc.output) {
+				t.Fatalf("Expected substitution %d got %+v", tc.output, s)
+			}
+		})
+	}
+}
+
+func TestIngredientKey(t *testing.T) {
+	testCases := []struct {
+		input  string
+		output string
+	}{
+		{"Eggs", "egg"},
+		{"  Fresh   Tomatoes ", "fresh tomato"},
+		{"cherries", "cherry"},
+		{"peaches", "peach"},
+		{"radishes", "radish"},
+		{"Crème Fraîche", "creme fraiche"},
+		{"couscous", "couscous"},
+		{"swiss", "swiss"},
+		{"peas", "pea"},
+		{"", ""},
+	}
+
+	for i := range testCases {
+		if key := database.IngredientKey(testCases[i].input); key != testCases[i].output {
+			t.Fatalf("Expected %q to become %q got %q", testCases[i].input, testCases[i].output, key)
+		}
+	}
+}
+
+func TestSubstitutions_For(t *testing.T) {
+	subs := database.Substitutions{
+		{ID: 1, Ingredient: "egg", Substitutes: []string{"banana"}},
+		{ID: 2, Ingredient: "butter", Substitutes: []string{"margarine"}},
+		{ID: 3, Ingredient: "egg", Substitutes: []string{"flaxseed", "water"}},
+	}
+
+	if found := subs.For("Eggs"); len(found) != 2 || found[0].ID != 1 || found[1].ID != 3 {
+		t.Fatalf("Expected substitutions 1 and 3 got %+v", found)
+	}
+	if found := subs.For("milk"); len(found) != 0 {
+		t.Fatalf("Expected no substitutions got %+v", found)
+	}
+}
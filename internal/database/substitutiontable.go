@@ -0,0 +1,228 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const substitutionColumns = "s.id, s.ingredient, s.notes, s.created_at, s.updated_at"
+
+// SubstitutionTable object
+type SubstitutionTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewSubstitutionTable create a SubstitutionTable object
+func NewSubstitutionTable(db *sql.DB) *SubstitutionTable {
+	return &SubstitutionTable{
+		db:   db,
+		name: "substitution s",
+	}
+}
+
+// Get a substitution by id
+func (st *SubstitutionTable) Get(id uint64) (*Substitution, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, substitutionColumns, st.name)
+
+	var s Substitution
+	if err := st.db.QueryRow(query, id).Scan(
+		&s.ID, &s.Ingredient, &s.Notes, &s.CreatedAt, &s.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	subs, err := st.withSubstitutes(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &subs[0], nil
+}
+
+// List substitutions, when ingredients are given only substitutions for those ingredients are returned. Ingredients
+// are matched by their IngredientKey so case, spacing and plurals do not matter
+func (st *SubstitutionTable) List(ingredients ...string) (Substitutions, error) {
+	wanted := make(map[string]struct{}, len(ingredients))
+	for i := range ingredients {
+		wanted[IngredientKey(ingredients[i])] = struct{}{}
+	}
+
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY s.ingredient, s.id`, substitutionColumns, st.name)
+
+	rows, err := st.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs Substitutions
+	for rows.Next() {
+		s := Substitution{}
+		if err := rows.Scan(&s.ID, &s.Ingredient, &s.Notes, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if _, ok := wanted[IngredientKey(s.Ingredient)]; len(ingredients) > 0 && !ok {
+			continue
+		}
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return st.withSubstitutes(subs...)
+}
+
+// Insert a new substitution, returns inserted substitution id
+func (st *SubstitutionTable) Insert(s Substitution) (int64, error) {
+	var id int64
+	err := transaction(st.db, func(tx *sql.Tx) (err error) {
+		id, err = st.insert(tx, s)
+		return err
+	})
+
+	return id, err
+}
+
+// Update an existing substitution and replace its substitutes
+func (st *SubstitutionTable) Update(s Substitution) error {
+	return transaction(st.db, func(tx *sql.Tx) error {
+		q := `UPDATE substitution SET ingredient = ?, notes = ? WHERE id = ?`
+		if _, err := tx.Exec(q, normalizeName(s.Ingredient), s.Notes, s.ID); err != nil {
+			return fmt.Errorf("substitution error, %w", err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM substitution_ingredient WHERE substitution_id = ?`, s.ID); err != nil {
+			return fmt.Errorf("substitution error, %w", err)
+		}
+
+		return st.insertSubstitutes(tx, s.ID, s.Substitutes)
+	})
+}
+
+// Delete a substitution
+func (st *SubstitutionTable) Delete(id uint64) error {
+	res, err := st.db.Exec(`DELETE FROM substitution WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("substitution error, %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// Import bulk loads substitutions, existing substitutions of the imported ingredients are replaced
+func (st *SubstitutionTable) Import(subs Substitutions) (int, error) {
+	err := transaction(st.db, func(tx *sql.Tx) error {
+		for i := range subs {
+			q := `DELETE FROM substitution WHERE ingredient = ?`
+			if _, err := tx.Exec(q, normalizeName(subs[i].Ingredient)); err != nil {
+				return fmt.Errorf("substitution error, %w", err)
+			}
+		}
+
+		for i := range subs {
+			if _, err := st.insert(tx, subs[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(subs), nil
+}
+
+func (st *SubstitutionTable) insert(tx *sql.Tx, s Substitution) (int64, error) {
+	res, err := tx.Exec(`INSERT INTO substitution (ingredient, notes) VALUES (?, ?)`, normalizeName(s.Ingredient), s.Notes)
+	if err != nil {
+		return 0, fmt.Errorf("substitution error, %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("substitution error, %w", err)
+	}
+
+	return id, st.insertSubstitutes(tx, id, s.Substitutes)
+}
+
+func (st *SubstitutionTable) insertSubstitutes(tx *sql.Tx, id int64, substitutes []string) error {
+	if len(substitutes) == 0 {
+		return nil
+	}
+
+	// nolint:gosec
+	q := fmt.Sprintf(`INSERT INTO substitution_ingredient (substitution_id, name) VALUES %s`,
+		strings.TrimSuffix(strings.Repeat("(?, ?),", len(substitutes)), ","),
+	)
+
+	var args []interface{}
+	for i := range substitutes {
+		args = append(args, id, normalizeName(substitutes[i]))
+	}
+
+	if _, err := tx.Exec(q, args...); err != nil {
+		return fmt.Errorf("substitute error, %w", err)
+	}
+
+	return nil
+}
+
+// Get substitution substitutes
+func (st *SubstitutionTable) withSubstitutes(subs ...Substitution) (Substitutions, error) {
+	if len(subs) == 0 {
+		return subs, nil
+	}
+
+	var args []interface{}
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT si.substitution_id, si.name 
+FROM substitution_ingredient si 
+WHERE si.substitution_id IN (%s) 
+ORDER BY si.id`,
+		strings.TrimSuffix(strings.Repeat("?,", len(subs)), ","),
+	)
+	for i := range subs {
+		args = append(args, subs[i].ID)
+	}
+
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+
+		for i := range subs {
+			if subs[i].ID == id {
+				subs[i].Substitutes = append(subs[i].Substitutes, name)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
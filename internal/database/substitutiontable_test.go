@@ -0,0 +1,80 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestSubstitutionTable_Insert(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := db.Substitution.Insert(database.Substitution{
+		Ingredient:  "Buttermilk",
+		Substitutes: []string{"milk", "lemon juice"},
+		Notes:       "let it stand for 5 minutes",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := db.Substitution.Get(uint64(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Ingredient != "buttermilk" || len(s.Substitutes) != 2 {
+		t.Fatalf("Unexpected substitution %+v", s)
+	}
+
+	s.Substitutes = []string{"yogurt"}
+	if err := db.Substitution.Update(*s); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = db.Substitution.Get(uint64(id)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Substitutes) != 1 || s.Substitutes[0] != "yogurt" {
+		t.Fatalf("Expected substitutes to be replaced got %v", s.Substitutes)
+	}
+
+	if err := db.Substitution.Delete(uint64(id)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Substitution.Delete(uint64(id)); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected no rows error got %v", err)
+	}
+}
+
+func TestSubstitutionTable_Import(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subs := database.Substitutions{
+		{Ingredient: "vodka", Substitutes: []string{"sparkling water"}},
+		{Ingredient: "vodka", Notes: "omit"},
+	}
+
+	// Importing twice should replace existing substitutions instead of duplicating them
+	for i := 0; i < 2; i++ {
+		if _, err := db.Substitution.Import(subs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := db.Substitution.List("vodka")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Expected %d substitutions got %d", 2, len(list))
+	}
+	if s := list.Cover("vodka", nil); s == nil || len(s.Substitutes) != 0 {
+		t.Fatalf("Expected vodka to be omitted got %+v", s)
+	}
+}
@@ -0,0 +1,57 @@
+package database
+
+import "strings"
+
+// Tag kinds, cuisines, courses, diets and occasions are curated facets while free tags are anything authors choose
+const (
+	TagCuisine  = "cuisine"
+	TagCourse   = "course"
+	TagDiet     = "diet"
+	TagOccasion = "occasion"
+	TagFree     = "tag"
+)
+
+// TagKinds lists the supported tag kinds
+var TagKinds = []string{TagCuisine, TagCourse, TagDiet, TagOccasion, TagFree}
+
+// Tag entity, tags are shared by all recipes that use them
+type Tag struct {
+	ID   int64
+	Kind string
+	Name string
+}
+
+// Tags slice of tag entities
+type Tags []Tag
+
+// NewTag creates a tag with a lower cased name without repeated spaces, unknown kinds make free tags
+func NewTag(kind string, name string) Tag {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if !IsTagKind(kind) {
+		kind = TagFree
+	}
+
+	return Tag{Kind: kind, Name: strings.Join(strings.Fields(strings.ToLower(name)), " ")}
+}
+
+// IsTagKind reports whether kind is one of the supported tag kinds
+func IsTagKind(kind string) bool {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	for i := range TagKinds {
+		if TagKinds[i] == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Facet the number of recipes of a result set that have a tag
+type Facet struct {
+	Kind  string
+	Name  string
+	Count int64
+}
+
+// Facets slice of facet objects
+type Facets []Facet
@@ -0,0 +1,29 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestNewTag(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		name     string
+		expected database.Tag
+	}{
+		{"cuisine", "Greek", database.Tag{Kind: database.TagCuisine, Name: "greek"}},
+		{" Course ", "  Main   Course ", database.Tag{Kind: database.TagCourse, Name: "main course"}},
+		{"mood", "cozy", database.Tag{Kind: database.TagFree, Name: "cozy"}},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.kind+" "+tc.name, func(t *testing.T) {
+			if tag := database.NewTag(tc.kind, tc.name); tag != tc.expected {
+				t.Fatalf("Expected %+v got %+v", tc.expected, tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const tagColumns = "t.id, t.kind, t.name"
+
+// TagTable object
+type TagTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewTagTable create a TagTable object
+func NewTagTable(db *sql.DB) *TagTable {
+	return &TagTable{
+		db:   db,
+		name: "tag t",
+	}
+}
+
+// List the tags of a kind ordered by name, all tags ordered by kind and name when kind is empty
+func (tt *TagTable) List(kind string) (Tags, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE ? = '' OR t.kind = ? ORDER BY t.kind, t.name`, tagColumns, tt.name)
+
+	rows, err := tt.db.Query(query, kind, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := Tags{}
+	for rows.Next() {
+		t := Tag{}
+		if err := rows.Scan(&t.ID, &t.Kind, &t.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// Set replaces the tags of a recipe, tags that do not exist yet are created
+func (tt *TagTable) Set(recipeID int64, tags Tags) error {
+	return transaction(tt.db, func(tx *sql.Tx) error {
+		return setRecipeTags(tx, recipeID, tags)
+	})
+}
+
+// setRecipeTags replaces the tags of a recipe inside a transaction, tags are normalized and repeated ones dropped
+func setRecipeTags(tx *sql.Tx, recipeID int64, tags Tags) error {
+	if _, err := tx.Exec(`DELETE FROM recipe_tag WHERE recipe_id = ?`, recipeID); err != nil {
+		return fmt.Errorf("tag error, %w", err)
+	}
+
+	var args []interface{}
+	seen := make(map[Tag]struct{})
+	for i := range tags {
+		t := NewTag(tags[i].Kind, tags[i].Name)
+		if _, ok := seen[t]; ok || t.Name == "" {
+			continue
+		}
+		seen[t] = struct{}{}
+		args = append(args, t.Kind, t.Name)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	// nolint:gosec
+	q := fmt.Sprintf(`INSERT IGNORE INTO tag (kind, name) VALUES %s`,
+		strings.TrimSuffix(strings.Repeat("(?, ?),", len(seen)), ","),
+	)
+	if _, err := tx.Exec(q, args...); err != nil {
+		return fmt.Errorf("tag error, %w", err)
+	}
+
+	// nolint:gosec
+	q = fmt.Sprintf(`INSERT INTO recipe_tag (recipe_id, tag_id) SELECT ?, t.id FROM tag t WHERE %s`,
+		strings.TrimSuffix(strings.Repeat("(t.kind = ? AND t.name = ?) OR ", len(seen)), " OR "),
+	)
+	if _, err := tx.Exec(q, append([]interface{}{recipeID}, args...)...); err != nil {
+		return fmt.Errorf("tag error, %w", err)
+	}
+
+	return nil
+}
+
+// recipeTags returns the tags of recipes by recipe id ordered by kind and name
+func recipeTags(db *sql.DB, recipeIDs []interface{}) (map[int64]Tags, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT rg.recipe_id, %s FROM recipe_tag rg
+JOIN tag t ON t.id = rg.tag_id
+WHERE rg.recipe_id IN (%s)
+ORDER BY t.kind, t.name`,
+		tagColumns,
+		strings.TrimSuffix(strings.Repeat("?,", len(recipeIDs)), ","),
+	)
+
+	rows, err := db.Query(query, recipeIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("tag error, %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int64]Tags)
+	for rows.Next() {
+		var recipeID int64
+		t := Tag{}
+		if err := rows.Scan(&recipeID, &t.ID, &t.Kind, &t.Name); err != nil {
+			return nil, err
+		}
+		tags[recipeID] = append(tags[recipeID], t)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,108 @@
+package database_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestTagTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	greek, italian := database.NewTag(database.TagCuisine, "Greek"), database.NewTag(database.TagCuisine, "italian")
+	main, spicy := database.NewTag(database.TagCourse, "main"), database.NewTag(database.TagFree, "spicy")
+
+	var ids []int64
+	for _, r := range []database.Recipe{
+		{Title: "Tagged Souvlaki", Tags: database.Tags{greek, main, spicy}},
+		{Title: "Tagged Pastitsio", Tags: database.Tags{greek, main, greek}},
+		{Title: "Tagged Arrabbiata", Tags: database.Tags{italian, main, spicy}},
+	} {
+		r.Ingredients = database.Ingredients{{Name: "salt"}}
+		id, err := db.Recipe.Insert(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	recipe, err := db.Recipe.Get(uint64(ids[1]), database.Viewer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipe.Tags) != 2 || recipe.Tags[0].Kind != database.TagCourse || recipe.Tags[1].Name != "greek" {
+		t.Fatalf("Expected the recipe to have its tags once got %+v", recipe.Tags)
+	}
+
+	testData := []struct {
+		desc           string
+		filters        database.RecipeFilters
+		expected       []int64
+		expectedFacets database.Facets
+	}{
+		{"Should match all tags", database.RecipeFilters{Term: "Tagged", Tags: database.Tags{main, spicy}},
+			[]int64{ids[0], ids[2]}, database.Facets{
+				{Kind: database.TagCourse, Name: "main", Count: 2},
+				{Kind: database.TagCuisine, Name: "greek", Count: 1},
+				{Kind: database.TagCuisine, Name: "italian", Count: 1},
+				{Kind: database.TagFree, Name: "spicy", Count: 2},
+			}},
+		{"Should match any tag", database.RecipeFilters{Term: "Tagged", Tags: database.Tags{italian, greek}, AnyTag: true},
+			[]int64{ids[0], ids[1], ids[2]}, database.Facets{
+				{Kind: database.TagCourse, Name: "main", Count: 3},
+				{Kind: database.TagCuisine, Name: "greek", Count: 2},
+				{Kind: database.TagCuisine, Name: "italian", Count: 1},
+				{Kind: database.TagFree, Name: "spicy", Count: 2},
+			}},
+		{"Should match nothing", database.RecipeFilters{Term: "Tagged", Tags: database.Tags{italian, greek}},
+			nil, database.Facets{}},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			recipes, _, err := db.Recipe.Paginate(1, &tc.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var found []int64
+			for i := range recipes {
+				found = append(found, recipes[i].ID)
+			}
+			if !reflect.DeepEqual(found, tc.expected) {
+				t.Fatalf("Expected recipes %v got %v", tc.expected, found)
+			}
+
+			facets, err := db.Recipe.Facets(&tc.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(facets, tc.expectedFacets) {
+				t.Fatalf("Expected facets %+v got %+v", tc.expectedFacets, facets)
+			}
+		})
+	}
+
+	if err := db.Tag.Set(ids[0], database.Tags{italian}); err != nil {
+		t.Fatal(err)
+	}
+	if recipe, err = db.Recipe.Get(uint64(ids[0]), database.Viewer{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(recipe.Tags) != 1 || recipe.Tags[0].Name != "italian" {
+		t.Fatalf("Expected the tags to be replaced got %+v", recipe.Tags)
+	}
+
+	tags, err := db.Tag.List(database.TagCuisine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].Name != "greek" || tags[1].Name != "italian" {
+		t.Fatalf("Expected the cuisine tags got %+v", tags)
+	}
+}
@@ -0,0 +1,163 @@
+package database
+
+import "strings"
+
+// Taxonomy entity, a single node of the ingredient hierarchy (dairy > cheese > cheddar)
+type Taxonomy struct {
+	ID        int64
+	ParentID  int64
+	Name      string
+	Allergen  string
+	Aisle     string
+	CreatedAt string
+	UpdatedAt string
+}
+
+// Taxonomies slice of taxonomy entities
+type Taxonomies []Taxonomy
+
+// TaxonomyNode object used to bulk load a taxonomy tree, children inherit their parent
+type TaxonomyNode struct {
+	Name     string
+	Allergen string
+	Aisle    string
+	Children []TaxonomyNode
+}
+
+// TaxonomyTree in memory representation of the ingredient hierarchy
+type TaxonomyTree struct {
+	byName   map[string]*Taxonomy
+	byID     map[int64]*Taxonomy
+	children map[int64][]*Taxonomy
+}
+
+// NewTaxonomyTree builds a tree from a flat list of taxonomy entities
+func NewTaxonomyTree(nodes Taxonomies) *TaxonomyTree {
+	tt := TaxonomyTree{
+		byName:   make(map[string]*Taxonomy, len(nodes)),
+		byID:     make(map[int64]*Taxonomy, len(nodes)),
+		children: make(map[int64][]*Taxonomy),
+	}
+
+	for i := range nodes {
+		n := &nodes[i]
+		tt.byName[normalizeName(n.Name)] = n
+		tt.byID[n.ID] = n
+		tt.children[n.ParentID] = append(tt.children[n.ParentID], n)
+	}
+
+	return &tt
+}
+
+// Expand returns the given names followed by the names of all their descendants, names that are not part of the
+// taxonomy are returned as is. Names are compared normalized, only the first spelling of a name is returned
+func (tt *TaxonomyTree) Expand(names ...string) []string {
+	seen := make(map[string]struct{})
+	var expanded []string
+
+	add := func(name string) bool {
+		key := normalizeName(name)
+		if _, ok := seen[key]; ok {
+			return false
+		}
+		seen[key] = struct{}{}
+		expanded = append(expanded, name)
+		return true
+	}
+
+	var walk func(n *Taxonomy)
+	walk = func(n *Taxonomy) {
+		for _, c := range tt.children[n.ID] {
+			if add(c.Name) {
+				walk(c)
+			}
+		}
+	}
+
+	for i := range names {
+		if !add(names[i]) {
+			continue
+		}
+		if n, ok := tt.byName[normalizeName(names[i])]; ok {
+			walk(n)
+		}
+	}
+
+	return expanded
+}
+
+// Allergen returns the allergen group of an ingredient, inherited from the closest ancestor that defines one
+func (tt *TaxonomyTree) Allergen(name string) string {
+	for _, n := range tt.ancestors(name) {
+		if n.Allergen != "" {
+			return n.Allergen
+		}
+	}
+
+	return ""
+}
+
+// Aisle returns the shopping list aisle of an ingredient, inherited from the closest ancestor that defines one
+func (tt *TaxonomyTree) Aisle(name string) string {
+	for _, n := range tt.ancestors(name) {
+		if n.Aisle != "" {
+			return n.Aisle
+		}
+	}
+
+	return ""
+}
+
+// Lineage returns the normalized name followed by the names of all its ancestors, closest first
+func (tt *TaxonomyTree) Lineage(name string) []string {
+	lineage := []string{normalizeName(name)}
+	for i, n := range tt.ancestors(name) {
+		if i > 0 {
+			lineage = append(lineage, normalizeName(n.Name))
+		}
+	}
+
+	return lineage
+}
+
+// ancestors returns the node of name followed by its ancestors, closest first. The walk stops at the first node it
+// already visited so a parent cycle can not loop forever
+func (tt *TaxonomyTree) ancestors(name string) []*Taxonomy {
+	var nodes []*Taxonomy
+	seen := make(map[int64]struct{})
+
+	for n := tt.byName[normalizeName(name)]; n != nil; n = tt.byID[n.ParentID] {
+		if _, ok := seen[n.ID]; ok {
+			break
+		}
+		seen[n.ID] = struct{}{}
+		nodes = append(nodes, n)
+	}
+
+	return nodes
+}
+
+// cyclic reports whether following the parents of any node leads back to that node, parents maps node ids to the
+// id of their parent, zero for root nodes
+func cyclic(parents map[int64]int64) bool {
+	acyclic := make(map[int64]bool, len(parents))
+
+	for id := range parents {
+		path := make(map[int64]struct{})
+		for n := id; n != 0 && !acyclic[n]; n = parents[n] {
+			if _, ok := path[n]; ok {
+				return true
+			}
+			path[n] = struct{}{}
+		}
+		for n := range path {
+			acyclic[n] = true
+		}
+	}
+
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
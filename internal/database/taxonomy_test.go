@@ -0,0 +1,97 @@
+package database_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestTaxonomyTree(t *testing.T) {
+	tree := database.NewTaxonomyTree(database.Taxonomies{
+		{ID: 1, Name: "dairy", Allergen: "milk", Aisle: "dairy"},
+		{ID: 2, ParentID: 1, Name: "cheese"},
+		{ID: 3, ParentID: 2, Name: "cheddar cheese", Aisle: "deli"},
+		{ID: 4, ParentID: 2, Name: "parmesan"},
+		{ID: 5, Name: "poultry", Aisle: "meat"},
+		{ID: 6, ParentID: 5, Name: "chicken"},
+	})
+
+	t.Run("Should expand names with their descendants", func(t *testing.T) {
+		testCases := []struct {
+			input  []string
+			output []string
+		}{
+			{[]string{"cheese"}, []string{"cheese", "cheddar cheese", "parmesan"}},
+			{[]string{"Dairy"}, []string{"Dairy", "cheese", "cheddar cheese", "parmesan"}},
+			{[]string{"parmesan", "cheese"}, []string{"parmesan", "cheese", "cheddar cheese"}},
+			{[]string{"Cheese", "cheese"}, []string{"Cheese", "cheddar cheese", "parmesan"}},
+			{[]string{"Parmesan ", "cheese"}, []string{"Parmesan ", "cheese", "cheddar cheese"}},
+			{[]string{"chicken", "eggs"}, []string{"chicken", "eggs"}},
+			{[]string{"eggs"}, []string{"eggs"}},
+		}
+
+		for i := range testCases {
+			if expanded := tree.Expand(testCases[i].input...); !reflect.DeepEqual(expanded, testCases[i].output) {
+				t.Fatalf("Expected %v got %v", testCases[i].output, expanded)
+			}
+		}
+	})
+
+	t.Run("Should inherit allergen and aisle from ancestors", func(t *testing.T) {
+		testCases := []struct {
+			input    string
+			allergen string
+			aisle    string
+		}{
+			{"parmesan", "milk", "dairy"},
+			{"cheddar cheese", "milk", "deli"},
+			{"chicken", "", "meat"},
+			{"eggs", "", ""},
+		}
+
+		for i := range testCases {
+			tc := testCases[i]
+			if allergen := tree.Allergen(tc.input); allergen != tc.allergen {
+				t.Fatalf("Expected %s allergen to be %s got %s", tc.input, tc.allergen, allergen)
+			}
+			if aisle := tree.Aisle(tc.input); aisle != tc.aisle {
+				t.Fatalf("Expected %s aisle to be %s got %s", tc.input, tc.aisle, aisle)
+			}
+		}
+	})
+
+	t.Run("Should list names with their ancestors", func(t *testing.T) {
+		testCases := []struct {
+			input  string
+			output []string
+		}{
+			{"Cheddar Cheese", []string{"cheddar cheese", "cheese", "dairy"}},
+			{"chicken", []string{"chicken", "poultry"}},
+			{"eggs", []string{"eggs"}},
+		}
+
+		for i := range testCases {
+			if lineage := tree.Lineage(testCases[i].input); !reflect.DeepEqual(lineage, testCases[i].output) {
+				t.Fatalf("Expected %v got %v", testCases[i].output, lineage)
+			}
+		}
+	})
+
+	t.Run("Should stop at parent cycles", func(t *testing.T) {
+		cycle := database.NewTaxonomyTree(database.Taxonomies{
+			{ID: 1, ParentID: 2, Name: "dairy"},
+			{ID: 2, ParentID: 1, Name: "cheese"},
+		})
+
+		if allergen := cycle.Allergen("cheese"); allergen != "" {
+			t.Fatalf("Expected no allergen got %s", allergen)
+		}
+		if aisle := cycle.Aisle("cheese"); aisle != "" {
+			t.Fatalf("Expected no aisle got %s", aisle)
+		}
+		if lineage := cycle.Lineage("cheese"); !reflect.DeepEqual(lineage, []string{"cheese", "dairy"}) {
+			t.Fatalf("Expected %v got %v", []string{"cheese", "dairy"}, lineage)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const taxonomyColumns = "t.id, t.parent_id, t.name, t.allergen, t.aisle, t.created_at, t.updated_at"
+
+// TaxonomyTable object
+type TaxonomyTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewTaxonomyTable create a TaxonomyTable object
+func NewTaxonomyTable(db *sql.DB) *TaxonomyTable {
+	return &TaxonomyTable{
+		db:   db,
+		name: "taxonomy t",
+	}
+}
+
+// Get a taxonomy node by id
+func (tt *TaxonomyTable) Get(id uint64) (*Taxonomy, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, taxonomyColumns, tt.name)
+
+	t, err := scanTaxonomy(tt.db.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// List all taxonomy nodes
+func (tt *TaxonomyTable) List() (Taxonomies, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY t.name`, taxonomyColumns, tt.name)
+
+	rows, err := tt.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes Taxonomies
+	for rows.Next() {
+		t, err := scanTaxonomy(rows)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// Tree loads the whole taxonomy and returns it as a tree
+func (tt *TaxonomyTable) Tree() (*TaxonomyTree, error) {
+	nodes, err := tt.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTaxonomyTree(nodes), nil
+}
+
+// Insert a new taxonomy node, returns inserted node id
+func (tt *TaxonomyTable) Insert(t Taxonomy) (int64, error) {
+	q := `INSERT INTO taxonomy (parent_id, name, allergen, aisle) VALUES (?, ?, ?, ?)`
+	res, err := tt.db.Exec(q, nullID(t.ParentID), normalizeName(t.Name), t.Allergen, t.Aisle)
+	if err != nil {
+		if strings.Contains(err.Error(), "Error 1062") {
+			return 0, ErrDuplicateEntry
+		}
+		return 0, fmt.Errorf("taxonomy error, %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("taxonomy error, %w", err)
+	}
+
+	return id, nil
+}
+
+// Update an existing taxonomy node. Updates that would make a node its own ancestor are rolled back with
+// ErrTaxonomyCycle
+func (tt *TaxonomyTable) Update(t Taxonomy) error {
+	q := `UPDATE taxonomy SET parent_id = ?, name = ?, allergen = ?, aisle = ? WHERE id = ?`
+
+	tx, err := tt.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		if err := lockTaxonomy(tx); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(q, nullID(t.ParentID), normalizeName(t.Name), t.Allergen, t.Aisle, t.ID); err != nil {
+			if strings.Contains(err.Error(), "Error 1062") {
+				return ErrDuplicateEntry
+			}
+			return fmt.Errorf("taxonomy error, %w", err)
+		}
+
+		return checkCycles(tx)
+	}()
+
+	if err != nil {
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete a taxonomy node, children of the deleted node are moved under its parent
+func (tt *TaxonomyTable) Delete(id uint64) error {
+	t, err := tt.Get(id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := tt.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		if _, err := tx.Exec(`UPDATE taxonomy SET parent_id = ? WHERE parent_id = ?`, nullID(t.ParentID), t.ID); err != nil {
+			return fmt.Errorf("taxonomy error, %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM taxonomy WHERE id = ?`, t.ID); err != nil {
+			return fmt.Errorf("taxonomy error, %w", err)
+		}
+
+		return nil
+	}()
+
+	if err != nil {
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Import bulk loads a taxonomy tree, existing nodes are matched by name and updated, returns the number of
+// imported nodes. Imports that would make a node its own ancestor are rolled back with ErrTaxonomyCycle
+func (tt *TaxonomyTable) Import(nodes []TaxonomyNode) (int, error) {
+	q := `INSERT INTO taxonomy (parent_id, name, allergen, aisle) VALUES (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE parent_id = VALUES(parent_id), allergen = VALUES(allergen), aisle = VALUES(aisle),
+id = LAST_INSERT_ID(id)`
+
+	tx, err := tt.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	var insert func(parentID int64, nodes []TaxonomyNode) error
+	insert = func(parentID int64, nodes []TaxonomyNode) error {
+		for i := range nodes {
+			res, err := tx.Exec(q, nullID(parentID), normalizeName(nodes[i].Name), nodes[i].Allergen, nodes[i].Aisle)
+			if err != nil {
+				return fmt.Errorf("taxonomy error, %s, %w", nodes[i].Name, err)
+			}
+
+			id, err := res.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("taxonomy error, %w", err)
+			}
+			total++
+
+			if err := insert(id, nodes[i].Children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err = lockTaxonomy(tx)
+	if err == nil {
+		err = insert(0, nodes)
+	}
+	if err == nil {
+		err = checkCycles(tx)
+	}
+	if err != nil {
+		if err := tx.Rollback(); err != nil {
+			return 0, err
+		}
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// lockTaxonomy locks every taxonomy row until tx ends, so concurrent moves can not create a cycle that
+// checkCycles of either transaction would miss
+func lockTaxonomy(tx *sql.Tx) error {
+	if _, err := tx.Exec(`SELECT id FROM taxonomy FOR UPDATE`); err != nil {
+		return fmt.Errorf("taxonomy error, %w", err)
+	}
+
+	return nil
+}
+
+// checkCycles returns ErrTaxonomyCycle when the taxonomy as seen by tx has a parent cycle
+func checkCycles(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, IFNULL(parent_id, 0) FROM taxonomy`)
+	if err != nil {
+		return fmt.Errorf("taxonomy error, %w", err)
+	}
+	defer rows.Close()
+
+	parents := make(map[int64]int64)
+	for rows.Next() {
+		var id, parentID int64
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return fmt.Errorf("taxonomy error, %w", err)
+		}
+		parents[id] = parentID
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("taxonomy error, %w", err)
+	}
+
+	if cyclic(parents) {
+		return ErrTaxonomyCycle
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTaxonomy(s scanner) (*Taxonomy, error) {
+	var t Taxonomy
+	var parentID sql.NullInt64
+	if err := s.Scan(
+		&t.ID, &parentID, &t.Name, &t.Allergen, &t.Aisle, &t.CreatedAt, &t.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	t.ParentID = parentID.Int64
+
+	return &t, nil
+}
+
+// nullID maps a zero id to NULL, used for optional foreign keys
+func nullID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+
+	return id
+}
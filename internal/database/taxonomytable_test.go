@@ -0,0 +1,120 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestTaxonomyTable_Import(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := []database.TaxonomyNode{
+		{
+			Name:     "dairy",
+			Allergen: "milk",
+			Aisle:    "dairy",
+			Children: []database.TaxonomyNode{
+				{Name: "cheese", Children: []database.TaxonomyNode{{Name: "cheddar cheese"}, {Name: "parmesan"}}},
+				{Name: "butter"},
+			},
+		},
+	}
+
+	// Importing twice should update existing nodes instead of failing
+	for i := 0; i < 2; i++ {
+		total, err := db.Taxonomy.Import(nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 5 {
+			t.Fatalf("Expected to import %d nodes got %d", 5, total)
+		}
+	}
+
+	tree, err := db.Taxonomy.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded := tree.Expand("cheese"); len(expanded) != 3 {
+		t.Fatalf("Expected cheese to expand to %d names got %v", 3, expanded)
+	}
+	if allergen := tree.Allergen("parmesan"); allergen != "milk" {
+		t.Fatalf("Expected parmesan allergen to be milk got %s", allergen)
+	}
+
+	// Listing cheese again under its own child would make it its own ancestor, nothing of the import is kept
+	cycle := []database.TaxonomyNode{{
+		Name:     "cheese",
+		Children: []database.TaxonomyNode{{Name: "dairy", Children: []database.TaxonomyNode{{Name: "cheese"}}}},
+	}}
+	if _, err := db.Taxonomy.Import(cycle); !errors.Is(err, database.ErrTaxonomyCycle) {
+		t.Fatalf("Expected a cycle error got %v", err)
+	}
+	if tree, err = db.Taxonomy.Tree(); err != nil {
+		t.Fatal(err)
+	}
+	if lineage := tree.Lineage("parmesan"); len(lineage) != 3 {
+		t.Fatalf("Expected parmesan to keep its ancestors got %v", lineage)
+	}
+}
+
+func TestTaxonomyTable_Insert(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := db.Taxonomy.Insert(database.Taxonomy{Name: "poultry", Aisle: "meat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	childID, err := db.Taxonomy.Insert(database.Taxonomy{ParentID: id, Name: "Chicken "})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Taxonomy.Insert(database.Taxonomy{Name: "chicken"}); !errors.Is(err, database.ErrDuplicateEntry) {
+		t.Fatalf("Expected duplicate entry error got %v", err)
+	}
+
+	child, err := db.Taxonomy.Get(uint64(childID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.Name != "chicken" || child.ParentID != id {
+		t.Fatalf("Unexpected node %+v", child)
+	}
+
+	// Moving a node under its own child is rolled back
+	err = db.Taxonomy.Update(database.Taxonomy{ID: id, ParentID: childID, Name: "poultry", Aisle: "meat"})
+	if !errors.Is(err, database.ErrTaxonomyCycle) {
+		t.Fatalf("Expected a cycle error got %v", err)
+	}
+	parent, err := db.Taxonomy.Get(uint64(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.ParentID != 0 {
+		t.Fatalf("Expected node to keep its parent got %d", parent.ParentID)
+	}
+
+	// Deleting a node moves its children under its parent
+	if err := db.Taxonomy.Delete(uint64(id)); err != nil {
+		t.Fatal(err)
+	}
+	if child, err = db.Taxonomy.Get(uint64(childID)); err != nil {
+		t.Fatal(err)
+	}
+	if child.ParentID != 0 {
+		t.Fatalf("Expected node to have no parent got %d", child.ParentID)
+	}
+	if err := db.Taxonomy.Delete(uint64(id)); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected no rows error got %v", err)
+	}
+}
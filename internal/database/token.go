@@ -0,0 +1,16 @@
+package database
+
+import "time"
+
+// RefreshToken entity, refresh tokens are stored by the sha256 hash of their value and can be used once. Every
+// refresh issues a new token of the same family, a family starts when a user signs in. AccessID and AccessExpiresAt
+// identify the access token issued together with the refresh token so it can be revoked with its family.
+type RefreshToken struct {
+	ID              int64
+	UserID          int64
+	Family          string
+	Hash            string
+	AccessID        string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+}
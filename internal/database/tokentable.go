@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TokenTable object
+type TokenTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewTokenTable create a TokenTable object
+func NewTokenTable(db *sql.DB) *TokenTable {
+	return &TokenTable{
+		db:   db,
+		name: "refresh_token t",
+	}
+}
+
+// Insert a new refresh token, returns inserted token id
+func (tt *TokenTable) Insert(t RefreshToken) (int64, error) {
+	q := `INSERT INTO refresh_token (user_id, family, token_hash, access_jti, access_expires_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)`
+
+	res, err := tt.db.Exec(q, t.UserID, t.Family, t.Hash, t.AccessID, t.AccessExpiresAt.UTC(), t.ExpiresAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("token error, %w", err)
+	}
+
+	return res.LastInsertId()
+}
+
+// Rotate marks the refresh token with the given hash as used and returns it. Unknown, expired and revoked tokens
+// return ErrNoRows. A token that was already used means it leaked, its whole family is revoked and ErrTokenReused
+// is returned.
+func (tt *TokenTable) Rotate(hash string, now time.Time) (*RefreshToken, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT t.id, t.user_id, t.family, t.used_at IS NOT NULL FROM %s
+WHERE t.token_hash = ? AND t.revoked_at IS NULL AND t.expires_at > ?`, tt.name)
+
+	t := RefreshToken{Hash: hash}
+	var used bool
+	if err := tt.db.QueryRow(query, hash, now.UTC()).Scan(&t.ID, &t.UserID, &t.Family, &used); err != nil {
+		return nil, err
+	}
+
+	// Concurrent refreshes with the same token race on this update, only one of them wins
+	res, err := tt.db.Exec(`UPDATE refresh_token SET used_at = ? WHERE id = ? AND used_at IS NULL`, now.UTC(), t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("token error, %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if used || n == 0 {
+		if err := tt.RevokeFamily(t.Family, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+
+	return &t, nil
+}
+
+// RevokeFamily revokes all refresh tokens of a family together with the access tokens issued with them
+func (tt *TokenTable) RevokeFamily(family string, now time.Time) error {
+	return transaction(tt.db, func(tx *sql.Tx) error {
+		q := `UPDATE refresh_token SET revoked_at = ? WHERE family = ? AND revoked_at IS NULL`
+		if _, err := tx.Exec(q, now.UTC(), family); err != nil {
+			return fmt.Errorf("token error, %w", err)
+		}
+
+		q = `INSERT IGNORE INTO revoked_token (jti, expires_at)
+SELECT t.access_jti, t.access_expires_at FROM refresh_token t WHERE t.family = ? AND t.access_expires_at > ?`
+		if _, err := tx.Exec(q, family, now.UTC()); err != nil {
+			return fmt.Errorf("token error, %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RevokeUserFamily revokes the family of the refresh token with the given hash when it belongs to the user, returns
+// ErrNoRows otherwise
+func (tt *TokenTable) RevokeUserFamily(hash string, userID int64, now time.Time) error {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT t.family FROM %s WHERE t.token_hash = ? AND t.user_id = ?`, tt.name)
+
+	var family string
+	if err := tt.db.QueryRow(query, hash, userID).Scan(&family); err != nil {
+		return err
+	}
+
+	return tt.RevokeFamily(family, now)
+}
+
+// RevokeUser revokes the access tokens issued to a user that have not expired yet, so claims read from them can not
+// outlive a change of the user. With refresh the refresh tokens of the user are revoked too and the user has to
+// sign in again.
+func (tt *TokenTable) RevokeUser(userID int64, refresh bool, now time.Time) error {
+	return transaction(tt.db, func(tx *sql.Tx) error {
+		q := `INSERT IGNORE INTO revoked_token (jti, expires_at)
+SELECT t.access_jti, t.access_expires_at FROM refresh_token t WHERE t.user_id = ? AND t.access_expires_at > ?`
+		if _, err := tx.Exec(q, userID, now.UTC()); err != nil {
+			return fmt.Errorf("token error, %w", err)
+		}
+
+		if refresh {
+			q = `UPDATE refresh_token SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`
+			if _, err := tx.Exec(q, now.UTC(), userID); err != nil {
+				return fmt.Errorf("token error, %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Revoke an access token by its jti until it expires
+func (tt *TokenTable) Revoke(jti string, expiresAt time.Time) error {
+	q := `INSERT IGNORE INTO revoked_token (jti, expires_at) VALUES (?, ?)`
+	if _, err := tt.db.Exec(q, jti, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("token error, %w", err)
+	}
+
+	return nil
+}
+
+// Revoked returns the jti of all revoked access tokens that have not expired yet
+func (tt *TokenTable) Revoked(now time.Time) (map[string]struct{}, error) {
+	rows, err := tt.db.Query(`SELECT r.jti FROM revoked_token r WHERE r.expires_at > ?`, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("token error, %w", err)
+	}
+	defer rows.Close()
+
+	revoked := make(map[string]struct{})
+	for rows.Next() {
+		var jti string
+		if err := rows.Scan(&jti); err != nil {
+			return nil, err
+		}
+		revoked[jti] = struct{}{}
+	}
+
+	return revoked, rows.Err()
+}
+
+// Purge removes expired refresh tokens and revocations of expired access tokens, returns the number of removed rows
+func (tt *TokenTable) Purge(now time.Time) (int64, error) {
+	var total int64
+	for _, q := range []string{
+		`DELETE FROM refresh_token WHERE expires_at <= ?`,
+		`DELETE FROM revoked_token WHERE expires_at <= ?`,
+	} {
+		res, err := tt.db.Exec(q, now.UTC())
+		if err != nil {
+			return 0, fmt.Errorf("token error, %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+
+	return total, nil
+}
@@ -0,0 +1,130 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestTokenTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	insert := func(hash string, jti string) {
+		if _, err := db.Token.Insert(database.RefreshToken{
+			UserID:          1,
+			Family:          "family1",
+			Hash:            hash,
+			AccessID:        jti,
+			AccessExpiresAt: now.Add(15 * time.Minute),
+			ExpiresAt:       now.Add(24 * time.Hour),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("hash1", "jti1")
+
+	t.Run("Should rotate a token once", func(t *testing.T) {
+		rt, err := db.Token.Rotate("hash1", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rt.UserID != 1 || rt.Family != "family1" {
+			t.Fatalf("Expected the token of family1 got %+v", rt)
+		}
+		insert("hash2", "jti2")
+
+		revoked, err := db.Token.Revoked(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revoked) != 0 {
+			t.Fatalf("Expected no revoked tokens got %v", revoked)
+		}
+	})
+
+	t.Run("Should revoke the family of a reused token", func(t *testing.T) {
+		if _, err := db.Token.Rotate("hash1", now); !errors.Is(err, database.ErrTokenReused) {
+			t.Fatalf("Expected reused token error got %v", err)
+		}
+		if _, err := db.Token.Rotate("hash2", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected the family to be revoked got %v", err)
+		}
+
+		revoked, err := db.Token.Revoked(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := revoked["jti1"]; !ok || len(revoked) != 2 {
+			t.Fatalf("Expected the access tokens of the family to be revoked got %v", revoked)
+		}
+	})
+
+	t.Run("Should not rotate expired tokens", func(t *testing.T) {
+		if _, err := db.Token.Rotate("unknown", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error got %v", err)
+		}
+	})
+
+	t.Run("Should purge expired tokens", func(t *testing.T) {
+		if err := db.Token.Revoke("jti3", now.Add(-time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		n, err := db.Token.Purge(now.Add(25 * time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 5 {
+			t.Fatalf("Expected 5 purged rows got %d", n)
+		}
+	})
+}
+
+func TestTokenTable_RevokeUser(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if _, err := db.Token.Insert(database.RefreshToken{
+		UserID:          1,
+		Family:          "family2",
+		Hash:            "hash4",
+		AccessID:        "jti4",
+		AccessExpiresAt: now.Add(15 * time.Minute),
+		ExpiresAt:       now.Add(24 * time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should revoke the access tokens of a user", func(t *testing.T) {
+		if err := db.Token.RevokeUser(1, false, now); err != nil {
+			t.Fatal(err)
+		}
+		revoked, err := db.Token.Revoked(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := revoked["jti4"]; !ok {
+			t.Fatalf("Expected the access token to be revoked got %v", revoked)
+		}
+	})
+
+	t.Run("Should revoke the refresh tokens of a user", func(t *testing.T) {
+		if _, err := db.Token.Rotate("hash4", now); err != nil {
+			t.Fatalf("Expected the refresh token to be valid got %v", err)
+		}
+		if err := db.Token.RevokeUser(1, true, now); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Token.Rotate("hash4", now); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected the refresh token to be revoked got %v", err)
+		}
+	})
+}
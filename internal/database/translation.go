@@ -0,0 +1,15 @@
+package database
+
+// Translation entity, the title and ingredient names of a recipe in a locale other than the one it was written in.
+// Ingredients maps ingredient ids to their translated names, untranslated ingredients keep their original name.
+type Translation struct {
+	RecipeID    int64
+	Locale      string
+	Title       string
+	Ingredients map[int64]string
+	CreatedAt   string
+	UpdatedAt   string
+}
+
+// Translations slice of translation entities
+type Translations []Translation
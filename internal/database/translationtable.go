@@ -0,0 +1,236 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const translationColumns = "t.recipe_id, t.locale, t.title, t.created_at, t.updated_at"
+
+// TranslationTable object
+type TranslationTable struct {
+	db   *sql.DB
+	name string
+}
+
+// NewTranslationTable create a TranslationTable object
+func NewTranslationTable(db *sql.DB) *TranslationTable {
+	return &TranslationTable{
+		db:   db,
+		name: "recipe_translation t",
+	}
+}
+
+// List the translations of a recipe ordered by locale
+func (tt *TranslationTable) List(recipeID uint64) (Translations, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE t.recipe_id = ? ORDER BY t.locale`, translationColumns, tt.name)
+
+	rows, err := tt.db.Query(query, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var translations Translations
+	for rows.Next() {
+		t := Translation{Ingredients: make(map[int64]string)}
+		if err := rows.Scan(&t.RecipeID, &t.Locale, &t.Title, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		translations = append(translations, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(translations) == 0 {
+		return translations, nil
+	}
+
+	names, err := tt.ingredientNames([]int64{int64(recipeID)}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range translations {
+		for id, locales := range names {
+			if name, ok := locales[translations[i].Locale]; ok {
+				translations[i].Ingredients[id] = name
+			}
+		}
+	}
+
+	return translations, nil
+}
+
+// Save creates or replaces the translation of a recipe in a locale, ingredient names of ingredients that do not
+// belong to the recipe are ignored
+func (tt *TranslationTable) Save(t Translation) error {
+	return transaction(tt.db, func(tx *sql.Tx) error {
+		q := `INSERT INTO recipe_translation (recipe_id, locale, title) VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE title = VALUES(title)`
+		if _, err := tx.Exec(q, t.RecipeID, t.Locale, t.Title); err != nil {
+			return fmt.Errorf("translation error, %w", err)
+		}
+
+		q = `DELETE it FROM ingredient_translation it JOIN ingredient i ON i.id = it.ingredient_id
+WHERE i.recipe_id = ? AND it.locale = ?`
+		if _, err := tx.Exec(q, t.RecipeID, t.Locale); err != nil {
+			return fmt.Errorf("translation error, %w", err)
+		}
+
+		q = `INSERT INTO ingredient_translation (ingredient_id, locale, name)
+SELECT i.id, ?, ? FROM ingredient i WHERE i.id = ? AND i.recipe_id = ?`
+		for id, name := range t.Ingredients {
+			if _, err := tx.Exec(q, t.Locale, name, id, t.RecipeID); err != nil {
+				return fmt.Errorf("translation error, %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete the translation of a recipe in a locale, returns ErrNoRows when there is no such translation
+func (tt *TranslationTable) Delete(recipeID uint64, locale string) error {
+	return transaction(tt.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM recipe_translation WHERE recipe_id = ? AND locale = ?`, recipeID, locale)
+		if err != nil {
+			return fmt.Errorf("translation error, %w", err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("translation error, %w", err)
+		}
+		if affected == 0 {
+			return ErrNoRows
+		}
+
+		q := `DELETE it FROM ingredient_translation it JOIN ingredient i ON i.id = it.ingredient_id
+WHERE i.recipe_id = ? AND it.locale = ?`
+		if _, err := tx.Exec(q, recipeID, locale); err != nil {
+			return fmt.Errorf("translation error, %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Translate serves each recipe in the first locale of the chain it is available in, either its own locale or one
+// it was translated to. Recipe locales are updated to the served locale, recipes that are not available in any
+// locale of the chain are left in their own locale.
+func (tt *TranslationTable) Translate(recipes Recipes, locales []string) (Recipes, error) {
+	if len(recipes) == 0 || len(locales) == 0 {
+		return recipes, nil
+	}
+
+	ids := make([]int64, 0, len(recipes))
+	for i := range recipes {
+		ids = append(ids, recipes[i].ID)
+	}
+
+	titles, err := tt.titles(ids, locales)
+	if err != nil {
+		return nil, err
+	}
+	names, err := tt.ingredientNames(ids, locales)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range recipes {
+		for _, locale := range locales {
+			if locale == recipes[i].Locale {
+				break
+			}
+
+			title, ok := titles[recipes[i].ID][locale]
+			if !ok {
+				continue
+			}
+
+			recipes[i].Locale = locale
+			recipes[i].Title = title
+			for j := range recipes[i].Ingredients {
+				if name, ok := names[recipes[i].Ingredients[j].ID][locale]; ok {
+					recipes[i].Ingredients[j].Name = name
+				}
+			}
+			break
+		}
+	}
+
+	return recipes, nil
+}
+
+// titles returns the translated titles of recipes mapped by recipe id and locale
+func (tt *TranslationTable) titles(recipeIDs []int64, locales []string) (map[int64]map[string]string, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT t.recipe_id, t.locale, t.title FROM %s
+WHERE t.recipe_id IN (%s) AND t.locale IN (%s)`,
+		tt.name,
+		strings.TrimSuffix(strings.Repeat("?,", len(recipeIDs)), ","),
+		strings.TrimSuffix(strings.Repeat("?,", len(locales)), ","),
+	)
+
+	args := make([]interface{}, 0, len(recipeIDs)+len(locales))
+	for i := range recipeIDs {
+		args = append(args, recipeIDs[i])
+	}
+	for i := range locales {
+		args = append(args, locales[i])
+	}
+
+	return tt.localized(query, args...)
+}
+
+// ingredientNames returns the translated ingredient names of recipes mapped by ingredient id and locale, without
+// locales names in every locale are returned
+func (tt *TranslationTable) ingredientNames(recipeIDs []int64, locales []string) (map[int64]map[string]string, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT it.ingredient_id, it.locale, it.name FROM ingredient_translation it
+JOIN ingredient i ON i.id = it.ingredient_id
+WHERE i.recipe_id IN (%s)`, strings.TrimSuffix(strings.Repeat("?,", len(recipeIDs)), ","))
+
+	args := make([]interface{}, 0, len(recipeIDs)+len(locales))
+	for i := range recipeIDs {
+		args = append(args, recipeIDs[i])
+	}
+	if len(locales) > 0 {
+		query += fmt.Sprintf(" AND it.locale IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(locales)), ","))
+		for i := range locales {
+			args = append(args, locales[i])
+		}
+	}
+
+	return tt.localized(query, args...)
+}
+
+// localized runs a query that selects an id, a locale and a value and maps the values by id and locale
+func (tt *TranslationTable) localized(query string, args ...interface{}) (map[int64]map[string]string, error) {
+	rows, err := tt.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[int64]map[string]string)
+	for rows.Next() {
+		var id int64
+		var locale, value string
+		if err := rows.Scan(&id, &locale, &value); err != nil {
+			return nil, err
+		}
+
+		if _, ok := values[id]; !ok {
+			values[id] = make(map[string]string)
+		}
+		values[id][locale] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
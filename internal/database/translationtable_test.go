@@ -0,0 +1,100 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+func TestTranslationTable(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Translated Moussaka",
+		URL:         "http://example.com/translated-moussaka",
+		Ingredients: database.Ingredients{{Name: "eggplant"}, {Name: "minced meat"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipe, err := db.Recipe.Get(uint64(id), database.Viewer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe.Locale != database.DefaultLocale {
+		t.Fatalf("Expected locale %s got %s", database.DefaultLocale, recipe.Locale)
+	}
+
+	eggplant := recipe.Ingredients[0].ID
+	if err := db.Translation.Save(database.Translation{
+		RecipeID:    id,
+		Locale:      "el",
+		Title:       "Μουσακάς",
+		Ingredients: map[int64]string{eggplant: "μελιτζάνα"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		desc          string
+		locales       []string
+		expectedLoc   string
+		expectedTitle string
+		expectedFirst string
+	}{
+		{"Should serve the translation", []string{"el", "en"}, "el", "Μουσακάς", "μελιτζάνα"},
+		{"Should fall back through the chain", []string{"de", "el", "en"}, "el", "Μουσακάς", "μελιτζάνα"},
+		{"Should prefer the recipe locale", []string{"en", "el"}, "en", "Translated Moussaka", "eggplant"},
+		{"Should keep the recipe locale when nothing matches", []string{"de"}, "en", "Translated Moussaka", "eggplant"},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			r, err := db.Recipe.Get(uint64(id), database.Viewer{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recipes, err := db.Translation.Translate(database.Recipes{*r}, tc.locales)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recipes[0].Locale != tc.expectedLoc || recipes[0].Title != tc.expectedTitle {
+				t.Fatalf("Expected %s in %s got %s in %s", tc.expectedTitle, tc.expectedLoc, recipes[0].Title, recipes[0].Locale)
+			}
+			if recipes[0].Ingredients[0].Name != tc.expectedFirst || recipes[0].Ingredients[1].Name != "minced meat" {
+				t.Fatalf("Unexpected ingredients %+v", recipes[0].Ingredients)
+			}
+		})
+	}
+
+	recipes, total, err := db.Recipe.Paginate(1, &database.RecipeFilters{Term: "Μουσακ", Locales: []string{"el", "en"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || recipes[0].ID != id {
+		t.Fatalf("Expected to find the recipe by its translated title, got %d results", total)
+	}
+
+	translations, err := db.Translation.List(uint64(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(translations) != 1 || translations[0].Ingredients[eggplant] != "μελιτζάνα" {
+		t.Fatalf("Unexpected translations %+v", translations)
+	}
+
+	if err := db.Translation.Delete(uint64(id), "el"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Translation.Delete(uint64(id), "el"); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected no rows error got %v", err)
+	}
+}
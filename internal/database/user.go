@@ -1,5 +1,28 @@
 package database
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// User roles, each role grants the permissions of the handler package role table
+const (
+	RoleUser      = "user"
+	RoleEditor    = "editor"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// What happens to the recipes of a user whose account is erased
+const (
+	ContentReassign = "reassign"
+	ContentDelete   = "delete"
+)
+
+// Roles lists the supported user roles
+var Roles = []string{RoleUser, RoleEditor, RoleModerator, RoleAdmin}
+
 // User entity
 type User struct {
 	ID        int64
@@ -8,9 +31,71 @@ type User struct {
 	FullName  string
 	Email     string
 	Active    bool
+	Role      string
 	CreatedAt string
 	UpdatedAt string
+	DeletedAt string
+
+	// VerifiedAt is when the user confirmed their email address, empty for unverified addresses
+	VerifiedAt string
+	// PendingEmail is the address the user asked to switch to, it replaces Email once confirmed
+	PendingEmail string
+	Preferences  Preferences
+	// LockedUntil is when the sign in lockout of the user ends, empty when the user is not locked
+	LockedUntil string
+}
+
+// Preferences of a user, stored as a JSON document
+type Preferences struct {
+	// Locale the user prefers content in
+	Locale string `json:"locale,omitempty"`
+	// Diet the user follows
+	Diet string `json:"diet,omitempty"`
+}
+
+// Scan implements the sql.Scanner interface, NULL scans to empty preferences
+func (p *Preferences) Scan(src interface{}) error {
+	*p = Preferences{}
+
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("preferences error, unsupported type %T", src)
+	}
+
+	if err := json.Unmarshal(b, p); err != nil {
+		return fmt.Errorf("preferences error, %w", err)
+	}
+
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (p Preferences) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("preferences error, %w", err)
+	}
+
+	return string(b), nil
 }
 
 // Users slice or user entities
 type Users []User
+
+// IsRole reports whether role is one of the supported user roles
+func IsRole(role string) bool {
+	for i := range Roles {
+		if Roles[i] == role {
+			return true
+		}
+	}
+
+	return false
+}
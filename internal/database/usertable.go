@@ -4,32 +4,47 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 )
 
-const userColumns = "u.id, u.username, u.fullName, u.email, u.active, u.created_at, u.updated_at"
+const userColumns = "u.id, u.username, u.fullName, u.email, u.active, u.role, IFNULL(u.email_verified_at, ''), u.created_at, " +
+	"u.updated_at, IFNULL(u.pending_email, ''), u.preferences, IF(u.locked_until > UTC_TIMESTAMP(), u.locked_until, '')"
+
+// UserFilters narrow down the users listed by Paginate, zero values do not filter
+type UserFilters struct {
+	// Term matches part of the username, full name or email
+	Term   string
+	Role   string
+	Active *bool
+	// Locked lists the users whose sign in lockout has not ended, or the ones without
+	Locked *bool
+}
 
 // UserTable object
 type UserTable struct {
-	db   *sql.DB
-	name string
+	db       *sql.DB
+	name     string
+	pageSize uint64
 }
 
 // NewUserTable object
 func NewUserTable(db *sql.DB) *UserTable {
 	return &UserTable{
-		db:   db,
-		name: "user u",
+		db:       db,
+		name:     "user u",
+		pageSize: 20,
 	}
 }
 
-// Get user by id
+// Get user by id, deleted users are not returned
 func (ut *UserTable) Get(id uint64) (*User, error) {
 	// nolint:gosec
-	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, userColumns, ut.name)
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = ? AND deleted_at IS NULL`, userColumns, ut.name)
 
 	var u User
 	if err := ut.db.QueryRow(query, id).Scan(
-		&u.ID, &u.Username, &u.FullName, &u.Email, &u.Active, &u.CreatedAt, &u.UpdatedAt,
+		&u.ID, &u.Username, &u.FullName, &u.Email, &u.Active, &u.Role, &u.VerifiedAt, &u.CreatedAt, &u.UpdatedAt,
+		&u.PendingEmail, &u.Preferences, &u.LockedUntil,
 	); err != nil {
 		return nil, err
 	}
@@ -37,14 +52,15 @@ func (ut *UserTable) Get(id uint64) (*User, error) {
 	return &u, nil
 }
 
-// Get user by id
+// Get user by username, deleted users are not returned
 func (ut *UserTable) GetByUsername(uName string) (*User, error) {
 	// nolint:gosec
-	query := fmt.Sprintf(`SELECT %s, password FROM %s WHERE username = ?`, userColumns, ut.name)
+	query := fmt.Sprintf(`SELECT %s, password FROM %s WHERE username = ? AND deleted_at IS NULL`, userColumns, ut.name)
 
 	var u User
 	if err := ut.db.QueryRow(query, uName).Scan(
-		&u.ID, &u.Username, &u.FullName, &u.Email, &u.Active, &u.CreatedAt, &u.UpdatedAt, &u.Password,
+		&u.ID, &u.Username, &u.FullName, &u.Email, &u.Active, &u.Role, &u.VerifiedAt, &u.CreatedAt, &u.UpdatedAt,
+		&u.PendingEmail, &u.Preferences, &u.LockedUntil, &u.Password,
 	); err != nil {
 		return nil, err
 	}
@@ -52,15 +68,101 @@ func (ut *UserTable) GetByUsername(uName string) (*User, error) {
 	return &u, nil
 }
 
-// Insert a new user and return a unique identifier
-func (ut *UserTable) Insert(u User) (int64, error) {
-	q := `INSERT INTO user (username, password, fullName, email) VALUES (?, ?, ?, ?)`
-	res, err := ut.db.Exec(q, u.Username, u.Password, u.FullName, u.Email)
+// GetByEmail gets a user by email address, deleted users are not returned
+func (ut *UserTable) GetByEmail(email string) (*User, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE email = ? AND deleted_at IS NULL`, userColumns, ut.name)
+
+	var u User
+	if err := ut.db.QueryRow(query, email).Scan(
+		&u.ID, &u.Username, &u.FullName, &u.Email, &u.Active, &u.Role, &u.VerifiedAt, &u.CreatedAt, &u.UpdatedAt,
+		&u.PendingEmail, &u.Preferences, &u.LockedUntil,
+	); err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// Paginate lists the users that are not deleted and match filters, ordered by id, returns the users of the page
+// together with the total number of matching users
+func (ut *UserTable) Paginate(page uint64, filters *UserFilters) (Users, int64, error) {
+	cond := []string{"u.deleted_at IS NULL"}
+	var args []interface{}
+	if filters != nil {
+		if filters.Term != "" {
+			like := "%" + filters.Term + "%"
+			cond = append(cond, "(u.username LIKE ? OR u.fullName LIKE ? OR u.email LIKE ?)")
+			args = append(args, like, like, like)
+		}
+		if filters.Role != "" {
+			cond = append(cond, "u.role = ?")
+			args = append(args, filters.Role)
+		}
+		if filters.Active != nil {
+			cond = append(cond, "u.active = ?")
+			args = append(args, *filters.Active)
+		}
+		if filters.Locked != nil {
+			cond = append(cond, "IFNULL(u.locked_until > UTC_TIMESTAMP(), 0) = ?")
+			args = append(args, *filters.Locked)
+		}
+	}
+	where := strings.Join(cond, " AND ")
+
+	var total int64
+	// nolint:gosec
+	if err := ut.db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, ut.name, where), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("user error, %w", err)
+	}
+
+	if page > 0 {
+		page--
+	}
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s ORDER BY u.id LIMIT ?, ?`, userColumns, ut.name, where)
+
+	rows, err := ut.db.Query(query, append(args, ut.pageSize*page, ut.pageSize)...)
 	if err != nil {
-		if strings.Contains(err.Error(), "Error 1062") {
-			return 0, ErrDuplicateEntry
+		return nil, 0, fmt.Errorf("user error, %w", err)
+	}
+	defer rows.Close()
+
+	var users Users
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(
+			&u.ID, &u.Username, &u.FullName, &u.Email, &u.Active, &u.Role, &u.VerifiedAt, &u.CreatedAt, &u.UpdatedAt,
+			&u.PendingEmail, &u.Preferences, &u.LockedUntil,
+		); err != nil {
+			return nil, 0, err
 		}
-		return 0, fmt.Errorf("user error, %w", err)
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// Insert a new user and return a unique identifier, users without a role get RoleUser. Users that are inserted
+// active do not go through email verification and their address counts as verified.
+func (ut *UserTable) Insert(u User) (int64, error) {
+	return insertUser(ut.db, u)
+}
+
+// insertUser inserts u with db or within a transaction, see Insert
+func insertUser(db execer, u User) (int64, error) {
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
+
+	q := `INSERT INTO user (username, password, fullName, email, active, role, email_verified_at)
+VALUES (?, ?, ?, ?, ?, ?, IF(?, UTC_TIMESTAMP(), NULL))`
+	res, err := db.Exec(q, u.Username, u.Password, u.FullName, u.Email, u.Active, u.Role, u.Active)
+	if err != nil {
+		return 0, duplicateError(err)
 	}
 
 	var uID int64
@@ -70,3 +172,246 @@ func (ut *UserTable) Insert(u User) (int64, error) {
 
 	return uID, nil
 }
+
+// SetRole changes the role of a user that is not deleted
+func (ut *UserTable) SetRole(id uint64, role string) error {
+	return ut.exec(`UPDATE user SET role = ? WHERE id = ? AND deleted_at IS NULL`, role, id)
+}
+
+// SetActive deactivates or reactivates a user that is not deleted, inactive users can not sign in
+func (ut *UserTable) SetActive(id uint64, active bool) error {
+	return ut.exec(`UPDATE user SET active = ? WHERE id = ? AND deleted_at IS NULL`, active, id)
+}
+
+// Verify confirms the email address of a user and activates them, returns ErrNoRows when the user no longer has that
+// address or already verified it
+func (ut *UserTable) Verify(id uint64, email string) error {
+	return ut.exec(`UPDATE user SET active = 1, email_verified_at = UTC_TIMESTAMP()
+WHERE id = ? AND email = ? AND email_verified_at IS NULL AND deleted_at IS NULL`, id, email)
+}
+
+// Inactive returns the ids of users that are not active, unverified, deactivated and deleted users
+func (ut *UserTable) Inactive() (map[int64]struct{}, error) {
+	rows, err := ut.db.Query(`SELECT u.id FROM user u WHERE u.active = 0 OR u.deleted_at IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("user error, %w", err)
+	}
+	defer rows.Close()
+
+	inactive := make(map[int64]struct{})
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		inactive[id] = struct{}{}
+	}
+
+	return inactive, rows.Err()
+}
+
+// Delete moves a user to the trash, deleted users can not sign in
+func (ut *UserTable) Delete(id uint64) error {
+	return ut.exec(`UPDATE user SET deleted_at = UTC_TIMESTAMP() WHERE id = ? AND deleted_at IS NULL`, id)
+}
+
+// ScheduleErase moves a user that deleted their account to the trash until eraseAt, when Erase removes them
+func (ut *UserTable) ScheduleErase(id uint64, eraseAt time.Time) error {
+	q := `UPDATE user SET deleted_at = UTC_TIMESTAMP(), erase_at = ? WHERE id = ? AND deleted_at IS NULL`
+
+	return ut.exec(q, eraseAt.UTC(), id)
+}
+
+// Restore brings a user back from the trash, a scheduled erasure is cancelled
+func (ut *UserTable) Restore(id uint64) error {
+	return ut.exec(`UPDATE user SET deleted_at = NULL, erase_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)
+}
+
+// Trash lists deleted users, most recently deleted first
+func (ut *UserTable) Trash() (Users, error) {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT %s, u.deleted_at FROM %s WHERE u.deleted_at IS NOT NULL ORDER BY u.deleted_at DESC, u.id`,
+		userColumns, ut.name)
+
+	rows, err := ut.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users Users
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(
+			&u.ID, &u.Username, &u.FullName, &u.Email, &u.Active, &u.Role, &u.VerifiedAt, &u.CreatedAt, &u.UpdatedAt,
+			&u.PendingEmail, &u.Preferences, &u.LockedUntil, &u.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Purge permanently removes users that were deleted before the given time, returns the number of purged users. Users
+// that deleted their account are left to Erase.
+func (ut *UserTable) Purge(before time.Time) (int64, error) {
+	res, err := ut.db.Exec(`DELETE FROM user WHERE deleted_at < ? AND erase_at IS NULL`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("user error, %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// Erasable returns the ids of users whose account deletion is due
+func (ut *UserTable) Erasable(now time.Time) ([]int64, error) {
+	rows, err := ut.db.Query(`SELECT u.id FROM user u WHERE u.erase_at <= ? ORDER BY u.id`, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("user error, %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Erase permanently removes a user together with their personal data. With ContentDelete the recipes of the user
+// are removed, with ContentReassign they are given to the user with id reassignTo, or left without an author when
+// reassignTo is 0.
+func (ut *UserTable) Erase(id int64, content string, reassignTo int64) error {
+	return transaction(ut.db, func(tx *sql.Tx) error {
+		q := `UPDATE recipe SET user_id = NULLIF(?, 0) WHERE user_id = ?`
+		args := []interface{}{reassignTo, id}
+		if content == ContentDelete {
+			iq := `DELETE i FROM ingredient i INNER JOIN recipe r ON r.id = i.recipe_id WHERE r.user_id = ?`
+			if _, err := tx.Exec(iq, id); err != nil {
+				return fmt.Errorf("user error, %w", err)
+			}
+			q, args = `DELETE FROM recipe WHERE user_id = ?`, []interface{}{id}
+		}
+		if _, err := tx.Exec(q, args...); err != nil {
+			return fmt.Errorf("user error, %w", err)
+		}
+
+		// Shares are not tied to the user by a foreign key, tokens and reset links are removed with the user
+		if _, err := tx.Exec(`DELETE FROM recipe_share WHERE user_id = ?`, id); err != nil {
+			return fmt.Errorf("user error, %w", err)
+		}
+
+		res, err := tx.Exec(`DELETE FROM user WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("user error, %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNoRows
+		}
+
+		return nil
+	})
+}
+
+// Lock prevents a user that is not deleted from signing in until the given time
+func (ut *UserTable) Lock(id uint64, until time.Time) error {
+	return ut.exec(`UPDATE user SET locked_until = ? WHERE id = ? AND deleted_at IS NULL`, until.UTC(), id)
+}
+
+// Unlock ends the sign in lockout of a user, returns ErrNoRows when the user is not locked
+func (ut *UserTable) Unlock(id uint64) error {
+	return ut.exec(`UPDATE user SET locked_until = NULL
+WHERE id = ? AND locked_until > UTC_TIMESTAMP() AND deleted_at IS NULL`, id)
+}
+
+// UpdateProfile sets the full name and preferences of a user
+func (ut *UserTable) UpdateProfile(id uint64, fullName string, p Preferences) error {
+	return ut.exec(`UPDATE user SET fullName = ?, preferences = ? WHERE id = ? AND deleted_at IS NULL`, fullName, p, id)
+}
+
+// SetPassword replaces the password hash of a user
+func (ut *UserTable) SetPassword(id uint64, password string) error {
+	return ut.exec(`UPDATE user SET password = ? WHERE id = ? AND deleted_at IS NULL`, password, id)
+}
+
+// RequestEmail stores the address a user wants to switch to until it is confirmed, replacing any earlier request.
+// Returns ErrEmailTaken when another user already has the address.
+func (ut *UserTable) RequestEmail(id uint64, email string) error {
+	// nolint:gosec
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE u.email = ? AND u.id != ?`, ut.name)
+
+	var taken int
+	if err := ut.db.QueryRow(query, email, id).Scan(&taken); err != nil {
+		return fmt.Errorf("user error, %w", err)
+	}
+	if taken > 0 {
+		return ErrEmailTaken
+	}
+
+	return ut.exec(`UPDATE user SET pending_email = ? WHERE id = ? AND deleted_at IS NULL`, email, id)
+}
+
+// ConfirmEmail switches a user to the pending address email and marks it verified, returns ErrNoRows when email is
+// not the pending address of the user and ErrEmailTaken when another user took the address in the meantime
+func (ut *UserTable) ConfirmEmail(id uint64, email string) error {
+	res, err := ut.db.Exec(`UPDATE user SET email = pending_email, pending_email = NULL, email_verified_at = UTC_TIMESTAMP()
+WHERE id = ? AND pending_email = ? AND deleted_at IS NULL`, id, email)
+	if err != nil {
+		return duplicateError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("user error, %w", err)
+	}
+	if affected == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// exec runs a single user update, returns ErrNoRows when no user was affected
+func (ut *UserTable) exec(q string, args ...interface{}) error {
+	res, err := ut.db.Exec(q, args...)
+	if err != nil {
+		return fmt.Errorf("user error, %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("user error, %w", err)
+	}
+	if affected == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// duplicateError maps unique key violations of the user table to ErrEmailTaken for addresses and
+// ErrDuplicateEntry otherwise
+func duplicateError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), "user_email_uindex"):
+		return ErrEmailTaken
+	case strings.Contains(err.Error(), "Error 1062"):
+		return ErrDuplicateEntry
+	default:
+		return fmt.Errorf("user error, %w", err)
+	}
+}
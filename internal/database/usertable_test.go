@@ -4,6 +4,7 @@ import (
 	"errors"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/georlav/recipeapi/internal/config"
 	"github.com/georlav/recipeapi/internal/database"
@@ -136,10 +137,19 @@ func TestUserTable_Insert(t *testing.T) {
 			database.User{
 				Username: "user2",
 				FullName: "test user",
-				Email:    "test2@test.gr",
+				Email:    "test3@test.gr",
 			},
 			database.ErrDuplicateEntry,
 		},
+		{
+			"Should fail to create a user with a used email",
+			database.User{
+				Username: "user3",
+				FullName: "test user",
+				Email:    "test2@test.gr",
+			},
+			database.ErrEmailTaken,
+		},
 	}
 
 	cfg, err := config.New("config", "testdata")
@@ -166,3 +176,434 @@ func TestUserTable_Insert(t *testing.T) {
 		})
 	}
 }
+
+func TestUserTable_Trash(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	id, err := db.User.Insert(database.User{
+		Username: "trashed1",
+		Password: "password",
+		FullName: "trashed user",
+		Email:    "trashed@test.gr",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inTrash := func() bool {
+		users, err := db.User.Trash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range users {
+			if users[i].ID == id {
+				return users[i].DeletedAt != ""
+			}
+		}
+		return false
+	}
+
+	steps := []struct {
+		desc    string
+		action  func(id uint64) error
+		error   error
+		deleted bool
+	}{
+		{"Should delete a user", db.User.Delete, nil, true},
+		{"Should fail to delete a deleted user", db.User.Delete, database.ErrNoRows, true},
+		{"Should restore a deleted user", db.User.Restore, nil, false},
+		{"Should fail to restore a user that is not deleted", db.User.Restore, database.ErrNoRows, false},
+		{"Should delete a restored user", db.User.Delete, nil, true},
+	}
+
+	for i := range steps {
+		tc := steps[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := tc.action(uint64(id)); !errors.Is(err, tc.error) {
+				t.Fatalf("Expected error %v got %v", tc.error, err)
+			}
+
+			if _, err := db.User.Get(uint64(id)); tc.deleted != errors.Is(err, database.ErrNoRows) {
+				t.Fatalf("Expected deleted %t got error %v", tc.deleted, err)
+			}
+			if _, err := db.User.GetByUsername("trashed1"); tc.deleted != errors.Is(err, database.ErrNoRows) {
+				t.Fatalf("Expected deleted %t got error %v", tc.deleted, err)
+			}
+			if tc.deleted != inTrash() {
+				t.Fatalf("Expected user in trash %t", tc.deleted)
+			}
+		})
+	}
+
+	purged, err := db.User.Purge(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged < 1 || inTrash() {
+		t.Fatalf("Expected user to be purged, purged %d", purged)
+	}
+}
+
+func TestUserTable_Paginate(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	id, err := db.User.Insert(database.User{
+		Username: "editor1",
+		Password: "password",
+		FullName: "managed user",
+		Email:    "editor1@test.gr",
+		Role:     database.RoleEditor,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.User.SetActive(uint64(id), false); err != nil {
+		t.Fatal(err)
+	}
+
+	inactive, active := false, true
+	testCases := []struct {
+		desc    string
+		filters database.UserFilters
+		total   int64
+	}{
+		{"Should search by term", database.UserFilters{Term: "managed"}, 1},
+		{"Should filter by role", database.UserFilters{Term: "managed", Role: database.RoleEditor}, 1},
+		{"Should filter out other roles", database.UserFilters{Term: "managed", Role: database.RoleAdmin}, 0},
+		{"Should filter by status", database.UserFilters{Term: "managed", Active: &inactive}, 1},
+		{"Should filter out other statuses", database.UserFilters{Term: "managed", Active: &active}, 0},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			users, total, err := db.User.Paginate(1, &tc.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != tc.total || int64(len(users)) != tc.total {
+				t.Fatalf("Expected %d users got %d of %d", tc.total, len(users), total)
+			}
+			if total > 0 && (users[0].ID != id || users[0].Role != database.RoleEditor || users[0].Active) {
+				t.Fatalf("Unexpected user %+v", users[0])
+			}
+		})
+	}
+
+	t.Run("Should change the role of a user", func(t *testing.T) {
+		if err := db.User.SetRole(uint64(id), database.RoleModerator); err != nil {
+			t.Fatal(err)
+		}
+		u, err := db.User.Get(uint64(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Role != database.RoleModerator {
+			t.Fatalf("Expected role %s got %s", database.RoleModerator, u.Role)
+		}
+		if err := db.User.SetRole(0, database.RoleAdmin); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error got %v", err)
+		}
+	})
+}
+
+func TestUserTable_Verify(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	id, err := db.User.Insert(database.User{
+		Username: "unverified1",
+		Password: "password",
+		Email:    "unverified1@test.gr",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isInactive := func() bool {
+		inactive, err := db.User.Inactive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, ok := inactive[id]
+		return ok
+	}
+
+	u, err := db.User.GetByEmail("unverified1@test.gr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != id || u.Active || u.VerifiedAt != "" || !isInactive() {
+		t.Fatalf("Expected an inactive unverified user got %+v", u)
+	}
+
+	if err := db.User.Verify(uint64(id), "other@test.gr"); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected no rows error for another address got %v", err)
+	}
+	if err := db.User.Verify(uint64(id), "unverified1@test.gr"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.User.Verify(uint64(id), "unverified1@test.gr"); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected no rows error for a verified user got %v", err)
+	}
+
+	if u, err = db.User.Get(uint64(id)); err != nil {
+		t.Fatal(err)
+	}
+	if !u.Active || u.VerifiedAt == "" || isInactive() {
+		t.Fatalf("Expected an active verified user got %+v", u)
+	}
+
+	if err := db.User.Delete(uint64(id)); err != nil {
+		t.Fatal(err)
+	}
+	if !isInactive() {
+		t.Fatal("Expected a deleted user to be inactive")
+	}
+}
+
+func TestUserTable_ChangeEmail(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := db.User.Insert(database.User{Username: "mover1", Password: "password", Email: "mover1@test.gr", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.User.Insert(database.User{Username: "mover2", Password: "password", Email: "mover2@test.gr"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should store profiles", func(t *testing.T) {
+		prefs := database.Preferences{Locale: "el", Diet: "vegan"}
+		if err := db.User.UpdateProfile(uint64(id), "mover one", prefs); err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := db.User.Get(uint64(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.FullName != "mover one" || u.Preferences != prefs {
+			t.Fatalf("Expected the updated profile got %+v", u)
+		}
+	})
+
+	t.Run("Should not request used addresses", func(t *testing.T) {
+		if err := db.User.RequestEmail(uint64(id), "mover2@test.gr"); !errors.Is(err, database.ErrEmailTaken) {
+			t.Fatalf("Expected email taken error got %v", err)
+		}
+	})
+
+	t.Run("Should only confirm the pending address", func(t *testing.T) {
+		if err := db.User.RequestEmail(uint64(id), "moved1@test.gr"); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.User.ConfirmEmail(uint64(id), "other@test.gr"); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error for another address got %v", err)
+		}
+		if err := db.User.ConfirmEmail(uint64(id), "moved1@test.gr"); err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := db.User.Get(uint64(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Email != "moved1@test.gr" || u.PendingEmail != "" || u.VerifiedAt == "" {
+			t.Fatalf("Expected the confirmed address got %+v", u)
+		}
+	})
+
+	t.Run("Should not confirm addresses taken in the meantime", func(t *testing.T) {
+		if err := db.User.RequestEmail(uint64(id), "taken1@test.gr"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.User.Insert(database.User{Username: "mover3", Password: "password", Email: "taken1@test.gr"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.User.ConfirmEmail(uint64(id), "taken1@test.gr"); !errors.Is(err, database.ErrEmailTaken) {
+			t.Fatalf("Expected email taken error got %v", err)
+		}
+	})
+}
+
+func TestUserTable_Erase(t *testing.T) {
+	db, err := db()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	// leaving inserts a user that deleted their account with a recipe and a share of it
+	leaving := func(name string) (int64, int64) {
+		id, err := db.User.Insert(database.User{Username: name, Password: "password", Email: name + "@test.gr", Active: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipeID, err := db.Recipe.Insert(database.Recipe{Title: name + " pie", URL: "http://example.com/" + name, UserID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Share.Insert(recipeID, id, now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.User.ScheduleErase(uint64(id), now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		return id, recipeID
+	}
+
+	keptID, keptRecipe := leaving("eraser1")
+	removedID, removedRecipe := leaving("eraser2")
+
+	t.Run("Should leave scheduled accounts to erase", func(t *testing.T) {
+		if _, err := db.User.Purge(now.Add(48 * time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		ids, err := db.User.Erasable(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 0 {
+			t.Fatalf("Expected no erasable accounts before the grace period ends got %v", ids)
+		}
+		if ids, err = db.User.Erasable(now.Add(2 * time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 2 || ids[0] != keptID || ids[1] != removedID {
+			t.Fatalf("Expected both scheduled accounts to be erasable got %v", ids)
+		}
+	})
+
+	t.Run("Should reassign recipes", func(t *testing.T) {
+		if err := db.User.Erase(keptID, database.ContentReassign, 1); err != nil {
+			t.Fatal(err)
+		}
+		r, err := db.Recipe.Get(uint64(keptRecipe), database.Viewer{Moderator: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.UserID != 1 {
+			t.Fatalf("Expected the recipe to be reassigned got user %d", r.UserID)
+		}
+		shares, err := db.Share.ByUser(keptID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shares) != 0 {
+			t.Fatalf("Expected the shares of the user to be removed got %v", shares)
+		}
+	})
+
+	t.Run("Should delete recipes", func(t *testing.T) {
+		if err := db.User.Erase(removedID, database.ContentDelete, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Recipe.Get(uint64(removedRecipe), database.Viewer{Moderator: true}); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected the recipe to be removed got %v", err)
+		}
+		if err := db.User.Erase(removedID, database.ContentDelete, 0); !errors.Is(err, database.ErrNoRows) {
+			t.Fatalf("Expected no rows error for an erased user got %v", err)
+		}
+	})
+}
+
+func TestUserTable_Lock(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	id, err := db.User.Insert(database.User{
+		Username: "locked1",
+		Password: "password",
+		Email:    "locked1@test.gr",
+		Active:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locked := true
+	isLocked := func() bool {
+		users, _, err := db.User.Paginate(1, &database.UserFilters{Locked: &locked})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range users {
+			if u.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	if err := db.User.Unlock(uint64(id)); !errors.Is(err, database.ErrNoRows) {
+		t.Fatalf("Expected no rows error for a user that is not locked got %v", err)
+	}
+
+	// Lockouts that ended are treated as no lockout
+	if err := db.User.Lock(uint64(id), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	u, err := db.User.GetByUsername("locked1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.LockedUntil != "" || isLocked() {
+		t.Fatalf("Expected an ended lockout to be ignored got %+v", u)
+	}
+
+	if err := db.User.Lock(uint64(id), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if u, err = db.User.GetByUsername("locked1"); err != nil {
+		t.Fatal(err)
+	}
+	if u.LockedUntil == "" || !isLocked() {
+		t.Fatalf("Expected a locked user got %+v", u)
+	}
+
+	if err := db.User.Unlock(uint64(id)); err != nil {
+		t.Fatal(err)
+	}
+	if u, err = db.User.GetByUsername("locked1"); err != nil {
+		t.Fatal(err)
+	}
+	if u.LockedUntil != "" || isLocked() {
+		t.Fatalf("Expected an unlocked user got %+v", u)
+	}
+}
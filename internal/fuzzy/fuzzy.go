@@ -0,0 +1,113 @@
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fold lower cases s and strips diacritics, "Crème Brûlée" becomes "creme brulee" and "Σουβλάκι" becomes
+// "σουβλακι"
+func Fold(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// Tolerance returns the number of typos that are tolerated for a query of the given length
+func Tolerance(query string) int {
+	switch n := len([]rune(query)); {
+	case n < 4:
+		return 0
+	case n < 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// PrefixDistance returns the smallest edit distance between query and a prefix of s or a prefix of one of the
+// words in s, both arguments are expected to be folded
+func PrefixDistance(query, s string) int {
+	best := prefixDistance([]rune(query), []rune(s))
+	for _, w := range strings.Fields(s) {
+		if d := prefixDistance([]rune(query), []rune(w)); d < best {
+			best = d
+		}
+	}
+
+	return best
+}
+
+// Distance returns the optimal string alignment distance between a and b, counting insertions, deletions,
+// substitutions and transpositions of adjacent characters as a single edit
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := distanceMatrix(ra, rb)
+
+	return d[len(ra)][len(rb)]
+}
+
+// prefixDistance is the distance between q and the closest prefix of s, the minimum of the last matrix row
+func prefixDistance(q, s []rune) int {
+	if len(s) == 0 {
+		return len(q)
+	}
+
+	d := distanceMatrix(q, s)
+	best := d[len(q)][0]
+	for j := 1; j <= len(s); j++ {
+		if d[len(q)][j] < best {
+			best = d[len(q)][j]
+		}
+	}
+
+	return best
+}
+
+func distanceMatrix(a, b []rune) [][]int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = minimum(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minimum(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d
+}
+
+func minimum(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,69 @@
+package fuzzy_test
+
+import (
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/fuzzy"
+)
+
+func TestFold(t *testing.T) {
+	testCases := []struct {
+		input  string
+		output string
+	}{
+		{"Crème Brûlée", "creme brulee"},
+		{"Jalapeño", "jalapeno"},
+		{" Σουβλάκι ", "σουβλακι"},
+		{"Käsespätzle", "kasespatzle"},
+		{"chicken", "chicken"},
+	}
+
+	for i := range testCases {
+		if folded := fuzzy.Fold(testCases[i].input); folded != testCases[i].output {
+			t.Fatalf("Expected %s to fold to %s got %s", testCases[i].input, testCases[i].output, folded)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		distance int
+	}{
+		{"chicken", "chicken", 0},
+		{"chicken", "chikcen", 1},
+		{"chicken", "chiken", 1},
+		{"chicken", "chickens", 1},
+		{"chicken", "kitchen", 4},
+		{"", "abc", 3},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		if d := fuzzy.Distance(tc.a, tc.b); d != tc.distance {
+			t.Fatalf("Expected distance between %s and %s to be %d got %d", tc.a, tc.b, tc.distance, d)
+		}
+	}
+}
+
+func TestPrefixDistance(t *testing.T) {
+	testCases := []struct {
+		query, s string
+		distance int
+	}{
+		{"chi", "chicken", 0},
+		{"chikc", "chicken", 1},
+		{"brot", "chicken broth", 0},
+		{"parm", "parmesan", 0},
+		{"pram", "parmesan", 1},
+		{"xyz", "chicken", 3},
+		{"xyz", "", 3},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		if d := fuzzy.PrefixDistance(tc.query, tc.s); d != tc.distance {
+			t.Fatalf("Expected prefix distance between %s and %s to be %d got %d", tc.query, tc.s, tc.distance, d)
+		}
+	}
+}
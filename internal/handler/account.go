@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// AccountExport godoc
+// @Summary Export personal data
+// @Description Download everything tied to the signed in user as a JSON document, the profile, the recipes the user
+// @Description authored whatever their status with their translations, the diet labels the user overrode, the share
+// @Description links and API keys the user created and the identity provider accounts linked to the user. API keys
+// @Description are listed without the key.
+// @ID user-export
+// @Produce  json
+// @Success 200 {object} handler.ExportResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user/export [get]
+func (h *Handler) AccountExport(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	user, err := h.db.User.Get(uint64(token.UserID))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownUser, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	recipes, err := h.db.Recipe.ByAuthor(user.ID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	var translations database.Translations
+	for i := range recipes {
+		t, err := h.db.Translation.List(uint64(recipes[i].ID))
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		translations = append(translations, t...)
+	}
+
+	overrides, err := h.db.Diet.ByUser(user.ID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	shares, err := h.db.Share.ByUser(user.ID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	keys, err := h.db.APIKey.List(user.ID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	identities, err := h.db.Identity.ByUser(user.ID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	resp := ExportResponse{
+		ExportedAt:    now.Format(time.RFC3339),
+		Profile:       NewUserProfileResponse(*user),
+		Recipes:       []RecipeResponseItem{},
+		Translations:  []TranslationResponseItem{},
+		DietOverrides: DietOverrideResponse{},
+		Shares:        []ShareResponseItem{},
+		APIKeys:       []APIKeyResponseItem{},
+		Identities:    []IdentityResponseItem{},
+	}
+	if err := EncodeEntities(recipes, &resp, "Recipes"); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	for i := range overrides {
+		resp.DietOverrides = append(resp.DietOverrides, DietOverrideResponseItem(overrides[i]))
+	}
+	for i := range translations {
+		resp.Translations = append(resp.Translations, NewTranslationResponseItem(translations[i]))
+	}
+	for i := range shares {
+		resp.Shares = append(resp.Shares, NewShareResponseItem(shares[i]))
+	}
+	// Keys are stored hashed, the export lists them like the API keys endpoint does
+	for i := range keys {
+		resp.APIKeys = append(resp.APIKeys, NewAPIKeyResponseItem(keys[i]))
+	}
+	for i := range identities {
+		resp.Identities = append(resp.Identities, IdentityResponseItem{
+			Provider:  identities[i].Provider,
+			Subject:   identities[i].Subject,
+			Email:     identities[i].Email,
+			CreatedAt: identities[i].CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s-%s.json"`, user.Username, now.Format("20060102")))
+	h.respond(w, resp, http.StatusOK)
+}
+
+// AccountDelete godoc
+// @Summary Delete the account
+// @Description Delete the account of the signed in user, the current password is required. The user is signed out
+// @Description and the account is erased once the grace period ends, until then administrators can restore it. Erasing
+// @Description removes the personal data of the user and reassigns or removes their recipes as configured.
+// @ID user-delete
+// @Accept  json
+// @Produce  json
+// @Param body body handler.AccountDeleteRequest true "current password"
+// @Success 202 {object} handler.AccountDeleteResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 423 {object} handler.ErrorResponse
+// @Failure 429 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user [delete]
+func (h *Handler) AccountDelete(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	ar := AccountDeleteRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&ar); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(ar); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	user, ok := h.checkPassword(w, r, token, ar.Password)
+	if !ok {
+		return
+	}
+
+	eraseAt := time.Now().Add(time.Duration(h.cfg.Account.Grace) * 24 * time.Hour).UTC()
+	if err := h.db.User.ScheduleErase(uint64(user.ID), eraseAt); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	h.cache.Delete(cacheKeyInactiveUsers)
+	if err := h.revokeUserTokens(user.ID, true); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, AccountDeleteResponse{EraseAt: eraseAt.Format(time.RFC3339)}, http.StatusAccepted)
+}
+
+// EraseAccounts erases the accounts whose deletion grace period ended before now, their recipes are reassigned or
+// removed as configured
+func (h *Handler) EraseAccounts(now time.Time) error {
+	ids, err := h.db.User.Erasable(now)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	content := database.ContentReassign
+	if h.cfg.Account.Content == database.ContentDelete {
+		content = database.ContentDelete
+	}
+
+	var reassignTo int64
+	if content == database.ContentReassign && h.cfg.Account.ReassignTo > 0 {
+		// By id, usernames can be changed or taken over by another account once their owner is erased
+		u, err := h.db.User.Get(uint64(h.cfg.Account.ReassignTo))
+		if err != nil {
+			// Erasing is not postponed, personal data has to go even when the configured user is missing
+			h.log.Errorf("unknown user %d to reassign recipes to, %s", h.cfg.Account.ReassignTo, err)
+		} else {
+			reassignTo = u.ID
+		}
+	}
+
+	for i := range ids {
+		if err := h.db.User.Erase(ids[i], content, reassignTo); err != nil {
+			return err
+		}
+	}
+	h.log.Printf("Erased %d deleted accounts", len(ids))
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_Account(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := db.User.Insert(database.User{
+		Username: "leaving1",
+		Password: string(hash),
+		Email:    "leaving1@test.gr",
+		Active:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipeID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Farewell Pie",
+		URL:         "http://example.com/farewell-pie",
+		Ingredients: database.Ingredients{{Name: "flour"}, {Name: "butter"}},
+		UserID:      userID,
+		Status:      database.RecipeDraft,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Share.Insert(recipeID, userID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Translation.Save(database.Translation{RecipeID: recipeID, Locale: "el", Title: "Πίτα"}); err != nil {
+		t.Fatal(err)
+	}
+	override := database.DietOverride{Diet: database.DietVegan, Label: true, UserID: userID, Note: "vegan butter"}
+	if err := db.Diet.Override(recipeID, override); err != nil {
+		t.Fatal(err)
+	}
+	key := database.APIKey{UserID: userID, Name: "backup", Prefix: "rk_leave", Hash: "leaving1-key", Scopes: []string{"recipes:read"}}
+	if _, err := db.APIKey.Insert(key, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Identity.Link(database.Identity{
+		UserID: userID, Provider: "google", Subject: "leaving1-sub", Email: "leaving1@test.gr",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// send calls fn with body behind the authorization middleware
+	send := func(fn http.HandlerFunc, bearer string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rr := httptest.NewRecorder()
+		h.AuthorizationMiddleware(fn).ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	signIn := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"username":"leaving1","password":"password"}`))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.SignIn).ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := signIn()
+	tr := handler.TokenResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &tr); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should export the data of the user", func(t *testing.T) {
+		rr := send(h.AccountExport, tr.Token, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if !strings.HasPrefix(rr.Header().Get("Content-Disposition"), `attachment; filename="leaving1-`) {
+			t.Fatalf("Expected a downloadable file got %s", rr.Header().Get("Content-Disposition"))
+		}
+
+		resp := handler.ExportResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Profile.Username != "leaving1" || len(resp.Recipes) != 1 || len(resp.Shares) != 1 {
+			t.Fatalf("Expected the profile, the draft recipe and the share link got %+v", resp)
+		}
+		if resp.Recipes[0].Title != "Farewell Pie" || len(resp.Recipes[0].Ingredients) != 2 {
+			t.Fatalf("Unexpected recipe %+v", resp.Recipes[0])
+		}
+		if len(resp.Translations) != 1 || resp.Translations[0].RecipeID != recipeID || resp.Translations[0].Locale != "el" {
+			t.Fatalf("Expected the greek translation got %+v", resp.Translations)
+		}
+		if len(resp.DietOverrides) != 1 || resp.DietOverrides[0].RecipeID != recipeID || !resp.DietOverrides[0].Label {
+			t.Fatalf("Expected the vegan override got %+v", resp.DietOverrides)
+		}
+		if len(resp.APIKeys) != 1 || resp.APIKeys[0].Name != "backup" || resp.APIKeys[0].Key != "" {
+			t.Fatalf("Expected the API key without the key got %+v", resp.APIKeys)
+		}
+		if len(resp.Identities) != 1 || resp.Identities[0].Provider != "google" {
+			t.Fatalf("Expected the linked identity got %+v", resp.Identities)
+		}
+	})
+
+	t.Run("Should require the password to delete the account", func(t *testing.T) {
+		rr := send(h.AccountDelete, tr.Token, `{"password":"wrong"}`)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), handler.CodeWrongPassword) {
+			t.Fatalf("Expected a wrong password error got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should erase deleted accounts after the grace period", func(t *testing.T) {
+		rr := send(h.AccountDelete, tr.Token, `{"password":"password"}`)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusAccepted, rr.Body.String())
+		}
+		if rr := send(h.User, tr.Token, ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the access token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := signIn(); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected deleted users to not sign in got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		if err := h.EraseAccounts(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		trash, err := db.User.Trash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for i := range trash {
+			found = found || trash[i].ID == userID
+		}
+		if !found {
+			t.Fatal("Expected the account to stay in the trash during the grace period")
+		}
+
+		if err := h.EraseAccounts(time.Now().Add(31 * 24 * time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if trash, err = db.User.Trash(); err != nil {
+			t.Fatal(err)
+		}
+		for i := range trash {
+			if trash[i].ID == userID {
+				t.Fatal("Expected the account to be erased")
+			}
+		}
+
+		recipe, err := db.Recipe.Get(uint64(recipeID), database.Viewer{Moderator: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recipe.UserID != 0 {
+			t.Fatalf("Expected the recipe to be left without an author got user %d", recipe.UserID)
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// Users godoc
+// @Summary Get users
+// @Description Get users that are not deleted ordered by id, the term matches part of the username, full name or email
+// @ID get-admin-users
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param page query int false "Page number"
+// @Param term query string false "Search term"
+// @Param role query string false "Role" Enums(user, editor, moderator, admin)
+// @Param active query bool false "Active status"
+// @Param locked query bool false "Sign in lockout status"
+// @Success 200 {object} handler.UsersResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/users [get]
+func (h *Handler) Users(w http.ResponseWriter, r *http.Request) {
+	ur := UsersRequest{Page: 1}
+	if err := h.schema.Decode(&ur, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(ur); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	users, total, err := h.db.User.Paginate(ur.Page, &database.UserFilters{
+		Term:   ur.Term,
+		Role:   ur.Role,
+		Active: ur.Active,
+		Locked: ur.Locked,
+	})
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := UsersResponse{Data: []UserProfileResponse{}, Metadata: &Metadata{Total: total}}
+	for i := range users {
+		resp.Data = append(resp.Data, NewUserProfileResponse(users[i]))
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// UserRole godoc
+// @Summary Change the role of a user
+// @Description Grant a user a role, access tokens issued before the change are revoked so the new role applies as
+// @Description soon as the user refreshes their token. Administrators can not change their own role.
+// @ID put-admin-user-role
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param body body handler.UserRoleRequest true "role"
+// @Success 200 {object} handler.UserProfileResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/role [put]
+func (h *Handler) UserRole(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.managedUser(w, r)
+	if !ok {
+		return
+	}
+
+	ur := UserRoleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&ur); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(ur); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	if user.Role != ur.Role {
+		if err := h.db.User.SetRole(uint64(user.ID), ur.Role); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		if err := h.revokeUserTokens(user.ID, false); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+	}
+
+	h.respondUser(w, r, user.ID)
+}
+
+// UserDeactivate godoc
+// @Summary Deactivate a user
+// @Description Deactivate a user, all tokens of the user are revoked and the user can not sign in until reactivated.
+// @Description Administrators can not deactivate themselves.
+// @ID post-admin-user-deactivate
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} handler.UserProfileResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/deactivate [post]
+func (h *Handler) UserDeactivate(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.managedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.Active {
+		if err := h.db.User.SetActive(uint64(user.ID), false); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		h.cache.Delete(cacheKeyInactiveUsers)
+		if err := h.revokeUserTokens(user.ID, true); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+	}
+
+	h.respondUser(w, r, user.ID)
+}
+
+// UserActivate godoc
+// @Summary Reactivate a user
+// @Description Reactivate a deactivated user, the user can sign in again
+// @ID post-admin-user-activate
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} handler.UserProfileResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/activate [post]
+func (h *Handler) UserActivate(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.managedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !user.Active {
+		if err := h.db.User.SetActive(uint64(user.ID), true); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		h.cache.Delete(cacheKeyInactiveUsers)
+	}
+
+	h.respondUser(w, r, user.ID)
+}
+
+// UserUnlock godoc
+// @Summary Unlock a user
+// @Description End the sign in lockout of a user that failed to sign in too many times, the failed sign ins of the
+// @Description user are forgotten
+// @ID post-admin-user-unlock
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} handler.UserProfileResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/unlock [post]
+func (h *Handler) UserUnlock(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.managedUser(w, r)
+	if !ok {
+		return
+	}
+
+	// The lockout may end between loading the user and unlocking it
+	if user.LockedUntil != "" {
+		if err := h.db.User.Unlock(uint64(user.ID)); err != nil && !errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, err)
+			return
+		}
+	}
+	h.loginUsers.Reset(loginKey(user.Username))
+
+	h.log.SecurityEvent("account_unlocked", true, map[string]interface{}{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"admin_id": h.caller(r).UserID,
+		"ip":       clientIP(r),
+	})
+
+	h.respondUser(w, r, user.ID)
+}
+
+// managedUser loads the user of the id url parameter for a role or status change, responds with an error and
+// returns false when the user does not exist or is the caller
+func (h *Handler) managedUser(w http.ResponseWriter, r *http.Request) (*database.User, bool) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUserIDRequired, StatusCode: http.StatusBadRequest})
+		return nil, false
+	}
+
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return nil, false
+	}
+
+	// Admins locking themselves out could leave the api without an administrator
+	if int64(id) == token.UserID {
+		h.respondError(w, r, APIError{Code: CodeOwnAccount, StatusCode: http.StatusForbidden})
+		return nil, false
+	}
+
+	user, err := h.db.User.Get(id)
+	if err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeUnknownUser, StatusCode: http.StatusNotFound})
+			return nil, false
+		}
+		h.respondError(w, r, err)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// revokeUserTokens revokes the live access tokens of a user, and with refresh their refresh tokens as well
+func (h *Handler) revokeUserTokens(userID int64, refresh bool) error {
+	if err := h.db.Token.RevokeUser(userID, refresh, time.Now()); err != nil {
+		return err
+	}
+	h.cache.Delete(cacheKeyRevokedTokens)
+
+	return nil
+}
+
+// respondUser responds with the freshly loaded profile of a user
+func (h *Handler) respondUser(w http.ResponseWriter, r *http.Request, id int64) {
+	user, err := h.db.User.Get(uint64(id))
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, NewUserProfileResponse(*user), http.StatusOK)
+}
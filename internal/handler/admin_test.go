@@ -0,0 +1,201 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-chi/chi"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_UserAdministration(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staffID, err := db.User.Insert(database.User{
+		Username: "staff1",
+		Password: string(hash),
+		Email:    "staff1@test.gr",
+		Active:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprint(staffID)
+	admin := handler.Token{UserID: 1, Username: "username1"}
+
+	// manage calls hf as the admin, id is injected as url parameter
+	manage := func(hf http.HandlerFunc, id string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, admin))
+
+		rr := httptest.NewRecorder()
+		hf.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// post sends body to fn, behind the authorization middleware when a bearer token is given
+	post := func(fn http.HandlerFunc, bearer string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		if bearer == "" {
+			fn.ServeHTTP(rr, req)
+			return rr
+		}
+
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		h.AuthorizationMiddleware(fn).ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	signIn := func() *httptest.ResponseRecorder {
+		return post(h.SignIn, "", `{"username": "staff1", "password": "password"}`)
+	}
+
+	// tokens decodes a token response and the claims of its access token
+	tokens := func(rr *httptest.ResponseRecorder) (handler.TokenResponse, handler.Token) {
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		tr := handler.TokenResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &tr); err != nil {
+			t.Fatal(err)
+		}
+		claims := handler.Token{}
+		if _, _, err := new(jwt.Parser).ParseUnverified(tr.Token, &claims); err != nil {
+			t.Fatal(err)
+		}
+		return tr, claims
+	}
+
+	t.Run("Should list and search users", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?term=staff&role=user&active=true", nil)
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, admin))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.Users).ServeHTTP(rr, req)
+
+		resp := handler.UsersResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if rr.Code != http.StatusOK || resp.Metadata == nil || resp.Metadata.Total != 1 || len(resp.Data) != 1 {
+			t.Fatalf("Expected a single matching user got %d, %s", rr.Code, rr.Body.String())
+		}
+		if resp.Data[0].Username != "staff1" || resp.Data[0].Role != database.RoleUser {
+			t.Fatalf("Unexpected user %+v", resp.Data[0])
+		}
+	})
+
+	t.Run("Should apply role changes to live tokens", func(t *testing.T) {
+		tr, claims := tokens(signIn())
+		if claims.Role != database.RoleUser {
+			t.Fatalf("Expected role %s in the token got %s", database.RoleUser, claims.Role)
+		}
+
+		if rr := manage(h.UserRole, id, `{"role":"editor"}`); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if rr := post(h.User, tr.Token, ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the access token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		_, claims = tokens(post(h.TokenRefresh, "", fmt.Sprintf(`{"refreshToken":%q}`, tr.RefreshToken)))
+		if claims.Role != database.RoleEditor {
+			t.Fatalf("Expected role %s after refreshing got %s", database.RoleEditor, claims.Role)
+		}
+	})
+
+	t.Run("Should reject unknown roles", func(t *testing.T) {
+		if rr := manage(h.UserRole, id, `{"role":"owner"}`); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+		}
+	})
+
+	t.Run("Should lock out deactivated users", func(t *testing.T) {
+		tr, _ := tokens(signIn())
+
+		if rr := manage(h.UserDeactivate, id, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if rr := signIn(); rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), handler.CodeUserInactive) {
+			t.Fatalf("Expected an inactive user error got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := post(h.User, tr.Token, ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the access token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+		body := fmt.Sprintf(`{"refreshToken":%q}`, tr.RefreshToken)
+		if rr := post(h.TokenRefresh, "", body); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the refresh token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		if rr := manage(h.UserActivate, id, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		tokens(signIn())
+	})
+
+	t.Run("Should lock out deleted users", func(t *testing.T) {
+		tr, _ := tokens(signIn())
+
+		if rr := manage(h.UserDelete, id, ""); rr.Code != http.StatusNoContent {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNoContent, rr.Body.String())
+		}
+		if rr := post(h.User, tr.Token, ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the access token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+		body := fmt.Sprintf(`{"refreshToken":%q}`, tr.RefreshToken)
+		if rr := post(h.TokenRefresh, "", body); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the refresh token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		if rr := manage(h.UserRestore, id, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		tr, _ = tokens(signIn())
+		if rr := post(h.User, tr.Token, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Expected a restored user to be let in got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should not let admins manage their own account", func(t *testing.T) {
+		rr := manage(h.UserDeactivate, "1", "")
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), handler.CodeOwnAccount) {
+			t.Fatalf("Expected an own account error got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should report unknown users", func(t *testing.T) {
+		if rr := manage(h.UserRole, "99999", `{"role":"admin"}`); rr.Code != http.StatusNotFound {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNotFound, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// headerAPIKey is the request header API keys are sent in
+const headerAPIKey = "X-API-Key"
+
+// apiKeyPrefix starts every API key so leaked keys are easy to recognize
+const apiKeyPrefix = "rk_"
+
+// ctxKeyScopes holds the scopes API keys need on the route of a request
+const ctxKeyScopes contextKey = "scopes"
+
+// keyScopes are the scopes API keys need to read and to write through a group of routes
+type keyScopes struct {
+	read  string
+	write string
+}
+
+// APIKeys godoc
+// @Summary Get API keys
+// @Description Get the API keys of the signed in user, most recent first. Revoked and expired keys are listed too.
+// @ID user-api-keys
+// @Produce  json
+// @Success 200 {object} handler.APIKeysResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user/keys [get]
+func (h *Handler) APIKeys(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	keys, err := h.db.APIKey.List(token.UserID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := APIKeysResponse{Data: []APIKeyResponseItem{}}
+	for i := range keys {
+		resp.Data = append(resp.Data, NewAPIKeyResponseItem(keys[i]))
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// APIKeyCreate godoc
+// @Summary Create an API key
+// @Description Create a personal API key for scripts, requests send it in the X-API-Key header and act as the user
+// @Description within the scopes of the key. The key is only returned in this response.
+// @ID user-api-key-create
+// @Accept  json
+// @Produce  json
+// @Param body body handler.APIKeyCreateRequest true "key name, scopes and lifetime"
+// @Success 201 {object} handler.APIKeyResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user/keys [post]
+func (h *Handler) APIKeyCreate(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	kr := APIKeyCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&kr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(kr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	key := apiKeyPrefix + secret
+
+	var expiresAt time.Time
+	if kr.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(kr.ExpiresIn) * 24 * time.Hour)
+	}
+
+	id, err := h.db.APIKey.Insert(database.APIKey{
+		UserID: token.UserID,
+		Name:   kr.Name,
+		Prefix: secret[:8],
+		Hash:   hashToken(key),
+		Scopes: kr.Scopes,
+	}, expiresAt)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	k, err := h.db.APIKey.Get(uint64(id), token.UserID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := NewAPIKeyResponseItem(*k)
+	resp.Key = key
+
+	h.respond(w, resp, http.StatusCreated)
+}
+
+// APIKeyRevoke godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key of the signed in user, requests with the key are rejected from then on
+// @ID user-api-key-revoke
+// @Produce  json
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user/keys/{id} [delete]
+func (h *Handler) APIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeAPIKeyIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	if err := h.db.APIKey.Revoke(id, token.UserID, time.Now()); err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeUnknownAPIKey, StatusCode: http.StatusNotFound})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// keyCaller authenticates a request by API key and returns the caller, it responds with an error and returns false
+// when the route does not accept API keys, the key is unknown or it lacks the scope of the request
+func (h *Handler) keyCaller(w http.ResponseWriter, r *http.Request, key string) (*Token, bool) {
+	scopes, ok := r.Context().Value(ctxKeyScopes).(keyScopes)
+	if !ok {
+		h.respondError(w, r, APIError{Code: CodeAPIKeyNotAllowed, StatusCode: http.StatusForbidden})
+		return nil, false
+	}
+
+	now := time.Now()
+	k, err := h.db.APIKey.Active(hashToken(key), now)
+	if err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeInvalidAPIKey, StatusCode: http.StatusUnauthorized})
+			return nil, false
+		}
+		h.respondError(w, r, err)
+		return nil, false
+	}
+
+	scope := scopes.write
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		scope = scopes.read
+	}
+	if !k.HasScope(scope) {
+		h.respondError(w, r, NewAPIError(CodeInsufficientScope, http.StatusForbidden, scope))
+		return nil, false
+	}
+
+	// Keys of deleted users are removed with them only once the account is erased
+	user, err := h.db.User.Get(uint64(k.UserID))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeInvalidAPIKey, StatusCode: http.StatusUnauthorized})
+		return nil, false
+	}
+	if !user.Active {
+		h.respondError(w, r, APIError{Code: CodeUserInactive, StatusCode: http.StatusForbidden})
+		return nil, false
+	}
+
+	if err := h.db.APIKey.Touch(k.ID, now); err != nil {
+		h.log.Errorf("failed to record use of api key %d, %s", k.ID, err)
+	}
+
+	return &Token{UserID: user.ID, Username: user.Username, Role: user.Role, Scopes: k.Scopes}, true
+}
@@ -0,0 +1,174 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_APIKeys(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := db.User.Insert(database.User{
+		Username: "scripter1",
+		Password: string(hash),
+		Email:    "scripter1@test.gr",
+		Active:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := handler.Token{UserID: userID, Username: "scripter1", Role: database.RoleUser}
+
+	// manage calls fn as the signed in owner of the keys, id is injected as url parameter
+	manage := func(fn http.HandlerFunc, id string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, owner))
+
+		rr := httptest.NewRecorder()
+		fn.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// withKey calls fn with an API key, scoped routes accept keys with the recipe scopes
+	withKey := func(fn http.HandlerFunc, method string, key string, scoped bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+
+		next := h.AuthorizationMiddleware(fn)
+		if scoped {
+			next = h.APIKeyScopes(database.ScopeRecipesRead, database.ScopeRecipesWrite)(next)
+		}
+		next.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// caller responds with the caller the middleware built
+	caller := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(r.Context().Value(handler.CtxKeyToken))
+	})
+
+	create := func(body string) handler.APIKeyResponseItem {
+		rr := manage(h.APIKeyCreate, "", body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+		resp := handler.APIKeyResponseItem{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	readKey := create(`{"name":"nightly import","scopes":["recipes:read"],"expiresIn":30}`)
+
+	t.Run("Should show keys once", func(t *testing.T) {
+		if !strings.HasPrefix(readKey.Key, "rk_"+readKey.Prefix) || readKey.ExpiresAt == "" {
+			t.Fatalf("Expected a new key with an expiry got %+v", readKey)
+		}
+
+		rr := manage(h.APIKeys, "", "")
+		resp := handler.APIKeysResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) != 1 || resp.Data[0].Key != "" || resp.Data[0].Name != "nightly import" {
+			t.Fatalf("Expected the key without its value got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Should reject invalid keys", func(t *testing.T) {
+		if rr := manage(h.APIKeyCreate, "", `{"name":"admin","scopes":["users:manage"]}`); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected unknown scopes to be rejected got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := withKey(caller, http.MethodGet, "rk_unknown", true); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusUnauthorized, rr.Body.String())
+		}
+	})
+
+	t.Run("Should authenticate requests as the owner of the key", func(t *testing.T) {
+		rr := withKey(caller, http.MethodGet, readKey.Key, true)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		token := handler.Token{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &token); err != nil {
+			t.Fatal(err)
+		}
+		if token.UserID != userID || token.Username != "scripter1" || len(token.Scopes) != 1 {
+			t.Fatalf("Expected the owner of the key got %+v", token)
+		}
+
+		keys, err := db.APIKey.List(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys[0].LastUsedAt == "" {
+			t.Fatal("Expected the use of the key to be recorded")
+		}
+	})
+
+	t.Run("Should enforce scopes", func(t *testing.T) {
+		rr := withKey(caller, http.MethodPost, readKey.Key, true)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), handler.CodeInsufficientScope) {
+			t.Fatalf("Expected an insufficient scope error got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		writeKey := create(`{"name":"uploader","scopes":["recipes:read","recipes:write"]}`)
+		if rr := withKey(caller, http.MethodPost, writeKey.Key, true); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+	})
+
+	t.Run("Should only accept keys on scoped routes", func(t *testing.T) {
+		rr := withKey(h.User, http.MethodGet, readKey.Key, false)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), handler.CodeAPIKeyNotAllowed) {
+			t.Fatalf("Expected an api key not allowed error got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should revoke keys", func(t *testing.T) {
+		id := fmt.Sprint(readKey.ID)
+		if rr := manage(h.APIKeyRevoke, id, ""); rr.Code != http.StatusNoContent {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNoContent, rr.Body.String())
+		}
+		if rr := manage(h.APIKeyRevoke, id, ""); rr.Code != http.StatusNotFound {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNotFound, rr.Body.String())
+		}
+		if rr := withKey(caller, http.MethodGet, readKey.Key, true); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the revoked key to be rejected got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+}
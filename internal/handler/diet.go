@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// RecipeDietOverride godoc
+// @Summary Override a recipe diet label
+// @Description Set whether a recipe fits a diet in place of the label derived from its ingredients, only the recipe
+// @Description author can override labels. Overrides are kept when the recipe is reclassified and are listed in
+// @Description dietOverrides.
+// @ID put-recipe-diet
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param diet path string true "Diet" Enums(vegan, vegetarian, gluten-free, keto)
+// @Param body body handler.DietOverrideRequest true "override"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/diets/{diet} [put]
+func (h *Handler) RecipeDietOverride(w http.ResponseWriter, r *http.Request) {
+	diet := chi.URLParam(r, "diet")
+	if !database.IsDiet(diet) {
+		h.respondError(w, r, APIError{Code: CodeUnknownDiet, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	do := DietOverrideRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&do); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(do); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	override := database.DietOverride{Diet: diet, Label: *do.Label, UserID: h.caller(r).UserID, Note: do.Note}
+	if err := h.db.Diet.Override(recipe.ID, override); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respondRecipe(w, r, recipe.ID)
+}
+
+// RecipeDietReset godoc
+// @Summary Remove a recipe diet override
+// @Description Remove the author override of a recipe diet label, the label derived from the ingredients applies again
+// @ID delete-recipe-diet
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param diet path string true "Diet" Enums(vegan, vegetarian, gluten-free, keto)
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/diets/{diet} [delete]
+func (h *Handler) RecipeDietReset(w http.ResponseWriter, r *http.Request) {
+	diet := chi.URLParam(r, "diet")
+	if !database.IsDiet(diet) {
+		h.respondError(w, r, APIError{Code: CodeUnknownDiet, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Diet.ClearOverride(recipe.ID, diet); err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeUnknownDietOverride, StatusCode: http.StatusNotFound})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respondRecipe(w, r, recipe.ID)
+}
+
+// DietAttributes godoc
+// @Summary Get diet attributes
+// @Description Get the ingredient to diet attribute table recipes are classified with
+// @ID get-diet-attributes
+// @Produce  json
+// @Success 200 {object} handler.DietAttributesResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/diet-attributes [get]
+func (h *Handler) DietAttributes(w http.ResponseWriter, r *http.Request) {
+	attrs, err := h.db.Diet.Attributes()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := DietAttributesResponse{Data: []DietAttributeResponseItem{}}
+	for ingredient := range attrs {
+		resp.Data = append(resp.Data, DietAttributeResponseItem{Ingredient: ingredient, Attributes: attrs[ingredient]})
+	}
+	sort.Slice(resp.Data, func(i, j int) bool {
+		return resp.Data[i].Ingredient < resp.Data[j].Ingredient
+	})
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// DietAttributesImport godoc
+// @Summary Bulk load diet attributes
+// @Description Bulk load ingredient diet attributes from a JSON or YAML document, existing attributes of the
+// @Description imported ingredients are replaced and all recipes are reclassified
+// @ID import-diet-attributes
+// @Accept  json
+// @Accept  x-yaml
+// @Produce  json
+// @Param body body handler.DietAttributesImportRequest true "diet attributes"
+// @Success 200 {object} handler.DietAttributesImportResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/diet-attributes/import [post]
+func (h *Handler) DietAttributesImport(w http.ResponseWriter, r *http.Request) {
+	di := DietAttributesImportRequest{}
+	if err := h.decodeImport(w, r, &di); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	attrs := database.DietAttributes{}
+	for i := range di.Attributes {
+		attrs[di.Attributes[i].Ingredient] = append(attrs[di.Attributes[i].Ingredient], di.Attributes[i].Attributes...)
+	}
+
+	total, err := h.db.Diet.Import(attrs)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, DietAttributesImportResponse{Imported: total}, http.StatusOK)
+}
+
+// taxonomyChanged drops the cached taxonomy and reclassifies every recipe, ingredients inherit diet attributes and
+// nutrition facts from the taxonomy so a moved or renamed node can change the labels and calories of recipes
+func (h *Handler) taxonomyChanged() error {
+	h.cache.Delete(cacheKeyTaxonomy)
+
+	if err := h.db.Diet.Classify(); err != nil {
+		return err
+	}
+
+	return h.db.Nutrient.Calculate()
+}
+
+// respondRecipe responds with a freshly loaded recipe
+func (h *Handler) respondRecipe(w http.ResponseWriter, r *http.Request, id int64) {
+	recipe, err := h.db.Recipe.Get(uint64(id), h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := RecipeResponseItem{}
+	if err := EncodeEntity(recipe, &resp); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
@@ -0,0 +1,164 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+func TestHandler_RecipeDiets(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/diet-attributes/import", strings.NewReader(`
+attributes:
+  - ingredient: graviera
+    attributes: [dairy]
+  - ingredient: phyllo
+    attributes: [gluten, starch]
+`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.DietAttributesImport).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to import diet attributes, %s", rr.Body.String())
+	}
+
+	id, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Diet Tiropita",
+		URL:         "http://example.com/diet-tiropita",
+		Ingredients: database.Ingredients{{Name: "graviera"}, {Name: "phyllo"}},
+		UserID:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// diet sends a diet override request for the recipe as token, an empty body removes the override
+	diet := func(token handler.Token, name string, body string) *httptest.ResponseRecorder {
+		method, fn := http.MethodPut, h.RecipeDietOverride
+		if body == "" {
+			method, fn = http.MethodDelete, h.RecipeDietReset
+		}
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", fmt.Sprint(id))
+		ctx.URLParams.Add("diet", name)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, token))
+
+		rr := httptest.NewRecorder()
+		fn(rr, req)
+
+		return rr
+	}
+
+	author := handler.Token{UserID: 1, Username: "username1"}
+	testData := []struct {
+		desc          string
+		token         handler.Token
+		diet          string
+		body          string
+		expectedCode  int
+		expectedDiets []string
+	}{
+		{"Should not let other users override", handler.Token{UserID: 2, Username: "username2"}, "keto",
+			`{"label":true}`, http.StatusForbidden, nil},
+		{"Should reject unknown diets", author, "paleo", `{"label":true}`, http.StatusNotFound, nil},
+		{"Should require a label", author, "keto", `{"note":"low carb phyllo"}`, http.StatusBadRequest, nil},
+		{"Should override a label", author, "keto", `{"label":true,"note":"low carb phyllo"}`, http.StatusOK,
+			[]string{"vegetarian", "keto"}},
+		{"Should remove an override", author, "keto", "", http.StatusOK, []string{"vegetarian"}},
+		{"Should not remove a missing override", author, "keto", "", http.StatusNotFound, nil},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			rr := diet(tc.token, tc.diet, tc.body)
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+			if tc.expectedDiets == nil {
+				return
+			}
+
+			resp := handler.RecipeResponseItem{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(resp.Diets) != fmt.Sprint(tc.expectedDiets) {
+				t.Fatalf("Expected diets %v got %v", tc.expectedDiets, resp.Diets)
+			}
+			if overridden := len(resp.DietOverrides) == 1 && resp.DietOverrides[0].Note == "low carb phyllo"; overridden != (tc.body != "") {
+				t.Fatalf("Expected the override to be listed only while set got %+v", resp.DietOverrides)
+			}
+		})
+	}
+
+	// Recipes filtered by diet
+	req = httptest.NewRequest(http.MethodGet, "/?term=Diet%20Tiropita&diet=vegetarian", nil)
+	rr = httptest.NewRecorder()
+	h.Recipes(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	resp := handler.RecipesResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Metadata.Total != 1 {
+		t.Fatalf("Expected 1 vegetarian recipe got %d", resp.Metadata.Total)
+	}
+
+	// Ingredients moved under a taxonomy node inherit its attributes
+	kasseriID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Diet Saganaki",
+		URL:         "http://example.com/diet-saganaki",
+		Ingredients: database.Ingredients{{Name: "kasseri"}},
+		UserID:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/admin/taxonomy/import", strings.NewReader(`
+taxonomy:
+  - name: graviera
+    children:
+      - name: kasseri
+`))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(h.TaxonomyImport).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to import taxonomy, %s", rr.Body.String())
+	}
+
+	recipe, err := db.Recipe.Get(uint64(kasseriID), database.Viewer{Moderator: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range recipe.Diets {
+		if d == database.DietVegan {
+			t.Fatalf("Expected kasseri to lose the vegan label got %v", recipe.Diets)
+		}
+	}
+}
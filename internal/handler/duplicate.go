@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// RecipeDuplicates godoc
+// @Summary Get near duplicate recipes
+// @Description Get groups of existing recipes that look like near duplicates of each other so they can be merged.
+// @Description Recipes are paged oldest first, a page lists the groups whose oldest recipe is on the page.
+// @ID get-admin-recipe-duplicates
+// @Produce  json
+// @Param page query int false "Page number"
+// @Success 200 {object} handler.DuplicateClustersResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/recipes/duplicates [get]
+func (h *Handler) RecipeDuplicates(w http.ResponseWriter, r *http.Request) {
+	pr := PageRequest{Page: 1}
+	if err := h.schema.Decode(&pr, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(pr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	viewer := database.Viewer{Moderator: true}
+	recipes, total, err := h.db.Recipe.List(viewer, pr.Page)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	// Each recipe of the page is compared with the candidates found for it in SQL
+	rules := h.duplicateRules()
+	onPage := make(map[int64]struct{}, len(recipes))
+	pool := make(map[int64]struct{}, len(recipes))
+	for i := range recipes {
+		onPage[recipes[i].ID] = struct{}{}
+		pool[recipes[i].ID] = struct{}{}
+	}
+	compared := append(database.Recipes{}, recipes...)
+	for i := range recipes {
+		candidates, err := h.db.Recipe.DuplicateCandidates(recipes[i], viewer, rules)
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		for _, d := range rules.Find(recipes[i], candidates) {
+			if _, ok := pool[d.Recipe.ID]; !ok {
+				pool[d.Recipe.ID] = struct{}{}
+				compared = append(compared, d.Recipe)
+			}
+		}
+	}
+
+	resp := DuplicateClustersResponse{Data: []RecipeResponseItems{}, Metadata: Metadata{Total: total}}
+	for _, cluster := range rules.Clusters(compared) {
+		// Groups reaching back to an older page were listed there
+		oldest := cluster[0]
+		for i := range cluster {
+			if cluster[i].ID < oldest.ID {
+				oldest = cluster[i]
+			}
+		}
+		if _, ok := onPage[oldest.ID]; !ok {
+			continue
+		}
+
+		items := RecipeResponseItems{}
+		if err := EncodeEntity(cluster, &items); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		resp.Data = append(resp.Data, items)
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// respondDuplicates responds with status 409 and the near duplicates of a rejected recipe
+func (h *Handler) respondDuplicates(w http.ResponseWriter, r *http.Request, duplicates database.Duplicates) {
+	resp := DuplicateRecipeResponse{
+		ErrorResponse: ErrorResponse{
+			Code:          CodeRecipeDuplicate,
+			Message:       messages.Message(append(h.localeChain(r), fallbackLocale), CodeRecipeDuplicate),
+			StatusCode:    http.StatusConflict,
+			StatusMessage: http.StatusText(http.StatusConflict),
+		},
+		Duplicates: DuplicateResponseItems{},
+	}
+
+	for i := range duplicates {
+		item := DuplicateResponseItem{
+			Reasons:           duplicates[i].Reasons,
+			IngredientOverlap: duplicates[i].IngredientOverlap,
+		}
+		if err := EncodeEntity(duplicates[i].Recipe, &item.Recipe); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		resp.Duplicates = append(resp.Duplicates, item)
+	}
+
+	h.respond(w, resp, http.StatusConflict)
+}
+
+// duplicateRules returns the configured near duplicate detection rules
+func (h *Handler) duplicateRules() database.DuplicateRules {
+	return database.DuplicateRules{
+		IngredientOverlap: h.cfg.Duplicates.IngredientOverlap,
+		MinIngredients:    h.cfg.Duplicates.MinIngredients,
+	}
+}
@@ -0,0 +1,127 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_RecipeDuplicates(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	// Bypass detection to store a near duplicate
+	id, err := db.Recipe.Insert(database.Recipe{
+		Title: "The Shrimp Gumbo",
+		URL:   "http://example.com/shrimp-gumbo",
+		Ingredients: database.Ingredients{
+			{Name: "shrimp"}, {Name: "okra"}, {Name: "onions"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/recipes/duplicates", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.RecipeDuplicates).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	resp := handler.DuplicateClustersResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cluster := range resp.Data {
+		titles := make(map[string]int64)
+		for _, r := range cluster {
+			titles[r.Title] = r.ID
+		}
+		if titles["The Shrimp Gumbo"] == id {
+			if _, ok := titles["Shrimp Gumbo"]; !ok {
+				t.Fatalf("Expected Shrimp Gumbo to be in the same cluster, got %+v", cluster)
+			}
+			return
+		}
+	}
+
+	t.Fatalf("Expected a duplicate cluster for Shrimp Gumbo, %s", rr.Body.String())
+}
+
+func TestHandler_RecipeImport(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	// The first recipe points to the source of an existing one, the last one repeats the second
+	payload := `recipes:
+  - title: Gumbo from the source
+    url: http://allrecipes.com/Recipe/Shrimp-Gumbo/Detail.aspx
+    ingredients: [shrimp, okra]
+  - title: Red Lentil Soup
+    url: http://example.com/red-lentil-soup
+    ingredients: [red lentils, carrots, celery]
+    prepTime: 10
+  - title: Red Lentil Soup!
+    url: http://example.com/lentils
+    ingredients: [red lentils]
+`
+	req := httptest.NewRequest(http.MethodPost, "/recipes/import", strings.NewReader(payload))
+	req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, handler.Token{UserID: 1, Username: "username1"}))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.RecipeImport).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	resp := handler.RecipeImportResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Imported) != 1 || len(resp.Skipped) != 2 {
+		t.Fatalf("Expected a single imported recipe and two skipped, %s", rr.Body.String())
+	}
+	for i, index := range []int{0, 2} {
+		skipped := resp.Skipped[i]
+		if skipped.Index != index || skipped.Code != handler.CodeRecipeDuplicate || len(skipped.Duplicates) == 0 {
+			t.Fatalf("Expected recipe %d to be skipped as a near duplicate got %+v", index, skipped)
+		}
+	}
+
+	recipe, err := db.Recipe.Get(uint64(resp.Imported[0]), database.Viewer{Moderator: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe.Title != "Red Lentil Soup" || recipe.PrepTime != 10 || recipe.Status != database.RecipeDraft {
+		t.Fatalf("Unexpected imported recipe %+v", recipe)
+	}
+}
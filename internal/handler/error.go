@@ -1,19 +1,111 @@
 package handler
 
-// APIError
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Error codes are stable machine readable identifiers of api errors that clients can branch on, each code is also
+// the key of its message in the message catalogs
+const (
+	CodeInternal                = "internal_error"
+	CodeBadRequest              = "bad_request"
+	CodeValidation              = "validation_failed"
+	CodeAuthRequired            = "authentication_required"
+	CodeInvalidToken            = "invalid_token"
+	CodeInvalidShareLink        = "invalid_share_link"
+	CodeInvalidRefreshToken     = "invalid_refresh_token"
+	CodeRefreshTokenReused      = "refresh_token_reused"
+	CodeInvalidCredentials      = "invalid_credentials"
+	CodeLoginThrottled          = "login_throttled"
+	CodeAccountLocked           = "account_locked"
+	CodeUsernameTaken           = "username_taken"
+	CodeEmailTaken              = "email_taken"
+	CodeWrongPassword           = "wrong_password"
+	CodeRateLimited             = "rate_limit_exceeded"
+	CodePermissionRequired      = "permission_required"
+	CodeUserInactive            = "user_inactive"
+	CodeEmailUnverified         = "email_unverified"
+	CodeInvalidVerification     = "invalid_verification_link"
+	CodeInvalidResetToken       = "invalid_reset_token"
+	CodeInvalidAPIKey           = "invalid_api_key"
+	CodeAPIKeyNotAllowed        = "api_key_not_allowed"
+	CodeInsufficientScope       = "insufficient_scope"
+	CodeInvalidLoginState       = "invalid_login_state"
+	CodeProviderSignInFailed    = "provider_sign_in_failed"
+	CodeProviderEmailUnverified = "provider_email_unverified"
+	CodeAccountUnverified       = "account_unverified"
+	CodeOwnAccount              = "own_account"
+	CodeAuthorRequired          = "author_required"
+	CodeIDRequired              = "id_required"
+	CodeRecipeIDRequired        = "recipe_id_required"
+	CodeUserIDRequired          = "user_id_required"
+	CodeIngredientIDRequired    = "ingredient_id_required"
+	CodeShareIDRequired         = "share_id_required"
+	CodeAPIKeyIDRequired        = "api_key_id_required"
+	CodeUnknownRecipe           = "unknown_recipe"
+	CodeUnknownDeletedRecipe    = "unknown_deleted_recipe"
+	CodeUnknownUser             = "unknown_user"
+	CodeUnknownDeletedUser      = "unknown_deleted_user"
+	CodeUnknownIngredient       = "unknown_ingredient"
+	CodeUnknownRecipeIngredient = "unknown_recipe_ingredient"
+	CodeUnknownSubstitution     = "unknown_substitution"
+	CodeUnknownTaxonomyNode     = "unknown_taxonomy_node"
+	CodeUnknownParent           = "unknown_parent"
+	CodeUnknownShare            = "unknown_share"
+	CodeUnknownAPIKey           = "unknown_api_key"
+	CodeUnknownProvider         = "unknown_provider"
+	CodeUnknownTranslation      = "unknown_translation"
+	CodeUnknownDiet             = "unknown_diet"
+	CodeUnknownDietOverride     = "unknown_diet_override"
+	CodeTaxonomyNodeExists      = "taxonomy_node_exists"
+	CodeTaxonomyNodeCycle       = "taxonomy_node_cycle"
+	CodeRecipeTitleExists       = "recipe_title_exists"
+	CodeRecipeDuplicate         = "recipe_duplicate"
+	CodeRecipeLocale            = "recipe_locale"
+	CodeUnsupportedLocale       = "unsupported_locale"
+	CodeStatusTransition        = "status_transition"
+	CodeCreateRecipeFailed      = "create_recipe_failed"
+	CodeSaveTranslationFailed   = "save_translation_failed"
+)
+
+// errAuthRequired responds to anonymous callers of routes that need a signed in user
+var errAuthRequired = APIError{Code: CodeAuthRequired, StatusCode: http.StatusUnauthorized}
+
+// errBadRequest responds to requests that could not be decoded
+var errBadRequest = APIError{Code: CodeBadRequest, StatusCode: http.StatusBadRequest}
+
+// APIError is an error that is sent to clients, its message is looked up by Code in the message catalog of the
+// language of the request and formatted with Args
 type APIError struct {
-	Message    string
+	Code       string
+	Args       []interface{}
 	StatusCode int
+	// Fields holds the failed validations of requests rejected by the validator
+	Fields validator.ValidationErrors
 }
 
-func NewAPIError(message string, status int) APIError {
+func NewAPIError(code string, status int, args ...interface{}) APIError {
 	return APIError{
-		Message:    message,
+		Code:       code,
+		Args:       args,
 		StatusCode: status,
 	}
 }
 
-// Error implements error interface
+// Error implements error interface, the message is in the fallback locale
 func (a APIError) Error() string {
-	return a.Message
+	return messages.Message([]string{fallbackLocale}, a.Code, a.Args...)
+}
+
+// validationError converts the errors of the validator to an api error that lists the invalid fields
+func validationError(err error) APIError {
+	ve := validator.ValidationErrors{}
+	if !errors.As(err, &ve) {
+		return errBadRequest
+	}
+
+	return APIError{Code: CodeValidation, StatusCode: http.StatusBadRequest, Fields: ve}
 }
@@ -0,0 +1,92 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_LocalizedErrors(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewHandler(nil, cfg, logger.NewLogger(cfg.Logger))
+
+	// anonymous calls a route that requires a signed in user
+	anonymous := h.RequirePermission(handler.PermManageUsers)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// create calls Create as a signed in user with a recipe that has no title
+	create := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), handler.CtxKeyToken, handler.Token{UserID: 1, Username: "username1"}))
+		h.Create(w, r)
+	})
+
+	testData := []struct {
+		desc            string
+		handler         http.Handler
+		url             string
+		acceptLanguage  string
+		body            string
+		expectedCode    string
+		expectedMessage string
+		expectedFields  []handler.FieldErrorResponse
+	}{
+		{"Should respond in the default locale", anonymous, "/", "", "", handler.CodeAuthRequired,
+			"authentication required", nil},
+		{"Should respond in the Accept-Language locale", anonymous, "/", "fr, el-GR;q=0.8", "",
+			handler.CodeAuthRequired, "απαιτείται σύνδεση", nil},
+		{"Should fall back to english for locales without a catalog", anonymous, "/", "de", "",
+			handler.CodeAuthRequired, "authentication required", nil},
+		{"Should prefer the lang parameter", anonymous, "/?lang=el", "en", "", handler.CodeAuthRequired,
+			"απαιτείται σύνδεση", nil},
+		{"Should localize validation errors", create, "/", "el", `{"url":"http://example.com/x","ingredients":["salt"]}`,
+			handler.CodeValidation, "το αίτημα έχει μη έγκυρα πεδία", []handler.FieldErrorResponse{
+				{Field: "title", Code: "required", Message: "το πεδίο title είναι υποχρεωτικό"},
+			}},
+		{"Should word validation errors by field kind", create, "/", "", `{"title":"a","url":"http://example.com/x"}`,
+			handler.CodeValidation, "the request has invalid fields", []handler.FieldErrorResponse{
+				{Field: "title", Code: "min", Message: "title must be at least 2 characters long"},
+				{Field: "ingredients", Code: "required", Message: "ingredients is required"},
+			}},
+		{"Should keep the code of api errors", create, "/", "",
+			`{"title":"Soup","url":"http://example.com/soup","ingredients":["salt"],"locale":"fr"}`,
+			handler.CodeUnsupportedLocale, "unsupported locale", nil},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, req)
+
+			resp := handler.ErrorResponse{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != tc.expectedCode || resp.Message != tc.expectedMessage {
+				t.Fatalf("Expected %s %s got %s %s", tc.expectedCode, tc.expectedMessage, resp.Code, resp.Message)
+			}
+			if len(resp.Fields) != len(tc.expectedFields) {
+				t.Fatalf("Expected fields %+v got %+v", tc.expectedFields, resp.Fields)
+			}
+			for i := range tc.expectedFields {
+				if resp.Fields[i] != tc.expectedFields[i] {
+					t.Fatalf("Expected fields %+v got %+v", tc.expectedFields, resp.Fields)
+				}
+			}
+		})
+	}
+}
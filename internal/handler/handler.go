@@ -1,62 +1,238 @@
 package handler
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/georlav/recipeapi/internal/backoff"
+	"github.com/georlav/recipeapi/internal/cache"
 	"github.com/georlav/recipeapi/internal/config"
 	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/i18n"
+	"github.com/georlav/recipeapi/internal/keyring"
 	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/georlav/recipeapi/internal/mailer"
+	"github.com/georlav/recipeapi/internal/ratelimit"
+	"github.com/georlav/recipeapi/internal/views"
+	"github.com/go-chi/chi"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/schema"
+	"gopkg.in/yaml.v2"
 )
 
 type contextKey string
 
 const CtxKeyToken contextKey = "token"
 
+// CtxKeyShare holds the share link of requests authorized with a share token
+const CtxKeyShare contextKey = "share"
+
+const cacheKeyTaxonomy = "taxonomy"
+
+// cacheKeyRevokedTokens holds the jti of revoked access tokens
+const cacheKeyRevokedTokens = "revoked_tokens"
+
+// cacheKeyInactiveUsers holds the ids of unverified, deactivated and deleted users
+const cacheKeyInactiveUsers = "inactive_users"
+
 type Handler struct {
 	db       *database.Database
 	cfg      *config.Config
 	log      *logger.Logger
 	schema   *schema.Decoder
 	validate *validator.Validate
+	cache    *cache.Cache
+	views    *views.Counter
+	// anonymousLimit limits callers without a token by ip address, userLimit limits signed in users
+	anonymousLimit *ratelimit.Limiter
+	userLimit      *ratelimit.Limiter
+	locales        *i18n.Locales
+	mailer         mailer.Mailer
+	// verifyLimit limits the verification emails sent to each address
+	verifyLimit *ratelimit.Limiter
+	// resetLimit limits the password reset emails sent to each address
+	resetLimit *ratelimit.Limiter
+	// pending tracks emails sent off the request path
+	pending *sync.WaitGroup
+	// keys sign and verify access, share link and emailed link tokens
+	keys *keyring.Keyring
+	// client sends requests to identity providers
+	client *http.Client
+	// loginUsers and loginIPs back off failed sign ins by username and by ip address
+	loginUsers *backoff.Tracker
+	loginIPs   *backoff.Tracker
 }
 
 func NewHandler(db *database.Database, c *config.Config, l *logger.Logger) *Handler {
-	return &Handler{
+	h := Handler{
 		db:       db,
 		cfg:      c,
 		log:      l,
 		schema:   schema.NewDecoder(),
 		validate: validator.New(),
+		cache:    cache.New(time.Duration(c.Cache.TTL) * time.Second),
+
+		anonymousLimit: ratelimit.New(c.RateLimit.Anonymous, time.Duration(c.RateLimit.Window)*time.Second),
+		userLimit:      ratelimit.New(c.RateLimit.Authenticated, time.Duration(c.RateLimit.Window)*time.Second),
+		locales:        i18n.New(c.Locale.Default, c.Locale.Supported...),
+		mailer:         mailer.NewSMTP(c.Mail),
+		verifyLimit:    ratelimit.New(c.Verify.ResendLimit, time.Duration(c.Verify.ResendWindow)*time.Second),
+		resetLimit:     ratelimit.New(c.Reset.Limit, time.Duration(c.Reset.Window)*time.Second),
+		pending:        &sync.WaitGroup{},
+		keys:           keyring.New(c.Token.Secret),
+		client:         &http.Client{Timeout: 10 * time.Second},
+		loginUsers:     newLoginTracker(c.Login, c.Login.Free),
+		loginIPs:       newLoginTracker(c.Login, c.Login.IPFree),
 	}
+	h.validate.RegisterTagNameFunc(fieldName)
+
+	h.views = views.NewCounter(
+		views.StoreFunc(func(v map[int64]int64, at time.Time) error {
+			return h.db.Recipe.AddViews(v, at)
+		}),
+		time.Duration(c.Views.FlushInterval)*time.Second,
+		c.Views.BufferSize,
+	)
+
+	return &h
 }
 
-func (h *Handler) newToken(u *database.User) (*string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"uname": u.Username,
-		"uid":   u.ID,
-		"iat":   time.Now().Unix(),
-		"exp":   time.Now().Add(time.Duration(h.cfg.Token.TTL) * time.Minute).Unix(),
-	})
+// SetMailer replaces the mailer user emails are sent with, by default they are sent through the configured SMTP server
+func (h *Handler) SetMailer(m mailer.Mailer) {
+	h.mailer = m
+}
 
-	tokenSigned, err := token.SignedString([]byte(h.cfg.Token.Secret))
-	if err != nil {
-		return nil, fmt.Errorf("signature error, %w", err)
-	}
+// Run starts the handler background jobs and blocks until ctx is done
+func (h *Handler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
 
-	return &tokenSigned, nil
+	go func() {
+		defer wg.Done()
+		h.views.Run(ctx, func(err error) {
+			h.log.Errorf("failed to flush recipe views, %s", err)
+		})
+	}()
+
+	go func() {
+		defer wg.Done()
+		h.runPurge(ctx)
+	}()
+
+	wg.Wait()
 }
 
-func (h *Handler) getToken(r *http.Request) (*Token, error) {
+// Close waits for pending emails and flushes buffered data to the database, call it once the http server has shut
+// down and Run has returned so no flush of Run is still writing
+func (h *Handler) Close() error {
+	h.Wait()
+
+	return h.views.Flush()
+}
+
+// LoadKeys loads the signing keys of c, until called tokens are signed with the HS256 secret. Call it again with
+// the new configuration to rotate keys, tokens keep being signed with the previous keys when loading fails
+func (h *Handler) LoadKeys(c config.Token) error {
+	return h.keys.Load(c)
+}
+
+// Wait blocks until the emails requests sent off the request path are out
+func (h *Handler) Wait() {
+	h.pending.Wait()
+}
+
+// background runs fn off the request path, Wait and Close wait for it to return
+func (h *Handler) background(fn func()) {
+	h.pending.Add(1)
+	go func() {
+		defer h.pending.Done()
+		fn()
+	}()
+}
+
+// caller returns the token of the signed in user that sent the request, nil when the request is anonymous
+func (h *Handler) caller(r *http.Request) *Token {
 	token, ok := r.Context().Value(CtxKeyToken).(Token)
 	if !ok {
-		return nil, errors.New("token not present in header")
+		return nil
+	}
+
+	return &token
+}
+
+// viewer returns who is reading recipes, requests without a token only see published recipes and the recipe of
+// their share link. Moderators are plain users here so drafts and private recipes stay out of their listings.
+func (h *Handler) viewer(r *http.Request) database.Viewer {
+	token := h.caller(r)
+	if token == nil {
+		if share, ok := r.Context().Value(CtxKeyShare).(database.Share); ok {
+			return database.Viewer{SharedRecipeID: share.RecipeID}
+		}
+		return database.Viewer{}
+	}
+
+	return database.Viewer{UserID: token.UserID}
+}
+
+// localeChain returns the locales to serve content in, the lang query parameter takes precedence over the
+// Accept-Language header and the default locale is always last
+func (h *Handler) localeChain(r *http.Request) []string {
+	preferred := i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		preferred = append([]string{lang}, preferred...)
+	}
+
+	return h.locales.Chain(preferred...)
+}
+
+// urlID reads a positive numeric url parameter
+func urlID(r *http.Request, key string) (uint64, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, key), 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%s is required", key)
+	}
+
+	return id, nil
+}
+
+// maxImportSize is the largest document accepted by the bulk import handlers
+const maxImportSize = 4 << 20
+
+// decodeImport reads a JSON or YAML import document into v and validates it. YAML is a superset of JSON, a single
+// decoder handles both formats
+func (h *Handler) decodeImport(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		return errBadRequest
+	}
+
+	if err := yaml.Unmarshal(b, v); err != nil {
+		return errBadRequest
+	}
+
+	if err := h.validate.Struct(v); err != nil {
+		return validationError(err)
+	}
+
+	return nil
+}
+
+// taxonomyTree returns the ingredient taxonomy, the tree is cached and invalidated when the taxonomy changes
+func (h *Handler) taxonomyTree() (*database.TaxonomyTree, error) {
+	if tree, ok := h.cache.Get(cacheKeyTaxonomy); ok {
+		return tree.(*database.TaxonomyTree), nil
+	}
+
+	tree, err := h.db.Taxonomy.Tree()
+	if err != nil {
+		return nil, err
 	}
+	h.cache.Set(cacheKeyTaxonomy, tree)
 
-	return &token, nil
+	return tree, nil
 }
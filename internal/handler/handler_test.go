@@ -54,6 +54,7 @@ func TestMain(m *testing.M) {
 		Password: string(hash),
 		FullName: "test user",
 		Email:    "test@test.gr",
+		Active:   true,
 	}); err != nil {
 		log.Fatal(err)
 	}
@@ -72,6 +73,60 @@ func TestMain(m *testing.M) {
 	if _, err := db.Handle.Exec(`TRUNCATE TABLE ingredient`); err != nil {
 		log.Fatal(err)
 	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE recipe_share`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE recipe_translation`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE ingredient_translation`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE recipe_view`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE substitution`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE substitution_ingredient`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE taxonomy`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE tag`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE recipe_tag`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE diet_attribute`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE recipe_diet`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE nutrient`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE refresh_token`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE revoked_token`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE password_reset`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE api_key`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE oidc_login`); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Handle.Exec(`TRUNCATE TABLE user_identity`); err != nil {
+		log.Fatal(err)
+	}
 	if _, err := db.Handle.Exec(`SET FOREIGN_KEY_CHECKS = 1`); err != nil {
 		log.Fatal(err)
 	}
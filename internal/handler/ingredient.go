@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/fuzzy"
+)
+
+const cacheKeyIngredientNames = "ingredient:names"
+
+// Ingredient godoc
+// @Summary Get an ingredient
+// @Description Get an ingredient by ID
+// @ID get-ingredient-by-int
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param id path int true "Ingredient ID"
+// @Success 200 {object} handler.IngredientResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /ingredients/{id} [get]
+func (h *Handler) Ingredient(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeIngredientIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	ingredient, err := h.db.Ingredient.Get(id, h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownIngredient, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	resp := IngredientResponseItem{}
+	if err := EncodeEntity(ingredient, &resp); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respondCacheable(w, r, resp)
+}
+
+// Ingredients godoc
+// @Summary Ingredient autocomplete
+// @Description Suggest ingredient names starting with prefix, ranked by the number of recipes using them.
+// @Description Diacritics are ignored and a few typos are tolerated depending on the prefix length.
+// @ID get-ingredients
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param prefix query string true "Ingredient name prefix"
+// @Param limit query int false "Maximum number of suggestions"
+// @Success 200 {object} handler.IngredientSuggestionsResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /ingredients [get]
+func (h *Handler) Ingredients(w http.ResponseWriter, r *http.Request) {
+	// Map request to struct
+	ir := IngredientsRequest{Limit: 10}
+	if err := h.schema.Decode(&ir, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	// validate data in struct
+	if err := h.validate.Struct(ir); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	names, err := h.ingredientNames()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	query := fuzzy.Fold(ir.Prefix)
+	tolerance := fuzzy.Tolerance(query)
+
+	type match struct {
+		IngredientSuggestion
+		distance int
+	}
+	var matches []match
+	for i := range names {
+		if d := fuzzy.PrefixDistance(query, names[i].folded); d <= tolerance {
+			matches = append(matches, match{IngredientSuggestion: names[i].IngredientSuggestion, distance: d})
+		}
+	}
+
+	// Names are already ordered by popularity, keep that order for matches with the same distance
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+	if len(matches) > ir.Limit {
+		matches = matches[:ir.Limit]
+	}
+
+	resp := IngredientSuggestionsResponse{Data: make([]IngredientSuggestion, len(matches))}
+	for i := range matches {
+		resp.Data[i] = matches[i].IngredientSuggestion
+	}
+
+	h.respondCacheable(w, r, resp)
+}
+
+type foldedIngredientName struct {
+	IngredientSuggestion
+	folded string
+}
+
+// ingredientNames returns the distinct ingredient names of published public recipes together with their folded form,
+// ordered by popularity. Names of drafts and private recipes are left out so the same list can be cached for everyone.
+func (h *Handler) ingredientNames() ([]foldedIngredientName, error) {
+	if names, ok := h.cache.Get(cacheKeyIngredientNames); ok {
+		return names.([]foldedIngredientName), nil
+	}
+
+	rows, err := h.db.Ingredient.Names(database.Viewer{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]foldedIngredientName, len(rows))
+	for i := range rows {
+		names[i] = foldedIngredientName{
+			IngredientSuggestion: IngredientSuggestion{Name: rows[i].Name, Recipes: rows[i].Recipes},
+			folded:               fuzzy.Fold(rows[i].Name),
+		}
+	}
+	h.cache.Set(cacheKeyIngredientNames, names)
+
+	return names, nil
+}
@@ -0,0 +1,223 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+func TestHandler_Ingredient(t *testing.T) {
+	testData := []struct {
+		input        uint64
+		expectedCode int
+	}{
+		{1, http.StatusOK},
+		{99999, http.StatusNotFound},
+		{0, http.StatusBadRequest},
+	}
+
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(fmt.Sprintf(`Get ingredient with id %d`, tc.input), func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/ingredients/%d", tc.input), nil)
+			ctx := chi.NewRouteContext()
+			if tc.input != 0 {
+				ctx.URLParams.Add("id", fmt.Sprintf(`%d`, tc.input))
+			}
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.Ingredient).ServeHTTP(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx)))
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Ingredients(t *testing.T) {
+	testData := []struct {
+		params       url.Values
+		first        string
+		expectedCode int
+	}{
+		{url.Values{"prefix": []string{"oni"}}, "onions", http.StatusOK},
+		{url.Values{"prefix": []string{"Ónion"}}, "onions", http.StatusOK},
+		{url.Values{"prefix": []string{"garlik"}}, "garlic", http.StatusOK},
+		{url.Values{"prefix": []string{"brot"}}, "chicken broth", http.StatusOK},
+		{url.Values{"prefix": []string{"veg"}, "limit": []string{"1"}}, "vegetable oil", http.StatusOK},
+		{url.Values{"prefix": []string{"zzz"}}, "", http.StatusOK},
+		{url.Values{"prefix": []string{""}}, "", http.StatusBadRequest},
+		{url.Values{"prefix": []string{"oni"}, "limit": []string{"100"}}, "", http.StatusBadRequest},
+		{url.Values{"prefix": []string{"oni"}, "limit": []string{"0"}}, "", http.StatusBadRequest},
+	}
+
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(fmt.Sprintf(`Test Case %+v`, tc.params), func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/ingredients?"+tc.params.Encode(), nil)
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.Ingredients).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			resp := handler.IngredientSuggestionsResponse{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if tc.first == "" && len(resp.Data) > 0 {
+				t.Fatalf("Expected no suggestions got %+v", resp.Data)
+			}
+			if tc.first != "" && (len(resp.Data) == 0 || resp.Data[0].Name != tc.first) {
+				t.Fatalf("Expected first suggestion to be %s got %+v", tc.first, resp.Data)
+			}
+		})
+	}
+
+	t.Run("Should respond with not modified when etag matches", func(t *testing.T) {
+		// suggest sends the autocomplete request of a signed in user with the given If-None-Match header
+		suggest := func(ifNoneMatch string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/ingredients?prefix=oni", nil)
+			req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, handler.Token{UserID: 1}))
+			if ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", ifNoneMatch)
+			}
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.Ingredients).ServeHTTP(rr, req)
+			return rr
+		}
+
+		rr := suggest("")
+		etag := rr.Header().Get("ETag")
+		if etag == "" || !strings.HasPrefix(rr.Header().Get("Cache-Control"), "private") {
+			t.Fatalf("Expected private cache headers got %+v", rr.Header())
+		}
+		if !strings.Contains(rr.Header().Get("Vary"), "Authorization") {
+			t.Fatalf("Expected responses to vary by caller got %+v", rr.Header())
+		}
+
+		for _, header := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+			if rr := suggest(header); rr.Code != http.StatusNotModified {
+				t.Fatalf("Wrong status code for %s got %d expected %d", header, rr.Code, http.StatusNotModified)
+			}
+		}
+		if rr := suggest(`"other"`); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d", rr.Code, http.StatusOK)
+		}
+	})
+}
+
+func TestHandler_IngredientVisibility(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	recipeID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Secret Quince Paste",
+		URL:         "http://example.com/secret-quince-paste",
+		Ingredients: database.Ingredients{{Name: "secret quince"}, {Name: "sugar"}},
+		UserID:      1,
+		Status:      database.RecipeDraft,
+		Visibility:  database.RecipePrivate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipe, err := db.Recipe.Get(uint64(recipeID), database.Viewer{UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingredientID := fmt.Sprint(recipe.Ingredients[0].ID)
+
+	// get requests the ingredient as token, anonymously when token is nil
+	get := func(token *handler.Token) int {
+		req := httptest.NewRequest(http.MethodGet, "/ingredients/"+ingredientID, nil)
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", ingredientID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		if token != nil {
+			req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, *token))
+		}
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.Ingredient).ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	if code := get(nil); code != http.StatusNotFound {
+		t.Fatalf("Expected ingredients of private drafts to be hidden got %d", code)
+	}
+	if code := get(&handler.Token{UserID: 2, Username: "username2"}); code != http.StatusNotFound {
+		t.Fatalf("Expected ingredients of private drafts to be hidden from other users got %d", code)
+	}
+	if code := get(&handler.Token{UserID: 1, Username: "username1"}); code != http.StatusOK {
+		t.Fatalf("Expected the author to get the ingredient got %d", code)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.Ingredients).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ingredients?prefix=secret", nil))
+	resp := handler.IngredientSuggestionsResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range resp.Data {
+		if s.Name == "secret quince" {
+			t.Fatalf("Expected private ingredient names to be left out of suggestions got %+v", resp.Data)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// JWKS godoc
+// @Summary Get token signing keys
+// @Description Get the public keys access tokens are signed with as JSON web key set, other services verify tokens
+// @Description by the kid of their header. Keys are published before they sign and stay until their tokens expire
+// @ID get-jwks
+// @Produce  json
+// @Success 200 {object} keyring.JWKS
+// @Success 304
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	h.respondCacheable(w, r, h.keys.JWKS())
+}
@@ -0,0 +1,119 @@
+package handler_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/keyring"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_JWKS(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "signing.pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := cfg.Token
+	tc.SigningKey = "2024-01"
+	tc.Keys = []config.SigningKey{{ID: "2024-01", Algorithm: keyring.AlgorithmEdDSA, File: file}}
+	if err := h.LoadKeys(tc); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.User.Insert(database.User{
+		Username: "jwksuser1",
+		Password: string(hash),
+		Email:    "jwksuser1@test.gr",
+		Active:   true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should publish the signing key", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.Routes(h).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if !strings.HasPrefix(rr.Header().Get("Cache-Control"), "public") {
+			t.Fatalf("Expected the key set to be publicly cacheable got %s", rr.Header().Get("Cache-Control"))
+		}
+
+		set := keyring.JWKS{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &set); err != nil {
+			t.Fatal(err)
+		}
+		if len(set.Keys) != 1 || set.Keys[0].KeyID != "2024-01" || set.Keys[0].Algorithm != keyring.AlgorithmEdDSA {
+			t.Fatalf("Expected the signing key got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Should sign and verify access tokens with the signing key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"username":"jwksuser1","password":"password"}`))
+		rr := httptest.NewRecorder()
+		h.SignIn(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+
+		resp := handler.TokenResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := new(jwt.Parser).ParseUnverified(resp.Token, &handler.Token{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Header["kid"] != "2024-01" || token.Method.Alg() != keyring.AlgorithmEdDSA {
+			t.Fatalf("Expected an EdDSA token of the signing key got %v", token.Header)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+resp.Token)
+		rr = httptest.NewRecorder()
+		h.AuthorizationMiddleware(http.HandlerFunc(h.User)).ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+	})
+}
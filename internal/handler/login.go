@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/backoff"
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// newLoginTracker creates a tracker of failed sign ins that allows free failures before backing off
+func newLoginTracker(c config.Login, free int) *backoff.Tracker {
+	return backoff.New(
+		free,
+		time.Duration(c.BaseDelay)*time.Second,
+		time.Duration(c.MaxDelay)*time.Second,
+		time.Duration(c.Window)*time.Second,
+	)
+}
+
+// loginKey is the tracker key of a username, usernames are matched case insensitively
+func loginKey(username string) string {
+	return strings.ToLower(username)
+}
+
+// loginThrottled responds with the time left to callers that have to back off before signing in as username again
+func (h Handler) loginThrottled(w http.ResponseWriter, r *http.Request, username string, now time.Time) bool {
+	ip := clientIP(r)
+	wait := h.loginUsers.Wait(loginKey(username), now)
+	if ipWait := h.loginIPs.Wait(ip, now); ipWait > wait {
+		wait = ipWait
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	h.log.SecurityEvent("login_throttled", false, map[string]interface{}{
+		"username": username,
+		"ip":       ip,
+	})
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	h.respondError(w, r, NewAPIError(CodeLoginThrottled, http.StatusTooManyRequests, seconds))
+
+	return true
+}
+
+// loginFailed records a failed sign in as username, u is the user of username when it exists. Users are locked
+// once their failures reach the lockout threshold
+func (h Handler) loginFailed(r *http.Request, username string, u *database.User, reason string, now time.Time) {
+	ip := clientIP(r)
+	failures := h.loginUsers.Fail(loginKey(username), now)
+	h.loginIPs.Fail(ip, now)
+
+	h.log.SecurityEvent("login_failed", false, map[string]interface{}{
+		"username": username,
+		"ip":       ip,
+		"reason":   reason,
+		"failures": failures,
+	})
+
+	threshold := h.cfg.Login.LockoutThreshold
+	if u == nil || threshold < 1 || failures < threshold {
+		return
+	}
+
+	until := now.Add(time.Duration(h.cfg.Login.LockoutDuration) * time.Minute).UTC()
+	if err := h.db.User.Lock(uint64(u.ID), until); err != nil {
+		h.log.Errorf("failed to lock user %d, %s", u.ID, err)
+		return
+	}
+	// The lockout takes over from the backoff until it ends
+	h.loginUsers.Reset(loginKey(username))
+
+	h.log.SecurityEvent("account_locked", false, map[string]interface{}{
+		"user_id":      u.ID,
+		"username":     u.Username,
+		"ip":           ip,
+		"locked_until": until.Format("2006-01-02 15:04:05"),
+	})
+}
@@ -0,0 +1,217 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_SignInThrottling(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	signIn := func(username string, password string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"username":%q,"password":%q}`, username, password)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.SignIn).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rr
+	}
+
+	t.Run("Should delay sign ins after the free failures", func(t *testing.T) {
+		for i := 0; i < cfg.Login.Free; i++ {
+			if rr := signIn("username1", "wrong"); rr.Code != http.StatusUnauthorized {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusUnauthorized, rr.Body.String())
+			}
+		}
+
+		rr := signIn("username1", "wrong")
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusUnauthorized, rr.Body.String())
+		}
+
+		// The correct password is throttled as well until the delay ends
+		rr = signIn("USERNAME1", "password")
+		if rr.Code != http.StatusTooManyRequests || !strings.Contains(rr.Body.String(), handler.CodeLoginThrottled) {
+			t.Fatalf("Expected a throttled sign in got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr.Header().Get("Retry-After") == "" {
+			t.Fatal("Expected a Retry-After header")
+		}
+	})
+
+	t.Run("Should throttle usernames independently", func(t *testing.T) {
+		if rr := signIn("unknown-user", "wrong"); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusUnauthorized, rr.Body.String())
+		}
+	})
+}
+
+func TestHandler_PasswordCheckThrottling(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+	owner := handler.Token{UserID: 1, Username: "username1"}
+
+	change := func(current string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"currentPassword":%q,"password":"password2","repeatPassword":"password2"}`, current)
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, owner))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.PasswordChange).ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Should delay password checks after the free failures", func(t *testing.T) {
+		for i := 0; i <= cfg.Login.Free; i++ {
+			if rr := change("wrong"); rr.Code != http.StatusForbidden {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusForbidden, rr.Body.String())
+			}
+		}
+
+		rr := change("password")
+		if rr.Code != http.StatusTooManyRequests || !strings.Contains(rr.Body.String(), handler.CodeLoginThrottled) {
+			t.Fatalf("Expected a throttled password check got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should share the backoff with sign ins", func(t *testing.T) {
+		body := `{"username":"username1","password":"password"}`
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.SignIn).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected a throttled sign in got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+}
+
+func TestHandler_SignInLockout(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Failures are not delayed so the lockout threshold is reached right away
+	cfg.Login.BaseDelay = 0
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := db.User.Insert(database.User{
+		Username: "lockout1",
+		Password: string(hash),
+		Email:    "lockout1@test.gr",
+		Active:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"username":"lockout1","password":%q}`, password)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.SignIn).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rr
+	}
+
+	// admin calls hf as the admin, the target is injected as url parameter
+	admin := func(hf http.HandlerFunc, method string, target string, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, handler.Token{UserID: 1, Username: "username1"}))
+
+		rr := httptest.NewRecorder()
+		hf.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("Should lock users that reach the threshold", func(t *testing.T) {
+		for i := 0; i < cfg.Login.LockoutThreshold; i++ {
+			if rr := signIn("wrong"); rr.Code != http.StatusUnauthorized {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusUnauthorized, rr.Body.String())
+			}
+		}
+
+		rr := signIn("password")
+		if rr.Code != http.StatusLocked || !strings.Contains(rr.Body.String(), handler.CodeAccountLocked) {
+			t.Fatalf("Expected a locked account got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should list locked users", func(t *testing.T) {
+		rr := admin(h.Users, http.MethodGet, "/?locked=true", "")
+
+		resp := handler.UsersResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if rr.Code != http.StatusOK || len(resp.Data) != 1 {
+			t.Fatalf("Expected a single locked user got %d, %s", rr.Code, rr.Body.String())
+		}
+		if resp.Data[0].ID != userID || resp.Data[0].LockedUntil == "" {
+			t.Fatalf("Unexpected user %+v", resp.Data[0])
+		}
+	})
+
+	t.Run("Should let admins unlock users", func(t *testing.T) {
+		rr := admin(h.UserUnlock, http.MethodPost, "/", fmt.Sprint(userID))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		resp := handler.UserProfileResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.LockedUntil != "" {
+			t.Fatalf("Expected an unlocked user got %+v", resp)
+		}
+
+		if rr := signIn("password"); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+	})
+
+	t.Run("Should unlock users that are not locked", func(t *testing.T) {
+		if rr := admin(h.UserUnlock, http.MethodPost, "/", fmt.Sprint(userID)); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/georlav/recipeapi/internal/i18n"
+	"github.com/go-playground/validator/v10"
+)
+
+// fallbackLocale is used for messages that are missing from the catalogs of the requested locales
+const fallbackLocale = "en"
+
+// messages holds the catalogs of api error messages, keys are error codes and validation keys
+var messages = i18n.Catalog{
+	"en": {
+		CodeInternal:                "internal server error",
+		CodeBadRequest:              "the request could not be decoded",
+		CodeValidation:              "the request has invalid fields",
+		CodeAuthRequired:            "authentication required",
+		CodeInvalidToken:            "invalid token",
+		CodeInvalidShareLink:        "the share link is invalid, has expired or was revoked",
+		CodeInvalidRefreshToken:     "the refresh token is invalid, has expired or was revoked",
+		CodeRefreshTokenReused:      "the refresh token was already used, sign in again",
+		CodeInvalidCredentials:      "You have entered an invalid username or password",
+		CodeLoginThrottled:          "too many failed sign ins, try again in %d seconds",
+		CodeAccountLocked:           "the account is locked after too many failed sign ins, try again later",
+		CodeUsernameTaken:           "Username is taken",
+		CodeEmailTaken:              "the email address is used by another account",
+		CodeWrongPassword:           "the current password is wrong",
+		CodeRateLimited:             "rate limit exceeded",
+		CodePermissionRequired:      "your role does not allow this action",
+		CodeUserInactive:            "the account is deactivated",
+		CodeEmailUnverified:         "verify your email address to activate the account",
+		CodeInvalidVerification:     "the verification link is invalid, has expired or was already used",
+		CodeInvalidResetToken:       "the password reset link is invalid, has expired or was already used",
+		CodeInvalidAPIKey:           "the api key is invalid, has expired or was revoked",
+		CodeAPIKeyNotAllowed:        "api keys can not be used for this request, sign in instead",
+		CodeInsufficientScope:       "the api key lacks the %s scope",
+		CodeInvalidLoginState:       "the sign in is invalid, has expired or was already completed, start it again",
+		CodeProviderSignInFailed:    "signing in with the identity provider failed",
+		CodeProviderEmailUnverified: "the identity provider has not verified your email address",
+		CodeAccountUnverified:       "an unverified account uses your email address, verify it first",
+		CodeOwnAccount:              "you can not change the role or status of your own account",
+		CodeAuthorRequired:          "only the author can manage a recipe",
+		CodeIDRequired:              "id is required.",
+		CodeRecipeIDRequired:        "recipe id is required.",
+		CodeUserIDRequired:          "user id is required.",
+		CodeIngredientIDRequired:    "ingredient id is required.",
+		CodeShareIDRequired:         "share id is required.",
+		CodeAPIKeyIDRequired:        "api key id is required.",
+		CodeUnknownRecipe:           "unknown recipe",
+		CodeUnknownDeletedRecipe:    "unknown deleted recipe",
+		CodeUnknownUser:             "unknown user",
+		CodeUnknownDeletedUser:      "unknown deleted user",
+		CodeUnknownIngredient:       "unknown ingredient",
+		CodeUnknownRecipeIngredient: "unknown recipe ingredient",
+		CodeUnknownSubstitution:     "unknown substitution",
+		CodeUnknownTaxonomyNode:     "unknown taxonomy node",
+		CodeUnknownParent:           "unknown parent",
+		CodeUnknownShare:            "unknown share",
+		CodeUnknownAPIKey:           "unknown api key",
+		CodeUnknownProvider:         "unknown identity provider",
+		CodeUnknownTranslation:      "unknown translation",
+		CodeUnknownDiet:             "unknown diet",
+		CodeUnknownDietOverride:     "recipe has no override for this diet",
+		CodeTaxonomyNodeExists:      "taxonomy node already exists",
+		CodeTaxonomyNodeCycle:       "a node can not be moved under itself",
+		CodeRecipeTitleExists:       "recipe title already exists",
+		CodeRecipeDuplicate:         "recipe looks like a duplicate, pass force=true to create it anyway",
+		CodeRecipeLocale:            "recipe is written in %s",
+		CodeUnsupportedLocale:       "unsupported locale",
+		CodeStatusTransition:        "recipe can not be moved to %s from its current status",
+		CodeCreateRecipeFailed:      "failed to create recipe",
+		CodeSaveTranslationFailed:   "failed to save translation",
+
+		"validation_required":   "%s is required",
+		"validation_min":        "%s must be at least %s",
+		"validation_min_length": "%s must be at least %s characters long",
+		"validation_min_items":  "%s must have at least %s items",
+		"validation_max":        "%s must be at most %s",
+		"validation_max_length": "%s must be at most %s characters long",
+		"validation_max_items":  "%s must have at most %s items",
+		"validation_email":      "%s must be a valid email address",
+		"validation_eqfield":    "%s must match %s",
+		"validation_oneof":      "%s must be one of %s",
+		"validation_invalid":    "%s is invalid",
+	},
+	"el": {
+		CodeInternal:                "εσωτερικό σφάλμα διακομιστή",
+		CodeBadRequest:              "το αίτημα δεν ήταν δυνατό να αποκωδικοποιηθεί",
+		CodeValidation:              "το αίτημα έχει μη έγκυρα πεδία",
+		CodeAuthRequired:            "απαιτείται σύνδεση",
+		CodeInvalidToken:            "μη έγκυρο διακριτικό",
+		CodeInvalidShareLink:        "ο σύνδεσμος κοινοποίησης δεν είναι έγκυρος, έχει λήξει ή έχει ανακληθεί",
+		CodeInvalidRefreshToken:     "το διακριτικό ανανέωσης δεν είναι έγκυρο, έχει λήξει ή έχει ανακληθεί",
+		CodeRefreshTokenReused:      "το διακριτικό ανανέωσης έχει ήδη χρησιμοποιηθεί, συνδεθείτε ξανά",
+		CodeInvalidCredentials:      "Εισαγάγατε λάθος όνομα χρήστη ή κωδικό πρόσβασης",
+		CodeLoginThrottled:          "πάρα πολλές αποτυχημένες συνδέσεις, δοκιμάστε ξανά σε %d δευτερόλεπτα",
+		CodeAccountLocked:           "ο λογαριασμός κλειδώθηκε μετά από πολλές αποτυχημένες συνδέσεις, δοκιμάστε ξανά αργότερα",
+		CodeUsernameTaken:           "Το όνομα χρήστη χρησιμοποιείται ήδη",
+		CodeEmailTaken:              "η διεύθυνση email χρησιμοποιείται από άλλο λογαριασμό",
+		CodeWrongPassword:           "ο τρέχων κωδικός πρόσβασης είναι λάθος",
+		CodeRateLimited:             "έγινε υπέρβαση του ορίου αιτημάτων",
+		CodePermissionRequired:      "ο ρόλος σας δεν επιτρέπει αυτή την ενέργεια",
+		CodeUserInactive:            "ο λογαριασμός είναι απενεργοποιημένος",
+		CodeEmailUnverified:         "επιβεβαιώστε τη διεύθυνση email σας για να ενεργοποιηθεί ο λογαριασμός",
+		CodeInvalidVerification:     "ο σύνδεσμος επιβεβαίωσης δεν είναι έγκυρος, έχει λήξει ή έχει ήδη χρησιμοποιηθεί",
+		CodeInvalidResetToken:       "ο σύνδεσμος επαναφοράς κωδικού δεν είναι έγκυρος, έχει λήξει ή έχει ήδη χρησιμοποιηθεί",
+		CodeInvalidAPIKey:           "το κλειδί api δεν είναι έγκυρο, έχει λήξει ή έχει ανακληθεί",
+		CodeAPIKeyNotAllowed:        "τα κλειδιά api δεν μπορούν να χρησιμοποιηθούν για αυτό το αίτημα, συνδεθείτε",
+		CodeInsufficientScope:       "το κλειδί api δεν έχει το δικαίωμα %s",
+		CodeInvalidLoginState:       "η σύνδεση δεν είναι έγκυρη, έχει λήξει ή έχει ήδη ολοκληρωθεί, ξεκινήστε την ξανά",
+		CodeProviderSignInFailed:    "η σύνδεση μέσω του παρόχου ταυτότητας απέτυχε",
+		CodeProviderEmailUnverified: "ο πάροχος ταυτότητας δεν έχει επιβεβαιώσει τη διεύθυνση email σας",
+		CodeAccountUnverified:       "ένας μη επιβεβαιωμένος λογαριασμός έχει τη διεύθυνση email σας, επιβεβαιώστε τον πρώτα",
+		CodeOwnAccount:              "δεν μπορείτε να αλλάξετε τον ρόλο ή την κατάσταση του δικού σας λογαριασμού",
+		CodeAuthorRequired:          "μόνο ο συντάκτης μπορεί να διαχειριστεί μια συνταγή",
+		CodeIDRequired:              "το id είναι υποχρεωτικό.",
+		CodeRecipeIDRequired:        "το id της συνταγής είναι υποχρεωτικό.",
+		CodeUserIDRequired:          "το id του χρήστη είναι υποχρεωτικό.",
+		CodeIngredientIDRequired:    "το id του υλικού είναι υποχρεωτικό.",
+		CodeShareIDRequired:         "το id της κοινοποίησης είναι υποχρεωτικό.",
+		CodeAPIKeyIDRequired:        "το id του κλειδιού api είναι υποχρεωτικό.",
+		CodeUnknownRecipe:           "άγνωστη συνταγή",
+		CodeUnknownDeletedRecipe:    "άγνωστη διαγραμμένη συνταγή",
+		CodeUnknownUser:             "άγνωστος χρήστης",
+		CodeUnknownDeletedUser:      "άγνωστος διαγραμμένος χρήστης",
+		CodeUnknownIngredient:       "άγνωστο υλικό",
+		CodeUnknownRecipeIngredient: "άγνωστο υλικό συνταγής",
+		CodeUnknownSubstitution:     "άγνωστη υποκατάσταση",
+		CodeUnknownTaxonomyNode:     "άγνωστος κόμβος ταξινομίας",
+		CodeUnknownParent:           "άγνωστος γονικός κόμβος",
+		CodeUnknownShare:            "άγνωστη κοινοποίηση",
+		CodeUnknownAPIKey:           "άγνωστο κλειδί api",
+		CodeUnknownProvider:         "άγνωστος πάροχος ταυτότητας",
+		CodeUnknownTranslation:      "άγνωστη μετάφραση",
+		CodeUnknownDiet:             "άγνωστη διατροφή",
+		CodeUnknownDietOverride:     "η συνταγή δεν έχει παράκαμψη για αυτή τη διατροφή",
+		CodeTaxonomyNodeExists:      "ο κόμβος ταξινομίας υπάρχει ήδη",
+		CodeTaxonomyNodeCycle:       "ένας κόμβος δεν μπορεί να μετακινηθεί κάτω από τον εαυτό του",
+		CodeRecipeTitleExists:       "υπάρχει ήδη συνταγή με αυτόν τον τίτλο",
+		CodeRecipeDuplicate:         "η συνταγή μοιάζει με διπλότυπο, στείλτε force=true για να δημιουργηθεί παρ' όλα αυτά",
+		CodeRecipeLocale:            "η συνταγή είναι γραμμένη στη γλώσσα %s",
+		CodeUnsupportedLocale:       "μη υποστηριζόμενη γλώσσα",
+		CodeStatusTransition:        "η συνταγή δεν μπορεί να μεταβεί στην κατάσταση %s από την τρέχουσα κατάστασή της",
+		CodeCreateRecipeFailed:      "η δημιουργία της συνταγής απέτυχε",
+		CodeSaveTranslationFailed:   "η αποθήκευση της μετάφρασης απέτυχε",
+
+		"validation_required":   "το πεδίο %s είναι υποχρεωτικό",
+		"validation_min":        "το πεδίο %s πρέπει να είναι τουλάχιστον %s",
+		"validation_min_length": "το πεδίο %s πρέπει να έχει τουλάχιστον %s χαρακτήρες",
+		"validation_min_items":  "το πεδίο %s πρέπει να έχει τουλάχιστον %s στοιχεία",
+		"validation_max":        "το πεδίο %s πρέπει να είναι το πολύ %s",
+		"validation_max_length": "το πεδίο %s πρέπει να έχει το πολύ %s χαρακτήρες",
+		"validation_max_items":  "το πεδίο %s πρέπει να έχει το πολύ %s στοιχεία",
+		"validation_email":      "το πεδίο %s πρέπει να είναι έγκυρη διεύθυνση email",
+		"validation_eqfield":    "το πεδίο %s πρέπει να ταιριάζει με το %s",
+		"validation_oneof":      "το πεδίο %s πρέπει να είναι ένα από τα %s",
+		"validation_invalid":    "το πεδίο %s δεν είναι έγκυρο",
+	},
+}
+
+// fieldMessage returns the localized message of a failed validation, min and max are worded by the kind of field
+func fieldMessage(chain []string, fe validator.FieldError) string {
+	key := "validation_" + fe.Tag()
+	switch fe.Tag() {
+	case "required", "email":
+		return messages.Message(chain, key, fe.Field())
+	case "min", "max":
+		switch fe.Kind() {
+		case reflect.String:
+			key += "_length"
+		case reflect.Slice, reflect.Map, reflect.Array:
+			key += "_items"
+		}
+		return messages.Message(chain, key, fe.Field(), fe.Param())
+	case "eqfield", "oneof":
+		return messages.Message(chain, key, fe.Field(), fe.Param())
+	}
+
+	return messages.Message(chain, "validation_invalid", fe.Field())
+}
+
+// fieldName names fields in validation errors after their json, query or yaml key so messages refer to the names
+// clients send
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "schema", "yaml"} {
+		if name := strings.Split(f.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return f.Name
+}
@@ -3,8 +3,12 @@ package handler
 import (
 	"context"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
@@ -24,33 +28,125 @@ func (h Handler) CorsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Cross Origin Resource Sharing
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, X-API-Key, If-None-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Retry-After, Content-Disposition")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
 		next.ServeHTTP(w, r)
 	})
 }
 
-// Authorization middleware assign to all routes that require users to be signed in
+// Authorization middleware assign to all routes that require users to be signed in. Reading a single recipe is also
+// allowed with a share link token passed as share query parameter instead of a bearer token.
 func (h Handler) AuthorizationMiddleware(next http.Handler) http.Handler {
+	return h.authorize(next, false)
+}
+
+// OptionalAuthorizationMiddleware assign to routes that are open to anonymous callers, requests without a token
+// proceed as anonymous while requests with an invalid token are still rejected
+func (h Handler) OptionalAuthorizationMiddleware(next http.Handler) http.Handler {
+	return h.authorize(next, true)
+}
+
+// APIKeyScopes returns a middleware that opens routes to API keys, reading requests need the read scope and all
+// others the write scope. Assign before AuthorizationMiddleware or OptionalAuthorizationMiddleware, routes without it
+// only accept access tokens.
+func (h Handler) APIKeyScopes(read string, write string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ctxKeyScopes, keyScopes{read: read, write: write})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission returns a middleware that lets through callers whose role grants all of perms, assign after
+// AuthorizationMiddleware
+func (h Handler) RequirePermission(perms ...Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := h.caller(r)
+			if token == nil {
+				h.respondError(w, r, errAuthRequired)
+				return
+			}
+
+			if !h.can(token, perms...) {
+				h.respondError(w, r, APIError{Code: CodePermissionRequired, StatusCode: http.StatusForbidden})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorize validates the bearer token, API key or share link token of a request and stores the caller in the
+// request context, optional lets requests without any token through
+func (h Handler) authorize(next http.Handler, optional bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auth := r.Header.Get("Authorization")
+		if auth == "" && r.Method == http.MethodGet && r.URL.Query().Get("share") != "" {
+			share, err := h.shareFromRequest(r)
+			if err != nil {
+				h.respondError(w, r, APIError{Code: CodeInvalidShareLink, StatusCode: http.StatusUnauthorized})
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), CtxKeyShare, *share)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if key := r.Header.Get(headerAPIKey); auth == "" && key != "" {
+			tr, ok := h.keyCaller(w, r, key)
+			if !ok {
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), CtxKeyToken, *tr)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if auth == "" && optional {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		tokenString := strings.TrimPrefix(auth, "Bearer ")
 
 		tr := Token{}
-		token, err := jwt.ParseWithClaims(tokenString, &tr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method")
-			}
+		token, err := jwt.ParseWithClaims(tokenString, &tr, h.keys.Keyfunc)
+		// Share link tokens are signed with the same keys but carry no user
+		if err != nil || !token.Valid || tr.UserID <= 0 {
+			h.respondError(w, r, APIError{Code: CodeInvalidToken, StatusCode: http.StatusUnauthorized})
+			return
+		}
 
-			return []byte(h.cfg.Token.Secret), nil
-		})
-		if err != nil || !token.Valid {
-			h.respondError(w, APIError{
-				Message:    "invalid token",
-				StatusCode: http.StatusUnauthorized,
-			})
+		// Every access token carries a jti, tokens without one could never be revoked
+		if tr.Id == "" {
+			h.respondError(w, r, APIError{Code: CodeInvalidToken, StatusCode: http.StatusUnauthorized})
+			return
+		}
+		revoked, err := h.isRevoked(tr.Id)
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		if revoked {
+			h.respondError(w, r, APIError{Code: CodeInvalidToken, StatusCode: http.StatusUnauthorized})
+			return
+		}
+
+		inactive, err := h.isInactive(tr.UserID)
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		if inactive {
+			h.respondError(w, r, APIError{Code: CodeUserInactive, StatusCode: http.StatusForbidden})
 			return
 		}
 
@@ -58,3 +154,32 @@ func (h Handler) AuthorizationMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RateLimitMiddleware limits the number of requests of each caller, anonymous callers are counted by ip address and
+// get a stricter limit than signed in users. Assign after AuthorizationMiddleware or OptionalAuthorizationMiddleware.
+func (h Handler) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter, key := h.anonymousLimit, "ip:"+clientIP(r)
+		if token := h.caller(r); token != nil {
+			limiter, key = h.userLimit, fmt.Sprintf("user:%d", token.UserID)
+		}
+
+		if ok, retry := limiter.Allow(key, time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			h.respondError(w, r, APIError{Code: CodeRateLimited, StatusCode: http.StatusTooManyRequests})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the ip address of the client that sent the request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
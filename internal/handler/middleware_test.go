@@ -1,6 +1,7 @@
 package handler_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
 	"github.com/georlav/recipeapi/internal/handler"
 	"github.com/georlav/recipeapi/internal/logger"
 )
@@ -22,6 +24,7 @@ func TestHandler_Authorization(t *testing.T) {
 	token1 := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"uname": "user1",
 		"uid":   1,
+		"jti":   "authorization-token-1",
 		"iat":   time.Now().Unix(),
 		"exp":   time.Now().Add(time.Duration(5) * time.Minute).Unix(),
 	})
@@ -33,6 +36,7 @@ func TestHandler_Authorization(t *testing.T) {
 	token2 := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"uname": "user1",
 		"uid":   1,
+		"jti":   "authorization-token-2",
 		"iat":   time.Now().Unix(),
 		"exp":   time.Now().Add(time.Duration(-60) * time.Minute).Unix(),
 	})
@@ -41,6 +45,52 @@ func TestHandler_Authorization(t *testing.T) {
 		t.Fatal("signature error, %w", err)
 	}
 
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unverified users are inactive, their tokens are rejected
+	inactiveID, err := db.User.Insert(database.User{Username: "inactive1", Password: "password", Email: "inactive1@test.gr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token4 := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uname": "inactive1",
+		"uid":   inactiveID,
+		"jti":   "authorization-token-4",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Duration(5) * time.Minute).Unix(),
+	})
+	tokenSigned4, err := token4.SignedString([]byte(cfg.Token.Secret))
+	if err != nil {
+		t.Fatal("signature error, %w", err)
+	}
+
+	// Share link tokens are signed with the same secret but must not sign users in
+	token3 := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sid": 1,
+		"rid": 1,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Duration(5) * time.Minute).Unix(),
+	})
+	tokenSigned3, err := token3.SignedString([]byte(cfg.Token.Secret))
+	if err != nil {
+		t.Fatal("signature error, %w", err)
+	}
+
+	// Tokens without a jti could never be revoked
+	token5 := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uname": "user1",
+		"uid":   1,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Duration(5) * time.Minute).Unix(),
+	})
+	tokenSigned5, err := token5.SignedString([]byte(cfg.Token.Secret))
+	if err != nil {
+		t.Fatal("signature error, %w", err)
+	}
+
 	testCases := []struct {
 		desc         string
 		token        string
@@ -61,9 +111,24 @@ func TestHandler_Authorization(t *testing.T) {
 			tokenSigned2,
 			http.StatusUnauthorized,
 		},
+		{
+			"Share link token should not be valid",
+			tokenSigned3,
+			http.StatusUnauthorized,
+		},
+		{
+			"Token of an inactive user should not be valid",
+			tokenSigned4,
+			http.StatusForbidden,
+		},
+		{
+			"Token without a jti should not be valid",
+			tokenSigned5,
+			http.StatusUnauthorized,
+		},
 	}
 
-	h := handler.NewHandler(nil, cfg, logger.NewLogger(cfg.Logger))
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
 
 	for i := range testCases {
 		tc := testCases[i]
@@ -120,7 +185,172 @@ func TestHandler_CorsMiddleware(t *testing.T) {
 	if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
 		t.Fatal("Invalid origin")
 	}
-	if rr.Header().Get("Access-Control-Allow-Methods") != "GET, POST, PUT, DELETE, OPTIONS" {
+	if rr.Header().Get("Access-Control-Allow-Methods") != "GET, POST, PUT, PATCH, DELETE, OPTIONS" {
 		t.Fatal("Invalid content type")
 	}
 }
+
+func TestHandler_RequirePermission(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		perms        []handler.Permission
+		token        *handler.Token
+		expectedCode int
+	}{
+		{"Should allow roles that grant the permission", []handler.Permission{handler.PermManageContent},
+			&handler.Token{UserID: 1, Username: "user1", Role: "editor"}, http.StatusNoContent},
+		{"Should forbid roles that do not grant the permission", []handler.Permission{handler.PermModerateRecipes},
+			&handler.Token{UserID: 1, Username: "user1", Role: "editor"}, http.StatusForbidden},
+		{"Should require all permissions", []handler.Permission{handler.PermManageContent, handler.PermManageUsers},
+			&handler.Token{UserID: 1, Username: "user1", Role: "editor"}, http.StatusForbidden},
+		{"Should allow admins everything", []handler.Permission{handler.PermManageContent, handler.PermManageUsers},
+			&handler.Token{UserID: 1, Username: "user1", Role: "admin"}, http.StatusNoContent},
+		{"Should forbid users", []handler.Permission{handler.PermModerateRecipes},
+			&handler.Token{UserID: 2, Username: "user2", Role: "user"}, http.StatusForbidden},
+		{"Should forbid tokens without a role", []handler.Permission{handler.PermModerateRecipes},
+			&handler.Token{UserID: 2, Username: "user2"}, http.StatusForbidden},
+		{"Should allow configured admin users", []handler.Permission{handler.PermManageUsers},
+			&handler.Token{UserID: 3, Username: "admin"}, http.StatusNoContent},
+		{"Should not grant configured roles by username", []handler.Permission{handler.PermManageUsers},
+			&handler.Token{UserID: 5, Username: "admin"}, http.StatusForbidden},
+		{"Should allow configured moderators to moderate", []handler.Permission{handler.PermModerateRecipes},
+			&handler.Token{UserID: 4, Username: "moderator", Role: "user"}, http.StatusNoContent},
+		{"Should forbid configured moderators to manage users", []handler.Permission{handler.PermManageUsers},
+			&handler.Token{UserID: 4, Username: "moderator"}, http.StatusForbidden},
+		{"Should reject requests without a token", []handler.Permission{handler.PermModerateRecipes}, nil,
+			http.StatusUnauthorized},
+	}
+
+	cfg := &config.Config{Admin: config.Admin{Users: []int64{3}, Moderators: []int64{4}}}
+	h := handler.NewHandler(nil, cfg, logger.NewLogger(cfg.Logger))
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.token != nil {
+				req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, *tc.token))
+			}
+			rr := httptest.NewRecorder()
+
+			h.RequirePermission(tc.perms...)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNoContent)
+				}),
+			).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_OptionalAuthorizationMiddleware(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uname": "user1",
+		"uid":   1,
+		"jti":   "optional-authorization-token",
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+	})
+	tokenSigned, err := token.SignedString([]byte(cfg.Token.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc         string
+		auth         string
+		expectedCode int
+		expectedUser string
+	}{
+		{"Should let anonymous requests through", "", http.StatusOK, ""},
+		{"Should identify signed in users", "Bearer " + tokenSigned, http.StatusOK, "user1"},
+		{"Should reject invalid tokens", "Bearer xxx.yyyy.zzzz", http.StatusUnauthorized, ""},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rr := httptest.NewRecorder()
+
+			h.OptionalAuthorizationMiddleware(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					token, _ := r.Context().Value(handler.CtxKeyToken).(handler.Token)
+					_, _ = w.Write([]byte(token.Username))
+				}),
+			).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+			if tc.expectedCode == http.StatusOK && rr.Body.String() != tc.expectedUser {
+				t.Fatalf("Expected caller %q got %q", tc.expectedUser, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_RateLimitMiddleware(t *testing.T) {
+	cfg := &config.Config{RateLimit: config.RateLimit{Window: 3600, Anonymous: 1, Authenticated: 2}}
+	h := handler.NewHandler(nil, cfg, logger.NewLogger(cfg.Logger))
+
+	user := &handler.Token{UserID: 1, Username: "user1"}
+	testCases := []struct {
+		desc         string
+		remoteAddr   string
+		token        *handler.Token
+		expectedCode int
+	}{
+		{"Should allow the first anonymous request", "10.0.0.1:1234", nil, http.StatusNoContent},
+		{"Should limit anonymous requests", "10.0.0.1:4321", nil, http.StatusTooManyRequests},
+		{"Should count each address separately", "10.0.0.2:1234", nil, http.StatusNoContent},
+		{"Should allow signed in users more requests", "10.0.0.1:1234", user, http.StatusNoContent},
+		{"Should allow signed in users more requests again", "10.0.0.1:1234", user, http.StatusNoContent},
+		{"Should limit signed in users", "10.0.0.1:1234", user, http.StatusTooManyRequests},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.token != nil {
+				req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, *tc.token))
+			}
+			rr := httptest.NewRecorder()
+
+			h.RateLimitMiddleware(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNoContent)
+				}),
+			).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+			if tc.expectedCode == http.StatusTooManyRequests && rr.Header().Get("Retry-After") == "" {
+				t.Fatal("Expected a Retry-After header")
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// RecipeSubmit godoc
+// @Summary Submit a recipe for review
+// @Description Submit a draft recipe to the moderation queue, only the recipe author can submit it
+// @ID post-recipe-submit
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/submit [post]
+func (h *Handler) RecipeSubmit(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	recipe, err := h.db.Recipe.Get(id, h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	if recipe.UserID != token.UserID {
+		h.respondError(w, r, APIError{Code: CodeAuthorRequired, StatusCode: http.StatusForbidden})
+		return
+	}
+
+	h.transitionRecipe(w, r, id, database.RecipePending, "")
+}
+
+// RecipeReopen godoc
+// @Summary Reopen an archived recipe
+// @Description Move an archived recipe back to draft so it can be edited and submitted for review again, only the
+// @Description recipe author can reopen it
+// @ID post-recipe-reopen
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/reopen [post]
+func (h *Handler) RecipeReopen(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	recipe, err := h.db.Recipe.Get(id, h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	if recipe.UserID != token.UserID {
+		h.respondError(w, r, APIError{Code: CodeAuthorRequired, StatusCode: http.StatusForbidden})
+		return
+	}
+
+	// Only archived recipes are reopened, drafts that wait for a resubmission are left alone
+	if recipe.Status != database.RecipeArchived {
+		h.respondError(w, r, NewAPIError(CodeStatusTransition, http.StatusConflict, database.RecipeDraft))
+		return
+	}
+
+	h.transitionRecipe(w, r, id, database.RecipeDraft, "", database.RecipeArchived)
+}
+
+// ModerationQueue godoc
+// @Summary Get the moderation queue
+// @Description Get recipes that wait for review, oldest submissions first
+// @ID get-moderation-recipes
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param page query int false "Page number"
+// @Success 200 {object} handler.RecipesResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /moderation/recipes [get]
+func (h *Handler) ModerationQueue(w http.ResponseWriter, r *http.Request) {
+	h.respondRecipePage(w, r, h.db.Recipe.Queue)
+}
+
+// RecipeApprove godoc
+// @Summary Approve a recipe
+// @Description Publish a recipe that waits for review
+// @ID post-moderation-recipe-approve
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /moderation/recipes/{id}/approve [post]
+func (h *Handler) RecipeApprove(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	h.transitionRecipe(w, r, id, database.RecipePublished, "")
+}
+
+// RecipeReject godoc
+// @Summary Reject a recipe
+// @Description Send a recipe that waits for review back to its author as a draft, the reason is shown to the author
+// @ID post-moderation-recipe-reject
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param body body handler.RecipeRejectRequest true "rejection reason"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /moderation/recipes/{id}/reject [post]
+func (h *Handler) RecipeReject(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	rr := RecipeRejectRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&rr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(rr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	// Only recipes waiting in the queue are rejected, archived recipes are reopened by their author
+	h.transitionRecipe(w, r, id, database.RecipeDraft, rr.Reason, database.RecipePending)
+}
+
+// RecipeUnpublish godoc
+// @Summary Unpublish a recipe
+// @Description Archive a published recipe, archived recipes are only visible to their author and to moderators
+// @ID post-moderation-recipe-unpublish
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /moderation/recipes/{id}/unpublish [post]
+func (h *Handler) RecipeUnpublish(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	h.transitionRecipe(w, r, id, database.RecipeArchived, "")
+}
+
+// transitionRecipe moves a recipe to status, from one of the from statuses when given, and responds with the updated
+// recipe
+func (h *Handler) transitionRecipe(w http.ResponseWriter, r *http.Request, id uint64, status, note string, from ...string) {
+	err := h.db.Recipe.Transition(id, status, note, from...)
+	switch {
+	case errors.Is(err, database.ErrNoRows):
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return
+	case errors.Is(err, database.ErrStatusTransition):
+		h.respondError(w, r, NewAPIError(CodeStatusTransition, http.StatusConflict, status))
+		return
+	case err != nil:
+		h.respondError(w, r, err)
+		return
+	}
+	h.cache.Delete(cacheKeyIngredientNames)
+
+	recipe, err := h.db.Recipe.Get(id, database.Viewer{Moderator: true})
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := RecipeResponseItem{}
+	if err := EncodeEntity(recipe, &resp); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
@@ -0,0 +1,161 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+func TestHandler_RecipeModeration(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	author := handler.Token{UserID: 1, Username: "username1"}
+	reader := handler.Token{UserID: 2, Username: "username2"}
+	moderator := handler.Token{UserID: 3, Username: "moderator1"}
+
+	// serve calls hf as token, id is injected as url parameter
+	serve := func(hf http.HandlerFunc, token handler.Token, id string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, token))
+
+		rr := httptest.NewRecorder()
+		hf.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := serve(h.Create, author, "", `{"title":"Moderated Tzatziki","url":"http://example.com/moderated-tzatziki",
+"ingredients":["greek yogurt","cucumber","dill"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	id := path.Base(rr.Header().Get("Location"))
+
+	testData := []struct {
+		desc         string
+		handler      http.HandlerFunc
+		token        handler.Token
+		body         string
+		expectedCode int
+		status       string
+	}{
+		{"Should show a draft to its author", h.Recipe, author, "", http.StatusOK, database.RecipeDraft},
+		{"Should hide a draft from other users", h.Recipe, reader, "", http.StatusNotFound, ""},
+		{"Should hide a draft from moderators outside the queue", h.Recipe, moderator, "", http.StatusNotFound, ""},
+		{"Should not reopen a draft", h.RecipeReopen, author, "", http.StatusConflict, ""},
+		{"Should not approve a draft", h.RecipeApprove, moderator, "", http.StatusConflict, ""},
+		{"Should not let other users submit a draft", h.RecipeSubmit, reader, "", http.StatusNotFound, ""},
+		{"Should submit a draft", h.RecipeSubmit, author, "", http.StatusOK, database.RecipePending},
+		{"Should not reject without a reason", h.RecipeReject, moderator, `{}`, http.StatusBadRequest, ""},
+		{"Should reject a pending recipe", h.RecipeReject, moderator, `{"reason":"add a photo"}`, http.StatusOK, database.RecipeDraft},
+		{"Should resubmit a rejected recipe", h.RecipeSubmit, author, "", http.StatusOK, database.RecipePending},
+		{"Should approve a pending recipe", h.RecipeApprove, moderator, "", http.StatusOK, database.RecipePublished},
+		{"Should show a published recipe to other users", h.Recipe, reader, "", http.StatusOK, database.RecipePublished},
+		{"Should unpublish a published recipe", h.RecipeUnpublish, moderator, "", http.StatusOK, database.RecipeArchived},
+		{"Should hide an archived recipe from other users", h.Recipe, reader, "", http.StatusNotFound, ""},
+		{"Should not reject an archived recipe", h.RecipeReject, moderator, `{"reason":"add a photo"}`, http.StatusConflict, ""},
+		{"Should not let other users reopen an archived recipe", h.RecipeReopen, reader, "", http.StatusNotFound, ""},
+		{"Should reopen an archived recipe", h.RecipeReopen, author, "", http.StatusOK, database.RecipeDraft},
+		{"Should resubmit a reopened recipe", h.RecipeSubmit, author, "", http.StatusOK, database.RecipePending},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			rr := serve(tc.handler, tc.token, id, tc.body)
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+			if tc.status == "" {
+				return
+			}
+
+			resp := handler.RecipeResponseItem{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tc.status {
+				t.Fatalf("Expected status %s got %s", tc.status, resp.Status)
+			}
+			if fmt.Sprint(resp.ID) != id {
+				t.Fatalf("Expected recipe %s got %d", id, resp.ID)
+			}
+		})
+	}
+}
+
+func TestHandler_ModerationQueue(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	id, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Queued Flatbread",
+		URL:         "http://example.com/queued-flatbread",
+		Ingredients: database.Ingredients{{Name: "flour"}, {Name: "water"}, {Name: "yeast"}},
+		UserID:      1,
+		Status:      database.RecipePending,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/moderation/recipes", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.ModerationQueue).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	resp := handler.RecipesResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data == nil {
+		t.Fatal("Expected to have results")
+	}
+	for _, r := range *resp.Data {
+		if r.Status != database.RecipePending {
+			t.Fatalf("Expected only pending recipes got %s", r.Status)
+		}
+		if r.ID == id {
+			return
+		}
+	}
+
+	t.Fatalf("Expected recipe %d to be queued, %s", id, rr.Body.String())
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// RecipeNutrition godoc
+// @Summary Recipe nutrition
+// @Description Get the nutrition of a recipe computed from the quantities of its ingredients, in total and per
+// @Description serving. Facts come from the nutrient database, ingredients without facts of their own use the facts
+// @Description of their closest taxonomy ancestor. Ingredients that could not be matched are listed in unmatched and
+// @Description the ones without a weighable quantity in unquantified, the totals leave both out.
+// @ID get-recipe-nutrition
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.RecipeNutritionResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/nutrition [get]
+func (h *Handler) RecipeNutrition(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	recipe, err := h.db.Recipe.Get(id, h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	tree, err := h.taxonomyTree()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	index, err := h.db.Nutrient.Index()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	lines := make([]string, len(recipe.Ingredients))
+	for i := range recipe.Ingredients {
+		lines[i] = recipe.Ingredients[i].Name
+	}
+	nutrition := index.Recipe(lines, recipe.Servings, tree)
+
+	resp := RecipeNutritionResponse{
+		Data:         []RecipeNutritionResponseItem{},
+		Servings:     recipe.Servings,
+		Complete:     nutrition.Complete,
+		Unmatched:    []string{},
+		Unquantified: []string{},
+	}
+	if err := EncodeEntity(nutrition.Total, &resp.Total); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	if resp.PerServing, err = nutrientResponse(nutrition.PerServing); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	for _, in := range nutrition.Ingredients {
+		item := RecipeNutritionResponseItem{Ingredient: in.Line, Matched: in.Per100g != nil}
+		if err := EncodeEntity(in.Quantity, &item.Quantity); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		if item.Per100g, err = nutrientResponse(in.Per100g); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		if item.Total, err = nutrientResponse(in.Total); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+
+		if in.Per100g == nil {
+			resp.Unmatched = append(resp.Unmatched, in.Line)
+		} else if in.Total == nil {
+			resp.Unquantified = append(resp.Unquantified, in.Line)
+		}
+		resp.Data = append(resp.Data, item)
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// Nutrients godoc
+// @Summary Get nutrients
+// @Description Get the nutrient database, the nutrition facts of 100g of each canonical ingredient
+// @ID get-nutrients
+// @Produce  json
+// @Success 200 {object} handler.NutrientsResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/nutrients [get]
+func (h *Handler) Nutrients(w http.ResponseWriter, r *http.Request) {
+	nutrients, err := h.db.Nutrient.List()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := NutrientsResponse{Data: []NutrientResponseItem{}}
+	if err := EncodeEntities(nutrients, &resp, "Data"); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// NutrientsImport godoc
+// @Summary Bulk load nutrients
+// @Description Bulk load the nutrient database from a CSV document with an ingredient, calories, protein, fat,
+// @Description carbs, fiber and sodium header, missing columns are zero. Existing facts of imported ingredients
+// @Description are replaced.
+// @ID import-nutrients
+// @Accept  text/csv
+// @Produce  json
+// @Success 200 {object} handler.NutrientsImportResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/nutrients/import [post]
+func (h *Handler) NutrientsImport(w http.ResponseWriter, r *http.Request) {
+	rows, err := decodeNutrientsCSV(r.Body)
+	if err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	ni := NutrientsImportRequest{Nutrients: rows}
+	if err := h.validate.Struct(ni); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	nutrients := make(database.Nutrients, len(ni.Nutrients))
+	for i := range ni.Nutrients {
+		nutrients[i] = database.Nutrient{
+			Ingredient: ni.Nutrients[i].Ingredient,
+			Calories:   ni.Nutrients[i].Calories,
+			Protein:    ni.Nutrients[i].Protein,
+			Fat:        ni.Nutrients[i].Fat,
+			Carbs:      ni.Nutrients[i].Carbs,
+			Fiber:      ni.Nutrients[i].Fiber,
+			Sodium:     ni.Nutrients[i].Sodium,
+		}
+	}
+
+	total, err := h.db.Nutrient.Import(nutrients)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, NutrientsImportResponse{Imported: total}, http.StatusOK)
+}
+
+// nutrientResponse maps optional nutrition facts, nil facts map to nil
+func nutrientResponse(n *database.Nutrient) (*NutrientResponseItem, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	item := NutrientResponseItem{}
+	if err := EncodeEntity(n, &item); err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// decodeNutrientsCSV reads nutrient rows from a CSV document, columns are matched by their header name
+func decodeNutrientsCSV(r io.Reader) ([]NutrientRequest, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i := range header {
+		columns[strings.ToLower(strings.TrimSpace(header[i]))] = i
+	}
+	if _, ok := columns["ingredient"]; !ok {
+		return nil, errors.New("ingredient column is missing")
+	}
+
+	var rows []NutrientRequest
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// value parses the named column, missing and empty columns are zero
+		value := func(name string) (float64, error) {
+			i, ok := columns[name]
+			if !ok || strings.TrimSpace(record[i]) == "" {
+				return 0, nil
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+			if err != nil {
+				return 0, fmt.Errorf("%s column, %w", name, err)
+			}
+			return v, nil
+		}
+
+		row := NutrientRequest{Ingredient: strings.TrimSpace(record[columns["ingredient"]])}
+		for name, field := range map[string]*float64{
+			"calories": &row.Calories,
+			"protein":  &row.Protein,
+			"fat":      &row.Fat,
+			"carbs":    &row.Carbs,
+			"fiber":    &row.Fiber,
+			"sodium":   &row.Sodium,
+		} {
+			if *field, err = value(name); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,109 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+func TestHandler_NutrientsImport(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	testData := []struct {
+		desc         string
+		body         string
+		expectedCode int
+	}{
+		{"Should require an ingredient column", "name,calories\nlentils,352\n", http.StatusBadRequest},
+		{"Should reject malformed numbers", "ingredient,calories\nlentils,many\n", http.StatusBadRequest},
+		{"Should reject negative facts", "ingredient,calories\nlentils,-1\n", http.StatusBadRequest},
+		{"Should require rows", "ingredient,calories\n", http.StatusBadRequest},
+		{"Should import nutrients", "ingredient, calories, protein, sodium\nlentils, 352, 24.63, 6\nfeta,264,14.21,\n",
+			http.StatusOK},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/nutrients/import", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.NutrientsImport).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_RecipeNutrition(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	if _, err := db.Nutrient.Import(database.Nutrients{{Ingredient: "split peas", Calories: 348, Protein: 23.8}}); err != nil {
+		t.Fatal(err)
+	}
+	id, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Nutrition Fava",
+		URL:         "http://example.com/nutrition-fava",
+		Ingredients: database.Ingredients{{Name: "500g split peas"}, {Name: "capers"}},
+		Servings:    4,
+		UserID:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/recipes/%d/nutrition", id), nil)
+	ctx := chi.NewRouteContext()
+	ctx.URLParams.Add("id", fmt.Sprint(id))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.RecipeNutrition).ServeHTTP(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	resp := handler.RecipeNutritionResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 2 || !resp.Data[0].Matched || resp.Data[0].Per100g.Calories != 348 || resp.Data[1].Matched {
+		t.Fatalf("Expected split peas to match and capers not to got %+v", resp.Data)
+	}
+	if len(resp.Unmatched) != 1 || resp.Unmatched[0] != "capers" {
+		t.Fatalf("Expected capers to be unmatched got %v", resp.Unmatched)
+	}
+	if resp.Total.Calories != 1740 || resp.PerServing == nil || resp.PerServing.Calories != 435 || resp.Complete {
+		t.Fatalf("Expected 1740 calories and 435 per serving of an incomplete recipe got %+v", resp)
+	}
+}
@@ -0,0 +1,440 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-chi/chi"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/keyring"
+)
+
+// cacheKeyProviderKeys prefixes the cached key sets of identity providers
+const cacheKeyProviderKeys = "provider_keys:"
+
+// errProviderSignIn responds to callbacks the identity provider or its ID token did not authenticate
+var errProviderSignIn = APIError{Code: CodeProviderSignInFailed, StatusCode: http.StatusUnauthorized}
+
+// usernameInvalid matches the characters that are dropped from the usernames of users created by an identity
+var usernameInvalid = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// OIDCLogin godoc
+// @Summary Sign in with an identity provider
+// @Description Redirect to the authorization endpoint of an OpenID Connect provider. The sign in uses the
+// @Description authorization code flow with PKCE and completes when the provider redirects back to OIDCCallback
+// @ID user-oidc-login
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /user/oidc/{provider}/login [get]
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.provider(r)
+	if !ok {
+		h.respondError(w, r, APIError{Code: CodeUnknownProvider, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	state, err := randomToken(32)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	nonce, err := randomToken(16)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	verifier, err := randomToken(32)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if _, err := h.db.OIDC.Insert(database.OIDCLogin{
+		Provider:  p.Name,
+		StateHash: hashToken(state),
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ExpiresAt: time.Now().Add(time.Duration(h.cfg.OIDC.StateTTL) * time.Minute),
+	}); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	link, err := url.Parse(p.AuthURL)
+	if err != nil {
+		h.respondError(w, r, fmt.Errorf("provider error, %w", err))
+		return
+	}
+
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	q := link.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", strings.Join(append([]string{"openid"}, scopes...), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	link.RawQuery = q.Encode()
+
+	http.Redirect(w, r, link.String(), http.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Complete a sign in with an identity provider
+// @Description Exchange the authorization code the provider redirected with for an ID token and sign in. The first
+// @Description sign in of an identity links it to the account with its verified email address, or creates an account
+// @Description when there is none
+// @ID user-oidc-callback
+// @Produce  json
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "Sign in state"
+// @Success 200 {object} handler.TokenResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /user/oidc/{provider}/callback [get]
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.provider(r)
+	if !ok {
+		h.respondError(w, r, APIError{Code: CodeUnknownProvider, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	// Providers add parameters of their own to the callback, only the known ones are read
+	q := r.URL.Query()
+	cr := OIDCCallbackRequest{Code: q.Get("code"), State: q.Get("state"), Error: q.Get("error")}
+	if err := h.validate.Struct(cr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	// The state is removed before anything else so a callback can not be replayed, also when it fails
+	login, err := h.db.OIDC.Take(p.Name, hashToken(cr.State), time.Now())
+	if err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeInvalidLoginState, StatusCode: http.StatusBadRequest})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+
+	if cr.Error != "" || cr.Code == "" {
+		h.respondError(w, r, errProviderSignIn)
+		return
+	}
+
+	claims, err := h.exchangeCode(p, cr.Code, login)
+	if err != nil {
+		h.log.Warnf("sign in with %s failed, %s", p.Name, err)
+		h.respondError(w, r, errProviderSignIn)
+		return
+	}
+
+	u, err := h.identityUser(p, claims)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp, err := h.newTokens(u, "")
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// provider returns the configured provider of the provider url parameter
+func (h *Handler) provider(r *http.Request) (config.Provider, bool) {
+	name := chi.URLParam(r, "provider")
+	for _, p := range h.cfg.OIDC.Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+
+	return config.Provider{}, false
+}
+
+// exchangeCode redeems the authorization code at the token endpoint of the provider with the PKCE verifier of the
+// sign in and returns the claims of the verified ID token
+func (h *Handler) exchangeCode(p config.Provider, code string, login *database.OIDCLogin) (*IDToken, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"code_verifier": {login.Verifier},
+	}
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+
+	resp, err := h.client.PostForm(p.TokenURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("provider error, %w", err)
+	}
+	defer resp.Body.Close()
+
+	tr := struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("provider error, %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.IDToken == "" {
+		return nil, fmt.Errorf("provider error, token endpoint responded %d %s", resp.StatusCode, tr.Error)
+	}
+
+	claims := IDToken{}
+	if _, err := jwt.ParseWithClaims(tr.IDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		kid, _ := token.Header["kid"].(string)
+
+		return h.providerKey(p, kid, token.Method.Alg())
+	}); err != nil {
+		return nil, fmt.Errorf("id token error, %w", err)
+	}
+
+	switch {
+	case !claims.VerifyIssuer(p.Issuer, true):
+		return nil, errors.New("id token error, unexpected issuer")
+	case !claims.Audience.contains(p.ClientID):
+		return nil, errors.New("id token error, unexpected audience")
+	case !claims.VerifyExpiresAt(time.Now().Unix(), true):
+		return nil, errors.New("id token error, token has no expiry")
+	case claims.Nonce != login.Nonce:
+		return nil, errors.New("id token error, nonce mismatch")
+	case claims.Subject == "":
+		return nil, errors.New("id token error, token has no subject")
+	}
+
+	return &claims, nil
+}
+
+// providerKey returns the public key with the given kid from the key set of the provider, key sets are cached and
+// fetched again when they do not have the key so providers can rotate keys
+func (h *Handler) providerKey(p config.Provider, kid string, alg string) (interface{}, error) {
+	set, cached := h.cache.Get(cacheKeyProviderKeys + p.Name)
+	if !cached {
+		var err error
+		if set, err = h.fetchKeys(p); err != nil {
+			return nil, err
+		}
+	}
+
+	jwk, ok := findKey(set.(keyring.JWKS), kid)
+	if !ok && cached {
+		var err error
+		if set, err = h.fetchKeys(p); err != nil {
+			return nil, err
+		}
+		jwk, ok = findKey(set.(keyring.JWKS), kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %s", kid)
+	}
+	if jwk.Algorithm != "" && jwk.Algorithm != alg {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return jwk.PublicKey()
+}
+
+// findKey returns the key of set with the given kid
+func findKey(set keyring.JWKS, kid string) (keyring.JWK, bool) {
+	for _, jwk := range set.Keys {
+		if jwk.KeyID == kid {
+			return jwk, true
+		}
+	}
+
+	return keyring.JWK{}, false
+}
+
+// fetchKeys gets the key set of the provider and caches it
+func (h *Handler) fetchKeys(p config.Provider) (keyring.JWKS, error) {
+	set := keyring.JWKS{}
+
+	resp, err := h.client.Get(p.JWKSURL)
+	if err != nil {
+		return set, fmt.Errorf("provider error, %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return set, fmt.Errorf("provider error, key set endpoint responded %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return set, fmt.Errorf("provider error, %w", err)
+	}
+	h.cache.Set(cacheKeyProviderKeys+p.Name, set)
+
+	return set, nil
+}
+
+// identityUser returns the user an identity signs in as. Identities seen for the first time are linked to the
+// account with their email address, which the provider must have verified, or get a new account. Accounts that
+// never verified the address are not linked, whoever created them may not own it.
+func (h *Handler) identityUser(p config.Provider, claims *IDToken) (*database.User, error) {
+	identity, err := h.db.Identity.Get(p.Name, claims.Subject)
+	switch {
+	case err == nil:
+		return h.activeUser(identity.UserID)
+	case !errors.Is(err, database.ErrNoRows):
+		return nil, err
+	case !claims.EmailVerified || claims.Email == "":
+		return nil, APIError{Code: CodeProviderEmailUnverified, StatusCode: http.StatusForbidden}
+	}
+
+	link := database.Identity{Provider: p.Name, Subject: claims.Subject, Email: claims.Email}
+
+	u, err := h.db.User.GetByEmail(claims.Email)
+	if err == nil {
+		if u.VerifiedAt == "" {
+			return nil, APIError{Code: CodeAccountUnverified, StatusCode: http.StatusConflict}
+		}
+		link.UserID = u.ID
+		if _, err := h.db.Identity.Link(link); err != nil {
+			return nil, err
+		}
+		return h.activeUser(u.ID)
+	}
+	if !errors.Is(err, database.ErrNoRows) {
+		return nil, err
+	}
+
+	// Users created by an identity have no usable password, they can set one with a password reset
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	base := identityUsername(claims)
+	for attempt := 0; ; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix, err := randomToken(2)
+			if err != nil {
+				return nil, err
+			}
+			username = base + suffix
+		}
+
+		id, err := h.db.Identity.InsertUser(database.User{
+			Username: username,
+			Password: string(hash),
+			FullName: claims.Name,
+			Email:    claims.Email,
+			Active:   true,
+		}, link)
+		switch {
+		case err == nil:
+			return h.db.User.Get(uint64(id))
+		case errors.Is(err, database.ErrEmailTaken):
+			return nil, APIError{Code: CodeEmailTaken, StatusCode: http.StatusConflict}
+		case !errors.Is(err, database.ErrDuplicateEntry) || attempt == 4:
+			return nil, err
+		}
+	}
+}
+
+// activeUser returns the user with the given id, deleted, unverified and deactivated users can not sign in
+func (h *Handler) activeUser(id int64) (*database.User, error) {
+	u, err := h.db.User.Get(uint64(id))
+	if err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			return nil, APIError{Code: CodeUserInactive, StatusCode: http.StatusForbidden}
+		}
+		return nil, err
+	}
+	if !u.Active {
+		return nil, APIError{Code: CodeUserInactive, StatusCode: http.StatusForbidden}
+	}
+
+	return u, nil
+}
+
+// identityUsername derives the username of a user created by an identity from its preferred username or the local
+// part of its email address, names are kept between 5 and 16 characters to leave room for a suffix
+func identityUsername(claims *IDToken) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	name = usernameInvalid.ReplaceAllString(strings.ToLower(name), "")
+	if len(name) > 16 {
+		name = name[:16]
+	}
+	if len(name) < 5 {
+		name += "_user"
+	}
+
+	return name
+}
+
+// audience is the aud claim of ID tokens, providers send a single audience as string and several as array
+type audience []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+
+	return nil
+}
+
+// contains reports whether the audience includes clientID
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+
+	return false
+}
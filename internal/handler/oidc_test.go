@@ -0,0 +1,327 @@
+package handler_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/keyring"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+// identity is a user of the stand in identity provider
+type identity struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// grant is an authorization code the stand in provider issued
+type grant struct {
+	identity  identity
+	challenge string
+	nonce     string
+}
+
+// provider is a stand in OpenID Connect provider, its token endpoint checks the PKCE verifier and returns an RS256
+// ID token that its key set endpoint publishes the key of
+type provider struct {
+	*httptest.Server
+	keys   *keyring.Keyring
+	mu     sync.Mutex
+	grants map[string]grant
+	// nonce replaces the nonce of issued ID tokens when set
+	nonce string
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "provider.pem")
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(file, pemKey, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := provider{keys: keyring.New(""), grants: make(map[string]grant)}
+	if err := p.keys.Load(config.Token{
+		SigningKey: "provider-1",
+		Keys:       []config.SigningKey{{ID: "provider-1", Algorithm: keyring.AlgorithmRS256, File: file}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(p.keys.JWKS())
+	})
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return &p
+}
+
+// authorize signs id in at the authorization url the api redirected to, returns the callback query
+func (p *provider) authorize(t *testing.T, location string, id identity) url.Values {
+	t.Helper()
+
+	link, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := link.Query()
+	if link.Path != "/authorize" || q.Get("response_type") != "code" || q.Get("client_id") != "recipeapi" ||
+		q.Get("code_challenge_method") != "S256" || !strings.HasPrefix(q.Get("scope"), "openid") {
+		t.Fatalf("Unexpected authorization request %s", location)
+	}
+
+	code := fmt.Sprintf("code-%s-%d", id.Subject, time.Now().UnixNano())
+	p.mu.Lock()
+	p.grants[code] = grant{identity: id, challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	p.mu.Unlock()
+
+	return url.Values{"code": {code}, "state": {q.Get("state")}, "session_state": {"ignored"}}
+}
+
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "authorization_code" ||
+		r.Form.Get("client_id") != "recipeapi" || r.Form.Get("client_secret") != "secret" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_request"}`))
+		return
+	}
+
+	// Codes are single use and only redeem with the verifier of their challenge
+	p.mu.Lock()
+	g, ok := p.grants[r.Form.Get("code")]
+	delete(p.grants, r.Form.Get("code"))
+	p.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != g.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	nonce := g.nonce
+	if p.nonce != "" {
+		nonce = p.nonce
+	}
+	idToken, err := p.keys.Sign(jwt.MapClaims{
+		"iss":                p.URL,
+		"aud":                []string{"recipeapi", "other"},
+		"sub":                g.identity.Subject,
+		"email":              g.identity.Email,
+		"email_verified":     g.identity.EmailVerified,
+		"name":               g.identity.Name,
+		"preferred_username": g.identity.PreferredUsername,
+		"nonce":              nonce,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+func TestHandler_OIDC(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := newProvider(t)
+	cfg.OIDC.Providers = []config.Provider{{
+		Name:         "standin",
+		Issuer:       p.URL,
+		ClientID:     "recipeapi",
+		ClientSecret: "secret",
+		AuthURL:      p.URL + "/authorize",
+		TokenURL:     p.URL + "/token",
+		JWKSURL:      p.URL + "/jwks",
+		RedirectURL:  "http://127.0.0.1:8080/api/user/oidc/standin/callback",
+	}}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+	routes := handler.Routes(h)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr
+	}
+
+	// signIn runs the sign in of id and returns the response of the callback
+	signIn := func(t *testing.T, id identity) *httptest.ResponseRecorder {
+		t.Helper()
+
+		rr := serve("/api/user/oidc/standin/login")
+		if rr.Code != http.StatusFound {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusFound, rr.Body.String())
+		}
+
+		return serve("/api/user/oidc/standin/callback?" + p.authorize(t, rr.Header().Get("Location"), id).Encode())
+	}
+
+	// signedIn returns the user the tokens of a successful sign in belong to
+	signedIn := func(t *testing.T, rr *httptest.ResponseRecorder) handler.UserProfileResponse {
+		t.Helper()
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		tokens := handler.TokenResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &tokens); err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/user/", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.Token)
+		rr = httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected the tokens to sign in got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		profile := handler.UserProfileResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &profile); err != nil {
+			t.Fatal(err)
+		}
+		return profile
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should create a user on the first sign in", func(t *testing.T) {
+		id := identity{Subject: "sub-1", Email: "oidc1@test.gr", EmailVerified: true, Name: "Oidc One", PreferredUsername: "Oidc.One"}
+
+		profile := signedIn(t, signIn(t, id))
+		if profile.Username != "oidc.one" || profile.Email != "oidc1@test.gr" {
+			t.Fatalf("Expected a new user of the identity got %+v", profile)
+		}
+
+		// The identity stays linked when its email address changes at the provider
+		id.Email = "changed@test.gr"
+		if again := signedIn(t, signIn(t, id)); again.ID != profile.ID {
+			t.Fatalf("Expected user %d got %d", profile.ID, again.ID)
+		}
+	})
+
+	t.Run("Should link the user with the verified email address", func(t *testing.T) {
+		userID, err := db.User.Insert(database.User{
+			Username: "oidclocal1",
+			Password: string(hash),
+			Email:    "oidclocal1@test.gr",
+			Active:   true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		profile := signedIn(t, signIn(t, identity{Subject: "sub-2", Email: "oidclocal1@test.gr", EmailVerified: true}))
+		if profile.ID != userID {
+			t.Fatalf("Expected user %d got %d", userID, profile.ID)
+		}
+	})
+
+	t.Run("Should not link unverified accounts", func(t *testing.T) {
+		if _, err := db.User.Insert(database.User{
+			Username: "oidclocal2",
+			Password: string(hash),
+			Email:    "oidclocal2@test.gr",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		rr := signIn(t, identity{Subject: "sub-3", Email: "oidclocal2@test.gr", EmailVerified: true})
+		if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), handler.CodeAccountUnverified) {
+			t.Fatalf("Expected an account unverified error got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should reject addresses the provider did not verify", func(t *testing.T) {
+		rr := signIn(t, identity{Subject: "sub-4", Email: "oidc4@test.gr"})
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), handler.CodeProviderEmailUnverified) {
+			t.Fatalf("Expected a provider email unverified error got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should complete each sign in once", func(t *testing.T) {
+		rr := serve("/api/user/oidc/standin/login")
+		callback := p.authorize(t, rr.Header().Get("Location"), identity{Subject: "sub-5", Email: "oidc5@test.gr", EmailVerified: true})
+
+		signedIn(t, serve("/api/user/oidc/standin/callback?"+callback.Encode()))
+		if rr := serve("/api/user/oidc/standin/callback?" + callback.Encode()); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+		}
+	})
+
+	t.Run("Should reject ID tokens of another sign in", func(t *testing.T) {
+		p.nonce = "replayed"
+		defer func() { p.nonce = "" }()
+
+		rr := signIn(t, identity{Subject: "sub-6", Email: "oidc6@test.gr", EmailVerified: true})
+		if rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), handler.CodeProviderSignInFailed) {
+			t.Fatalf("Expected a provider sign in error got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should reject codes without the PKCE verifier of the sign in", func(t *testing.T) {
+		first := serve("/api/user/oidc/standin/login")
+		second := serve("/api/user/oidc/standin/login")
+
+		// The code was issued to the first sign in, redeeming it with the state of the second sends the wrong verifier
+		code := p.authorize(t, first.Header().Get("Location"), identity{Subject: "sub-7", Email: "oidc7@test.gr", EmailVerified: true})
+		other := p.authorize(t, second.Header().Get("Location"), identity{Subject: "sub-7", Email: "oidc7@test.gr", EmailVerified: true})
+		code.Set("state", other.Get("state"))
+
+		if rr := serve("/api/user/oidc/standin/callback?" + code.Encode()); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusUnauthorized, rr.Body.String())
+		}
+	})
+
+	t.Run("Should reject unknown providers", func(t *testing.T) {
+		if rr := serve("/api/user/oidc/unknown/login"); rr.Code != http.StatusNotFound {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNotFound, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// pantryPageSize is the number of recipes in a page of pantry search results
+const pantryPageSize = 10
+
+// pantryCandidates is the number of recipes a pantry search matches, those with the fewest ingredients outside the
+// pantry before substitutions
+const pantryCandidates = 500
+
+// PantryRecipes godoc
+// @Summary Pantry search
+// @Description Find the recipes that can be made with the given pantry. An ingredient the pantry lacks counts as
+// @Description covered when one of its substitutions can be made from the pantry, each recipe lists the
+// @Description substitutions it uses and the ingredients still missing. Pantry items also cover the broader
+// @Description ingredients of the taxonomy they belong to. Recipes need at least one pantry item and are ordered
+// @Description by the number of missing ingredients, then by the number of substitutions.
+// @ID get-recipes-pantry
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param pantry query []string true "Available ingredients"
+// @Param maxMissing query int false "Missing ingredients allowed, defaults to 0"
+// @Param page query int false "Page number"
+// @Param lang query string false "Locale"
+// @Success 200 {object} handler.PantryRecipesResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/pantry [get]
+func (h *Handler) PantryRecipes(w http.ResponseWriter, r *http.Request) {
+	pr := PantryRecipesRequest{Page: 1}
+	if err := h.schema.Decode(&pr, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(pr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	// Cheddar in the pantry also covers recipes that ask for cheese
+	tree, err := h.taxonomyTree()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	var pantry []string
+	for i := range pr.Pantry {
+		pantry = append(pantry, tree.Lineage(pr.Pantry[i])...)
+	}
+
+	recipes, err := h.db.Recipe.UsingPantry(h.viewer(r), pantry, pantryCandidates)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	subs, err := h.db.Substitution.List()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	matches := database.MatchPantry(recipes, pantry, subs, pr.MaxMissing)
+	resp := PantryRecipesResponse{
+		Data:     []PantryRecipeResponseItem{},
+		Metadata: Metadata{Total: int64(len(matches))},
+	}
+
+	var start uint64
+	if pr.Page > 0 {
+		start = (pr.Page - 1) * pantryPageSize
+	}
+	if start >= uint64(len(matches)) {
+		h.respond(w, resp, http.StatusOK)
+		return
+	}
+	matches = matches[start:]
+	if len(matches) > pantryPageSize {
+		matches = matches[:pantryPageSize]
+	}
+
+	page := make(database.Recipes, len(matches))
+	for i := range matches {
+		page[i] = matches[i].Recipe
+	}
+	if page, err = h.db.Translation.Translate(page, h.localeChain(r)); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Language")
+
+	for i := range matches {
+		item := PantryRecipeResponseItem{Missing: matches[i].Missing, Substitutions: SubstitutionResponseItems{}}
+		if err := EncodeEntity(page[i], &item.Recipe); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		if item.Missing == nil {
+			item.Missing = []string{}
+		}
+		for _, s := range matches[i].Substitutions {
+			item.Substitutions = append(item.Substitutions, NewSubstitutionResponseItem(s))
+		}
+
+		resp.Data = append(resp.Data, item)
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
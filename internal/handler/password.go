@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/mailer"
+)
+
+// PasswordForgot godoc
+// @Summary Request a password reset
+// @Description Email a single use password reset link to the account with the given address. The response is the
+// @Description same whether an account with the address exists or not, and also when the address was sent too many
+// @Description links recently.
+// @ID user-password-forgot
+// @Accept  json
+// @Produce  json
+// @Param body body handler.PasswordForgotRequest true "email address"
+// @Success 202
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /user/password/forgot [post]
+func (h *Handler) PasswordForgot(w http.ResponseWriter, r *http.Request) {
+	pr := PasswordForgotRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(pr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	// Throttled addresses get the same response, a 429 would tell that an email was sent to the address before. The
+	// account is looked up and emailed off the request path so the response time does not tell whether it exists.
+	if ok, _ := h.resetLimit.Allow("email:"+strings.ToLower(pr.Email), time.Now()); ok {
+		h.background(func() {
+			user, err := h.db.User.GetByEmail(pr.Email)
+			if err != nil {
+				if !errors.Is(err, database.ErrNoRows) {
+					h.log.Errorf("failed to look up the account of a password reset, %s", err)
+				}
+				return
+			}
+			h.sendReset(user)
+		})
+	}
+
+	h.respond(w, nil, http.StatusAccepted)
+}
+
+// PasswordReset godoc
+// @Summary Reset a password
+// @Description Set a new password with the token of a password reset link. Reset tokens can be used once, a
+// @Description successful reset signs the user out of every session and revokes their API keys.
+// @ID user-password-reset
+// @Accept  json
+// @Produce  json
+// @Param body body handler.PasswordResetRequest true "reset token and new password"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /user/password/reset [post]
+func (h *Handler) PasswordReset(w http.ResponseWriter, r *http.Request) {
+	pr := PasswordResetRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(pr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pr.Password), bcrypt.DefaultCost)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	userID, err := h.db.Reset.Redeem(hashToken(pr.Token), string(hash), time.Now())
+	if err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeInvalidResetToken, StatusCode: http.StatusBadRequest})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+
+	// Whoever knew the old password may hold tokens or API keys of the account, they stop working with it
+	if err := h.revokeUserTokens(userID, true); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	if _, err := h.db.APIKey.RevokeUser(userID, time.Now()); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// PasswordChange godoc
+// @Summary Change the password
+// @Description Replace the password of the signed in user, the current password is required. Every other session of
+// @Description the user is signed out, their API keys are revoked and a new token pair is returned for the current one.
+// @ID user-password-change
+// @Accept  json
+// @Produce  json
+// @Param body body handler.PasswordChangeRequest true "current and new password"
+// @Success 200 {object} handler.TokenResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 423 {object} handler.ErrorResponse
+// @Failure 429 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user/password [put]
+func (h *Handler) PasswordChange(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	pr := PasswordChangeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(pr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	user, ok := h.checkPassword(w, r, token, pr.CurrentPassword)
+	if !ok {
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pr.Password), bcrypt.DefaultCost)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if err := h.db.User.SetPassword(uint64(user.ID), string(hash)); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	if err := h.revokeUserTokens(user.ID, true); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	if _, err := h.db.APIKey.RevokeUser(user.ID, time.Now()); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp, err := h.newTokens(user, "")
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// checkPassword loads the caller and compares password with theirs, responds with an error and returns false when
+// the password is wrong. Wrong passwords count as failed sign ins of the user, a stolen token can not be used to
+// guess the password faster than signing in would.
+func (h *Handler) checkPassword(w http.ResponseWriter, r *http.Request, token *Token, password string) (*database.User, bool) {
+	now := time.Now()
+	if h.loginThrottled(w, r, token.Username, now) {
+		return nil, false
+	}
+
+	user, err := h.db.User.GetByUsername(token.Username)
+	if err != nil || user.ID != token.UserID {
+		h.respondError(w, r, APIError{Code: CodeUnknownUser, StatusCode: http.StatusNotFound})
+		return nil, false
+	}
+	if user.LockedUntil != "" {
+		h.respondError(w, r, APIError{Code: CodeAccountLocked, StatusCode: http.StatusLocked})
+		return nil, false
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		h.loginFailed(r, token.Username, user, "wrong_password", now)
+		h.respondError(w, r, APIError{Code: CodeWrongPassword, StatusCode: http.StatusForbidden})
+		return nil, false
+	}
+	h.loginUsers.Reset(loginKey(token.Username))
+
+	return user, true
+}
+
+// sendReset stores a new reset token for a user and emails its link, failures are logged since the response of
+// PasswordForgot must not depend on them
+func (h *Handler) sendReset(u *database.User) {
+	token, err := randomToken(32)
+	if err != nil {
+		h.log.Errorf("failed to create reset token of user %d, %s", u.ID, err)
+		return
+	}
+
+	if _, err := h.db.Reset.Insert(database.PasswordReset{
+		UserID:    u.ID,
+		Hash:      hashToken(token),
+		ExpiresAt: time.Now().Add(time.Duration(h.cfg.Reset.TTL) * time.Minute),
+	}); err != nil {
+		h.log.Errorf("failed to store reset token of user %d, %s", u.ID, err)
+		return
+	}
+
+	link, err := withToken(h.cfg.Reset.URL, token)
+	if err != nil {
+		h.log.Errorf("failed to create reset link of user %d, %s", u.ID, err)
+		return
+	}
+
+	if err := h.mailer.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to choose a new password, the link expires in %d minutes "+
+			"and can be used once.\n\n%s\n\nIf you did not ask for a password reset you can ignore this email, "+
+			"your password has not changed.\n", displayName(u), h.cfg.Reset.TTL, link),
+	}); err != nil {
+		h.log.Errorf("failed to send password reset email to user %d, %s", u.ID, err)
+	}
+}
@@ -0,0 +1,186 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/georlav/recipeapi/internal/mailer"
+)
+
+func TestHandler_PasswordReset(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+	mails := mailer.NewMemory()
+	h.SetMailer(mails)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := db.User.Insert(database.User{
+		Username: "forgetful1",
+		Password: string(hash),
+		Email:    "forgetful1@test.gr",
+		Active:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// post sends body to fn, behind the authorization middleware when a bearer token is given
+	post := func(fn http.HandlerFunc, bearer string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		if bearer == "" {
+			fn.ServeHTTP(rr, req)
+			return rr
+		}
+
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		h.AuthorizationMiddleware(fn).ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// link returns the token of the last reset link sent to the address
+	link := func(email string) string {
+		m, ok := mails.Last(email)
+		if !ok {
+			t.Fatalf("Expected a password reset email to %s", email)
+		}
+		u, err := url.Parse(regexp.MustCompile(`http\S+`).FindString(m.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Query().Get("token")
+	}
+
+	forgot := func(email string) {
+		if rr := post(h.PasswordForgot, "", fmt.Sprintf(`{"email":%q}`, email)); rr.Code != http.StatusAccepted {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusAccepted, rr.Body.String())
+		}
+		h.Wait()
+	}
+
+	reset := func(token string, password string) *httptest.ResponseRecorder {
+		return post(h.PasswordReset, "", fmt.Sprintf(`{"token":%q,"password":%q,"repeatPassword":%q}`,
+			token, password, password))
+	}
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		return post(h.SignIn, "", fmt.Sprintf(`{"username":"forgetful1","password":%q}`, password))
+	}
+
+	// withKey reads the profile with an API key through the middleware of the recipe routes
+	withKey := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		next := h.AuthorizationMiddleware(http.HandlerFunc(h.User))
+		h.APIKeyScopes(database.ScopeRecipesRead, database.ScopeRecipesWrite)(next).ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Should respond the same way to unknown addresses", func(t *testing.T) {
+		forgot("nobody@test.gr")
+		if _, ok := mails.Last("nobody@test.gr"); ok {
+			t.Fatal("Expected no email to an unknown address")
+		}
+	})
+
+	t.Run("Should reset the password and sign out every session", func(t *testing.T) {
+		owner := handler.Token{UserID: userID, Username: "forgetful1"}
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"backup","scopes":["recipes:read"]}`))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, owner))
+		rr := httptest.NewRecorder()
+		h.APIKeyCreate(rr, req)
+		key := handler.APIKeyResponseItem{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &key); err != nil || rr.Code != http.StatusCreated {
+			t.Fatalf("Expected an API key got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := withKey(key.Key); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+
+		rr = signIn("password")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		tr := handler.TokenResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &tr); err != nil {
+			t.Fatal(err)
+		}
+
+		forgot("forgetful1@test.gr")
+		token := link("forgetful1@test.gr")
+
+		if rr := reset(token, "password2"); rr.Code != http.StatusNoContent {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNoContent, rr.Body.String())
+		}
+		if rr := post(h.User, tr.Token, ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the access token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+		body := fmt.Sprintf(`{"refreshToken":%q}`, tr.RefreshToken)
+		if rr := post(h.TokenRefresh, "", body); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the refresh token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := withKey(key.Key); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the API key to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		if rr := signIn("password"); rr.Code == http.StatusOK {
+			t.Fatal("Expected the old password to be rejected")
+		}
+		if rr := signIn("password2"); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+
+		rr = reset(token, "password3")
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), handler.CodeInvalidResetToken) {
+			t.Fatalf("Expected a used reset token to be rejected got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should only accept the latest reset link", func(t *testing.T) {
+		forgot("forgetful1@test.gr")
+		first := link("forgetful1@test.gr")
+		forgot("forgetful1@test.gr")
+
+		if rr := reset(first, "password3"); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+		}
+		if rr := reset(link("forgetful1@test.gr"), "password3"); rr.Code != http.StatusNoContent {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNoContent, rr.Body.String())
+		}
+	})
+
+	t.Run("Should silently throttle reset emails", func(t *testing.T) {
+		sent := len(mails.Messages())
+		forgot("forgetful1@test.gr")
+		if n := len(mails.Messages()); n != sent {
+			t.Fatalf("Expected no email past the limit got %d", n-sent)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// Permission names an action that is restricted to some user roles
+type Permission string
+
+// Permissions routes are guarded with
+const (
+	// PermModerateRecipes review submitted recipes and manage recipes of any author
+	PermModerateRecipes Permission = "recipes:moderate"
+	// PermManageContent edit the reference data recipes are classified with, the taxonomy, substitutions, diet
+	// attributes and nutrients
+	PermManageContent Permission = "content:manage"
+	// PermManageUsers list users and change their role and status
+	PermManageUsers Permission = "users:manage"
+)
+
+// rolePermissions maps each user role to the permissions it grants
+var rolePermissions = map[string][]Permission{
+	database.RoleUser:      {},
+	database.RoleEditor:    {PermManageContent},
+	database.RoleModerator: {PermModerateRecipes},
+	database.RoleAdmin:     {PermModerateRecipes, PermManageContent, PermManageUsers},
+}
+
+// roles returns the roles of the caller, the role of the token together with the role the admin config gives to
+// the user id so that a fresh install has an administrator before any role was granted
+func (h *Handler) roles(token *Token) []string {
+	roles := []string{token.Role}
+	for i := range h.cfg.Admin.Users {
+		if h.cfg.Admin.Users[i] == token.UserID {
+			roles = append(roles, database.RoleAdmin)
+		}
+	}
+	for i := range h.cfg.Admin.Moderators {
+		if h.cfg.Admin.Moderators[i] == token.UserID {
+			roles = append(roles, database.RoleModerator)
+		}
+	}
+
+	return roles
+}
+
+// can reports whether the caller has all of the given permissions, anonymous callers have none
+func (h *Handler) can(token *Token, perms ...Permission) bool {
+	if token == nil {
+		return false
+	}
+
+	granted := make(map[Permission]struct{})
+	for _, role := range h.roles(token) {
+		for _, p := range rolePermissions[role] {
+			granted[p] = struct{}{}
+		}
+	}
+
+	for i := range perms {
+		if _, ok := granted[perms[i]]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/mailer"
+)
+
+// UserUpdate godoc
+// @Summary Update the user profile
+// @Description Update the full name and preferences of the signed in user, omitted fields are left unchanged and
+// @Description given preferences replace the stored ones
+// @ID user-profile-update
+// @Accept  json
+// @Produce  json
+// @Param body body handler.UserUpdateRequest true "profile fields"
+// @Success 200 {object} handler.UserProfileResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user [patch]
+func (h *Handler) UserUpdate(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	ur := UserUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&ur); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(ur); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	user, err := h.db.User.Get(uint64(token.UserID))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownUser, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	fullName, prefs := user.FullName, user.Preferences
+	if ur.FullName != nil {
+		fullName = strings.TrimSpace(*ur.FullName)
+	}
+	if ur.Preferences != nil {
+		if ur.Preferences.Locale != "" && !h.locales.IsSupported(ur.Preferences.Locale) {
+			h.respondError(w, r, APIError{Code: CodeUnsupportedLocale, StatusCode: http.StatusBadRequest})
+			return
+		}
+		prefs = database.Preferences{Locale: ur.Preferences.Locale, Diet: ur.Preferences.Diet}
+	}
+
+	// Unchanged profiles are not written, the update would affect no rows
+	if fullName != user.FullName || prefs != user.Preferences {
+		if err := h.db.User.UpdateProfile(uint64(user.ID), fullName, prefs); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+	}
+
+	h.respondUser(w, r, user.ID)
+}
+
+// EmailChange godoc
+// @Summary Change the email address
+// @Description Ask to switch the signed in user to a new email address, the current password is required. The
+// @Description address changes once the link emailed to the new address is confirmed, the current address is told
+// @Description about the request.
+// @ID user-email-change
+// @Accept  json
+// @Produce  json
+// @Param body body handler.EmailChangeRequest true "new address and current password"
+// @Success 202 {object} handler.UserProfileResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 423 {object} handler.ErrorResponse
+// @Failure 429 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user/email [post]
+func (h *Handler) EmailChange(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	er := EmailChangeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&er); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(er); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	user, ok := h.checkPassword(w, r, token, er.Password)
+	if !ok {
+		return
+	}
+
+	if strings.EqualFold(er.Email, user.Email) {
+		h.respondError(w, r, APIError{Code: CodeEmailTaken, StatusCode: http.StatusConflict})
+		return
+	}
+
+	// Asking again for the pending address only sends a new link
+	if er.Email != user.PendingEmail {
+		if err := h.db.User.RequestEmail(uint64(user.ID), er.Email); err != nil {
+			if errors.Is(err, database.ErrEmailTaken) {
+				h.respondError(w, r, APIError{Code: CodeEmailTaken, StatusCode: http.StatusConflict})
+				return
+			}
+			h.respondError(w, r, err)
+			return
+		}
+		user.PendingEmail = er.Email
+	}
+	h.sendEmailChange(user)
+
+	h.respond(w, NewUserProfileResponse(*user), http.StatusAccepted)
+}
+
+// EmailConfirm godoc
+// @Summary Confirm an email address change
+// @Description Switch a user to the address of an email change with the token of the link emailed to it
+// @ID user-email-confirm
+// @Accept  json
+// @Produce  json
+// @Param body body handler.EmailConfirmRequest true "confirmation token"
+// @Success 200 {object} handler.UserProfileResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /user/email/confirm [post]
+func (h *Handler) EmailConfirm(w http.ResponseWriter, r *http.Request) {
+	er := EmailConfirmRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&er); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(er); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	et := EmailChangeToken{}
+	token, err := jwt.ParseWithClaims(er.Token, &et, h.keys.Keyfunc)
+	// Verification links carry their claims under other names and fail here as well
+	if err != nil || !token.Valid || et.UserID <= 0 || et.Email == "" {
+		h.respondError(w, r, APIError{Code: CodeInvalidVerification, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	// Links of an earlier request that was replaced by another address, or that were already used, fail here
+	if err := h.db.User.ConfirmEmail(uint64(et.UserID), et.Email); err != nil {
+		switch {
+		case errors.Is(err, database.ErrNoRows):
+			h.respondError(w, r, APIError{Code: CodeInvalidVerification, StatusCode: http.StatusBadRequest})
+		case errors.Is(err, database.ErrEmailTaken):
+			h.respondError(w, r, APIError{Code: CodeEmailTaken, StatusCode: http.StatusConflict})
+		default:
+			h.respondError(w, r, err)
+		}
+		return
+	}
+
+	h.respondUser(w, r, et.UserID)
+}
+
+// sendEmailChange emails a confirmation link to the pending address of a user and a notice to the current one,
+// failures are logged since the user can ask again
+func (h *Handler) sendEmailChange(u *database.User) {
+	link, err := h.signedLink(h.cfg.Verify.ChangeURL, EmailChangeToken{
+		UserID:         u.ID,
+		Email:          u.PendingEmail,
+		StandardClaims: h.linkClaims(),
+	})
+	if err != nil {
+		h.log.Errorf("failed to create email change link of user %d, %s", u.ID, err)
+		return
+	}
+
+	for _, m := range []mailer.Message{
+		{
+			To:      u.PendingEmail,
+			Subject: "Confirm your new email address",
+			Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to confirm %s as the email address of your account, "+
+				"the link expires in %d hours.\n\n%s\n\nIf you did not ask for this change you can ignore this email.\n",
+				displayName(u), u.PendingEmail, h.cfg.Verify.TTL, link),
+		},
+		{
+			To:      u.Email,
+			Subject: "Your email address is about to change",
+			Body: fmt.Sprintf("Hi %s,\n\nA change of the email address of your account to %s was requested, it "+
+				"takes effect once the new address is confirmed.\n\nIf you did not ask for this change reset your "+
+				"password.\n", displayName(u), u.PendingEmail),
+		},
+	} {
+		if err := h.mailer.Send(m); err != nil {
+			h.log.Errorf("failed to send email change email to user %d, %s", u.ID, err)
+		}
+	}
+}
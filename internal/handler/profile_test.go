@@ -0,0 +1,168 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/georlav/recipeapi/internal/mailer"
+)
+
+func TestHandler_Profile(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+	mails := mailer.NewMemory()
+	h.SetMailer(mails)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.User.Insert(database.User{
+		Username: "profile1",
+		Password: string(hash),
+		Email:    "profile1@test.gr",
+		Active:   true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// send calls fn with body, behind the authorization middleware when a bearer token is given
+	send := func(fn http.HandlerFunc, bearer string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		if bearer == "" {
+			fn.ServeHTTP(rr, req)
+			return rr
+		}
+
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		h.AuthorizationMiddleware(fn).ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	tokens := func(rr *httptest.ResponseRecorder) handler.TokenResponse {
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		tr := handler.TokenResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &tr); err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+
+	profile := func(rr *httptest.ResponseRecorder, code int) handler.UserProfileResponse {
+		if rr.Code != code {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, code, rr.Body.String())
+		}
+		resp := handler.UserProfileResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	tr := tokens(send(h.SignIn, "", `{"username": "profile1", "password": "password"}`))
+
+	t.Run("Should update the profile", func(t *testing.T) {
+		body := `{"fullName":"profile user","preferences":{"locale":"el","diet":"vegan"}}`
+		resp := profile(send(h.UserUpdate, tr.Token, body), http.StatusOK)
+		if resp.FullName != "profile user" || resp.Preferences.Locale != "el" || resp.Preferences.Diet != "vegan" {
+			t.Fatalf("Expected the updated profile got %+v", resp)
+		}
+
+		resp = profile(send(h.UserUpdate, tr.Token, `{"fullName":"profile user"}`), http.StatusOK)
+		if resp.Preferences.Locale != "el" {
+			t.Fatalf("Expected omitted preferences to be kept got %+v", resp)
+		}
+	})
+
+	t.Run("Should reject invalid preferences", func(t *testing.T) {
+		for _, body := range []string{`{"preferences":{"locale":"xx"}}`, `{"preferences":{"diet":"carnivore"}}`} {
+			if rr := send(h.UserUpdate, tr.Token, body); rr.Code != http.StatusBadRequest {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("Should change the password with the current one", func(t *testing.T) {
+		body := `{"currentPassword":"wrong","password":"password2","repeatPassword":"password2"}`
+		rr := send(h.PasswordChange, tr.Token, body)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), handler.CodeWrongPassword) {
+			t.Fatalf("Expected a wrong password error got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		body = `{"currentPassword":"password","password":"password2","repeatPassword":"password2"}`
+		fresh := tokens(send(h.PasswordChange, tr.Token, body))
+		if rr := send(h.User, tr.Token, ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the old access token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := send(h.User, fresh.Token, ""); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		tr = fresh
+	})
+
+	t.Run("Should not switch to used addresses", func(t *testing.T) {
+		rr := send(h.EmailChange, tr.Token, `{"email":"test@test.gr","password":"password2"}`)
+		if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), handler.CodeEmailTaken) {
+			t.Fatalf("Expected an email taken error got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should change the email after confirming the new address", func(t *testing.T) {
+		resp := profile(send(h.EmailChange, tr.Token, `{"email":"moved@test.gr","password":"password2"}`), http.StatusAccepted)
+		if resp.Email != "profile1@test.gr" || resp.PendingEmail != "moved@test.gr" {
+			t.Fatalf("Expected a pending address got %+v", resp)
+		}
+		if _, ok := mails.Last("profile1@test.gr"); !ok {
+			t.Fatal("Expected a notice to the current address")
+		}
+
+		m, ok := mails.Last("moved@test.gr")
+		if !ok {
+			t.Fatal("Expected a confirmation email to the new address")
+		}
+		link, err := url.Parse(regexp.MustCompile(`http\S+`).FindString(m.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := fmt.Sprintf(`{"token":%q}`, link.Query().Get("token"))
+
+		if rr := send(h.VerifyEmail, "", body); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected the confirmation token to not verify accounts got %d, %s", rr.Code, rr.Body.String())
+		}
+
+		resp = profile(send(h.EmailConfirm, "", body), http.StatusOK)
+		if resp.Email != "moved@test.gr" || resp.PendingEmail != "" || !resp.Verified {
+			t.Fatalf("Expected the confirmed address got %+v", resp)
+		}
+
+		rr := send(h.EmailConfirm, "", body)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), handler.CodeInvalidVerification) {
+			t.Fatalf("Expected a used confirmation link to be rejected got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+}
@@ -2,20 +2,26 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/i18n"
 	"github.com/go-chi/chi"
 )
 
 // Recipe godoc
 // @Summary Get a recipe
-// @Description Get a recipe by ID
+// @Description Get a recipe by ID in the locale chosen with lang or Accept-Language, the served locale is reported
+// @Description in the response and in the Content-Language header
 // @ID get-recipe-by-int
 // @Accept  application/x-www-form-urlencoded
 // @Produce  json
 // @Param id path int true "Recipe ID"
+// @Param lang query string false "Locale"
 // @Success 200 {object} handler.RecipeResponseItem
 // @Failure 400 {object} handler.ErrorResponse
 // @Failure 404 {object} handler.ErrorResponse
@@ -26,21 +32,32 @@ func (h *Handler) Recipe(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	nID, err := strconv.Atoi(id)
 	if err != nil || id == "" {
-		h.respondError(w, APIError{Message: "recipe id is required.", StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
 		return
 	}
 
-	recipe, err := h.db.Recipe.Get(uint64(nID))
+	recipe, err := h.db.Recipe.Get(uint64(nID), h.viewer(r))
 	if err != nil {
-		h.respondError(w, APIError{Message: "unknown recipe", StatusCode: http.StatusNotFound})
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	// Views are buffered and flushed in batches
+	h.views.Add(recipe.ID)
+
+	recipes, err := h.db.Translation.Translate(database.Recipes{*recipe}, h.localeChain(r))
+	if err != nil {
+		h.respondError(w, r, err)
 		return
 	}
 
 	resp := RecipeResponseItem{}
-	if err := EncodeEntity(recipe, &resp); err != nil {
-		h.respondError(w, err)
+	if err := EncodeEntity(recipes[0], &resp); err != nil {
+		h.respondError(w, r, err)
 		return
 	}
+	w.Header().Set("Content-Language", resp.Locale)
+	w.Header().Add("Vary", "Accept-Language")
 
 	// Respond
 	h.respond(w, resp, http.StatusOK)
@@ -48,10 +65,19 @@ func (h *Handler) Recipe(w http.ResponseWriter, r *http.Request) {
 
 // Recipes godoc
 // @Summary Get recipes
-// @Description Get a list of recipes
+// @Description Get a list of recipes, titles are searched and served in the locale chosen with lang or
+// @Description Accept-Language. The metadata counts the recipes of the whole result set by tag.
 // @ID get-recipes
 // @Accept  application/x-www-form-urlencoded
 // @Produce  json
+// @Param lang query string false "Locale"
+// @Param maxTotalTime query int false "Maximum total time in minutes"
+// @Param difficulty query string false "Difficulty" Enums(easy, medium, hard)
+// @Param tag query []string false "Tags as kind:name, cuisine:greek"
+// @Param tagMatch query string false "Match all or any of the tags" Enums(all, any)
+// @Param diet query string false "Diet" Enums(vegan, vegetarian, gluten-free, keto)
+// @Param minCalories query number false "Minimum calories per serving"
+// @Param maxCalories query number false "Maximum calories per serving"
 // @Success 200 {object} handler.RecipesResponse
 // @Failure 400 {object} handler.ErrorResponse
 // @Failure 404 {object} handler.ErrorResponse
@@ -62,32 +88,64 @@ func (h Handler) Recipes(w http.ResponseWriter, r *http.Request) {
 	// Map request to struct
 	rr := RecipesRequest{Page: 1}
 	if err := h.schema.Decode(&rr, r.URL.Query()); err != nil {
-		h.respondError(w, APIError{Message: http.StatusText(http.StatusBadRequest), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, errBadRequest)
 		return
 	}
 
 	// validate data in struct
 	if err := h.validate.Struct(rr); err != nil {
-		h.respondError(w, APIError{Message: err.Error(), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, validationError(err))
 		return
 	}
 
 	// Create db filters from validated request data
+	locales := h.localeChain(r)
 	filters := database.RecipeFilters{
-		Term:        rr.Term,
-		Ingredients: rr.Ingredients,
+		Term:         rr.Term,
+		Ingredients:  rr.Ingredients,
+		Viewer:       h.viewer(r),
+		Locales:      locales,
+		MaxTotalTime: rr.MaxTotalTime,
+		Difficulty:   rr.Difficulty,
+		Tags:         queryTags(rr.Tags),
+		AnyTag:       rr.TagMatch == "any",
+		Diet:         rr.Diet,
+		MinCalories:  rr.MinCalories,
+		MaxCalories:  rr.MaxCalories,
+	}
+
+	// Broaden ingredient filters using the taxonomy, searching cheese also matches cheddar or parmesan
+	if len(rr.Ingredients) > 0 {
+		tree, err := h.taxonomyTree()
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		filters.Ingredients = tree.Expand(rr.Ingredients...)
 	}
 
 	// retrieve data from database
 	recipes, total, err := h.db.Recipe.Paginate(rr.Page, &filters)
 	if err != nil {
-		h.respondError(w, err)
+		h.respondError(w, r, err)
 		return
 	}
 
-	resp := RecipesResponse{Metadata: Metadata{Total: total}}
+	facets, err := h.db.Recipe.Facets(&filters)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if recipes, err = h.db.Translation.Translate(recipes, locales); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Language")
+
+	resp := RecipesResponse{Metadata: Metadata{Total: total, Facets: NewFacetsResponse(facets)}}
 	if err := EncodeEntities(recipes, &resp, "Data"); err != nil {
-		h.respondError(w, err)
+		h.respondError(w, r, err)
 		return
 	}
 
@@ -95,21 +153,243 @@ func (h Handler) Recipes(w http.ResponseWriter, r *http.Request) {
 	h.respond(w, resp, http.StatusOK)
 }
 
-// Create a new recipe
+// Trending godoc
+// @Summary Get trending recipes
+// @Description Get recipes ordered by time decayed popularity over the configured window
+// @ID get-recipes-trending
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param page query int false "Page number"
+// @Success 200 {object} handler.RecipesResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/trending [get]
+func (h *Handler) Trending(w http.ResponseWriter, r *http.Request) {
+	h.respondRecipePage(w, r, func(page uint64) (database.Recipes, int64, error) {
+		return h.db.Recipe.Trending(page, time.Now(), h.cfg.Views.TrendingWindow, h.cfg.Views.TrendingHalfLife)
+	})
+}
+
+// Popular godoc
+// @Summary Get popular recipes
+// @Description Get recipes ordered by their total number of views
+// @ID get-recipes-popular
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param page query int false "Page number"
+// @Success 200 {object} handler.RecipesResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/popular [get]
+func (h *Handler) Popular(w http.ResponseWriter, r *http.Request) {
+	h.respondRecipePage(w, r, h.db.Recipe.Popular)
+}
+
+// respondRecipePage responds with the requested page of recipes returned by paginate
+func (h *Handler) respondRecipePage(
+	w http.ResponseWriter,
+	r *http.Request,
+	paginate func(page uint64) (database.Recipes, int64, error),
+) {
+	pr := PageRequest{Page: 1}
+	if err := h.schema.Decode(&pr, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(pr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	recipes, total, err := paginate(pr.Page)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if recipes, err = h.db.Translation.Translate(recipes, h.localeChain(r)); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Language")
+
+	resp := RecipesResponse{Metadata: Metadata{Total: total}}
+	if err := EncodeEntities(recipes, &resp, "Data"); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// Create a new recipe, near duplicates of existing recipes are rejected with a 409 that lists the candidates unless
+// force=true is passed. New recipes are drafts owned by the signed in user, or pending review when submit is set.
 func (h Handler) Create(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	// Map query parameters
+	rq := RecipeCreateQuery{}
+	if err := h.schema.Decode(&rq, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
 	// Map request to struct
 	rc := RecipeCreateRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&rc); err != nil {
-		h.respondError(w, APIError{Message: http.StatusText(http.StatusBadRequest), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, errBadRequest)
 		return
 	}
 
 	// validate data in struct
 	if err := h.validate.Struct(rc); err != nil {
-		h.respondError(w, APIError{Message: http.StatusText(http.StatusBadRequest), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	recipe, err := h.newRecipe(token, rc)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	// Look for near duplicates
+	if !rq.Force {
+		duplicates, err := h.findDuplicates(r, recipe)
+		if err != nil {
+			h.respondError(w, r, APIError{Code: CodeCreateRecipeFailed, StatusCode: http.StatusInternalServerError})
+			return
+		}
+
+		if len(duplicates) > 0 {
+			h.respondDuplicates(w, r, duplicates)
+			return
+		}
+	}
+
+	// Insert new recipe
+	id, err := h.db.Recipe.Insert(recipe)
+	if err != nil {
+		if errors.Is(err, database.ErrDuplicateEntry) {
+			h.respondError(w, r, APIError{Code: CodeRecipeTitleExists, StatusCode: http.StatusConflict})
+			return
+		}
+		h.respondError(w, r, APIError{Code: CodeCreateRecipeFailed, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/recipes/%d", id))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// RecipeImport godoc
+// @Summary Import recipes
+// @Description Create recipes in bulk from a JSON or YAML document. Recipes that look like near duplicates of
+// @Description existing or previously imported recipes are skipped and listed with their duplicates unless
+// @Description force=true is passed.
+// @ID post-recipes-import
+// @Accept  json
+// @Accept  x-yaml
+// @Produce  json
+// @Param body body handler.RecipeImportRequest true "recipes"
+// @Param force query bool false "Skip near duplicate detection"
+// @Success 200 {object} handler.RecipeImportResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/import [post]
+func (h *Handler) RecipeImport(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	rq := RecipeCreateQuery{}
+	if err := h.schema.Decode(&rq, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
 		return
 	}
 
+	ri := RecipeImportRequest{}
+	if err := h.decodeImport(w, r, &ri); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	recipes := make(database.Recipes, len(ri.Recipes))
+	for i := range ri.Recipes {
+		recipe, err := h.newRecipe(token, ri.Recipes[i])
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		recipes[i] = recipe
+	}
+
+	// Recipes are inserted one by one, later recipes of the document are compared with the earlier ones as well
+	resp := RecipeImportResponse{Imported: []int64{}, Skipped: []RecipeImportSkippedItem{}}
+	for i := range recipes {
+		skipped := RecipeImportSkippedItem{Index: i, Title: recipes[i].Title, Duplicates: DuplicateResponseItems{}}
+
+		if !rq.Force {
+			duplicates, err := h.findDuplicates(r, recipes[i])
+			if err != nil {
+				h.respondError(w, r, err)
+				return
+			}
+
+			if len(duplicates) > 0 {
+				skipped.Code = CodeRecipeDuplicate
+				for j := range duplicates {
+					item := DuplicateResponseItem{
+						Reasons:           duplicates[j].Reasons,
+						IngredientOverlap: duplicates[j].IngredientOverlap,
+					}
+					if err := EncodeEntity(duplicates[j].Recipe, &item.Recipe); err != nil {
+						h.respondError(w, r, err)
+						return
+					}
+					skipped.Duplicates = append(skipped.Duplicates, item)
+				}
+				resp.Skipped = append(resp.Skipped, skipped)
+				continue
+			}
+		}
+
+		id, err := h.db.Recipe.Insert(recipes[i])
+		if errors.Is(err, database.ErrDuplicateEntry) {
+			skipped.Code = CodeRecipeTitleExists
+			resp.Skipped = append(resp.Skipped, skipped)
+			continue
+		}
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+		resp.Imported = append(resp.Imported, id)
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// newRecipe maps a create request to a new recipe of the signed in user
+func (h *Handler) newRecipe(token *Token, rc RecipeCreateRequest) (database.Recipe, error) {
+	if rc.Locale == "" {
+		rc.Locale = h.locales.Default
+	}
+	if !h.locales.IsSupported(rc.Locale) {
+		return database.Recipe{}, APIError{Code: CodeUnsupportedLocale, StatusCode: http.StatusBadRequest}
+	}
+
 	// Create a slice of ingredients
 	ingredients := func() (ing database.Ingredients) {
 		for i := range rc.Ingredients {
@@ -119,16 +399,36 @@ func (h Handler) Create(w http.ResponseWriter, r *http.Request) {
 		return ing
 	}()
 
-	// Insert new recipe
-	if _, err := h.db.Recipe.Insert(database.Recipe{
+	recipe := database.Recipe{
 		Title:       rc.Title,
 		URL:         rc.URL,
 		Thumbnail:   rc.Thumbnail,
 		Ingredients: ingredients,
-	}); err != nil {
-		h.respondError(w, APIError{Message: "failed to create recipe", StatusCode: http.StatusInternalServerError})
-		return
+		UserID:      token.UserID,
+		Status:      database.RecipeDraft,
+		Visibility:  rc.Visibility,
+		Locale:      i18n.Normalize(rc.Locale),
+		PrepTime:    rc.PrepTime,
+		CookTime:    rc.CookTime,
+		TotalTime:   rc.TotalTime,
+		Servings:    rc.Servings,
+		Yield:       rc.Yield,
+		Difficulty:  rc.Difficulty,
+		Tags:        requestTags(rc.Tags),
+	}
+	if rc.Submit {
+		recipe.Status = database.RecipePending
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	return recipe, nil
+}
+
+// findDuplicates returns the near duplicates of recipe, only recipes the caller can read are reported as candidates
+func (h *Handler) findDuplicates(r *http.Request, recipe database.Recipe) (database.Duplicates, error) {
+	recipes, err := h.db.Recipe.DuplicateCandidates(recipe, h.viewer(r), h.duplicateRules())
+	if err != nil {
+		return nil, err
+	}
+
+	return h.duplicateRules().Find(recipe, recipes), nil
 }
@@ -114,6 +114,10 @@ func TestHandler_Recipes(t *testing.T) {
 		{url.Values{"page": []string{"1"}, "ingredient": []string{"1", "2", "3", "4", "5", "6"}}, 0, http.StatusBadRequest},
 		{url.Values{"term": []string{"ab"}}, 0, http.StatusBadRequest},
 		{url.Values{"page": []string{"-5"}}, 0, http.StatusBadRequest},
+		{url.Values{"maxTotalTime": []string{"30"}}, 0, http.StatusOK},
+		{url.Values{"maxTotalTime": []string{"0"}}, 10, http.StatusOK},
+		{url.Values{"maxTotalTime": []string{"-30"}}, 0, http.StatusBadRequest},
+		{url.Values{"difficulty": []string{"extreme"}}, 0, http.StatusBadRequest},
 	}
 
 	cfg, err := config.New("config", "testdata")
@@ -152,47 +156,90 @@ func TestHandler_Recipes(t *testing.T) {
 
 func TestHandler_Create(t *testing.T) {
 	testData := []struct {
+		query         string
 		payload       string
 		expectedCode  int
 		expectedError string
 	}{
 		{
+			"",
+			`{"title":"Ginger Champagne2","url":"http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx",
+"ingredients":["champagne","ginger","ice","vodka"],"thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
+			http.StatusConflict,
+			"recipe looks like a duplicate",
+		},
+		{
+			"force=true",
 			`{"title":"Ginger Champagne2","url":"http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx",
 "ingredients":["champagne","ginger","ice","vodka"],"thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
 			http.StatusCreated,
 			"",
 		},
 		{
+			"force=true",
 			`{"title":"Ginger Champagne2","url":"http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx",
 "ingredients":["champagne","ginger","ice","vodka"],"thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
-			http.StatusInternalServerError,
-			"failed to create recipe",
+			http.StatusConflict,
+			"recipe title already exists",
+		},
+		{
+			"force=maybe",
+			`{"title":"Tzatziki","url":"http://example.com/tzatziki",
+"ingredients":["yogurt","cucumber","garlic"],"thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
+			http.StatusBadRequest,
+			"",
 		},
 		{
+			"",
+			`{"title":"Tzatziki","url":"http://example.com/tzatziki",
+"ingredients":["yogurt","cucumber","garlic"],"thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
+			http.StatusCreated,
+			"",
+		},
+		{
+			"",
 			`{"url":"http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx",
 "ingredients":["champagne","ginger","ice","vodka"],"thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
 			http.StatusBadRequest,
 			`Field validation for 'Title' failed on the 'required' tag`,
 		},
 		{
+			"",
 			`{"Title":"Ginger Champagne2", "URL":"http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx",
 "Ingredients":[],"Thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
 			http.StatusBadRequest,
 			`Field validation for 'Ingredients' failed on the 'min' tag`,
 		},
 		{
+			"",
 			`{"title":"t", "URL":"http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx",
 "ingredients":[],"thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
 			http.StatusBadRequest,
 			`Field validation for 'Title' failed on the 'min' tag`,
 		},
 		{
+			"",
 			`{"title":"Ginger Champagne2", "url":"http://allrecipes.com/Recipe/Ginger-Champagne/Detail.aspx",
 "thumbnail":"http://img.recipepuppy.com/1.jpg"}`,
 			http.StatusBadRequest,
 			`Field validation for 'Ingredients' failed on the 'required' tag"`,
 		},
 		{
+			"",
+			`{"title":"Crêpes Suzette","url":"http://example.com/crepes-suzette",
+"ingredients":["flour","milk","orange"],"locale":"fr"}`,
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"",
+			`{"title":"Slow Tzatziki","url":"http://example.com/slow-tzatziki",
+"ingredients":["yogurt","cucumber","garlic"],"prepTime":-10,"difficulty":"extreme"}`,
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"",
 			`invalid request`,
 			http.StatusBadRequest,
 			`jghg`,
@@ -211,17 +258,222 @@ func TestHandler_Create(t *testing.T) {
 
 	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
 
+	t.Run(`Sending payload without a token`, func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/recipes", strings.NewReader(testData[0].payload))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.Create).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusUnauthorized, rr.Body.String())
+		}
+	})
+
 	for i := range testData {
 		tc := testData[i]
 
 		t.Run(`Sending payload`, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodPost, "/recipes", strings.NewReader(tc.payload))
+			req := httptest.NewRequest(http.MethodPost, "/recipes?"+tc.query, strings.NewReader(tc.payload))
+			req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, handler.Token{UserID: 1, Username: "username1"}))
 
 			// initialize response recorder to monitor handler response data
 			rr := httptest.NewRecorder()
 			rh := http.HandlerFunc(h.Create)
 			rh.ServeHTTP(rr, req)
 
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+			if rr.Code == http.StatusConflict && !strings.Contains(rr.Body.String(), tc.expectedError) {
+				t.Fatalf("Expected error %s got %s", tc.expectedError, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_CreateDuplicates(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	payload := `{"title":"Potato & Cheese Frittata!","url":"https://www.allrecipes.com/Recipe/Potato-and-Cheese-Frittata/",
+"ingredients":["cheddar cheese","eggs","olive oil","onions","potato","salt"]}`
+	req := httptest.NewRequest(http.MethodPost, "/recipes", strings.NewReader(payload))
+	req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, handler.Token{UserID: 1, Username: "username1"}))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.Create).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusConflict, rr.Body.String())
+	}
+
+	resp := handler.DuplicateRecipeResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Duplicates) != 1 {
+		t.Fatalf("Expected 1 duplicate got %d, %s", len(resp.Duplicates), rr.Body.String())
+	}
+
+	duplicate := resp.Duplicates[0]
+	if duplicate.Recipe.Title != "Potato and Cheese Frittata" {
+		t.Fatalf("Expected duplicate to be Potato and Cheese Frittata got %s", duplicate.Recipe.Title)
+	}
+	expectedReasons := []string{database.DuplicateTitle, database.DuplicateURL, database.DuplicateIngredients}
+	if fmt.Sprint(duplicate.Reasons) != fmt.Sprint(expectedReasons) {
+		t.Fatalf("Expected reasons %v got %v", expectedReasons, duplicate.Reasons)
+	}
+	if duplicate.IngredientOverlap != 1 {
+		t.Fatalf("Expected ingredient overlap 1 got %f", duplicate.IngredientOverlap)
+	}
+}
+
+func TestHandler_PopularTrending(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	// View a recipe and flush the buffered views
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/recipes/7", nil)
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", "7")
+		http.HandlerFunc(h.Recipe).ServeHTTP(
+			httptest.NewRecorder(),
+			req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx)),
+		)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		desc       string
+		handler    http.HandlerFunc
+		params     url.Values
+		statusCode int
+	}{
+		{"Popular recipes", h.Popular, url.Values{}, http.StatusOK},
+		{"Popular recipes first page", h.Popular, url.Values{"page": []string{"1"}}, http.StatusOK},
+		{"Popular recipes invalid page", h.Popular, url.Values{"page": []string{"-1"}}, http.StatusBadRequest},
+		{"Trending recipes", h.Trending, url.Values{}, http.StatusOK},
+		{"Trending recipes invalid page", h.Trending, url.Values{"page": []string{"abc"}}, http.StatusBadRequest},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/recipes/popular?"+tc.params.Encode(), nil)
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.statusCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.statusCode, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			resp := handler.RecipesResponse{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Data == nil {
+				t.Fatal("Expected to have results")
+			}
+
+			var found bool
+			for _, r := range *resp.Data {
+				if r.ID == 7 && r.Views >= 3 {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("Expected viewed recipe to be listed, %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_AnonymousAccess(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := handler.Routes(handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger)))
+
+	publicID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Anonymous Lentil Soup",
+		URL:         "http://example.com/anonymous-lentil-soup",
+		Ingredients: database.Ingredients{{Name: "lentils"}, {Name: "carrot"}, {Name: "onion"}},
+		UserID:      1,
+		Status:      database.RecipePublished,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	draftID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Anonymous Draft Soup",
+		URL:         "http://example.com/anonymous-draft-soup",
+		Ingredients: database.Ingredients{{Name: "leek"}, {Name: "potato"}},
+		UserID:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		desc         string
+		method       string
+		url          string
+		auth         string
+		expectedCode int
+	}{
+		{"Should read a public recipe", http.MethodGet, fmt.Sprintf("/api/recipes/%d", publicID), "", http.StatusOK},
+		{"Should not read a draft", http.MethodGet, fmt.Sprintf("/api/recipes/%d", draftID), "", http.StatusNotFound},
+		{"Should list recipes", http.MethodGet, "/api/recipes/", "", http.StatusOK},
+		{"Should list popular recipes", http.MethodGet, "/api/recipes/popular", "", http.StatusOK},
+		{"Should read a shopping list", http.MethodGet, fmt.Sprintf("/api/recipes/%d/shopping-list", publicID), "",
+			http.StatusOK},
+		{"Should reject an invalid token", http.MethodGet, "/api/recipes/", "Bearer xxx.yyyy.zzzz", http.StatusUnauthorized},
+		{"Should not create a recipe", http.MethodPost, "/api/recipes/", "", http.StatusUnauthorized},
+		{"Should not delete a recipe", http.MethodDelete, fmt.Sprintf("/api/recipes/%d", publicID), "",
+			http.StatusUnauthorized},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(`{}`))
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
 			if rr.Code != tc.expectedCode {
 				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
 			}
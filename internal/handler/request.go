@@ -7,14 +7,156 @@ type RecipesRequest struct {
 	Page        uint64   `schema:"page" validate:"omitempty,min=1"`
 	Term        string   `schema:"term" validate:"omitempty,min=3"`
 	Ingredients []string `schema:"ingredient" validate:"omitempty,max=5"`
+	Lang        string   `schema:"lang" validate:"max=16"`
+	// MaxTotalTime in minutes
+	MaxTotalTime uint64 `schema:"maxTotalTime" validate:"omitempty,min=1,max=10080"`
+	Difficulty   string `schema:"difficulty" validate:"omitempty,oneof=easy medium hard"`
+	// Tags are kind:name pairs like cuisine:greek, names without a known kind are free tags. Recipes need all of the
+	// tags unless tagMatch is any.
+	Tags     []string `schema:"tag" validate:"omitempty,max=10,dive,min=1,max=80"`
+	TagMatch string   `schema:"tagMatch" validate:"omitempty,oneof=all any"`
+	Diet     string   `schema:"diet" validate:"omitempty,oneof=vegan vegetarian gluten-free keto"`
+	// MinCalories and MaxCalories per serving in kcal, recipes without complete nutrition facts are left out
+	MinCalories float64 `schema:"minCalories" validate:"omitempty,min=0,max=10000"`
+	MaxCalories float64 `schema:"maxCalories" validate:"omitempty,min=0,max=10000,gtefield=MinCalories"`
+}
+
+// PageRequest object to map incoming request for paginated handlers without filters
+type PageRequest struct {
+	Page uint64 `schema:"page" validate:"omitempty,min=1"`
+	Lang string `schema:"lang" validate:"max=16"`
+}
+
+// VerifyEmailRequest object to map incoming request for VerifyEmail handler
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required,max=1024"`
+}
+
+// VerifyEmailResendRequest object to map incoming request for VerifyEmailResend handler
+type VerifyEmailResendRequest struct {
+	Email string `json:"email" validate:"required,email,max=128"`
+}
+
+// PasswordForgotRequest object to map incoming request for PasswordForgot handler
+type PasswordForgotRequest struct {
+	Email string `json:"email" validate:"required,email,max=128"`
+}
+
+// PasswordResetRequest object to map incoming request for PasswordReset handler
+type PasswordResetRequest struct {
+	Token          string `json:"token" validate:"required,len=64"`
+	Password       string `json:"password" validate:"required,min=8,max=32"`
+	RepeatPassword string `json:"repeatPassword" validate:"eqfield=Password"`
+}
+
+// UserUpdateRequest object to map incoming request for UserUpdate handler, omitted fields are left unchanged
+type UserUpdateRequest struct {
+	FullName    *string             `json:"fullName" validate:"omitempty,max=128"`
+	Preferences *PreferencesRequest `json:"preferences"`
+}
+
+// PreferencesRequest object to map user preferences, preferences are replaced as a whole
+type PreferencesRequest struct {
+	Locale string `json:"locale" validate:"max=16"`
+	Diet   string `json:"diet" validate:"omitempty,oneof=vegan vegetarian gluten-free keto"`
+}
+
+// PasswordChangeRequest object to map incoming request for PasswordChange handler
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required,max=32"`
+	Password        string `json:"password" validate:"required,min=8,max=32"`
+	RepeatPassword  string `json:"repeatPassword" validate:"eqfield=Password"`
+}
+
+// EmailChangeRequest object to map incoming request for EmailChange handler
+type EmailChangeRequest struct {
+	Email    string `json:"email" validate:"required,email,max=128"`
+	Password string `json:"password" validate:"required,max=32"`
+}
+
+// EmailConfirmRequest object to map incoming request for EmailConfirm handler
+type EmailConfirmRequest struct {
+	Token string `json:"token" validate:"required,max=1024"`
+}
+
+// AccountDeleteRequest object to map incoming request for AccountDelete handler
+type AccountDeleteRequest struct {
+	Password string `json:"password" validate:"required,max=32"`
+}
+
+// APIKeyCreateRequest object to map incoming request for APIKeyCreate handler, expiresIn is the lifetime of the key
+// in days and keys without it never expire
+type APIKeyCreateRequest struct {
+	Name      string   `json:"name" validate:"required,max=64"`
+	Scopes    []string `json:"scopes" validate:"required,min=1,dive,oneof=recipes:read recipes:write"`
+	ExpiresIn int64    `json:"expiresIn" validate:"omitempty,min=1,max=3650"`
+}
+
+// UsersRequest object to map incoming request for Users handler
+type UsersRequest struct {
+	Page   uint64 `schema:"page" validate:"omitempty,min=1"`
+	Term   string `schema:"term" validate:"max=128"`
+	Role   string `schema:"role" validate:"omitempty,oneof=user editor moderator admin"`
+	Active *bool  `schema:"active"`
+	Locked *bool  `schema:"locked"`
+}
+
+// UserRoleRequest object to map incoming request for UserRole handler
+type UserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user editor moderator admin"`
+}
+
+// IngredientsRequest object to map incoming request for Ingredients handler
+type IngredientsRequest struct {
+	Prefix string `schema:"prefix" validate:"required,min=1,max=64"`
+	Limit  int    `schema:"limit" validate:"min=1,max=50"`
 }
 
 // CreateRecipeRequest object to map incoming request for Create handler
 type RecipeCreateRequest struct {
-	Title       string   `json:"title" validate:"required,min=2"`
-	URL         string   `json:"url" validate:"required,min=10"`
-	Thumbnail   string   `json:"thumbnail"`
-	Ingredients []string `json:"ingredients" validate:"required,max=30,min=1"`
+	Title       string   `json:"title" yaml:"title" validate:"required,min=2"`
+	URL         string   `json:"url" yaml:"url" validate:"required,min=10"`
+	Thumbnail   string   `json:"thumbnail" yaml:"thumbnail"`
+	Ingredients []string `json:"ingredients" yaml:"ingredients" validate:"required,max=30,min=1"`
+	Submit      bool     `json:"submit" yaml:"submit"`
+	Visibility  string   `json:"visibility" yaml:"visibility" validate:"omitempty,oneof=public unlisted private"`
+	Locale      string   `json:"locale" yaml:"locale" validate:"max=16"`
+	// PrepTime, CookTime and TotalTime in minutes, the total time defaults to prep plus cook time
+	PrepTime  int64  `json:"prepTime" yaml:"prepTime" validate:"min=0,max=10080"`
+	CookTime  int64  `json:"cookTime" yaml:"cookTime" validate:"min=0,max=10080"`
+	TotalTime int64  `json:"totalTime" yaml:"totalTime" validate:"min=0,max=10080"`
+	Servings  int64  `json:"servings" yaml:"servings" validate:"min=0,max=1000"`
+	Yield     string `json:"yield" yaml:"yield" validate:"max=64"`
+	// Difficulty is estimated when it is not given
+	Difficulty string       `json:"difficulty" yaml:"difficulty" validate:"omitempty,oneof=easy medium hard"`
+	Tags       []TagRequest `json:"tags" yaml:"tags" validate:"max=20,dive"`
+}
+
+// TagRequest object to map a recipe tag
+type TagRequest struct {
+	Kind string `json:"kind" yaml:"kind" validate:"required,oneof=cuisine course diet occasion tag"`
+	Name string `json:"name" yaml:"name" validate:"required,min=1,max=64"`
+}
+
+// RecipeTagsRequest object to map incoming request for RecipeTags handler, the tags replace the current ones
+type RecipeTagsRequest struct {
+	Tags []TagRequest `json:"tags" validate:"max=20,dive"`
+}
+
+// TagsRequest object to map incoming request for Tags handler
+type TagsRequest struct {
+	Kind string `schema:"kind" validate:"omitempty,oneof=cuisine course diet occasion tag"`
+}
+
+// RecipeCreateQuery object to map query parameters of Create and RecipeImport handlers, force skips near duplicate
+// detection
+type RecipeCreateQuery struct {
+	Force bool `schema:"force"`
+}
+
+// RecipeImportRequest object to map a recipes file, accepts both JSON and YAML documents
+type RecipeImportRequest struct {
+	Recipes []RecipeCreateRequest `json:"recipes" yaml:"recipes" validate:"required,min=1,max=100,dive"`
 }
 
 // SignUpRequest object to map sign up incoming request
@@ -23,6 +165,16 @@ type SignInRequest struct {
 	Password string `json:"password" validate:"required,min=1,max=32"`
 }
 
+// TokenRefreshRequest object to map incoming request for TokenRefresh handler
+type TokenRefreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required,len=64"`
+}
+
+// LogoutRequest object to map incoming request for Logout handler
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"omitempty,len=64"`
+}
+
 // SignUpRequest object to map sign up incoming request
 type SignUpRequest struct {
 	Email          string `json:"email" validate:"required,email"`
@@ -32,9 +184,159 @@ type SignUpRequest struct {
 	RepeatPassword string `json:"repeatPassword" validate:"eqfield=Password"`
 }
 
+// TaxonomyRequest object to map incoming request for taxonomy create and update handlers
+type TaxonomyRequest struct {
+	ParentID int64  `json:"parentId" validate:"omitempty,min=1"`
+	Name     string `json:"name" validate:"required,min=2,max=128"`
+	Allergen string `json:"allergen" validate:"max=64"`
+	Aisle    string `json:"aisle" validate:"max=64"`
+}
+
+// TaxonomyImportRequest object to map a taxonomy file, YAML is a superset of JSON so both formats are accepted
+type TaxonomyImportRequest struct {
+	Taxonomy []TaxonomyNodeRequest `json:"taxonomy" yaml:"taxonomy" validate:"required,min=1,dive"`
+}
+
+// TaxonomyNodeRequest object to map a single node of a taxonomy file
+type TaxonomyNodeRequest struct {
+	Name     string                `json:"name" yaml:"name" validate:"required,min=2,max=128"`
+	Allergen string                `json:"allergen" yaml:"allergen" validate:"max=64"`
+	Aisle    string                `json:"aisle" yaml:"aisle" validate:"max=64"`
+	Children []TaxonomyNodeRequest `json:"children" yaml:"children" validate:"dive"`
+}
+
+// SubstitutionRequest object to map incoming request for substitution create and update handlers, a substitution
+// without substitutes means that the ingredient can be omitted
+type SubstitutionRequest struct {
+	Ingredient  string   `json:"ingredient" yaml:"ingredient" validate:"required,min=2,max=128"`
+	Substitutes []string `json:"substitutes" yaml:"substitutes" validate:"max=10,dive,required,max=128"`
+	Notes       string   `json:"notes" yaml:"notes" validate:"max=512"`
+}
+
+// SubstitutionImportRequest object to map a substitutions file, accepts both JSON and YAML documents
+type SubstitutionImportRequest struct {
+	Substitutions []SubstitutionRequest `json:"substitutions" yaml:"substitutions" validate:"required,min=1,dive"`
+}
+
+// DietOverrideRequest object to map incoming request for RecipeDietOverride handler, label says whether the recipe
+// fits the diet whatever its ingredients say
+type DietOverrideRequest struct {
+	Label *bool  `json:"label" validate:"required"`
+	Note  string `json:"note" validate:"max=256"`
+}
+
+// DietAttributeRequest object to map the diet attributes of a single ingredient
+type DietAttributeRequest struct {
+	Ingredient string   `json:"ingredient" yaml:"ingredient" validate:"required,min=2,max=128"`
+	Attributes []string `json:"attributes" yaml:"attributes" validate:"max=8,dive,oneof=meat fish dairy egg honey gluten sugar starch"`
+}
+
+// DietAttributesImportRequest object to map a diet attributes file, accepts both JSON and YAML documents
+type DietAttributesImportRequest struct {
+	Attributes []DietAttributeRequest `json:"attributes" yaml:"attributes" validate:"required,min=1,dive"`
+}
+
+// NutrientRequest object to map the nutrition facts of 100g of an ingredient, a single row of a nutrients file
+type NutrientRequest struct {
+	Ingredient string  `json:"ingredient" validate:"required,min=2,max=128"`
+	Calories   float64 `json:"calories" validate:"min=0,max=900"`
+	Protein    float64 `json:"protein" validate:"min=0,max=100"`
+	Fat        float64 `json:"fat" validate:"min=0,max=100"`
+	Carbs      float64 `json:"carbs" validate:"min=0,max=100"`
+	Fiber      float64 `json:"fiber" validate:"min=0,max=100"`
+	// Sodium in mg
+	Sodium float64 `json:"sodium" validate:"min=0,max=100000"`
+}
+
+// NutrientsImportRequest object to map a nutrients CSV file
+type NutrientsImportRequest struct {
+	Nutrients []NutrientRequest `json:"nutrients" validate:"required,min=1,dive"`
+}
+
+// RecipeSubstitutionsRequest object to map incoming request for RecipeSubstitutions handler
+type RecipeSubstitutionsRequest struct {
+	Missing []string `schema:"missing" validate:"omitempty,max=10"`
+	Pantry  []string `schema:"pantry" validate:"omitempty,max=50"`
+}
+
+// PantryRecipesRequest object to map incoming request for PantryRecipes handler, MaxMissing is the number of
+// ingredients a recipe may need on top of the pantry and its substitutions
+type PantryRecipesRequest struct {
+	Page       uint64   `schema:"page" validate:"omitempty,min=1"`
+	Lang       string   `schema:"lang" validate:"max=16"`
+	Pantry     []string `schema:"pantry" validate:"required,min=1,max=50,dive,min=1,max=128"`
+	MaxMissing int      `schema:"maxMissing" validate:"min=0,max=10"`
+}
+
+// RecipeRejectRequest object to map incoming request for RecipeReject handler
+type RecipeRejectRequest struct {
+	Reason string `json:"reason" validate:"required,min=3,max=512"`
+}
+
+// RecipeVisibilityRequest object to map incoming request for RecipeVisibility handler
+type RecipeVisibilityRequest struct {
+	Visibility string `json:"visibility" validate:"required,oneof=public unlisted private"`
+}
+
+// ShareCreateRequest object to map incoming request for ShareCreate handler, ExpiresIn is in hours and defaults to
+// the configured share ttl
+type ShareCreateRequest struct {
+	ExpiresIn int64 `json:"expiresIn" validate:"omitempty,min=1,max=8760"`
+}
+
+// TranslationRequest object to map incoming request for TranslationSave handler, ingredients maps ingredient ids to
+// their translated names
+type TranslationRequest struct {
+	Title       string           `json:"title" validate:"required,min=2,max=256"`
+	Ingredients map[int64]string `json:"ingredients" validate:"max=30,dive,required,max=128"`
+}
+
 // Token object to map incoming authorization bearer token
 type Token struct {
 	UserID   int64  `json:"uid"`
 	Username string `json:"uname"`
+	Role     string `json:"role"`
+	// Scopes limits callers authenticated with an API key, it is empty for access tokens
+	Scopes []string `json:"scopes,omitempty"`
+	jwt.StandardClaims
+}
+
+// VerifyToken object to map the token of an email verification link
+type VerifyToken struct {
+	UserID int64  `json:"vuid"`
+	Email  string `json:"vemail"`
+	jwt.StandardClaims
+}
+
+// EmailChangeToken object to map the token of an email change confirmation link
+type EmailChangeToken struct {
+	UserID int64  `json:"ecuid"`
+	Email  string `json:"ecemail"`
+	jwt.StandardClaims
+}
+
+// IDToken object to map the ID token an identity provider returns for a sign in
+type IDToken struct {
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Audience          audience `json:"aud"`
+	jwt.StandardClaims
+}
+
+// OIDCCallbackRequest object to map the query parameters an identity provider redirects to OIDCCallback with,
+// providers send error instead of code when the user did not sign in
+type OIDCCallbackRequest struct {
+	Code  string `schema:"code"`
+	State string `schema:"state" validate:"required,max=64"`
+	Error string `schema:"error"`
+}
+
+// ShareToken object to map the share query parameter of a recipe share link
+type ShareToken struct {
+	ShareID  int64 `json:"sid"`
+	RecipeID int64 `json:"rid"`
 	jwt.StandardClaims
 }
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/sirupsen/logrus" //nolint:depguard
 )
@@ -19,22 +22,81 @@ func (h *Handler) respond(w http.ResponseWriter, data interface{}, statusCode in
 	w.WriteHeader(statusCode)
 
 	// Send the result back to the client.
+	// The status code is already sent so the error can only be logged
 	if err := json.NewEncoder(w).Encode(&data); err != nil {
-		h.respondError(w, err)
+		h.log.Error(err)
 	}
 }
 
-func (h *Handler) respondError(w http.ResponseWriter, err error) {
+// respondCacheable responds with status 200 and allows clients to cache the response, responses are tagged with an
+// ETag so clients can revalidate them using If-None-Match without receiving the body again. Responses to signed in
+// callers depend on the caller and may only be kept by the client itself.
+func (h *Handler) respondCacheable(w http.ResponseWriter, r *http.Request, data interface{}) {
+	b, err := json.Marshal(&data)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	scope := "public"
+	if h.caller(r) != nil {
+		scope = "private"
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(b))
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, h.cfg.Cache.MaxAge))
+	w.Header().Add("Vary", "Authorization, X-API-Key")
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(b); err != nil {
+		h.log.Error(err)
+	}
+}
+
+// etagMatches reports whether the If-None-Match header matches etag. The header is * or a comma separated list of
+// tags, If-None-Match compares tags weakly so W/ tags match as well
+func etagMatches(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// respondError sends an error to the client, messages of api errors are localized to the language of the request and
+// keep their stable code so clients can branch on it
+func (h *Handler) respondError(w http.ResponseWriter, r *http.Request, err error) {
 	er := ErrorResponse{
+		Code:          CodeInternal,
 		Message:       err.Error(),
 		StatusCode:    http.StatusInternalServerError,
 		StatusMessage: http.StatusText(http.StatusInternalServerError),
 	}
 
 	if err, ok := err.(APIError); ok {
-		er.Message = err.Message
+		chain := append(h.localeChain(r), fallbackLocale)
+		er.Code = err.Code
+		er.Message = messages.Message(chain, err.Code, err.Args...)
 		er.StatusCode = err.StatusCode
 		er.StatusMessage = http.StatusText(err.StatusCode)
+
+		for _, fe := range err.Fields {
+			er.Fields = append(er.Fields, FieldErrorResponse{
+				Field:   fe.Field(),
+				Code:    fe.Tag(),
+				Message: fieldMessage(chain, fe),
+			})
+		}
+		w.Header().Add("Vary", "Accept-Language")
 	}
 
 	h.log.WithFields(logrus.Fields{
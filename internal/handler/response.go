@@ -18,6 +18,27 @@ type RecipesResponse struct {
 // Metadata
 type Metadata struct {
 	Total int64
+	// Facets counts the recipes of the whole result set by tag, keyed by tag kind
+	Facets FacetsResponse `json:",omitempty"`
+}
+
+// FacetsResponse object to map tag facets by tag kind
+type FacetsResponse map[string][]FacetResponseItem
+
+// FacetResponseItem object to map the number of recipes that have a tag
+type FacetResponseItem struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// NewFacetsResponse creates a new FacetsResponse object
+func NewFacetsResponse(facets database.Facets) FacetsResponse {
+	resp := FacetsResponse{}
+	for i := range facets {
+		resp[facets[i].Kind] = append(resp[facets[i].Kind], FacetResponseItem{Name: facets[i].Name, Count: facets[i].Count})
+	}
+
+	return resp
 }
 
 // RecipeResponseItem object to map recipe items
@@ -29,9 +50,125 @@ type RecipeResponseItem struct {
 	Title       string             `json:"title"`
 	Href        string             `json:"href"`
 	Ingredients IngredientResponse `json:"ingredients"`
+	Tags        TagResponse        `json:"tags,omitempty"`
 	Thumbnail   string             `json:"thumbnail"`
+	Views       int64              `json:"views"`
+	UserID      int64              `json:"userId"`
+	Status      string             `json:"status"`
+	Visibility  string             `json:"visibility"`
+	Locale      string             `json:"locale"`
+	PrepTime    int64              `json:"prepTime"`
+	CookTime    int64              `json:"cookTime"`
+	TotalTime   int64              `json:"totalTime"`
+	Servings    int64              `json:"servings"`
+	Yield       string             `json:"yield"`
+	Difficulty  string             `json:"difficulty"`
+	ReviewNote  string             `json:"reviewNote"`
 	CreatedAt   string             `json:"createdAt"`
 	UpdatedAt   string             `json:"updatedAt"`
+	DeletedAt   string             `json:"deletedAt,omitempty"`
+	// Diets are the effective labels, dietOverrides shows which of them the author set by hand
+	Diets         []string             `json:"diets,omitempty"`
+	DietOverrides DietOverrideResponse `json:"dietOverrides,omitempty"`
+}
+
+// DietOverrideResponse object to map the diet overrides of a recipe
+type DietOverrideResponse []DietOverrideResponseItem
+
+// DietOverrideResponseItem object to map a diet label set by the recipe author, automatic is the label the
+// ingredients give
+type DietOverrideResponseItem struct {
+	RecipeID  int64  `json:"recipeId"`
+	Diet      string `json:"diet"`
+	Label     bool   `json:"label"`
+	Automatic bool   `json:"automatic"`
+	UserID    int64  `json:"userId"`
+	Note      string `json:"note"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// DietAttributesResponse object to map the ingredient to diet attribute table
+type DietAttributesResponse struct {
+	Data []DietAttributeResponseItem `json:"data"`
+}
+
+// DietAttributeResponseItem object to map the diet attributes of a single ingredient
+type DietAttributeResponseItem struct {
+	Ingredient string   `json:"ingredient"`
+	Attributes []string `json:"attributes"`
+}
+
+// DietAttributesImportResponse object to map diet attributes import response
+type DietAttributesImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// NutrientsResponse object to map the nutrient database
+type NutrientsResponse struct {
+	Data []NutrientResponseItem `json:"data"`
+}
+
+// NutrientResponseItem object to map the nutrition facts of 100g of an ingredient, sodium is in mg
+type NutrientResponseItem struct {
+	Ingredient string  `json:"ingredient"`
+	Calories   float64 `json:"calories"`
+	Protein    float64 `json:"protein"`
+	Fat        float64 `json:"fat"`
+	Carbs      float64 `json:"carbs"`
+	Fiber      float64 `json:"fiber"`
+	Sodium     float64 `json:"sodium"`
+}
+
+// NutrientsImportResponse object to map nutrients import response
+type NutrientsImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// RecipeNutritionResponse object to map recipe nutrition response. Total sums the ingredients that were matched and
+// weighed, perServing divides it by the servings of the recipe. Unmatched lists the ingredients the nutrient
+// database has no facts for and unquantified the ones without a weighable quantity, complete is false when either
+// has entries.
+type RecipeNutritionResponse struct {
+	Data         []RecipeNutritionResponseItem `json:"data"`
+	Servings     int64                         `json:"servings"`
+	Total        NutrientResponseItem          `json:"total"`
+	PerServing   *NutrientResponseItem         `json:"perServing,omitempty"`
+	Complete     bool                          `json:"complete"`
+	Unmatched    []string                      `json:"unmatched"`
+	Unquantified []string                      `json:"unquantified"`
+}
+
+// RecipeNutritionResponseItem object to map the nutrition facts of a single recipe ingredient, per100g.ingredient is
+// the canonical ingredient the facts belong to and total the facts of the quantity the recipe uses
+type RecipeNutritionResponseItem struct {
+	Ingredient string                     `json:"ingredient"`
+	Quantity   IngredientQuantityResponse `json:"quantity"`
+	Matched    bool                       `json:"matched"`
+	Per100g    *NutrientResponseItem      `json:"per100g,omitempty"`
+	Total      *NutrientResponseItem      `json:"total,omitempty"`
+}
+
+// IngredientQuantityResponse object to map the quantity parsed from an ingredient line, grams is zero when the
+// quantity can not be weighed
+type IngredientQuantityResponse struct {
+	Amount float64 `json:"amount"`
+	Unit   string  `json:"unit"`
+	Name   string  `json:"name"`
+	Grams  float64 `json:"grams"`
+}
+
+// TagResponse object to map tags
+type TagResponse []TagResponseItem
+
+// TagResponseItem object to map a single tag
+type TagResponseItem struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// TagsResponse object to map a list of tags
+type TagsResponse struct {
+	Data TagResponse `json:"data"`
 }
 
 // IngredientResponseItem object to map single ingredient
@@ -43,6 +180,120 @@ type IngredientResponseItem struct {
 // IngredientResponseItem object to map slice of ingredients
 type IngredientResponse []IngredientResponseItem
 
+// IngredientSuggestionsResponse object to map ingredient autocomplete response
+type IngredientSuggestionsResponse struct {
+	Data []IngredientSuggestion `json:"data"`
+}
+
+// IngredientSuggestion object to map a suggested ingredient name and the number of recipes using it
+type IngredientSuggestion struct {
+	Name    string `json:"name"`
+	Recipes int64  `json:"recipes"`
+}
+
+// TaxonomyResponse object to map taxonomy list response
+type TaxonomyResponse struct {
+	Data TaxonomyResponseItems `json:"data"`
+}
+
+// TaxonomyResponseItems object to map taxonomy nodes
+type TaxonomyResponseItems []TaxonomyResponseItem
+
+// TaxonomyResponseItem object to map a single taxonomy node
+type TaxonomyResponseItem struct {
+	ID        int64  `json:"id"`
+	ParentID  int64  `json:"parentId"`
+	Name      string `json:"name"`
+	Allergen  string `json:"allergen"`
+	Aisle     string `json:"aisle"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// TaxonomyImportResponse object to map taxonomy import response
+type TaxonomyImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// SubstitutionResponse object to map substitution list response
+type SubstitutionResponse struct {
+	Data SubstitutionResponseItems `json:"data"`
+}
+
+// SubstitutionResponseItems object to map substitutions
+type SubstitutionResponseItems []SubstitutionResponseItem
+
+// SubstitutionResponseItem object to map a single substitution
+type SubstitutionResponseItem struct {
+	ID          int64    `json:"id"`
+	Ingredient  string   `json:"ingredient"`
+	Substitutes []string `json:"substitutes"`
+	Notes       string   `json:"notes"`
+	CreatedAt   string   `json:"createdAt"`
+	UpdatedAt   string   `json:"updatedAt"`
+}
+
+// NewSubstitutionResponseItem creates a new SubstitutionResponseItem object
+func NewSubstitutionResponseItem(s database.Substitution) SubstitutionResponseItem {
+	item := SubstitutionResponseItem{
+		ID:          s.ID,
+		Ingredient:  s.Ingredient,
+		Substitutes: s.Substitutes,
+		Notes:       s.Notes,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+	if item.Substitutes == nil {
+		item.Substitutes = []string{}
+	}
+
+	return item
+}
+
+// SubstitutionImportResponse object to map substitution import response
+type SubstitutionImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// RecipeSubstitutionsResponse object to map recipe substitutions response
+type RecipeSubstitutionsResponse struct {
+	Data []RecipeSubstitutionsResponseItem `json:"data"`
+}
+
+// RecipeSubstitutionsResponseItem object to map the substitutions of a single missing ingredient, when a pantry
+// is given CoveredBy holds the id of the substitution that can be made with it
+type RecipeSubstitutionsResponseItem struct {
+	Ingredient    string                    `json:"ingredient"`
+	Covered       bool                      `json:"covered"`
+	CoveredBy     int64                     `json:"coveredBy,omitempty"`
+	Substitutions SubstitutionResponseItems `json:"substitutions"`
+}
+
+// PantryRecipesResponse object to map a page of recipes found by a pantry search
+type PantryRecipesResponse struct {
+	Data     []PantryRecipeResponseItem `json:"data"`
+	Metadata Metadata                   `json:"metadata"`
+}
+
+// PantryRecipeResponseItem object to map a recipe found by a pantry search, Missing lists the ingredients the pantry
+// lacks and Substitutions the substitutions that cover the others
+type PantryRecipeResponseItem struct {
+	Recipe        RecipeResponseItem        `json:"recipe"`
+	Missing       []string                  `json:"missing"`
+	Substitutions SubstitutionResponseItems `json:"substitutions"`
+}
+
+// IngredientGroupResponse object to map recipe ingredients grouped by a taxonomy attribute
+type IngredientGroupResponse struct {
+	Data []IngredientGroupResponseItem `json:"data"`
+}
+
+// IngredientGroupResponseItem object to map a single group of ingredients
+type IngredientGroupResponseItem struct {
+	Group       string   `json:"group"`
+	Ingredients []string `json:"ingredients"`
+}
+
 // UserProfileResponse object to map user profile response
 type UserProfileResponse struct {
 	ID        int64
@@ -50,8 +301,23 @@ type UserProfileResponse struct {
 	FullName  string `json:"fullName"`
 	Email     string `json:"email"`
 	Active    bool   `json:"active"`
+	Role      string `json:"role"`
+	Verified  bool   `json:"verified"`
 	CreatedAt string `json:"createdAt"`
 	UpdatedAt string `json:"updatedAt"`
+	DeletedAt string `json:"deletedAt,omitempty"`
+
+	// PendingEmail is the address the user asked to switch to and has not confirmed yet
+	PendingEmail string              `json:"pendingEmail,omitempty"`
+	Preferences  PreferencesResponse `json:"preferences"`
+	// LockedUntil is when the sign in lockout of the user ends, omitted when the user is not locked
+	LockedUntil string `json:"lockedUntil,omitempty"`
+}
+
+// PreferencesResponse object to map user preferences
+type PreferencesResponse struct {
+	Locale string `json:"locale,omitempty"`
+	Diet   string `json:"diet,omitempty"`
 }
 
 // NewUserProfileResponse creates a new UserProfileResponse object
@@ -62,21 +328,201 @@ func NewUserProfileResponse(u database.User) UserProfileResponse {
 		FullName:  u.FullName,
 		Email:     u.Email,
 		Active:    u.Active,
+		Role:      u.Role,
+		Verified:  u.VerifiedAt != "",
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
+		DeletedAt: u.DeletedAt,
+
+		PendingEmail: u.PendingEmail,
+		Preferences:  PreferencesResponse{Locale: u.Preferences.Locale, Diet: u.Preferences.Diet},
+		LockedUntil:  u.LockedUntil,
+	}
+}
+
+// UsersResponse object to map a list of users, paginated lists carry the total number of matching users
+type UsersResponse struct {
+	Data     []UserProfileResponse `json:"data"`
+	Metadata *Metadata             `json:"metadata,omitempty"`
+}
+
+// ExportResponse object to map the personal data export of a user
+type ExportResponse struct {
+	ExportedAt    string                    `json:"exportedAt"`
+	Profile       UserProfileResponse       `json:"profile"`
+	Recipes       []RecipeResponseItem      `json:"recipes"`
+	Translations  []TranslationResponseItem `json:"translations"`
+	DietOverrides DietOverrideResponse      `json:"dietOverrides"`
+	Shares        []ShareResponseItem       `json:"shares"`
+	APIKeys       []APIKeyResponseItem      `json:"apiKeys"`
+	Identities    []IdentityResponseItem    `json:"identities"`
+}
+
+// IdentityResponseItem object to map an identity provider account linked to a user
+type IdentityResponseItem struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// AccountDeleteResponse object to map a scheduled account deletion
+type AccountDeleteResponse struct {
+	EraseAt string `json:"eraseAt"`
+}
+
+// APIKeysResponse object to map the API keys of a user
+type APIKeysResponse struct {
+	Data []APIKeyResponseItem `json:"data"`
+}
+
+// APIKeyResponseItem object to map a single API key, the key is only returned when it is created
+type APIKeyResponseItem struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Key        string   `json:"key,omitempty"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	LastUsedAt string   `json:"lastUsedAt,omitempty"`
+	RevokedAt  string   `json:"revokedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+// NewAPIKeyResponseItem creates a new APIKeyResponseItem object
+func NewAPIKeyResponseItem(k database.APIKey) APIKeyResponseItem {
+	return APIKeyResponseItem{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
 	}
 }
 
+// SharesResponse object to map the share links of a recipe
+type SharesResponse struct {
+	Data []ShareResponseItem `json:"data"`
+}
+
+// ShareResponseItem object to map a single share link, the token is only returned when the link is created
+type ShareResponseItem struct {
+	ID        int64  `json:"id"`
+	RecipeID  int64  `json:"recipeId"`
+	Token     string `json:"token,omitempty"`
+	Href      string `json:"href,omitempty"`
+	ExpiresAt string `json:"expiresAt"`
+	RevokedAt string `json:"revokedAt,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// NewShareResponseItem creates a new ShareResponseItem object
+func NewShareResponseItem(s database.Share) ShareResponseItem {
+	return ShareResponseItem{
+		ID:        s.ID,
+		RecipeID:  s.RecipeID,
+		ExpiresAt: s.ExpiresAt,
+		RevokedAt: s.RevokedAt,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// TranslationsResponse object to map the translations of a recipe
+type TranslationsResponse struct {
+	Data []TranslationResponseItem `json:"data"`
+}
+
+// TranslationResponseItem object to map a single translation, ingredients maps ingredient ids to translated names
+type TranslationResponseItem struct {
+	RecipeID    int64            `json:"recipeId"`
+	Locale      string           `json:"locale"`
+	Title       string           `json:"title"`
+	Ingredients map[int64]string `json:"ingredients"`
+	CreatedAt   string           `json:"createdAt"`
+	UpdatedAt   string           `json:"updatedAt"`
+}
+
+// NewTranslationResponseItem creates a new TranslationResponseItem object
+func NewTranslationResponseItem(t database.Translation) TranslationResponseItem {
+	item := TranslationResponseItem{
+		RecipeID:    t.RecipeID,
+		Locale:      t.Locale,
+		Title:       t.Title,
+		Ingredients: t.Ingredients,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+	if item.Ingredients == nil {
+		item.Ingredients = map[int64]string{}
+	}
+
+	return item
+}
+
 // TokenResponse map token response
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn int64 `json:"expiresIn"`
+}
+
+// DuplicateRecipeResponse object to map a create request that was rejected as a near duplicate
+type DuplicateRecipeResponse struct {
+	ErrorResponse
+	Duplicates DuplicateResponseItems `json:"duplicates"`
 }
 
-// ErrorResponse object to map error response
+// DuplicateResponseItems object to map near duplicate recipes
+type DuplicateResponseItems []DuplicateResponseItem
+
+// DuplicateResponseItem object to map a near duplicate recipe and the reasons it was matched
+type DuplicateResponseItem struct {
+	Recipe            RecipeResponseItem `json:"recipe"`
+	Reasons           []string           `json:"reasons"`
+	IngredientOverlap float64            `json:"ingredientOverlap"`
+}
+
+// RecipeImportResponse object to map the result of a recipe import, Imported lists the ids of the created recipes
+type RecipeImportResponse struct {
+	Imported []int64                   `json:"imported"`
+	Skipped  []RecipeImportSkippedItem `json:"skipped"`
+}
+
+// RecipeImportSkippedItem object to map a recipe that was not imported, Index is its position in the document and
+// Code the reason, near duplicates list the recipes they were matched with
+type RecipeImportSkippedItem struct {
+	Index      int                    `json:"index"`
+	Title      string                 `json:"title"`
+	Code       string                 `json:"code"`
+	Duplicates DuplicateResponseItems `json:"duplicates"`
+}
+
+// DuplicateClustersResponse object to map a page of the duplicate report, each cluster is a group of near duplicate
+// recipes
+type DuplicateClustersResponse struct {
+	Data     []RecipeResponseItems `json:"data"`
+	Metadata Metadata              `json:"metadata"`
+}
+
+// ErrorResponse object to map error response, code is a stable identifier of the error and error is its message in
+// the language of the request
 type ErrorResponse struct {
-	Message       string `json:"error"`
-	StatusCode    int    `json:"statusCode"`
-	StatusMessage string `json:"statusMessage"`
+	Code          string               `json:"code"`
+	Message       string               `json:"error"`
+	StatusCode    int                  `json:"statusCode"`
+	StatusMessage string               `json:"statusMessage"`
+	Fields        []FieldErrorResponse `json:"fields,omitempty"`
+}
+
+// FieldErrorResponse object to map a request field that failed validation, code is the failed validation rule
+type FieldErrorResponse struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 // EncodeEntity generic function to map a database entity to a response object
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	_ "github.com/georlav/recipeapi/api/swagger"
+	"github.com/georlav/recipeapi/internal/database"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -21,10 +22,50 @@ func Routes(h *Handler) *chi.Mux {
 
 	// Recipe routes
 	r.Route("/recipes", func(r chi.Router) {
-		r.Use(h.AuthorizationMiddleware)
-		r.Get("/{id:[0-9]+}", h.Recipe)
-		r.Get("/", h.Recipes)
-		r.Post("/", h.Create)
+		r.Use(h.APIKeyScopes(database.ScopeRecipesRead, database.ScopeRecipesWrite))
+
+		// Public, anonymous callers only see published public recipes
+		r.Group(func(r chi.Router) {
+			r.Use(h.OptionalAuthorizationMiddleware, h.RateLimitMiddleware)
+			r.Get("/{id:[0-9]+}", h.Recipe)
+			r.Get("/{id:[0-9]+}/allergens", h.RecipeAllergens)
+			r.Get("/{id:[0-9]+}/shopping-list", h.RecipeShoppingList)
+			r.Get("/{id:[0-9]+}/substitutions", h.RecipeSubstitutions)
+			r.Get("/{id:[0-9]+}/nutrition", h.RecipeNutrition)
+			r.Get("/trending", h.Trending)
+			r.Get("/popular", h.Popular)
+			r.Get("/pantry", h.PantryRecipes)
+			r.Get("/tags", h.Tags)
+			r.Get("/", h.Recipes)
+		})
+
+		// Need authentication
+		r.Group(func(r chi.Router) {
+			r.Use(h.AuthorizationMiddleware, h.RateLimitMiddleware)
+			r.Delete("/{id:[0-9]+}", h.RecipeDelete)
+			r.Post("/{id:[0-9]+}/submit", h.RecipeSubmit)
+			r.Post("/{id:[0-9]+}/reopen", h.RecipeReopen)
+			r.Put("/{id:[0-9]+}/visibility", h.RecipeVisibility)
+			r.Put("/{id:[0-9]+}/tags", h.RecipeTags)
+			r.Put("/{id:[0-9]+}/diets/{diet:[a-z-]+}", h.RecipeDietOverride)
+			r.Delete("/{id:[0-9]+}/diets/{diet:[a-z-]+}", h.RecipeDietReset)
+			r.Get("/{id:[0-9]+}/shares", h.Shares)
+			r.Post("/{id:[0-9]+}/shares", h.ShareCreate)
+			r.Delete("/{id:[0-9]+}/shares/{shareId:[0-9]+}", h.ShareRevoke)
+			r.Get("/{id:[0-9]+}/translations", h.Translations)
+			r.Put("/{id:[0-9]+}/translations/{locale:[a-zA-Z_-]+}", h.TranslationSave)
+			r.Delete("/{id:[0-9]+}/translations/{locale:[a-zA-Z_-]+}", h.TranslationDelete)
+			r.Post("/import", h.RecipeImport)
+			r.Post("/", h.Create)
+		})
+	})
+
+	// Ingredient routes, public like the recipes they belong to
+	r.Route("/ingredients", func(r chi.Router) {
+		r.Use(h.APIKeyScopes(database.ScopeRecipesRead, database.ScopeRecipesWrite))
+		r.Use(h.OptionalAuthorizationMiddleware, h.RateLimitMiddleware)
+		r.Get("/{id:[0-9]+}", h.Ingredient)
+		r.Get("/", h.Ingredients)
 	})
 
 	// User routes
@@ -32,15 +73,89 @@ func Routes(h *Handler) *chi.Mux {
 		// Public
 		r.Post("/signin", h.SignIn)
 		r.Post("/signup", h.SignUp)
+		r.Post("/token/refresh", h.TokenRefresh)
+		r.Post("/verify", h.VerifyEmail)
+		r.Post("/verify/resend", h.VerifyEmailResend)
+		r.Post("/password/forgot", h.PasswordForgot)
+		r.Post("/password/reset", h.PasswordReset)
+		r.Post("/email/confirm", h.EmailConfirm)
+		r.Get("/oidc/{provider:[a-z0-9-]+}/login", h.OIDCLogin)
+		r.Get("/oidc/{provider:[a-z0-9-]+}/callback", h.OIDCCallback)
 
 		// Need authentication
 		r.With(h.AuthorizationMiddleware).Get("/", h.User)
+		r.With(h.AuthorizationMiddleware).Patch("/", h.UserUpdate)
+		r.With(h.AuthorizationMiddleware).Delete("/", h.AccountDelete)
+		r.With(h.AuthorizationMiddleware).Get("/export", h.AccountExport)
+		r.With(h.AuthorizationMiddleware).Put("/password", h.PasswordChange)
+		r.With(h.AuthorizationMiddleware).Post("/email", h.EmailChange)
+		r.With(h.AuthorizationMiddleware).Post("/logout", h.Logout)
+		r.With(h.AuthorizationMiddleware).Get("/keys", h.APIKeys)
+		r.With(h.AuthorizationMiddleware).Post("/keys", h.APIKeyCreate)
+		r.With(h.AuthorizationMiddleware).Delete("/keys/{id:[0-9]+}", h.APIKeyRevoke)
+	})
+
+	// Moderation routes
+	r.Route("/moderation", func(r chi.Router) {
+		r.Use(h.AuthorizationMiddleware, h.RequirePermission(PermModerateRecipes))
+		r.Get("/recipes", h.ModerationQueue)
+		r.Post("/recipes/{id:[0-9]+}/approve", h.RecipeApprove)
+		r.Post("/recipes/{id:[0-9]+}/reject", h.RecipeReject)
+		r.Post("/recipes/{id:[0-9]+}/unpublish", h.RecipeUnpublish)
+	})
+
+	// Admin routes, each group needs the permission of its role
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(h.AuthorizationMiddleware)
+
+		// Reference data recipes are classified with
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequirePermission(PermManageContent))
+			r.Get("/taxonomy", h.Taxonomy)
+			r.Post("/taxonomy", h.TaxonomyCreate)
+			r.Post("/taxonomy/import", h.TaxonomyImport)
+			r.Put("/taxonomy/{id:[0-9]+}", h.TaxonomyUpdate)
+			r.Delete("/taxonomy/{id:[0-9]+}", h.TaxonomyDelete)
+			r.Get("/substitutions", h.Substitutions)
+			r.Post("/substitutions", h.SubstitutionCreate)
+			r.Post("/substitutions/import", h.SubstitutionImport)
+			r.Put("/substitutions/{id:[0-9]+}", h.SubstitutionUpdate)
+			r.Delete("/substitutions/{id:[0-9]+}", h.SubstitutionDelete)
+			r.Get("/diet-attributes", h.DietAttributes)
+			r.Post("/diet-attributes/import", h.DietAttributesImport)
+			r.Get("/nutrients", h.Nutrients)
+			r.Post("/nutrients/import", h.NutrientsImport)
+		})
+
+		// Recipes of any author
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequirePermission(PermModerateRecipes))
+			r.Get("/recipes/duplicates", h.RecipeDuplicates)
+			r.Get("/trash/recipes", h.RecipeTrash)
+			r.Post("/trash/recipes/{id:[0-9]+}/restore", h.RecipeRestore)
+		})
+
+		// User accounts
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequirePermission(PermManageUsers))
+			r.Get("/users", h.Users)
+			r.Put("/users/{id:[0-9]+}/role", h.UserRole)
+			r.Post("/users/{id:[0-9]+}/deactivate", h.UserDeactivate)
+			r.Post("/users/{id:[0-9]+}/activate", h.UserActivate)
+			r.Post("/users/{id:[0-9]+}/unlock", h.UserUnlock)
+			r.Delete("/users/{id:[0-9]+}", h.UserDelete)
+			r.Get("/trash/users", h.UserTrash)
+			r.Post("/trash/users/{id:[0-9]+}/restore", h.UserRestore)
+		})
 	})
 
 	// Swagger Docs
 	rs := chi.NewRouter()
 	rs.Handle("/swagger/*", httpSwagger.Handler())
 
+	// Public keys of access tokens, at the well known location other services look for them
+	rs.With(h.CorsMiddleware, h.ContentTypeMiddleware).Get("/.well-known/jwks.json", h.JWKS)
+
 	// Mount recipes and users under /api and merge with swagger routes
 	rs.Mount("/api", r)
 
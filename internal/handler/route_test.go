@@ -18,12 +18,72 @@ func TestRoutes(t *testing.T) {
 	r := handler.Routes(h)
 
 	expectedRoutes := map[string]struct{}{
-		"/api/recipes/":            {},
-		"/api/recipes/{id:[0-9]+}": {},
-		"/api/user/":               {},
-		"/api/user/signin":         {},
-		"/api/user/signup":         {},
-		"/swagger/*":               {},
+		"/api/admin/recipes/duplicates":                              {},
+		"/api/admin/substitutions":                                   {},
+		"/api/admin/substitutions/import":                            {},
+		"/api/admin/diet-attributes":                                 {},
+		"/api/admin/nutrients":                                       {},
+		"/api/admin/nutrients/import":                                {},
+		"/api/admin/diet-attributes/import":                          {},
+		"/api/admin/substitutions/{id:[0-9]+}":                       {},
+		"/api/admin/taxonomy":                                        {},
+		"/api/admin/taxonomy/import":                                 {},
+		"/api/admin/taxonomy/{id:[0-9]+}":                            {},
+		"/api/admin/trash/recipes":                                   {},
+		"/api/admin/trash/recipes/{id:[0-9]+}/restore":               {},
+		"/api/admin/trash/users":                                     {},
+		"/api/admin/trash/users/{id:[0-9]+}/restore":                 {},
+		"/api/admin/users":                                           {},
+		"/api/admin/users/{id:[0-9]+}":                               {},
+		"/api/admin/users/{id:[0-9]+}/role":                          {},
+		"/api/admin/users/{id:[0-9]+}/deactivate":                    {},
+		"/api/admin/users/{id:[0-9]+}/activate":                      {},
+		"/api/admin/users/{id:[0-9]+}/unlock":                        {},
+		"/api/ingredients/":                                          {},
+		"/api/ingredients/{id:[0-9]+}":                               {},
+		"/api/moderation/recipes":                                    {},
+		"/api/moderation/recipes/{id:[0-9]+}/approve":                {},
+		"/api/moderation/recipes/{id:[0-9]+}/reject":                 {},
+		"/api/moderation/recipes/{id:[0-9]+}/unpublish":              {},
+		"/api/recipes/":                                              {},
+		"/api/recipes/import":                                        {},
+		"/api/recipes/pantry":                                        {},
+		"/api/recipes/popular":                                       {},
+		"/api/recipes/tags":                                          {},
+		"/api/recipes/trending":                                      {},
+		"/api/recipes/{id:[0-9]+}":                                   {},
+		"/api/recipes/{id:[0-9]+}/allergens":                         {},
+		"/api/recipes/{id:[0-9]+}/reopen":                            {},
+		"/api/recipes/{id:[0-9]+}/shares":                            {},
+		"/api/recipes/{id:[0-9]+}/shares/{shareId:[0-9]+}":           {},
+		"/api/recipes/{id:[0-9]+}/shopping-list":                     {},
+		"/api/recipes/{id:[0-9]+}/submit":                            {},
+		"/api/recipes/{id:[0-9]+}/nutrition":                         {},
+		"/api/recipes/{id:[0-9]+}/tags":                              {},
+		"/api/recipes/{id:[0-9]+}/diets/{diet:[a-z-]+}":              {},
+		"/api/recipes/{id:[0-9]+}/substitutions":                     {},
+		"/api/recipes/{id:[0-9]+}/translations":                      {},
+		"/api/recipes/{id:[0-9]+}/translations/{locale:[a-zA-Z_-]+}": {},
+		"/api/recipes/{id:[0-9]+}/visibility":                        {},
+		"/api/user/":                                                 {},
+		"/api/user/signin":                                           {},
+		"/api/user/signup":                                           {},
+		"/api/user/verify":                                           {},
+		"/api/user/verify/resend":                                    {},
+		"/api/user/password/forgot":                                  {},
+		"/api/user/password/reset":                                   {},
+		"/api/user/password":                                         {},
+		"/api/user/email":                                            {},
+		"/api/user/email/confirm":                                    {},
+		"/api/user/export":                                           {},
+		"/api/user/oidc/{provider:[a-z0-9-]+}/login":                 {},
+		"/api/user/oidc/{provider:[a-z0-9-]+}/callback":              {},
+		"/api/user/keys":                                             {},
+		"/api/user/keys/{id:[0-9]+}":                                 {},
+		"/api/user/token/refresh":                                    {},
+		"/api/user/logout":                                           {},
+		"/swagger/*":                                                 {},
+		"/.well-known/jwks.json":                                     {},
 	}
 
 	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
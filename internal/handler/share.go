@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// RecipeVisibility godoc
+// @Summary Change the visibility of a recipe
+// @Description Make a recipe public, unlisted or private, only the recipe author can change it
+// @ID put-recipe-visibility
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param body body handler.RecipeVisibilityRequest true "visibility level"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/visibility [put]
+func (h *Handler) RecipeVisibility(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	rv := RecipeVisibilityRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&rv); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(rv); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	if err := h.db.Recipe.SetVisibility(uint64(recipe.ID), rv.Visibility); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	recipe.Visibility = rv.Visibility
+	h.cache.Delete(cacheKeyIngredientNames)
+
+	resp := RecipeResponseItem{}
+	if err := EncodeEntity(recipe, &resp); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// ShareCreate godoc
+// @Summary Create a share link
+// @Description Create an expiring link that gives read access to a recipe without signing in, only the recipe author
+// @Description can share it. The token is only returned once.
+// @ID post-recipe-share
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param body body handler.ShareCreateRequest false "link lifetime"
+// @Success 201 {object} handler.ShareResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/shares [post]
+func (h *Handler) ShareCreate(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	// An empty body creates a link with the default lifetime
+	sc := ShareCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&sc); err != nil && !errors.Is(err, io.EOF) {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(sc); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+	if sc.ExpiresIn == 0 {
+		sc.ExpiresIn = h.cfg.Token.ShareTTL
+	}
+
+	// Round to seconds so the link and the database expire together
+	expiresAt := time.Now().Add(time.Duration(sc.ExpiresIn) * time.Hour).Truncate(time.Second)
+	id, err := h.db.Share.Insert(recipe.ID, recipe.UserID, expiresAt)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	token, err := h.newShareToken(id, recipe.ID, expiresAt)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := ShareResponseItem{
+		ID:        id,
+		RecipeID:  recipe.ID,
+		Token:     *token,
+		Href:      fmt.Sprintf("/api/recipes/%d?share=%s", recipe.ID, *token),
+		ExpiresAt: expiresAt.UTC().Format("2006-01-02 15:04:05"),
+	}
+
+	h.respond(w, resp, http.StatusCreated)
+}
+
+// Shares godoc
+// @Summary Get share links
+// @Description Get the share links of a recipe including expired and revoked ones, only the recipe author can list them
+// @ID get-recipe-shares
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.SharesResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/shares [get]
+func (h *Handler) Shares(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	shares, err := h.db.Share.List(uint64(recipe.ID))
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := SharesResponse{Data: []ShareResponseItem{}}
+	for i := range shares {
+		resp.Data = append(resp.Data, NewShareResponseItem(shares[i]))
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// ShareRevoke godoc
+// @Summary Revoke a share link
+// @Description Revoke a share link of a recipe, only the recipe author can revoke it
+// @ID delete-recipe-share
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param shareId path int true "Share ID"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/shares/{shareId} [delete]
+func (h *Handler) ShareRevoke(w http.ResponseWriter, r *http.Request) {
+	shareID, err := urlID(r, "shareId")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeShareIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Share.Revoke(shareID, uint64(recipe.ID)); err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeUnknownShare, StatusCode: http.StatusNotFound})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// authorRecipe loads the recipe of the id url parameter and makes sure the signed in user is its author, on failure
+// it responds with an error and returns false
+func (h *Handler) authorRecipe(w http.ResponseWriter, r *http.Request) (*database.Recipe, bool) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return nil, false
+	}
+
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return nil, false
+	}
+
+	recipe, err := h.db.Recipe.Get(id, h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return nil, false
+	}
+
+	if recipe.UserID != token.UserID {
+		h.respondError(w, r, APIError{Code: CodeAuthorRequired, StatusCode: http.StatusForbidden})
+		return nil, false
+	}
+
+	return recipe, true
+}
+
+// newShareToken signs a share link token with the signing key, the token expires together with the share
+func (h *Handler) newShareToken(shareID int64, recipeID int64, expiresAt time.Time) (*string, error) {
+	tokenSigned, err := h.keys.Sign(ShareToken{
+		ShareID:  shareID,
+		RecipeID: recipeID,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("signature error, %w", err)
+	}
+
+	return &tokenSigned, nil
+}
+
+// shareFromRequest validates the share query parameter of a request, share links only open the recipe they were
+// created for and stop working once revoked
+func (h *Handler) shareFromRequest(r *http.Request) (*database.Share, error) {
+	st := ShareToken{}
+	token, err := jwt.ParseWithClaims(r.URL.Query().Get("share"), &st, h.keys.Keyfunc)
+	if err != nil || !token.Valid || st.ShareID <= 0 || st.RecipeID <= 0 {
+		return nil, errors.New("invalid share link")
+	}
+
+	if !strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), fmt.Sprintf("/recipes/%d", st.RecipeID)) {
+		return nil, errors.New("share link is not valid for this resource")
+	}
+
+	share, err := h.db.Share.Active(uint64(st.ShareID), uint64(st.RecipeID), time.Now())
+	if err != nil {
+		return nil, errors.New("share link has expired or was revoked")
+	}
+
+	return share, nil
+}
@@ -0,0 +1,163 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_RecipeShares(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+	router := handler.Routes(h)
+
+	recipeID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Private Baklava",
+		URL:         "http://example.com/private-baklava",
+		Ingredients: database.Ingredients{{Name: "filo"}, {Name: "walnuts"}, {Name: "honey"}},
+		UserID:      1,
+		Status:      database.RecipePublished,
+		Visibility:  database.RecipePrivate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipeURL := fmt.Sprintf("/api/recipes/%d", recipeID)
+
+	// bearer signs a login token for a user
+	bearer := func(uid int64, uname string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"uname": uname,
+			"uid":   uid,
+			"jti":   fmt.Sprintf("share-test-%d", uid),
+			"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		})
+		signed, err := token.SignedString([]byte(cfg.Token.Secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return "Bearer " + signed
+	}
+	author := bearer(1, "username1")
+	reader := bearer(2, "username2")
+
+	// serve sends a request through the api router
+	serve := func(method string, url string, auth string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, body)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	if rr := serve(http.MethodGet, recipeURL, reader, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("Expected private recipe to be hidden got %d, %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodPost, recipeURL+"/shares", reader, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("Expected other users not to share a private recipe got %d, %s", rr.Code, rr.Body.String())
+	}
+
+	rr := serve(http.MethodPost, recipeURL+"/shares", author, strings.NewReader(`{"expiresIn":2}`))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	share := handler.ShareResponseItem{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &share); err != nil {
+		t.Fatal(err)
+	}
+	if share.Token == "" || share.Href != recipeURL+"?share="+share.Token {
+		t.Fatalf("Unexpected share link %+v", share)
+	}
+
+	testData := []struct {
+		desc         string
+		method       string
+		url          string
+		expectedCode int
+	}{
+		{"Should read a private recipe with a share link", http.MethodGet, share.Href, http.StatusOK},
+		{"Should reject a tampered share link", http.MethodGet, share.Href + "x", http.StatusUnauthorized},
+		{"Should not open other recipes", http.MethodGet, fmt.Sprintf("/api/recipes/%d?share=%s", recipeID+1, share.Token),
+			http.StatusUnauthorized},
+		{"Should not open other resources", http.MethodGet, recipeURL + "/shopping-list?share=" + share.Token,
+			http.StatusUnauthorized},
+		{"Should only allow reads", http.MethodDelete, share.Href, http.StatusUnauthorized},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			rr := serve(tc.method, tc.url, "", nil)
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+
+	rr = serve(http.MethodGet, recipeURL+"/shares", author, nil)
+	shares := handler.SharesResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &shares); err != nil {
+		t.Fatal(err)
+	}
+	if len(shares.Data) != 1 || shares.Data[0].ID != share.ID || shares.Data[0].Token != "" {
+		t.Fatalf("Expected the share to be listed without its token, %s", rr.Body.String())
+	}
+
+	shareURL := fmt.Sprintf("%s/shares/%d", recipeURL, share.ID)
+	if rr := serve(http.MethodDelete, shareURL, author, nil); rr.Code != http.StatusNoContent {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if rr := serve(http.MethodDelete, shareURL, author, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("Expected revoked share to be unknown got %d, %s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, share.Href, "", nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected revoked share link to be rejected got %d, %s", rr.Code, rr.Body.String())
+	}
+
+	// Unlisted recipes are readable by id but are not listed
+	rr = serve(http.MethodPut, recipeURL+"/visibility", author, strings.NewReader(`{"visibility":"unlisted"}`))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if rr := serve(http.MethodGet, recipeURL, reader, nil); rr.Code != http.StatusOK {
+		t.Fatalf("Expected unlisted recipe to be readable got %d, %s", rr.Code, rr.Body.String())
+	}
+
+	rr = serve(http.MethodGet, "/api/recipes/?term=Private+Baklava", reader, nil)
+	recipes := handler.RecipesResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &recipes); err != nil {
+		t.Fatal(err)
+	}
+	if recipes.Data != nil {
+		for _, r := range *recipes.Data {
+			if r.ID == recipeID {
+				t.Fatal("Expected unlisted recipe not to be listed")
+			}
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// Substitutions godoc
+// @Summary List substitutions
+// @Description Get all ingredient substitutions
+// @ID get-substitutions
+// @Produce  json
+// @Success 200 {object} handler.SubstitutionResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/substitutions [get]
+func (h *Handler) Substitutions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.db.Substitution.List()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := SubstitutionResponse{Data: SubstitutionResponseItems{}}
+	for i := range subs {
+		resp.Data = append(resp.Data, NewSubstitutionResponseItem(subs[i]))
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// SubstitutionCreate godoc
+// @Summary Create a substitution
+// @Description Add a new ingredient substitution
+// @ID create-substitution
+// @Accept  json
+// @Produce  json
+// @Param body body handler.SubstitutionRequest true "substitution"
+// @Success 201 {object} handler.SubstitutionResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/substitutions [post]
+func (h *Handler) SubstitutionCreate(w http.ResponseWriter, r *http.Request) {
+	sr, ok := h.decodeSubstitutionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := h.db.Substitution.Insert(database.Substitution{
+		Ingredient:  sr.Ingredient,
+		Substitutes: sr.Substitutes,
+		Notes:       sr.Notes,
+	})
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respondSubstitution(w, r, uint64(id), http.StatusCreated)
+}
+
+// SubstitutionUpdate godoc
+// @Summary Update a substitution
+// @Description Update an ingredient substitution and replace its substitutes
+// @ID update-substitution
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Substitution ID"
+// @Param body body handler.SubstitutionRequest true "substitution"
+// @Success 200 {object} handler.SubstitutionResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/substitutions/{id} [put]
+func (h *Handler) SubstitutionUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	sr, ok := h.decodeSubstitutionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.db.Substitution.Get(id); err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownSubstitution, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	if err := h.db.Substitution.Update(database.Substitution{
+		ID:          int64(id),
+		Ingredient:  sr.Ingredient,
+		Substitutes: sr.Substitutes,
+		Notes:       sr.Notes,
+	}); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respondSubstitution(w, r, id, http.StatusOK)
+}
+
+// SubstitutionDelete godoc
+// @Summary Delete a substitution
+// @Description Delete an ingredient substitution
+// @ID delete-substitution
+// @Param id path int true "Substitution ID"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/substitutions/{id} [delete]
+func (h *Handler) SubstitutionDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	err = h.db.Substitution.Delete(id)
+	if errors.Is(err, database.ErrNoRows) {
+		h.respondError(w, r, APIError{Code: CodeUnknownSubstitution, StatusCode: http.StatusNotFound})
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// SubstitutionImport godoc
+// @Summary Bulk load substitutions
+// @Description Bulk load substitutions from a JSON or YAML document, existing substitutions of the imported
+// @Description ingredients are replaced
+// @ID import-substitutions
+// @Accept  json
+// @Accept  x-yaml
+// @Produce  json
+// @Param body body handler.SubstitutionImportRequest true "substitutions"
+// @Success 200 {object} handler.SubstitutionImportResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/substitutions/import [post]
+func (h *Handler) SubstitutionImport(w http.ResponseWriter, r *http.Request) {
+	si := SubstitutionImportRequest{}
+	if err := h.decodeImport(w, r, &si); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	subs := make(database.Substitutions, len(si.Substitutions))
+	for i := range si.Substitutions {
+		subs[i] = database.Substitution{
+			Ingredient:  si.Substitutions[i].Ingredient,
+			Substitutes: si.Substitutions[i].Substitutes,
+			Notes:       si.Substitutions[i].Notes,
+		}
+	}
+
+	total, err := h.db.Substitution.Import(subs)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, SubstitutionImportResponse{Imported: total}, http.StatusOK)
+}
+
+// RecipeSubstitutions godoc
+// @Summary Recipe substitutions
+// @Description Get substitutions for missing recipe ingredients, when no ingredient is given substitutions for all
+// @Description recipe ingredients are returned. When a pantry is given each missing ingredient reports whether it
+// @Description is covered and which substitution covers it.
+// @ID get-recipe-substitutions
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param missing query []string false "Missing ingredients"
+// @Param pantry query []string false "Available ingredients"
+// @Success 200 {object} handler.RecipeSubstitutionsResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/substitutions [get]
+func (h *Handler) RecipeSubstitutions(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	rs := RecipeSubstitutionsRequest{}
+	if err := h.schema.Decode(&rs, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(rs); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	recipe, err := h.db.Recipe.Get(id, h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	var missing []string
+	for i := range rs.Missing {
+		missing = append(missing, strings.TrimSpace(rs.Missing[i]))
+	}
+	if len(missing) == 0 {
+		for i := range recipe.Ingredients {
+			missing = append(missing, recipe.Ingredients[i].Name)
+		}
+	}
+
+	subs, err := h.db.Substitution.List(missing...)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	// Recipe names and missing names are matched the same way substitutions are stored, "Eggs" finds "egg"
+	resp := RecipeSubstitutionsResponse{Data: []RecipeSubstitutionsResponseItem{}}
+	seen := make(map[string]struct{})
+	for i := range missing {
+		key := database.IngredientKey(missing[i])
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		item := RecipeSubstitutionsResponseItem{Ingredient: missing[i], Substitutions: SubstitutionResponseItems{}}
+		for _, s := range subs.For(missing[i]) {
+			item.Substitutions = append(item.Substitutions, NewSubstitutionResponseItem(s))
+		}
+
+		if len(rs.Pantry) > 0 {
+			if s := subs.Cover(missing[i], rs.Pantry); s != nil {
+				item.Covered = true
+				item.CoveredBy = s.ID
+			}
+		}
+
+		resp.Data = append(resp.Data, item)
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+func (h *Handler) decodeSubstitutionRequest(w http.ResponseWriter, r *http.Request) (*SubstitutionRequest, bool) {
+	sr := SubstitutionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&sr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return nil, false
+	}
+
+	if err := h.validate.Struct(sr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return nil, false
+	}
+
+	return &sr, true
+}
+
+func (h *Handler) respondSubstitution(w http.ResponseWriter, r *http.Request, id uint64, statusCode int) {
+	s, err := h.db.Substitution.Get(id)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, NewSubstitutionResponseItem(*s), statusCode)
+}
@@ -0,0 +1,225 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+func TestHandler_SubstitutionCreate(t *testing.T) {
+	testData := []struct {
+		desc         string
+		input        string
+		expectedCode int
+	}{
+		{"Should create a substitution", `{"ingredient": "rum", "substitutes": ["rum extract", "water"]}`, http.StatusCreated},
+		{"Should create an omit substitution", `{"ingredient": "rum", "notes": "omit"}`, http.StatusCreated},
+		{"Should fail to create a substitution without ingredient", `{"substitutes": ["water"]}`, http.StatusBadRequest},
+		{"Should fail to create a substitution with an empty substitute", `{"ingredient": "rum", "substitutes": [""]}`, http.StatusBadRequest},
+		{"Should fail to create a substitution from invalid input", `invalid`, http.StatusBadRequest},
+	}
+
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/substitutions", strings.NewReader(tc.input))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.SubstitutionCreate).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_RecipeSubstitutions(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	// Load substitutions for recipe 1 (Ginger Champagne)
+	req := httptest.NewRequest(http.MethodPost, "/admin/substitutions/import", strings.NewReader(`
+substitutions:
+  - ingredient: champagne
+    substitutes: [sparkling white grape juice]
+  - ingredient: vodka
+    substitutes: [sparkling water]
+  - ingredient: vodka
+    notes: omit
+  - ingredient: Egg
+    substitutes: [banana]
+`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.SubstitutionImport).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to import substitutions, %s", rr.Body.String())
+	}
+
+	testData := []struct {
+		params       url.Values
+		ingredients  int
+		covered      bool
+		expectedCode int
+	}{
+		{url.Values{}, 4, false, http.StatusOK},
+		{url.Values{"missing": []string{"Vodka"}}, 1, false, http.StatusOK},
+		{url.Values{"missing": []string{"vodka"}, "pantry": []string{"ice"}}, 1, true, http.StatusOK},
+		{url.Values{"missing": []string{"champagne"}, "pantry": []string{"sparkling white grape juice"}}, 1, true, http.StatusOK},
+		{url.Values{"missing": []string{"champagne"}, "pantry": []string{"ice"}}, 1, false, http.StatusOK},
+		{url.Values{"missing": []string{"Champagnes"}, "pantry": []string{"Sparkling  White Grape Juices"}}, 1, true, http.StatusOK},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(fmt.Sprintf(`Test Case %+v`, tc.params), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/recipes/1/substitutions?"+tc.params.Encode(), nil)
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("id", "1")
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.RecipeSubstitutions).ServeHTTP(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx)))
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+
+			resp := handler.RecipeSubstitutionsResponse{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Data) != tc.ingredients {
+				t.Fatalf("Expected %d ingredients got %d", tc.ingredients, len(resp.Data))
+			}
+			if resp.Data[0].Covered != tc.covered {
+				t.Fatalf("Expected covered to be %t got %+v", tc.covered, resp.Data[0])
+			}
+			if tc.covered && resp.Data[0].CoveredBy == 0 {
+				t.Fatal("Expected to know which substitution covers the ingredient")
+			}
+		})
+	}
+
+	t.Run("Should match recipe ingredients written differently", func(t *testing.T) {
+		// Recipe 2 (Potato and Cheese Frittata) lists eggs, the substitution is stored for egg
+		req := httptest.NewRequest(http.MethodGet, "/recipes/2/substitutions", nil)
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", "2")
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.RecipeSubstitutions).ServeHTTP(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx)))
+
+		resp := handler.RecipeSubstitutionsResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range resp.Data {
+			if item.Ingredient == "eggs" && len(item.Substitutions) == 1 {
+				return
+			}
+		}
+		t.Fatalf("Expected a substitution for eggs got %s", rr.Body.String())
+	})
+}
+
+func TestHandler_PantryRecipes(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/substitutions/import", strings.NewReader(`
+substitutions:
+  - ingredient: sugar
+    substitutes: [honey]
+`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.SubstitutionImport).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to import substitutions, %s", rr.Body.String())
+	}
+
+	search := func(params url.Values) (*httptest.ResponseRecorder, handler.PantryRecipesResponse) {
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.PantryRecipes).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/recipes/pantry?"+params.Encode(), nil))
+
+		resp := handler.PantryRecipesResponse{}
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr, resp
+	}
+
+	t.Run("Should cover missing ingredients with substitutions", func(t *testing.T) {
+		// Recipe 20 (Golden Wedding Punch) needs lemon juice, water and sugar
+		rr, resp := search(url.Values{"pantry": []string{"Lemon Juice", "water", "honey"}})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if len(resp.Data) == 0 || resp.Data[0].Recipe.ID != 20 || len(resp.Data[0].Missing) != 0 {
+			t.Fatalf("Expected recipe 20 without missing ingredients got %s", rr.Body.String())
+		}
+		if used := resp.Data[0].Substitutions; len(used) != 1 || used[0].Ingredient != "sugar" {
+			t.Fatalf("Expected the sugar substitution to be used got %+v", used)
+		}
+	})
+
+	t.Run("Should list missing ingredients up to maxMissing", func(t *testing.T) {
+		rr, resp := search(url.Values{"pantry": []string{"lemon juice", "water"}, "maxMissing": []string{"1"}})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		for _, item := range resp.Data {
+			if item.Recipe.ID == 20 && len(item.Missing) == 1 && item.Missing[0] == "sugar" {
+				return
+			}
+		}
+		t.Fatalf("Expected recipe 20 to miss sugar got %s", rr.Body.String())
+	})
+
+	t.Run("Should require a pantry", func(t *testing.T) {
+		if rr, _ := search(url.Values{}); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// Tags godoc
+// @Summary Get tags
+// @Description Get the tags in use, optionally of a single kind
+// @ID get-tags
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param kind query string false "Tag kind" Enums(cuisine, course, diet, occasion, tag)
+// @Success 200 {object} handler.TagsResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /recipes/tags [get]
+func (h *Handler) Tags(w http.ResponseWriter, r *http.Request) {
+	tr := TagsRequest{}
+	if err := h.schema.Decode(&tr, r.URL.Query()); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(tr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	tags, err := h.db.Tag.List(tr.Kind)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := TagsResponse{Data: TagResponse{}}
+	if err := EncodeEntities(tags, &resp, "Data"); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// RecipeTags godoc
+// @Summary Tag a recipe
+// @Description Replace the tags of a recipe, only the recipe author can tag it
+// @ID put-recipe-tags
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param body body handler.RecipeTagsRequest true "tags"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/tags [put]
+func (h *Handler) RecipeTags(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	rt := RecipeTagsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&rt); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(rt); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	if err := h.db.Tag.Set(recipe.ID, requestTags(rt.Tags)); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	recipe, err := h.db.Recipe.Get(uint64(recipe.ID), h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := RecipeResponseItem{}
+	if err := EncodeEntity(recipe, &resp); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// requestTags converts the tags of a request body to database tags
+func requestTags(tags []TagRequest) database.Tags {
+	var dt database.Tags
+	for i := range tags {
+		dt = append(dt, database.NewTag(tags[i].Kind, tags[i].Name))
+	}
+
+	return dt
+}
+
+// queryTags converts kind:name query values to database tags, values without a known kind are free tags and
+// repeated tags are dropped
+func queryTags(values []string) database.Tags {
+	var tags database.Tags
+	for _, v := range values {
+		t := database.NewTag(database.TagFree, v)
+		if i := strings.Index(v, ":"); i > 0 && database.IsTagKind(v[:i]) {
+			t = database.NewTag(v[:i], v[i+1:])
+		}
+
+		known := false
+		for i := range tags {
+			known = known || tags[i] == t
+		}
+		if !known && t.Name != "" {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,139 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+func TestHandler_RecipeTags(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	var ids []int64
+	for _, title := range []string{"Faceted Dakos", "Faceted Kalitsounia"} {
+		id, err := db.Recipe.Insert(database.Recipe{
+			Title:       title,
+			URL:         "http://example.com/" + strings.ToLower(strings.ReplaceAll(title, " ", "-")),
+			Ingredients: database.Ingredients{{Name: "mizithra"}},
+			UserID:      1,
+			Tags:        database.Tags{database.NewTag(database.TagCuisine, "cretan")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	// tag sends a tags request for the first recipe as token
+	tag := func(token handler.Token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", fmt.Sprint(ids[0]))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, token))
+
+		rr := httptest.NewRecorder()
+		h.RecipeTags(rr, req)
+
+		return rr
+	}
+
+	author := handler.Token{UserID: 1, Username: "username1"}
+	testData := []struct {
+		desc         string
+		token        handler.Token
+		body         string
+		expectedCode int
+	}{
+		{"Should not let other users tag", handler.Token{UserID: 2, Username: "username2"},
+			`{"tags":[{"kind":"course","name":"meze"}]}`, http.StatusForbidden},
+		{"Should reject unknown kinds", author, `{"tags":[{"kind":"mood","name":"cozy"}]}`, http.StatusBadRequest},
+		{"Should tag a recipe", author,
+			`{"tags":[{"kind":"cuisine","name":"Cretan"},{"kind":"course","name":"meze"},{"kind":"tag","name":"vegetarian"}]}`,
+			http.StatusOK},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if rr := tag(tc.token, tc.body); rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+
+	// search gets the faceted recipes with tag filters
+	search := func(query string) handler.RecipesResponse {
+		req := httptest.NewRequest(http.MethodGet, "/?term=Faceted&"+query, nil)
+		rr := httptest.NewRecorder()
+		h.Recipes(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+
+		resp := handler.RecipesResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		return resp
+	}
+
+	searchData := []struct {
+		desc           string
+		query          string
+		expectedTotal  int64
+		expectedFacets handler.FacetsResponse
+	}{
+		{"Should count facets of the result set", "", 2, handler.FacetsResponse{
+			"course":  {{Name: "meze", Count: 1}},
+			"cuisine": {{Name: "cretan", Count: 2}},
+			"tag":     {{Name: "vegetarian", Count: 1}},
+		}},
+		{"Should match all tags", "tag=cuisine:cretan&tag=course:meze", 1, handler.FacetsResponse{
+			"course":  {{Name: "meze", Count: 1}},
+			"cuisine": {{Name: "cretan", Count: 1}},
+			"tag":     {{Name: "vegetarian", Count: 1}},
+		}},
+		{"Should match any tag", "tag=cuisine:cretan&tag=vegetarian&tagMatch=any", 2, nil},
+		{"Should read free tags", "tag=vegetarian", 1, nil},
+	}
+
+	for i := range searchData {
+		tc := searchData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			resp := search(tc.query)
+			if resp.Metadata.Total != tc.expectedTotal {
+				t.Fatalf("Expected %d recipes got %d", tc.expectedTotal, resp.Metadata.Total)
+			}
+			if tc.expectedFacets == nil {
+				return
+			}
+			if fmt.Sprint(resp.Metadata.Facets) != fmt.Sprint(tc.expectedFacets) {
+				t.Fatalf("Expected facets %v got %v", tc.expectedFacets, resp.Metadata.Facets)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// Taxonomy godoc
+// @Summary List ingredient taxonomy
+// @Description Get all nodes of the ingredient taxonomy
+// @ID get-taxonomy
+// @Produce  json
+// @Success 200 {object} handler.TaxonomyResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/taxonomy [get]
+func (h *Handler) Taxonomy(w http.ResponseWriter, r *http.Request) {
+	nodes, err := h.db.Taxonomy.List()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := TaxonomyResponse{Data: TaxonomyResponseItems{}}
+	if err := EncodeEntities(nodes, &resp, "Data"); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// TaxonomyCreate godoc
+// @Summary Create a taxonomy node
+// @Description Add a new node to the ingredient taxonomy
+// @ID create-taxonomy
+// @Accept  json
+// @Produce  json
+// @Param body body handler.TaxonomyRequest true "taxonomy node"
+// @Success 201 {object} handler.TaxonomyResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/taxonomy [post]
+func (h *Handler) TaxonomyCreate(w http.ResponseWriter, r *http.Request) {
+	tr, ok := h.decodeTaxonomyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if tr.ParentID != 0 {
+		if _, err := h.db.Taxonomy.Get(uint64(tr.ParentID)); err != nil {
+			h.respondError(w, r, APIError{Code: CodeUnknownParent, StatusCode: http.StatusBadRequest})
+			return
+		}
+	}
+
+	id, err := h.db.Taxonomy.Insert(database.Taxonomy{
+		ParentID: tr.ParentID,
+		Name:     tr.Name,
+		Allergen: tr.Allergen,
+		Aisle:    tr.Aisle,
+	})
+	if errors.Is(err, database.ErrDuplicateEntry) {
+		h.respondError(w, r, APIError{Code: CodeTaxonomyNodeExists, StatusCode: http.StatusConflict})
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if err := h.taxonomyChanged(); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	h.respondTaxonomy(w, r, uint64(id), http.StatusCreated)
+}
+
+// TaxonomyUpdate godoc
+// @Summary Update a taxonomy node
+// @Description Rename, move or change the attributes of a taxonomy node
+// @ID update-taxonomy
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Taxonomy node ID"
+// @Param body body handler.TaxonomyRequest true "taxonomy node"
+// @Success 200 {object} handler.TaxonomyResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/taxonomy/{id} [put]
+func (h *Handler) TaxonomyUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	tr, ok := h.decodeTaxonomyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	node, err := h.db.Taxonomy.Get(id)
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownTaxonomyNode, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	if tr.ParentID != 0 {
+		if _, err := h.db.Taxonomy.Get(uint64(tr.ParentID)); err != nil {
+			h.respondError(w, r, APIError{Code: CodeUnknownParent, StatusCode: http.StatusBadRequest})
+			return
+		}
+	}
+
+	err = h.db.Taxonomy.Update(database.Taxonomy{
+		ID:       node.ID,
+		ParentID: tr.ParentID,
+		Name:     tr.Name,
+		Allergen: tr.Allergen,
+		Aisle:    tr.Aisle,
+	})
+	if errors.Is(err, database.ErrDuplicateEntry) {
+		h.respondError(w, r, APIError{Code: CodeTaxonomyNodeExists, StatusCode: http.StatusConflict})
+		return
+	}
+	// A node can not be moved under itself or under one of its descendants
+	if errors.Is(err, database.ErrTaxonomyCycle) {
+		h.respondError(w, r, APIError{Code: CodeTaxonomyNodeCycle, StatusCode: http.StatusBadRequest})
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if err := h.taxonomyChanged(); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	h.respondTaxonomy(w, r, id, http.StatusOK)
+}
+
+// TaxonomyDelete godoc
+// @Summary Delete a taxonomy node
+// @Description Delete a taxonomy node, its children are moved under its parent
+// @ID delete-taxonomy
+// @Param id path int true "Taxonomy node ID"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/taxonomy/{id} [delete]
+func (h *Handler) TaxonomyDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	err = h.db.Taxonomy.Delete(id)
+	if errors.Is(err, database.ErrNoRows) {
+		h.respondError(w, r, APIError{Code: CodeUnknownTaxonomyNode, StatusCode: http.StatusNotFound})
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if err := h.taxonomyChanged(); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// TaxonomyImport godoc
+// @Summary Bulk load taxonomy
+// @Description Bulk load a taxonomy tree from a JSON or YAML document, existing nodes are updated
+// @ID import-taxonomy
+// @Accept  json
+// @Accept  x-yaml
+// @Produce  json
+// @Param body body handler.TaxonomyImportRequest true "taxonomy tree"
+// @Success 200 {object} handler.TaxonomyImportResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/taxonomy/import [post]
+func (h *Handler) TaxonomyImport(w http.ResponseWriter, r *http.Request) {
+	ti := TaxonomyImportRequest{}
+	if err := h.decodeImport(w, r, &ti); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	var nodes []database.TaxonomyNode
+	if err := EncodeEntity(ti.Taxonomy, &nodes); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	total, err := h.db.Taxonomy.Import(nodes)
+	if errors.Is(err, database.ErrTaxonomyCycle) {
+		h.respondError(w, r, APIError{Code: CodeTaxonomyNodeCycle, StatusCode: http.StatusBadRequest})
+		return
+	}
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if err := h.taxonomyChanged(); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	h.respond(w, TaxonomyImportResponse{Imported: total}, http.StatusOK)
+}
+
+// RecipeAllergens godoc
+// @Summary Recipe allergens
+// @Description Get recipe ingredients grouped by allergen, derived from the ingredient taxonomy
+// @ID get-recipe-allergens
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.IngredientGroupResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/allergens [get]
+func (h *Handler) RecipeAllergens(w http.ResponseWriter, r *http.Request) {
+	h.respondIngredientGroups(w, r, func(tree *database.TaxonomyTree, name string) string {
+		return tree.Allergen(name)
+	})
+}
+
+// RecipeShoppingList godoc
+// @Summary Recipe shopping list
+// @Description Get recipe ingredients grouped by shopping list aisle, derived from the ingredient taxonomy
+// @ID get-recipe-shopping-list
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.IngredientGroupResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/shopping-list [get]
+func (h *Handler) RecipeShoppingList(w http.ResponseWriter, r *http.Request) {
+	h.respondIngredientGroups(w, r, func(tree *database.TaxonomyTree, name string) string {
+		if aisle := tree.Aisle(name); aisle != "" {
+			return aisle
+		}
+
+		return "other"
+	})
+}
+
+// respondIngredientGroups responds with the ingredients of the requested recipe grouped by the value that group
+// returns, ingredients with an empty group are omitted
+func (h *Handler) respondIngredientGroups(
+	w http.ResponseWriter,
+	r *http.Request,
+	group func(tree *database.TaxonomyTree, name string) string,
+) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	recipe, err := h.db.Recipe.Get(id, h.viewer(r))
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	tree, err := h.taxonomyTree()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	groups := make(map[string][]string)
+	for i := range recipe.Ingredients {
+		name := recipe.Ingredients[i].Name
+		if g := group(tree, name); g != "" {
+			groups[g] = append(groups[g], name)
+		}
+	}
+
+	resp := IngredientGroupResponse{Data: []IngredientGroupResponseItem{}}
+	for g := range groups {
+		resp.Data = append(resp.Data, IngredientGroupResponseItem{Group: g, Ingredients: groups[g]})
+	}
+	sort.Slice(resp.Data, func(i, j int) bool {
+		return resp.Data[i].Group < resp.Data[j].Group
+	})
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+func (h *Handler) decodeTaxonomyRequest(w http.ResponseWriter, r *http.Request) (*TaxonomyRequest, bool) {
+	tr := TaxonomyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&tr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return nil, false
+	}
+
+	if err := h.validate.Struct(tr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return nil, false
+	}
+
+	return &tr, true
+}
+
+func (h *Handler) respondTaxonomy(w http.ResponseWriter, r *http.Request, id uint64, statusCode int) {
+	node, err := h.db.Taxonomy.Get(id)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := TaxonomyResponseItem{}
+	if err := EncodeEntity(node, &resp); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, statusCode)
+}
@@ -0,0 +1,154 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+const taxonomyYAML = `
+taxonomy:
+  - name: dairy
+    allergen: milk
+    aisle: dairy
+    children:
+      - name: cheese
+        children:
+          - name: cheddar cheese
+          - name: parmesan
+      - name: butter
+`
+
+func TestHandler_TaxonomyImport(t *testing.T) {
+	testData := []struct {
+		desc         string
+		input        string
+		expectedCode int
+	}{
+		{"Should import a yaml taxonomy", taxonomyYAML, http.StatusOK},
+		{"Should import a json taxonomy", `{"taxonomy": [{"name": "poultry", "aisle": "meat", "children": [{"name": "chicken"}]}]}`, http.StatusOK},
+		{"Should fail to import an empty taxonomy", `{"taxonomy": []}`, http.StatusBadRequest},
+		{"Should fail to import a node without a name", `{"taxonomy": [{"aisle": "meat"}]}`, http.StatusBadRequest},
+		{"Should fail to import invalid input", `{"taxonomy": `, http.StatusBadRequest},
+		{
+			"Should fail to import a node under itself",
+			`{"taxonomy": [{"name": "fowl", "children": [{"name": "duck", "children": [{"name": "fowl"}]}]}]}`,
+			http.StatusBadRequest,
+		},
+	}
+
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/taxonomy/import", strings.NewReader(tc.input))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.TaxonomyImport).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("Should match recipes using ingredients under the searched one", func(t *testing.T) {
+		params := url.Values{"ingredient": []string{"cheese"}}
+		req := httptest.NewRequest(http.MethodGet, "/recipes?"+params.Encode(), nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.Recipes).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if !strings.Contains(rr.Body.String(), "Potato and Cheese Frittata") {
+			t.Fatalf("Expected to find recipes with cheddar cheese, %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Should group recipe ingredients by allergen and aisle", func(t *testing.T) {
+		for route, hf := range map[string]http.HandlerFunc{
+			"allergens":     h.RecipeAllergens,
+			"shopping-list": h.RecipeShoppingList,
+		} {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/recipes/2/%s", route), nil)
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("id", "2")
+			rr := httptest.NewRecorder()
+			hf.ServeHTTP(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx)))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
+
+			resp := handler.IngredientGroupResponse{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Data) == 0 || resp.Data[0].Ingredients[0] != "cheddar cheese" {
+				t.Fatalf("Expected cheddar cheese to be grouped, %+v", resp.Data)
+			}
+		}
+	})
+}
+
+func TestHandler_TaxonomyCreate(t *testing.T) {
+	testData := []struct {
+		desc         string
+		input        string
+		expectedCode int
+	}{
+		{"Should create a taxonomy node", `{"name": "seafood", "allergen": "shellfish", "aisle": "fish"}`, http.StatusCreated},
+		{"Should fail to create a duplicate node", `{"name": "Seafood"}`, http.StatusConflict},
+		{"Should fail to create a node with an unknown parent", `{"name": "crab", "parentId": 99999}`, http.StatusBadRequest},
+		{"Should fail to create a node without a name", `{"aisle": "fish"}`, http.StatusBadRequest},
+	}
+
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/taxonomy", strings.NewReader(tc.input))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.TaxonomyCreate).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// TokenRefresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token pair, refresh tokens can be used once.
+// @Description Using a refresh token twice revokes every token issued since the sign in it descends from.
+// @ID user-token-refresh
+// @Accept  json
+// @Produce  json
+// @Param body body handler.TokenRefreshRequest true "refresh token"
+// @Success 200 {object} handler.TokenResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /user/token/refresh [post]
+func (h *Handler) TokenRefresh(w http.ResponseWriter, r *http.Request) {
+	tr := TokenRefreshRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&tr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(tr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	now := time.Now()
+	rt, err := h.db.Token.Rotate(hashToken(tr.RefreshToken), now)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrTokenReused):
+			h.cache.Delete(cacheKeyRevokedTokens)
+			h.respondError(w, r, APIError{Code: CodeRefreshTokenReused, StatusCode: http.StatusUnauthorized})
+		case errors.Is(err, database.ErrNoRows):
+			h.respondError(w, r, APIError{Code: CodeInvalidRefreshToken, StatusCode: http.StatusUnauthorized})
+		default:
+			h.respondError(w, r, err)
+		}
+		return
+	}
+
+	user, err := h.db.User.Get(uint64(rt.UserID))
+	if err != nil || !user.Active {
+		h.respondError(w, r, APIError{Code: CodeInvalidRefreshToken, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	resp, err := h.newTokens(user, rt.Family)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// Logout godoc
+// @Summary user logout
+// @Description Revoke the access token of the request, when a refresh token is given its whole family is revoked
+// @Description as well
+// @ID user-logout
+// @Accept  json
+// @Produce  json
+// @Param body body handler.LogoutRequest false "refresh token"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /user/logout [post]
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	// The body is optional, logging out without a refresh token only revokes the access token
+	lr := LogoutRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&lr); err != nil && !errors.Is(err, io.EOF) {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(lr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	now := time.Now()
+	if token.Id != "" {
+		if err := h.db.Token.Revoke(token.Id, time.Unix(token.ExpiresAt, 0)); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+	}
+
+	if lr.RefreshToken != "" {
+		err := h.db.Token.RevokeUserFamily(hashToken(lr.RefreshToken), token.UserID, now)
+		if err != nil && !errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, err)
+			return
+		}
+	}
+
+	h.cache.Delete(cacheKeyRevokedTokens)
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// newTokens issues an access token together with a refresh token of family, an empty family starts a new one
+func (h *Handler) newTokens(u *database.User, family string) (*TokenResponse, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if family == "" {
+		if family, err = randomToken(16); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(h.cfg.Token.TTL) * time.Minute)
+	tokenSigned, err := h.keys.Sign(jwt.MapClaims{
+		"uname": u.Username,
+		"uid":   u.ID,
+		"role":  u.Role,
+		"jti":   jti,
+		"iat":   now.Unix(),
+		"exp":   expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("signature error, %w", err)
+	}
+
+	if _, err := h.db.Token.Insert(database.RefreshToken{
+		UserID:          u.ID,
+		Family:          family,
+		Hash:            hashToken(refresh),
+		AccessID:        jti,
+		AccessExpiresAt: expiresAt,
+		ExpiresAt:       now.Add(time.Duration(h.cfg.Token.RefreshTTL) * time.Hour),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        tokenSigned,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(expiresAt.Sub(now).Seconds()),
+	}, nil
+}
+
+// isRevoked reports whether the access token with the given jti was revoked, revocations are cached so checking
+// them does not query the database on every request
+func (h *Handler) isRevoked(jti string) (bool, error) {
+	revoked, ok := h.cache.Get(cacheKeyRevokedTokens)
+	if !ok {
+		var err error
+		if revoked, err = h.db.Token.Revoked(time.Now()); err != nil {
+			return false, err
+		}
+		h.cache.Set(cacheKeyRevokedTokens, revoked)
+	}
+
+	_, ok = revoked.(map[string]struct{})[jti]
+
+	return ok, nil
+}
+
+// randomToken returns size random bytes hex encoded
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("random error, %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns the hex encoded sha256 hash refresh tokens are stored by
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+)
+
+func TestHandler_TokenRefresh(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.User.Insert(database.User{
+		Username: "refresh1",
+		Password: string(hash),
+		Email:    "refresh1@test.gr",
+		Active:   true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// call sends body to fn behind the authorization middleware when a bearer token is given
+	call := func(fn http.HandlerFunc, bearer string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		if bearer == "" {
+			fn.ServeHTTP(rr, req)
+			return rr
+		}
+
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		h.AuthorizationMiddleware(fn).ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// tokens decodes a token response
+	tokens := func(rr *httptest.ResponseRecorder) handler.TokenResponse {
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		tr := handler.TokenResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &tr); err != nil {
+			t.Fatal(err)
+		}
+		if tr.Token == "" || len(tr.RefreshToken) != 64 || tr.ExpiresIn <= 0 {
+			t.Fatalf("Expected an access and refresh token pair got %+v", tr)
+		}
+		return tr
+	}
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		return call(h.TokenRefresh, "", fmt.Sprintf(`{"refreshToken":%q}`, token))
+	}
+
+	signIn := tokens(call(h.SignIn, "", `{"username": "refresh1", "password": "password"}`))
+	rotated := tokens(refresh(signIn.RefreshToken))
+	if rotated.RefreshToken == signIn.RefreshToken {
+		t.Fatal("Expected the refresh token to rotate")
+	}
+
+	if rr := call(h.User, rotated.Token, ""); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the rotated access token to be valid got %d, %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("Should reject invalid refresh tokens", func(t *testing.T) {
+		if rr := refresh(strings.Repeat("a", 64)); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusUnauthorized, rr.Body.String())
+		}
+		if rr := refresh("short"); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+		}
+	})
+
+	t.Run("Should revoke the family when a refresh token is replayed", func(t *testing.T) {
+		rr := refresh(signIn.RefreshToken)
+		if rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), handler.CodeRefreshTokenReused) {
+			t.Fatalf("Expected a reused token error got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := refresh(rotated.RefreshToken); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the rotated refresh token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := call(h.User, rotated.Token, ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the rotated access token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should revoke tokens on logout", func(t *testing.T) {
+		tr := tokens(call(h.SignIn, "", `{"username": "refresh1", "password": "password"}`))
+
+		body := fmt.Sprintf(`{"refreshToken":%q}`, tr.RefreshToken)
+		if rr := call(h.Logout, tr.Token, body); rr.Code != http.StatusNoContent {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNoContent, rr.Body.String())
+		}
+		if rr := call(h.User, tr.Token, ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the access token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := refresh(tr.RefreshToken); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected the refresh token to be revoked got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+}
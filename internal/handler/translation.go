@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/i18n"
+	"github.com/go-chi/chi"
+)
+
+// Translations godoc
+// @Summary Get recipe translations
+// @Description Get the translations of a recipe, only the recipe author can list them
+// @ID get-recipe-translations
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.TranslationsResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/translations [get]
+func (h *Handler) Translations(w http.ResponseWriter, r *http.Request) {
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return
+	}
+
+	translations, err := h.db.Translation.List(uint64(recipe.ID))
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := TranslationsResponse{Data: []TranslationResponseItem{}}
+	for i := range translations {
+		resp.Data = append(resp.Data, NewTranslationResponseItem(translations[i]))
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// TranslationSave godoc
+// @Summary Translate a recipe
+// @Description Create or replace the translation of a recipe title and ingredient names in a supported locale, only
+// @Description the recipe author can translate it. Ingredients are keyed by ingredient id, untranslated ingredients
+// @Description keep their original name.
+// @ID put-recipe-translation
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param locale path string true "Locale"
+// @Param body body handler.TranslationRequest true "translation"
+// @Success 200 {object} handler.TranslationResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/translations/{locale} [put]
+func (h *Handler) TranslationSave(w http.ResponseWriter, r *http.Request) {
+	recipe, locale, ok := h.translationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	tr := TranslationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&tr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(tr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	// Only ingredients of the recipe can be translated
+	for id := range tr.Ingredients {
+		known := false
+		for i := range recipe.Ingredients {
+			known = known || recipe.Ingredients[i].ID == id
+		}
+		if !known {
+			h.respondError(w, r, APIError{Code: CodeUnknownRecipeIngredient, StatusCode: http.StatusBadRequest})
+			return
+		}
+	}
+
+	if err := h.db.Translation.Save(database.Translation{
+		RecipeID:    recipe.ID,
+		Locale:      locale,
+		Title:       tr.Title,
+		Ingredients: tr.Ingredients,
+	}); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	translations, err := h.db.Translation.List(uint64(recipe.ID))
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	for i := range translations {
+		if translations[i].Locale == locale {
+			h.respond(w, NewTranslationResponseItem(translations[i]), http.StatusOK)
+			return
+		}
+	}
+
+	h.respondError(w, r, APIError{Code: CodeSaveTranslationFailed, StatusCode: http.StatusInternalServerError})
+}
+
+// TranslationDelete godoc
+// @Summary Delete a recipe translation
+// @Description Delete the translation of a recipe in a locale, only the recipe author can delete it
+// @ID delete-recipe-translation
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Param locale path string true "Locale"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id}/translations/{locale} [delete]
+func (h *Handler) TranslationDelete(w http.ResponseWriter, r *http.Request) {
+	recipe, locale, ok := h.translationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Translation.Delete(uint64(recipe.ID), locale); err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeUnknownTranslation, StatusCode: http.StatusNotFound})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// translationTarget loads the recipe of an author and the locale url parameter, the locale has to be supported and
+// differ from the locale the recipe is written in. On failure it responds with an error and returns false.
+func (h *Handler) translationTarget(w http.ResponseWriter, r *http.Request) (*database.Recipe, string, bool) {
+	recipe, ok := h.authorRecipe(w, r)
+	if !ok {
+		return nil, "", false
+	}
+
+	locale := i18n.Normalize(chi.URLParam(r, "locale"))
+	if !h.locales.IsSupported(locale) {
+		h.respondError(w, r, APIError{Code: CodeUnsupportedLocale, StatusCode: http.StatusBadRequest})
+		return nil, "", false
+	}
+	if locale == recipe.Locale {
+		h.respondError(w, r, NewAPIError(CodeRecipeLocale, http.StatusBadRequest, locale))
+		return nil, "", false
+	}
+
+	return recipe, locale, true
+}
@@ -0,0 +1,167 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+func TestHandler_RecipeTranslations(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	id, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Translated Spanakopita",
+		URL:         "http://example.com/translated-spanakopita",
+		Ingredients: database.Ingredients{{Name: "spinach"}, {Name: "feta"}, {Name: "filo"}},
+		UserID:      1,
+		Status:      database.RecipePublished,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipe, err := db.Recipe.Get(uint64(id), database.Viewer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spinach := recipe.Ingredients[0].ID
+
+	author := handler.Token{UserID: 1, Username: "username1"}
+	reader := handler.Token{UserID: 2, Username: "username2"}
+
+	// serve calls hf as token with the recipe id and locale url parameters
+	serve := func(hf http.HandlerFunc, token handler.Token, locale string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", fmt.Sprint(id))
+		ctx.URLParams.Add("locale", locale)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, token))
+
+		rr := httptest.NewRecorder()
+		hf.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	el := fmt.Sprintf(`{"title":"Σπανακόπιτα","ingredients":{"%d":"σπανάκι"}}`, spinach)
+	testData := []struct {
+		desc         string
+		handler      http.HandlerFunc
+		token        handler.Token
+		locale       string
+		body         string
+		expectedCode int
+	}{
+		{"Should not let other users translate", h.TranslationSave, reader, "el", el, http.StatusForbidden},
+		{"Should not translate to unsupported locales", h.TranslationSave, author, "fr", el, http.StatusBadRequest},
+		{"Should not translate to the recipe locale", h.TranslationSave, author, "en", el, http.StatusBadRequest},
+		{"Should not translate unknown ingredients", h.TranslationSave, author, "el",
+			`{"title":"Σπανακόπιτα","ingredients":{"999999":"τυρί"}}`, http.StatusBadRequest},
+		{"Should require a title", h.TranslationSave, author, "el", `{"ingredients":{}}`, http.StatusBadRequest},
+		{"Should translate a recipe", h.TranslationSave, author, "el", el, http.StatusOK},
+		{"Should replace a translation", h.TranslationSave, author, "el", el, http.StatusOK},
+		{"Should not translate to unsupported regions", h.TranslationSave, author, "de_DE",
+			`{"title":"Spinatkuchen"}`, http.StatusBadRequest},
+		{"Should translate to German", h.TranslationSave, author, "de", `{"title":"Spinatkuchen"}`, http.StatusOK},
+		{"Should delete a translation", h.TranslationDelete, author, "de", "", http.StatusNoContent},
+		{"Should fail to delete a missing translation", h.TranslationDelete, author, "de", "", http.StatusNotFound},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			rr := serve(tc.handler, tc.token, tc.locale, tc.body)
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+
+	// read gets the recipe with a lang query parameter and an Accept-Language header
+	read := func(lang string, acceptLanguage string) (handler.RecipeResponseItem, string) {
+		req := httptest.NewRequest(http.MethodGet, "/?lang="+url.QueryEscape(lang), nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", fmt.Sprint(id))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+		rr := httptest.NewRecorder()
+		h.Recipe(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+
+		resp := handler.RecipeResponseItem{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		return resp, rr.Header().Get("Content-Language")
+	}
+
+	localeData := []struct {
+		desc           string
+		lang           string
+		acceptLanguage string
+		expectedLocale string
+		expectedTitle  string
+	}{
+		{"Should serve the lang parameter", "el", "de", "el", "Σπανακόπιτα"},
+		{"Should serve Accept-Language", "", "el-GR,en;q=0.5", "el", "Σπανακόπιτα"},
+		{"Should fall back through Accept-Language", "", "de, el;q=0.8", "el", "Σπανακόπιτα"},
+		{"Should fall back to the recipe locale", "", "de", "en", "Translated Spanakopita"},
+		{"Should serve the recipe locale without preferences", "", "", "en", "Translated Spanakopita"},
+	}
+
+	for i := range localeData {
+		tc := localeData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			resp, contentLanguage := read(tc.lang, tc.acceptLanguage)
+			if resp.Locale != tc.expectedLocale || contentLanguage != tc.expectedLocale {
+				t.Fatalf("Expected locale %s got %s (%s)", tc.expectedLocale, resp.Locale, contentLanguage)
+			}
+			if resp.Title != tc.expectedTitle {
+				t.Fatalf("Expected title %s got %s", tc.expectedTitle, resp.Title)
+			}
+		})
+	}
+
+	if resp, _ := read("el", ""); resp.Ingredients[0].Name != "σπανάκι" || resp.Ingredients[1].Name != "feta" {
+		t.Fatalf("Expected translated ingredients to fall back to their original names, %+v", resp.Ingredients)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?lang=el&term="+url.QueryEscape("Σπανακ"), nil)
+	rr := httptest.NewRecorder()
+	h.Recipes(rr, req)
+
+	resp := handler.RecipesResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data == nil || len(*resp.Data) != 1 || (*resp.Data)[0].Locale != "el" {
+		t.Fatalf("Expected to find the recipe by its translated title, %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/database"
+)
+
+// RecipeDelete godoc
+// @Summary Delete a recipe
+// @Description Move a recipe to the trash, recipes can be deleted by their author and by moderators
+// @ID delete-recipe
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /recipes/{id} [delete]
+func (h *Handler) RecipeDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
+		return
+	}
+
+	// Moderators can trash recipes whatever their status and visibility
+	v := h.viewer(r)
+	v.Moderator = h.can(token, PermModerateRecipes)
+
+	recipe, err := h.db.Recipe.Get(id, v)
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUnknownRecipe, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	if recipe.UserID != token.UserID && !v.Moderator {
+		h.respondError(w, r, APIError{Code: CodeAuthorRequired, StatusCode: http.StatusForbidden})
+		return
+	}
+
+	if err := h.db.Recipe.Delete(id); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+	h.cache.Delete(cacheKeyIngredientNames)
+
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// RecipeTrash godoc
+// @Summary Get deleted recipes
+// @Description Get recipes in the trash, most recently deleted first
+// @ID get-admin-trash-recipes
+// @Accept  application/x-www-form-urlencoded
+// @Produce  json
+// @Param page query int false "Page number"
+// @Success 200 {object} handler.RecipesResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/trash/recipes [get]
+func (h *Handler) RecipeTrash(w http.ResponseWriter, r *http.Request) {
+	h.respondRecipePage(w, r, h.db.Recipe.Trash)
+}
+
+// RecipeRestore godoc
+// @Summary Restore a deleted recipe
+// @Description Bring a recipe and its ingredients back from the trash
+// @ID post-admin-trash-recipe-restore
+// @Produce  json
+// @Param id path int true "Recipe ID"
+// @Success 200 {object} handler.RecipeResponseItem
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/trash/recipes/{id}/restore [post]
+func (h *Handler) RecipeRestore(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeRecipeIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err := h.db.Recipe.Restore(id); err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeUnknownDeletedRecipe, StatusCode: http.StatusNotFound})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+	h.cache.Delete(cacheKeyIngredientNames)
+
+	recipe, err := h.db.Recipe.Get(id, database.Viewer{Moderator: true})
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := RecipeResponseItem{}
+	if err := EncodeEntity(recipe, &resp); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// UserDelete godoc
+// @Summary Delete a user
+// @Description Move a user to the trash, deleted users can not sign in
+// @ID delete-admin-user
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/users/{id} [delete]
+func (h *Handler) UserDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUserIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err := h.db.User.Delete(id); err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeUnknownUser, StatusCode: http.StatusNotFound})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+	h.cache.Delete(cacheKeyInactiveUsers)
+	if err := h.revokeUserTokens(int64(id), true); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, nil, http.StatusNoContent)
+}
+
+// UserTrash godoc
+// @Summary Get deleted users
+// @Description Get users in the trash, most recently deleted first
+// @ID get-admin-trash-users
+// @Produce  json
+// @Success 200 {object} handler.UsersResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/trash/users [get]
+func (h *Handler) UserTrash(w http.ResponseWriter, r *http.Request) {
+	users, err := h.db.User.Trash()
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp := UsersResponse{Data: []UserProfileResponse{}}
+	for i := range users {
+		resp.Data = append(resp.Data, NewUserProfileResponse(users[i]))
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// UserRestore godoc
+// @Summary Restore a deleted user
+// @Description Bring a user back from the trash, restoring an account its user deleted cancels its erasure
+// @ID post-admin-trash-user-restore
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} handler.UserProfileResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 404 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /admin/trash/users/{id}/restore [post]
+func (h *Handler) UserRestore(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		h.respondError(w, r, APIError{Code: CodeUserIDRequired, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err := h.db.User.Restore(id); err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeUnknownDeletedUser, StatusCode: http.StatusNotFound})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+	h.cache.Delete(cacheKeyInactiveUsers)
+
+	user, err := h.db.User.Get(id)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, NewUserProfileResponse(*user), http.StatusOK)
+}
+
+// PurgeTrash permanently removes recipes and users that have been in the trash longer than the configured retention
+func (h *Handler) PurgeTrash(now time.Time) error {
+	if h.cfg.Trash.Retention <= 0 {
+		return nil
+	}
+	before := now.Add(-time.Duration(h.cfg.Trash.Retention) * 24 * time.Hour)
+
+	recipes, err := h.db.Recipe.Purge(before)
+	if err != nil {
+		return err
+	}
+
+	users, err := h.db.User.Purge(before)
+	if err != nil {
+		return err
+	}
+
+	if recipes > 0 || users > 0 {
+		h.log.Printf("Purged %d recipes and %d users from the trash", recipes, users)
+	}
+
+	return nil
+}
+
+// PurgeTokens removes expired refresh tokens, access token revocations, used or expired password reset tokens and
+// expired provider sign ins
+func (h *Handler) PurgeTokens(now time.Time) error {
+	tokens, err := h.db.Token.Purge(now)
+	if err != nil {
+		return err
+	}
+	resets, err := h.db.Reset.Purge(now)
+	if err != nil {
+		return err
+	}
+	logins, err := h.db.OIDC.Purge(now)
+	if err != nil {
+		return err
+	}
+	tokens += resets + logins
+
+	if tokens > 0 {
+		h.log.Printf("Purged %d expired tokens", tokens)
+	}
+
+	return nil
+}
+
+// runPurge purges the trash and expired tokens and erases deleted accounts every purge interval until ctx is done
+func (h *Handler) runPurge(ctx context.Context) {
+	if h.cfg.Trash.PurgeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(h.cfg.Trash.PurgeInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := h.PurgeTrash(now); err != nil {
+				h.log.Errorf("failed to purge trash, %s", err)
+			}
+			if err := h.PurgeTokens(now); err != nil {
+				h.log.Errorf("failed to purge tokens, %s", err)
+			}
+			if err := h.EraseAccounts(now); err != nil {
+				h.log.Errorf("failed to erase accounts, %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/go-chi/chi"
+)
+
+func TestHandler_Trash(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	recipeID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Trashed Pancakes",
+		URL:         "http://example.com/trashed-pancakes",
+		Ingredients: database.Ingredients{{Name: "flour"}, {Name: "milk"}, {Name: "eggs"}},
+		UserID:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := db.User.Insert(database.User{
+		Username: "trashuser",
+		Password: "password",
+		FullName: "trash user",
+		Email:    "trash@test.gr",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	author := handler.Token{UserID: 1, Username: "username1"}
+	reader := handler.Token{UserID: 2, Username: "username2"}
+	moderator := handler.Token{UserID: 3, Username: "moderator1"}
+
+	testData := []struct {
+		desc         string
+		handler      http.HandlerFunc
+		token        handler.Token
+		id           int64
+		expectedCode int
+	}{
+		{"Should not let other users delete a recipe", h.RecipeDelete, reader, recipeID, http.StatusForbidden},
+		{"Should delete a recipe", h.RecipeDelete, author, recipeID, http.StatusNoContent},
+		{"Should hide a deleted recipe", h.Recipe, author, recipeID, http.StatusNotFound},
+		{"Should fail to delete a deleted recipe", h.RecipeDelete, author, recipeID, http.StatusNotFound},
+		{"Should restore a deleted recipe", h.RecipeRestore, moderator, recipeID, http.StatusOK},
+		{"Should fail to restore a recipe that is not deleted", h.RecipeRestore, moderator, recipeID, http.StatusNotFound},
+		{"Should show a restored recipe", h.Recipe, reader, recipeID, http.StatusOK},
+		{"Should let moderators delete a recipe", h.RecipeDelete, moderator, recipeID, http.StatusNoContent},
+		{"Should delete a user", h.UserDelete, moderator, userID, http.StatusNoContent},
+		{"Should fail to delete a deleted user", h.UserDelete, moderator, userID, http.StatusNotFound},
+		{"Should restore a deleted user", h.UserRestore, moderator, userID, http.StatusOK},
+		{"Should fail to restore a user that is not deleted", h.UserRestore, moderator, userID, http.StatusNotFound},
+		{"Should delete a restored user", h.UserDelete, moderator, userID, http.StatusNoContent},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("id", fmt.Sprint(tc.id))
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+			req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, tc.token))
+
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+
+	// inTrash lists the trash and reports whether the recipe and the user are in it
+	inTrash := func() (bool, bool) {
+		rr := httptest.NewRecorder()
+		h.RecipeTrash(rr, httptest.NewRequest(http.MethodGet, "/admin/trash/recipes", nil))
+		recipes := handler.RecipesResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &recipes); err != nil {
+			t.Fatal(err)
+		}
+
+		rr = httptest.NewRecorder()
+		h.UserTrash(rr, httptest.NewRequest(http.MethodGet, "/admin/trash/users", nil))
+		users := handler.UsersResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &users); err != nil {
+			t.Fatal(err)
+		}
+
+		var recipeFound, userFound bool
+		if recipes.Data != nil {
+			for _, r := range *recipes.Data {
+				recipeFound = recipeFound || (r.ID == recipeID && r.DeletedAt != "")
+			}
+		}
+		for _, u := range users.Data {
+			userFound = userFound || (u.ID == userID && u.DeletedAt != "")
+		}
+
+		return recipeFound, userFound
+	}
+
+	if recipeFound, userFound := inTrash(); !recipeFound || !userFound {
+		t.Fatalf("Expected recipe (%t) and user (%t) to be in the trash", recipeFound, userFound)
+	}
+
+	// Nothing expires within the retention period
+	if err := h.PurgeTrash(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if recipeFound, userFound := inTrash(); !recipeFound || !userFound {
+		t.Fatalf("Expected recipe (%t) and user (%t) to be kept", recipeFound, userFound)
+	}
+
+	retention := time.Duration(cfg.Trash.Retention+1) * 24 * time.Hour
+	if err := h.PurgeTrash(time.Now().Add(retention)); err != nil {
+		t.Fatal(err)
+	}
+	if recipeFound, userFound := inTrash(); recipeFound || userFound {
+		t.Fatalf("Expected recipe (%t) and user (%t) to be purged", recipeFound, userFound)
+	}
+}
+
+func TestHandler_TrashedIngredients(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+
+	recipeID, err := db.Recipe.Insert(database.Recipe{
+		Title:       "Trashed Saffron Rice",
+		URL:         "http://example.com/trashed-saffron-rice",
+		Ingredients: database.Ingredients{{Name: "trashed saffron"}, {Name: "rice"}},
+		UserID:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// serve calls hf as the author of the recipe
+	serve := func(hf http.HandlerFunc, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("id", fmt.Sprint(recipeID))
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		req = req.WithContext(context.WithValue(req.Context(), handler.CtxKeyToken, handler.Token{UserID: 1}))
+
+		rr := httptest.NewRecorder()
+		hf.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// suggested reports whether autocomplete suggests the ingredient of the recipe
+	suggested := func() bool {
+		rr := serve(h.Ingredients, "/ingredients?prefix=trashed+saff")
+		resp := handler.IngredientSuggestionsResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		for i := range resp.Data {
+			if resp.Data[i].Name == "trashed saffron" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !suggested() {
+		t.Fatal("Expected the ingredient to be suggested before the recipe is deleted")
+	}
+	if rr := serve(h.RecipeSubstitutions, "/"); rr.Code != http.StatusOK {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	if rr := serve(h.RecipeDelete, "/"); rr.Code != http.StatusNoContent {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+
+	if suggested() {
+		t.Fatal("Expected the ingredients of a deleted recipe not to be suggested")
+	}
+	if rr := serve(h.RecipeSubstitutions, "/"); rr.Code != http.StatusNotFound {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusNotFound, rr.Body.String())
+	}
+}
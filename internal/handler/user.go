@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/georlav/recipeapi/internal/database"
 	"golang.org/x/crypto/bcrypt"
@@ -22,15 +23,15 @@ import (
 // @Security ApiKeyAuth
 // @Router /user [get]
 func (h Handler) User(w http.ResponseWriter, r *http.Request) {
-	token, err := h.getToken(r)
-	if err != nil {
-		h.respondError(w, APIError{Message: err.Error(), StatusCode: http.StatusUnauthorized})
+	token := h.caller(r)
+	if token == nil {
+		h.respondError(w, r, errAuthRequired)
 		return
 	}
 
 	user, err := h.db.User.Get(uint64(token.UserID))
 	if err != nil {
-		h.respondError(w, APIError{Message: "unknown user", StatusCode: http.StatusNotFound})
+		h.respondError(w, r, APIError{Code: CodeUnknownUser, StatusCode: http.StatusNotFound})
 		return
 	}
 
@@ -46,113 +47,159 @@ func (h Handler) User(w http.ResponseWriter, r *http.Request) {
 // @Param credentials body handler.SignInRequest false "credentials payload"
 // @Success 200 {object} handler.TokenResponse
 // @Failure 400 {object} handler.ErrorResponse
+// @Failure 401 {object} handler.ErrorResponse
+// @Failure 403 {object} handler.ErrorResponse
 // @Failure 404 {object} handler.ErrorResponse
+// @Failure 423 {object} handler.ErrorResponse
+// @Failure 429 {object} handler.ErrorResponse
 // @Failure 500 {object} handler.ErrorResponse
 // @Router /user/signin [post]
 func (h Handler) SignIn(w http.ResponseWriter, r *http.Request) {
 	si := SignInRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&si); err != nil {
-		h.respondError(w, APIError{Message: http.StatusText(http.StatusBadRequest), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, errBadRequest)
 		return
 	}
 
 	// Validate sign up request
 	if err := h.validate.Struct(si); err != nil {
-		h.respondError(w, APIError{Message: http.StatusText(http.StatusBadRequest), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	// Callers that failed too often wait before they can try again
+	now := time.Now()
+	if h.loginThrottled(w, r, si.Username, now) {
 		return
 	}
 
 	// Get user
 	u, err := h.db.User.GetByUsername(si.Username)
 	if err != nil {
-		h.respondError(w, APIError{
-			Message:    "You have entered an invalid username or password",
+		h.loginFailed(r, si.Username, nil, "unknown_user", now)
+		h.respondError(w, r, APIError{
+			Code:       CodeInvalidCredentials,
 			StatusCode: http.StatusUnauthorized,
 		})
 		return
 	}
 
+	// Locked users are rejected before their password is checked
+	if u.LockedUntil != "" {
+		h.log.SecurityEvent("login_locked", false, map[string]interface{}{
+			"user_id":      u.ID,
+			"username":     u.Username,
+			"ip":           clientIP(r),
+			"locked_until": u.LockedUntil,
+		})
+		h.respondError(w, r, APIError{Code: CodeAccountLocked, StatusCode: http.StatusLocked})
+		return
+	}
+
 	// User exists check password
 	if err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(si.Password)); err != nil {
-		h.respondError(w, APIError{
-			Message:    "You have entered an invalid username or password",
+		h.loginFailed(r, si.Username, u, "wrong_password", now)
+		h.respondError(w, r, APIError{
+			Code:       CodeInvalidCredentials,
 			StatusCode: http.StatusUnauthorized,
 		})
 		return
 	}
+	h.loginUsers.Reset(loginKey(si.Username))
+
+	// Unverified and deactivated users keep their password but can not sign in
+	if !u.Active {
+		code := CodeUserInactive
+		if u.VerifiedAt == "" {
+			code = CodeEmailUnverified
+		}
+		h.respondError(w, r, APIError{Code: code, StatusCode: http.StatusForbidden})
+		return
+	}
 
-	// User password is correct create token
-	token, err := h.newToken(u)
+	// User password is correct create tokens
+	resp, err := h.newTokens(u, "")
 	if err != nil {
-		h.respondError(w, err)
+		h.respondError(w, r, err)
 		return
 	}
+	h.log.SecurityEvent("login_succeeded", true, map[string]interface{}{
+		"user_id":  u.ID,
+		"username": u.Username,
+		"ip":       clientIP(r),
+	})
 
-	// Respond with a valid token
-	resp := TokenResponse{Token: *token}
+	// Respond with valid tokens
 	h.respond(w, resp, http.StatusOK)
 }
 
 // SignUp godoc
 // @Summary user sign up
-// @Description user sign up
+// @Description Create an inactive account and email it a verification link, verifying the address activates the
+// @Description account and signs in
 // @ID user-sign-up
 // @Accept  json
 // @Produce  json
 // @Param body body handler.SignUpRequest true "sign up payload"
-// @Success 200 {object} handler.TokenResponse
+// @Success 201 {object} handler.UserProfileResponse
 // @Failure 400 {object} handler.ErrorResponse
 // @Failure 404 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
 // @Failure 500 {object} handler.ErrorResponse
 // @Router /user/signup [post]
 func (h Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 	// Map request to struct
 	u := SignUpRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		h.respondError(w, APIError{Message: http.StatusText(http.StatusBadRequest), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, errBadRequest)
 		return
 	}
 
 	// Validate sign up request
 	if err := h.validate.Struct(u); err != nil {
-		h.respondError(w, APIError{Message: http.StatusText(http.StatusBadRequest), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, validationError(err))
 		return
 	}
 
 	// Check if username is available
 	if _, err := h.db.User.GetByUsername(u.Username); err == nil {
-		h.respondError(w, APIError{Message: "Username is taken", StatusCode: http.StatusConflict})
+		h.respondError(w, r, APIError{Code: CodeUsernameTaken, StatusCode: http.StatusConflict})
 		return
 	}
 
 	// Create hash from password
 	hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 	if err != nil {
-		h.respondError(w, APIError{Message: http.StatusText(http.StatusBadRequest), StatusCode: http.StatusBadRequest})
+		h.respondError(w, r, errBadRequest)
 		return
 	}
 
-	// Create user
+	// Create user, accounts stay inactive until their email address is verified
 	uID, err := h.db.User.Insert(database.User{
 		Username: u.Username,
 		Password: string(hash),
 		FullName: u.FullName,
 		Email:    u.Email,
-		Active:   true,
 	})
 	if err != nil {
-		h.respondError(w, errors.New("failed to create user"))
+		switch {
+		case errors.Is(err, database.ErrEmailTaken):
+			h.respondError(w, r, APIError{Code: CodeEmailTaken, StatusCode: http.StatusConflict})
+		case errors.Is(err, database.ErrDuplicateEntry):
+			h.respondError(w, r, APIError{Code: CodeUsernameTaken, StatusCode: http.StatusConflict})
+		default:
+			h.respondError(w, r, errors.New("failed to create user"))
+		}
 		return
 	}
+	h.cache.Delete(cacheKeyInactiveUsers)
 
-	// User created, generate a token
-	token, err := h.newToken(&database.User{ID: uID, Username: u.Username})
+	user, err := h.db.User.Get(uint64(uID))
 	if err != nil {
-		h.respondError(w, err)
+		h.respondError(w, r, err)
 		return
 	}
+	h.sendVerification(user)
 
-	// Respond with a token
-	resp := TokenResponse{Token: *token}
-	h.respond(w, resp, http.StatusOK)
+	h.respond(w, NewUserProfileResponse(*user), http.StatusCreated)
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/georlav/recipeapi/internal/database"
 	"github.com/georlav/recipeapi/internal/handler"
 	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/georlav/recipeapi/internal/mailer"
 )
 
 func TestHandler_User(t *testing.T) {
@@ -155,7 +156,7 @@ func TestHandler_SignUp(t *testing.T) {
 		{
 			"Should create an account",
 			`{"username":"username2","password":"password","repeatPassword":"password","fullName":"test user","email":"email@email.com"}`,
-			http.StatusOK,
+			http.StatusCreated,
 		},
 		{
 			"Should fail because passwords doesnt match",
@@ -187,6 +188,7 @@ func TestHandler_SignUp(t *testing.T) {
 	}
 
 	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+	h.SetMailer(mailer.NewMemory())
 
 	for i := range testData {
 		tc := testData[i]
@@ -203,14 +205,14 @@ func TestHandler_SignUp(t *testing.T) {
 				t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, tc.expectedCode, rr.Body.String())
 			}
 
-			if rr.Code == http.StatusOK {
-				tr := handler.TokenResponse{}
-				if err := json.Unmarshal(rr.Body.Bytes(), &tr); err != nil {
+			if rr.Code == http.StatusCreated {
+				up := handler.UserProfileResponse{}
+				if err := json.Unmarshal(rr.Body.Bytes(), &up); err != nil {
 					t.Fatal(err)
 				}
 
-				if tr.Token == "" {
-					t.Fatal("Token is empty")
+				if up.Active || up.Verified {
+					t.Fatal("New accounts should be inactive until verified")
 				}
 			}
 		})
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/mailer"
+)
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Activate a new account with the token of its verification link and sign in
+// @ID user-verify
+// @Accept  json
+// @Produce  json
+// @Param body body handler.VerifyEmailRequest true "verification token"
+// @Success 200 {object} handler.TokenResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /user/verify [post]
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	vr := VerifyEmailRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&vr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(vr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	vt := VerifyToken{}
+	token, err := jwt.ParseWithClaims(vr.Token, &vt, h.keys.Keyfunc)
+	// Access and share link tokens are signed with the same keys but carry no verification claims
+	if err != nil || !token.Valid || vt.UserID <= 0 || vt.Email == "" {
+		h.respondError(w, r, APIError{Code: CodeInvalidVerification, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	// Links sent to an address the user no longer has, or that were already used, fail here
+	if err := h.db.User.Verify(uint64(vt.UserID), vt.Email); err != nil {
+		if errors.Is(err, database.ErrNoRows) {
+			h.respondError(w, r, APIError{Code: CodeInvalidVerification, StatusCode: http.StatusBadRequest})
+			return
+		}
+		h.respondError(w, r, err)
+		return
+	}
+	h.cache.Delete(cacheKeyInactiveUsers)
+
+	user, err := h.db.User.Get(uint64(vt.UserID))
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resp, err := h.newTokens(user, "")
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.respond(w, resp, http.StatusOK)
+}
+
+// VerifyEmailResend godoc
+// @Summary Resend a verification email
+// @Description Send a new verification link to an account that is not verified yet. The response is the same whether
+// @Description an account with the address exists or not, addresses can only be sent a few emails per hour.
+// @ID user-verify-resend
+// @Accept  json
+// @Produce  json
+// @Param body body handler.VerifyEmailResendRequest true "email address"
+// @Success 202
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 429 {object} handler.ErrorResponse
+// @Failure 500 {object} handler.ErrorResponse
+// @Router /user/verify/resend [post]
+func (h *Handler) VerifyEmailResend(w http.ResponseWriter, r *http.Request) {
+	vr := VerifyEmailResendRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&vr); err != nil {
+		h.respondError(w, r, errBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(vr); err != nil {
+		h.respondError(w, r, validationError(err))
+		return
+	}
+
+	// Throttle before looking the address up so throttling does not reveal which addresses have an account
+	if ok, retry := h.verifyLimit.Allow("email:"+strings.ToLower(vr.Email), time.Now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		h.respondError(w, r, APIError{Code: CodeRateLimited, StatusCode: http.StatusTooManyRequests})
+		return
+	}
+
+	user, err := h.db.User.GetByEmail(vr.Email)
+	if err == nil && !user.Active && user.VerifiedAt == "" {
+		h.sendVerification(user)
+	}
+
+	h.respond(w, nil, http.StatusAccepted)
+}
+
+// sendVerification emails a signed verification link to a user, failures are logged since the user can ask for
+// another link
+func (h *Handler) sendVerification(u *database.User) {
+	link, err := h.signedLink(h.cfg.Verify.URL, VerifyToken{UserID: u.ID, Email: u.Email, StandardClaims: h.linkClaims()})
+	if err != nil {
+		h.log.Errorf("failed to create verification link of user %d, %s", u.ID, err)
+		return
+	}
+
+	if err := h.mailer.Send(mailer.Message{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email address and activate your account, "+
+			"the link expires in %d hours.\n\n%s\n\nIf you did not sign up you can ignore this email.\n",
+			displayName(u), h.cfg.Verify.TTL, link),
+	}); err != nil {
+		h.log.Errorf("failed to send verification email to user %d, %s", u.ID, err)
+	}
+}
+
+// linkClaims returns the standard claims of a signed link emailed now, links expire after the verification link
+// lifetime
+func (h *Handler) linkClaims() jwt.StandardClaims {
+	now := time.Now()
+
+	return jwt.StandardClaims{
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Duration(h.cfg.Verify.TTL) * time.Hour).Unix(),
+	}
+}
+
+// signedLink returns base with claims signed as token query parameter
+func (h *Handler) signedLink(base string, claims jwt.Claims) (string, error) {
+	token, err := h.keys.Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("signature error, %w", err)
+	}
+
+	return withToken(base, token)
+}
+
+// withToken returns base with token added as token query parameter
+func withToken(base string, token string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid link url, %w", err)
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	return link.String(), nil
+}
+
+// displayName returns the name emails greet a user with
+func displayName(u *database.User) string {
+	if u.FullName != "" {
+		return u.FullName
+	}
+
+	return u.Username
+}
+
+// isInactive reports whether the user with the given id is unverified, deactivated or deleted, inactive users are
+// cached so checking them does not query the database on every request
+func (h *Handler) isInactive(userID int64) (bool, error) {
+	inactive, ok := h.cache.Get(cacheKeyInactiveUsers)
+	if !ok {
+		var err error
+		if inactive, err = h.db.User.Inactive(); err != nil {
+			return false, err
+		}
+		h.cache.Set(cacheKeyInactiveUsers, inactive)
+	}
+
+	_, ok = inactive.(map[int64]struct{})[userID]
+
+	return ok, nil
+}
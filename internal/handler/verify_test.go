@@ -0,0 +1,111 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/database"
+	"github.com/georlav/recipeapi/internal/handler"
+	"github.com/georlav/recipeapi/internal/logger"
+	"github.com/georlav/recipeapi/internal/mailer"
+)
+
+func TestHandler_VerifyEmail(t *testing.T) {
+	cfg, err := config.New("config", "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewHandler(db, cfg, logger.NewLogger(cfg.Logger))
+	mails := mailer.NewMemory()
+	h.SetMailer(mails)
+
+	post := func(fn http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		fn.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	// link returns the token of the last verification link sent to the address
+	link := func(email string) string {
+		m, ok := mails.Last(email)
+		if !ok {
+			t.Fatalf("Expected a verification email to %s", email)
+		}
+		u, err := url.Parse(regexp.MustCompile(`http\S+`).FindString(m.Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Query().Get("token")
+	}
+
+	signIn := `{"username": "verify1", "password": "password"}`
+	rr := post(h.SignUp, `{"username":"verify1","password":"password","repeatPassword":"password",
+"fullName":"verify user","email":"verify1@test.gr"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	first := link("verify1@test.gr")
+
+	t.Run("Should not sign in unverified users", func(t *testing.T) {
+		rr := post(h.SignIn, signIn)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), handler.CodeEmailUnverified) {
+			t.Fatalf("Expected an unverified email error got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should resend verification links", func(t *testing.T) {
+		sent := len(mails.Messages())
+		if rr := post(h.VerifyEmailResend, `{"email":"verify1@test.gr"}`); rr.Code != http.StatusAccepted {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusAccepted, rr.Body.String())
+		}
+		if rr := post(h.VerifyEmailResend, `{"email":"unknown@test.gr"}`); rr.Code != http.StatusAccepted {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusAccepted, rr.Body.String())
+		}
+		if n := len(mails.Messages()); n != sent+1 {
+			t.Fatalf("Expected a single email to be sent got %d", n-sent)
+		}
+	})
+
+	t.Run("Should throttle resending", func(t *testing.T) {
+		var rr *httptest.ResponseRecorder
+		for i := 0; i <= cfg.Verify.ResendLimit; i++ {
+			rr = post(h.VerifyEmailResend, `{"email":"throttled@test.gr"}`)
+		}
+		if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+			t.Fatalf("Expected the address to be throttled got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Should reject invalid links", func(t *testing.T) {
+		if rr := post(h.VerifyEmail, `{"token":"xxx.yyyy.zzzz"}`); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+		}
+	})
+
+	t.Run("Should verify and sign in", func(t *testing.T) {
+		rr := post(h.VerifyEmail, fmt.Sprintf(`{"token":%q}`, first))
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "refreshToken") {
+			t.Fatalf("Expected tokens got %d, %s", rr.Code, rr.Body.String())
+		}
+		if rr := post(h.SignIn, signIn); rr.Code != http.StatusOK {
+			t.Fatalf("Wrong status code got %d expected %d, %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if rr := post(h.VerifyEmail, fmt.Sprintf(`{"token":%q}`, first)); rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected used links to be rejected got %d, %s", rr.Code, rr.Body.String())
+		}
+	})
+}
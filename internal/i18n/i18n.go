@@ -0,0 +1,134 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locales resolves the locales requested by clients to the supported ones, content that is missing in a locale
+// falls back to the next locale of the chain and finally to the default locale
+type Locales struct {
+	Default   string
+	Supported []string
+}
+
+// New creates Locales, the default locale is always supported
+func New(def string, supported ...string) *Locales {
+	l := Locales{Default: Normalize(def)}
+	for _, s := range append([]string{def}, supported...) {
+		if s = Normalize(s); s != "" && !l.IsSupported(s) {
+			l.Supported = append(l.Supported, s)
+		}
+	}
+
+	return &l
+}
+
+// IsSupported reports whether locale is one of the supported locales
+func (l *Locales) IsSupported(locale string) bool {
+	locale = Normalize(locale)
+	for i := range l.Supported {
+		if l.Supported[i] == locale {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Chain returns the supported locales matching the preferred ones in order of preference, a regional locale that
+// is not supported falls back to its language, "de-AT" matches "de". The default locale is always last.
+func (l *Locales) Chain(preferred ...string) []string {
+	chain := make([]string, 0, len(preferred)+1)
+	add := func(locale string) {
+		if !l.IsSupported(locale) {
+			return
+		}
+		for i := range chain {
+			if chain[i] == locale {
+				return
+			}
+		}
+		chain = append(chain, locale)
+	}
+
+	for _, p := range preferred {
+		p = Normalize(p)
+		add(p)
+		if i := strings.Index(p, "-"); i > 0 {
+			add(p[:i])
+		}
+	}
+	add(l.Default)
+
+	return chain
+}
+
+// Normalize lower cases a locale and uses dashes as separators, "el_GR" becomes "el-gr"
+func Normalize(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header ordered by their quality value, locales
+// with a zero quality value and the wildcard are dropped
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var ws []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := Normalize(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if !strings.HasPrefix(f, "q=") {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimPrefix(f, "q="), 64)
+			if err != nil {
+				v = 0
+			}
+			q = v
+		}
+		if q <= 0 {
+			continue
+		}
+
+		ws = append(ws, weighted{locale: locale, q: q})
+	}
+
+	sort.SliceStable(ws, func(i, j int) bool {
+		return ws[i].q > ws[j].q
+	})
+
+	locales := make([]string, 0, len(ws))
+	for i := range ws {
+		locales = append(locales, ws[i].locale)
+	}
+
+	return locales
+}
+
+// Catalog holds translated messages by locale and message key, messages are fmt format strings
+type Catalog map[string]map[string]string
+
+// Message formats the message of key in the first locale of chain that has one, the key is returned when no locale
+// of the chain has a message for it
+func (c Catalog) Message(chain []string, key string, args ...interface{}) string {
+	for _, locale := range chain {
+		if format, ok := c[Normalize(locale)][key]; ok {
+			return fmt.Sprintf(format, args...)
+		}
+	}
+
+	return key
+}
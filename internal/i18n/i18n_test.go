@@ -0,0 +1,93 @@
+package i18n_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/i18n"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	testData := []struct {
+		header   string
+		expected []string
+	}{
+		{"", []string{}},
+		{"el", []string{"el"}},
+		{"de-DE,de;q=0.9,en;q=0.8", []string{"de-de", "de", "en"}},
+		{"en;q=0.5, el-GR", []string{"el-gr", "en"}},
+		{"fr;q=0, *;q=0.1, de;q=abc, el", []string{"el"}},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.header, func(t *testing.T) {
+			locales := i18n.ParseAcceptLanguage(tc.header)
+			if !reflect.DeepEqual(locales, tc.expected) {
+				t.Fatalf("Expected %v got %v", tc.expected, locales)
+			}
+		})
+	}
+}
+
+func TestLocales_Chain(t *testing.T) {
+	l := i18n.New("en", "el", "de")
+
+	testData := []struct {
+		desc      string
+		preferred []string
+		expected  []string
+	}{
+		{"Should fall back to the default locale", nil, []string{"en"}},
+		{"Should keep the order of preference", []string{"el", "de"}, []string{"el", "de", "en"}},
+		{"Should fall back to the language of a region", []string{"de-AT"}, []string{"de", "en"}},
+		{"Should drop unsupported locales", []string{"fr", "el_GR"}, []string{"el", "en"}},
+		{"Should not repeat locales", []string{"en", "el", "en"}, []string{"en", "el"}},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if chain := l.Chain(tc.preferred...); !reflect.DeepEqual(chain, tc.expected) {
+				t.Fatalf("Expected %v got %v", tc.expected, chain)
+			}
+		})
+	}
+
+	if !l.IsSupported("EL") || l.IsSupported("fr") {
+		t.Fatal("Unexpected supported locales")
+	}
+}
+
+func TestCatalog_Message(t *testing.T) {
+	c := i18n.Catalog{
+		"en": {"unknown_recipe": "unknown recipe", "recipe_locale": "recipe is written in %s"},
+		"el": {"unknown_recipe": "άγνωστη συνταγή"},
+	}
+
+	testData := []struct {
+		desc     string
+		chain    []string
+		key      string
+		args     []interface{}
+		expected string
+	}{
+		{"Should use the first locale of the chain", []string{"el", "en"}, "unknown_recipe", nil, "άγνωστη συνταγή"},
+		{"Should fall back to the next locale", []string{"el", "en"}, "recipe_locale", []interface{}{"de"},
+			"recipe is written in de"},
+		{"Should skip locales without a catalog", []string{"de", "en"}, "unknown_recipe", nil, "unknown recipe"},
+		{"Should return unknown keys", []string{"el", "en"}, "unknown_key", nil, "unknown_key"},
+	}
+
+	for i := range testData {
+		tc := testData[i]
+
+		t.Run(tc.desc, func(t *testing.T) {
+			if msg := c.Message(tc.chain, tc.key, tc.args...); msg != tc.expected {
+				t.Fatalf("Expected %s got %s", tc.expected, msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/georlav/recipeapi/internal/config"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Key is a key tokens are verified with, keys with a private part can also sign
+type Key struct {
+	ID        string
+	Algorithm string
+	public    crypto.PublicKey
+	private   crypto.Signer
+}
+
+// Keyring signs and verifies tokens, it is safe for concurrent use and its keys can be replaced while in use
+type Keyring struct {
+	mu      sync.RWMutex
+	secret  []byte
+	signing *Key
+	keys    []*Key
+}
+
+// New creates a keyring that signs and verifies HS256 tokens with secret
+func New(secret string) *Keyring {
+	return &Keyring{secret: []byte(secret)}
+}
+
+// Load replaces the keys of the keyring with the keys of cfg, the keys in use are kept when any key fails to load
+func (k *Keyring) Load(cfg config.Token) error {
+	keys := make([]*Key, 0, len(cfg.Keys))
+	var signing *Key
+
+	for _, sk := range cfg.Keys {
+		key, err := loadKey(sk)
+		if err != nil {
+			return err
+		}
+		for _, existing := range keys {
+			if existing.ID == key.ID {
+				return fmt.Errorf("keyring error, duplicate key id %s", key.ID)
+			}
+		}
+		if key.ID == cfg.SigningKey {
+			signing = key
+		}
+		keys = append(keys, key)
+	}
+
+	if cfg.SigningKey != "" && signing == nil {
+		return fmt.Errorf("keyring error, signing key %s is not configured", cfg.SigningKey)
+	}
+	if signing != nil && signing.private == nil {
+		return fmt.Errorf("keyring error, signing key %s has no private key", signing.ID)
+	}
+	// Without a signing key tokens are signed with the secret, an empty secret would sign tokens anyone can forge
+	if signing == nil && cfg.Secret == "" {
+		return fmt.Errorf("keyring error, neither a signing key nor a secret is configured")
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.secret, k.signing, k.keys = []byte(cfg.Secret), signing, keys
+
+	return nil
+}
+
+// Sign signs claims with the signing key, the kid header names the key. Without a signing key claims are signed
+// with the HS256 secret
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.signing.Algorithm), claims)
+	token.Header["kid"] = k.signing.ID
+
+	return token.SignedString(k.signing.private)
+}
+
+// Keyfunc is the jwt key function of tokens signed by the keyring, tokens with a kid header are verified with that
+// key and algorithm, tokens without one with the HS256 secret as long as it is set
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || (len(k.secret) == 0 && len(k.keys) > 0) {
+			return nil, errors.New("unexpected signing method")
+		}
+		return k.secret, nil
+	}
+
+	for _, key := range k.keys {
+		if key.ID == kid {
+			if token.Method.Alg() != key.Algorithm {
+				return nil, errors.New("unexpected signing method")
+			}
+			return key.public, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key %s", kid)
+}
+
+// JWK is the public part of a key as JSON web key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON web key set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the keyring, the secret is never published
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = encode(pub.N.Bytes())
+			jwk.E = encode(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.KeyType, jwk.Curve = "EC", pub.Curve.Params().Name
+			jwk.X = encode(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = encode(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.KeyType, jwk.Curve = "OKP", "Ed25519"
+			jwk.X = encode(pub)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// PublicKey returns the public key of the JSON web key, supports the key types the keyring publishes
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case j.KeyType == "RSA":
+		n, err := decode(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case j.KeyType == "EC" && j.Curve == "P-256":
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("keyring error, key %s is not on its curve", j.KeyID)
+		}
+		return &pub, nil
+	case j.KeyType == "OKP" && j.Curve == "Ed25519":
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("keyring error, key %s has an invalid size", j.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("keyring error, key %s has unsupported type %s", j.KeyID, j.KeyType)
+}
+
+// encode returns b base64 url encoded without padding
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decode returns the bytes of the base64 url encoded s
+func decode(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("keyring error, %w", err)
+	}
+
+	return b, nil
+}
+
+// loadKey reads the PEM file of sk, files holding only a public key give keys that verify but do not sign
+func loadKey(sk config.SigningKey) (*Key, error) {
+	if sk.ID == "" {
+		return nil, fmt.Errorf("keyring error, key %s has no id", sk.File)
+	}
+
+	b, err := os.ReadFile(sk.File)
+	if err != nil {
+		return nil, fmt.Errorf("keyring error, %w", err)
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("keyring error, key %s is not PEM encoded", sk.ID)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("keyring error, key %s has unsupported PEM type %s", sk.ID, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keyring error, key %s, %w", sk.ID, err)
+	}
+
+	key := Key{ID: sk.ID, Algorithm: sk.Algorithm}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private, key.public = signer, signer.Public()
+	} else {
+		key.public = parsed
+	}
+
+	if !fits(key.Algorithm, key.public) {
+		return nil, fmt.Errorf("keyring error, key %s can not be used with %s", sk.ID, sk.Algorithm)
+	}
+
+	return &key, nil
+}
+
+// fits reports whether pub is a key of algorithm, RSA keys need at least 2048 bits and ES256 keys the P-256 curve
+func fits(algorithm string, pub crypto.PublicKey) bool {
+	switch algorithm {
+	case AlgorithmRS256:
+		k, ok := pub.(*rsa.PublicKey)
+		return ok && k.N.BitLen() >= 2048
+	case AlgorithmES256:
+		k, ok := pub.(*ecdsa.PublicKey)
+		return ok && k.Curve == elliptic.P256()
+	case AlgorithmEdDSA:
+		_, ok := pub.(ed25519.PublicKey)
+		return ok
+	}
+
+	return false
+}
@@ -0,0 +1,303 @@
+package keyring_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/georlav/recipeapi/internal/config"
+	"github.com/georlav/recipeapi/internal/keyring"
+)
+
+// writeKey writes the PKCS8 private key, or the PKIX public key when public, of key to a PEM file in dir
+func writeKey(t *testing.T, dir string, name string, key interface{}, public bool) string {
+	t.Helper()
+
+	var (
+		b   []byte
+		typ = "PRIVATE KEY"
+		err error
+	)
+	if public {
+		typ = "PUBLIC KEY"
+		b, err = x509.MarshalPKIXPublicKey(key)
+	} else {
+		b, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+// keyFiles generates a key of each algorithm and returns their files by algorithm
+func keyFiles(t *testing.T) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]string{
+		keyring.AlgorithmRS256:          writeKey(t, dir, "rs256.pem", rsaKey, false),
+		keyring.AlgorithmES256:          writeKey(t, dir, "es256.pem", ecKey, false),
+		keyring.AlgorithmEdDSA:          writeKey(t, dir, "eddsa.pem", edKey, false),
+		keyring.AlgorithmEdDSA + ".pub": writeKey(t, dir, "eddsa.pub.pem", edPublic, true),
+	}
+}
+
+func claims() jwt.StandardClaims {
+	return jwt.StandardClaims{Subject: "1", ExpiresAt: time.Now().Add(time.Minute).Unix()}
+}
+
+// verify parses signed with the keyring and returns the kid it was signed with
+func verify(k *keyring.Keyring, signed string) (string, error) {
+	token, err := jwt.ParseWithClaims(signed, &jwt.StandardClaims{}, k.Keyfunc)
+	if err != nil {
+		return "", err
+	}
+	kid, _ := token.Header["kid"].(string)
+
+	return kid, nil
+}
+
+func TestKeyring_Sign(t *testing.T) {
+	files := keyFiles(t)
+
+	for _, alg := range []string{keyring.AlgorithmRS256, keyring.AlgorithmES256, keyring.AlgorithmEdDSA} {
+		t.Run("Should sign with "+alg, func(t *testing.T) {
+			k := keyring.New("")
+			err := k.Load(config.Token{SigningKey: "k1", Keys: []config.SigningKey{{ID: "k1", Algorithm: alg, File: files[alg]}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signed, err := k.Sign(claims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			kid, err := verify(k, signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kid != "k1" {
+				t.Fatalf("Expected kid k1 got %s", kid)
+			}
+		})
+	}
+
+	t.Run("Should sign with the secret without keys", func(t *testing.T) {
+		k := keyring.New("secret")
+		signed, err := k.Sign(claims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kid, err := verify(k, signed); err != nil || kid != "" {
+			t.Fatalf("Expected an HS256 token without kid got %s, %v", kid, err)
+		}
+		if _, err := verify(keyring.New("other"), signed); err == nil {
+			t.Fatal("Expected tokens of another secret to be rejected")
+		}
+	})
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	files := keyFiles(t)
+	k := keyring.New("secret")
+	legacy, err := k.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := config.SigningKey{ID: "2024-01", Algorithm: keyring.AlgorithmRS256, File: files[keyring.AlgorithmRS256]}
+	ed := config.SigningKey{ID: "2024-02", Algorithm: keyring.AlgorithmEdDSA, File: files[keyring.AlgorithmEdDSA]}
+
+	if err := k.Load(config.Token{Secret: "secret", SigningKey: rs.ID, Keys: []config.SigningKey{rs, ed}}); err != nil {
+		t.Fatal(err)
+	}
+	old, err := k.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verify(k, legacy); err != nil {
+		t.Fatalf("Expected tokens of the secret to verify while it is set, %s", err)
+	}
+
+	// The published key starts signing, tokens of the previous key keep working until it is removed
+	if err := k.Load(config.Token{SigningKey: ed.ID, Keys: []config.SigningKey{rs, ed}}); err != nil {
+		t.Fatal(err)
+	}
+	current, err := k.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, err := verify(k, current); err != nil || kid != ed.ID {
+		t.Fatalf("Expected a token of %s got %s, %v", ed.ID, kid, err)
+	}
+	if _, err := verify(k, old); err != nil {
+		t.Fatalf("Expected tokens of the previous key to verify, %s", err)
+	}
+	if _, err := verify(k, legacy); err == nil {
+		t.Fatal("Expected tokens of the secret to be rejected once it is removed")
+	}
+
+	if err := k.Load(config.Token{SigningKey: ed.ID, Keys: []config.SigningKey{ed}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verify(k, old); err == nil {
+		t.Fatal("Expected tokens of removed keys to be rejected")
+	}
+}
+
+func TestKeyring_Load(t *testing.T) {
+	files := keyFiles(t)
+	ed := config.SigningKey{ID: "ed", Algorithm: keyring.AlgorithmEdDSA, File: files[keyring.AlgorithmEdDSA]}
+	pub := config.SigningKey{ID: "pub", Algorithm: keyring.AlgorithmEdDSA, File: files[keyring.AlgorithmEdDSA+".pub"]}
+
+	testData := []struct {
+		description string
+		cfg         config.Token
+	}{
+		{"unknown signing key", config.Token{SigningKey: "missing", Keys: []config.SigningKey{ed}}},
+		{"signing key without private key", config.Token{SigningKey: "pub", Keys: []config.SigningKey{pub}}},
+		{"duplicate key id", config.Token{Keys: []config.SigningKey{ed, ed}}},
+		{"missing file", config.Token{Keys: []config.SigningKey{{ID: "x", Algorithm: keyring.AlgorithmEdDSA, File: "missing.pem"}}}},
+		{"algorithm of another key type", config.Token{Keys: []config.SigningKey{{ID: "x", Algorithm: keyring.AlgorithmRS256, File: ed.File}}}},
+		{"unsupported algorithm", config.Token{Keys: []config.SigningKey{{ID: "x", Algorithm: "HS256", File: ed.File}}}},
+		{"missing signing key and secret", config.Token{Keys: []config.SigningKey{ed}}},
+	}
+
+	k := keyring.New("")
+	if err := k.Load(config.Token{SigningKey: ed.ID, Keys: []config.SigningKey{ed, pub}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range testData {
+		t.Run("Should reject "+test.description, func(t *testing.T) {
+			if err := k.Load(test.cfg); err == nil {
+				t.Fatal("Expected an error")
+			}
+
+			// The keys in use are kept
+			signed, err := k.Sign(claims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kid, err := verify(k, signed); err != nil || kid != ed.ID {
+				t.Fatalf("Expected a token of %s got %s, %v", ed.ID, kid, err)
+			}
+		})
+	}
+
+	t.Run("Should reject tokens that switch the algorithm of a key", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+		token.Header["kid"] = ed.ID
+		signed, err := token.SignedString([]byte(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := verify(k, signed); err == nil {
+			t.Fatal("Expected an error")
+		}
+	})
+}
+
+func TestKeyring_JWKS(t *testing.T) {
+	files := keyFiles(t)
+	k := keyring.New("secret")
+	err := k.Load(config.Token{
+		Secret:     "secret",
+		SigningKey: "rs",
+		Keys: []config.SigningKey{
+			{ID: "rs", Algorithm: keyring.AlgorithmRS256, File: files[keyring.AlgorithmRS256]},
+			{ID: "es", Algorithm: keyring.AlgorithmES256, File: files[keyring.AlgorithmES256]},
+			{ID: "ed", Algorithm: keyring.AlgorithmEdDSA, File: files[keyring.AlgorithmEdDSA+".pub"]},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]struct {
+		kty string
+		crv string
+	}{
+		"rs": {"RSA", ""},
+		"es": {"EC", "P-256"},
+		"ed": {"OKP", "Ed25519"},
+	}
+
+	set := k.JWKS()
+	if len(set.Keys) != len(expected) {
+		t.Fatalf("Expected %d keys got %d", len(expected), len(set.Keys))
+	}
+	for _, jwk := range set.Keys {
+		e, ok := expected[jwk.KeyID]
+		if !ok || jwk.KeyType != e.kty || jwk.Curve != e.crv || jwk.Use != "sig" {
+			t.Fatalf("Unexpected key %+v", jwk)
+		}
+		switch jwk.KeyType {
+		case "RSA":
+			if jwk.N == "" || jwk.E != "AQAB" {
+				t.Fatalf("Expected modulus and exponent got %+v", jwk)
+			}
+		case "EC":
+			if len(jwk.X) != 43 || len(jwk.Y) != 43 {
+				t.Fatalf("Expected 32 byte coordinates got %+v", jwk)
+			}
+		case "OKP":
+			if len(jwk.X) != 43 {
+				t.Fatalf("Expected a 32 byte key got %+v", jwk)
+			}
+		}
+	}
+
+	t.Run("Should convert keys back to public keys", func(t *testing.T) {
+		signed, err := k.Sign(claims())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, jwk := range set.Keys {
+			pub, err := jwk.PublicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if jwk.KeyID != "rs" {
+				continue
+			}
+			if _, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return pub, nil }); err != nil {
+				t.Fatalf("Expected the published key to verify tokens, %s", err)
+			}
+		}
+
+		if _, err := (keyring.JWK{KeyID: "x", KeyType: "EC", Curve: "P-256", X: "AQ", Y: "AQ"}).PublicKey(); err == nil {
+			t.Fatal("Expected points off the curve to be rejected")
+		}
+	})
+}
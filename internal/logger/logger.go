@@ -50,3 +50,15 @@ func NewLogger(cfg config.Logger, options ...Option) *Logger {
 
 	return &l
 }
+
+// SecurityEvent logs an authentication or account event as a structured entry with the category security, the event
+// name and fields. Failures are logged as warnings so they can be alerted on, successes as info.
+func (l *Logger) SecurityEvent(event string, success bool, fields map[string]interface{}) {
+	entry := l.WithFields(fields).WithFields(logrus.Fields{"category": "security", "event": event, "success": success})
+	if success {
+		entry.Info(event)
+		return
+	}
+
+	entry.Warn(event)
+}
@@ -1,6 +1,8 @@
 package logger_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"log"
 	"os"
 	"testing"
@@ -48,3 +50,29 @@ func TestNew(t *testing.T) {
 		}
 	})
 }
+
+func TestLogger_SecurityEvent(t *testing.T) {
+	out := bytes.Buffer{}
+	l := logger.NewLogger(config.Logger{LogLevel: 4}, logger.SetOutput(&out))
+
+	l.SecurityEvent("login_failed", false, map[string]interface{}{"username": "user1", "ip": "192.0.2.1"})
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"category": "security",
+		"event":    "login_failed",
+		"success":  false,
+		"username": "user1",
+		"ip":       "192.0.2.1",
+		"level":    "warning",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Fatalf("Expected %s to be %v got %v", k, v, entry[k])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails, implementations must be safe for concurrent use
+type Mailer interface {
+	Send(m Message) error
+}
+
+// SMTP sends emails through an SMTP server
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTP creates an SMTP mailer, the server is only authenticated with when a username is configured
+func NewSMTP(cfg config.Mail) *SMTP {
+	s := SMTP{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		from: cfg.From,
+	}
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &s
+}
+
+// Send an email
+func (s *SMTP) Send(m Message) error {
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{m.To}, format(s.from, m, time.Now())); err != nil {
+		return fmt.Errorf("mail error, %w", err)
+	}
+
+	return nil
+}
+
+// format returns the RFC 5322 representation of a message
+func format(from string, m Message, now time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + m.To + "\r\n")
+	b.WriteString("Subject: " + m.Subject + "\r\n")
+	b.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// Memory keeps sent emails in memory instead of sending them, use it in tests
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemory creates an empty in memory mailer
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Send records an email
+func (mm *Memory) Send(m Message) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.messages = append(mm.messages, m)
+
+	return nil
+}
+
+// Messages returns the emails sent so far, oldest first
+func (mm *Memory) Messages() []Message {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	return append([]Message{}, mm.messages...)
+}
+
+// Last returns the most recent email sent to an address, the second return value reports whether there is one
+func (mm *Memory) Last(to string) (Message, bool) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	for i := len(mm.messages) - 1; i >= 0; i-- {
+		if mm.messages[i].To == to {
+			return mm.messages[i], true
+		}
+	}
+
+	return Message{}, false
+}
@@ -0,0 +1,39 @@
+package mailer_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/georlav/recipeapi/internal/mailer"
+)
+
+func TestMemory_Send(t *testing.T) {
+	m := mailer.NewMemory()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Send(mailer.Message{To: "user1@test.gr", Subject: "concurrent"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := m.Send(mailer.Message{To: "user1@test.gr", Subject: "last"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(m.Messages()); n != 11 {
+		t.Fatalf("Expected 11 messages got %d", n)
+	}
+
+	if msg, ok := m.Last("user1@test.gr"); !ok || !strings.EqualFold(msg.Subject, "last") {
+		t.Fatalf("Expected the last message got %+v", msg)
+	}
+	if _, ok := m.Last("user2@test.gr"); ok {
+		t.Fatal("Expected no message for an address nothing was sent to")
+	}
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a concurrency safe fixed window rate limiter, each key is allowed a number of requests per window.
+// All keys share the same window so counters are dropped together once it is over.
+type Limiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	start  time.Time
+	counts map[string]int
+}
+
+// New creates a new limiter that allows limit requests per key in every window, a limit lower than one disables it
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:  limit,
+		window: window,
+		counts: make(map[string]int),
+	}
+}
+
+// Allow reports whether a request for key can proceed at the given time, when it can not the second return value is
+// the time left until the current window is over
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	if l.limit < 1 || l.window <= 0 {
+		return true, 0
+	}
+
+	start := now.Truncate(l.window)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !start.Equal(l.start) {
+		l.start = start
+		l.counts = make(map[string]int)
+	}
+
+	if l.counts[key] >= l.limit {
+		return false, start.Add(l.window).Sub(now)
+	}
+	l.counts[key]++
+
+	return true, 0
+}
@@ -0,0 +1,58 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/ratelimit"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2020, 4, 1, 10, 0, 15, 0, time.UTC)
+
+	t.Run("Should allow requests up to the limit", func(t *testing.T) {
+		l := ratelimit.New(2, time.Minute)
+
+		for i := 0; i < 2; i++ {
+			if ok, _ := l.Allow("a", now); !ok {
+				t.Fatalf("Expected request %d to be allowed", i+1)
+			}
+		}
+
+		ok, retry := l.Allow("a", now)
+		if ok {
+			t.Fatal("Expected request over the limit to be rejected")
+		}
+		if retry != 45*time.Second {
+			t.Fatalf("Expected to retry after 45s got %s", retry)
+		}
+
+		if ok, _ := l.Allow("b", now); !ok {
+			t.Fatal("Expected other keys to be counted separately")
+		}
+	})
+
+	t.Run("Should reset counters once the window is over", func(t *testing.T) {
+		l := ratelimit.New(1, time.Minute)
+
+		if ok, _ := l.Allow("a", now); !ok {
+			t.Fatal("Expected request to be allowed")
+		}
+		if ok, _ := l.Allow("a", now.Add(30*time.Second)); ok {
+			t.Fatal("Expected request over the limit to be rejected")
+		}
+		if ok, _ := l.Allow("a", now.Add(45*time.Second)); !ok {
+			t.Fatal("Expected request in the next window to be allowed")
+		}
+	})
+
+	t.Run("Should allow everything when disabled", func(t *testing.T) {
+		l := ratelimit.New(0, time.Minute)
+
+		for i := 0; i < 10; i++ {
+			if ok, _ := l.Allow("a", now); !ok {
+				t.Fatal("Expected disabled limiter to allow requests")
+			}
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package views
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store persists buffered views, views maps recipe ids to the number of views they received
+type Store interface {
+	AddViews(views map[int64]int64, at time.Time) error
+}
+
+// Counter buffers recipe views in memory and flushes them to a store in batches, so counting a view never waits
+// for the database
+type Counter struct {
+	mu       sync.Mutex
+	views    map[int64]int64
+	store    Store
+	interval time.Duration
+	size     int
+	full     chan struct{}
+}
+
+// NewCounter creates a Counter that flushes every interval or as soon as size recipes are buffered
+func NewCounter(store Store, interval time.Duration, size int) *Counter {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if size <= 0 {
+		size = 1000
+	}
+
+	return &Counter{
+		views:    make(map[int64]int64),
+		store:    store,
+		interval: interval,
+		size:     size,
+		full:     make(chan struct{}, 1),
+	}
+}
+
+// Add a view to a recipe
+func (c *Counter) Add(recipeID int64) {
+	c.mu.Lock()
+	c.views[recipeID]++
+	full := len(c.views) >= c.size
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run flushes buffered views periodically until ctx is done, errors are passed to onError and the views that
+// failed to flush are kept for the next attempt
+func (c *Counter) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.full:
+		}
+
+		if err := c.Flush(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Flush writes all buffered views to the store
+func (c *Counter) Flush() error {
+	c.mu.Lock()
+	if len(c.views) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	views := c.views
+	c.views = make(map[int64]int64)
+	c.mu.Unlock()
+
+	if err := c.store.AddViews(views, time.Now()); err != nil {
+		// Put views back so they are not lost
+		c.mu.Lock()
+		for id, n := range views {
+			c.views[id] += n
+		}
+		c.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// StoreFunc adapts a function to the Store interface
+type StoreFunc func(views map[int64]int64, at time.Time) error
+
+// AddViews calls f
+func (f StoreFunc) AddViews(views map[int64]int64, at time.Time) error {
+	return f(views, at)
+}
@@ -0,0 +1,97 @@
+package views_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/georlav/recipeapi/internal/views"
+)
+
+type store struct {
+	mu    sync.Mutex
+	views map[int64]int64
+	err   error
+}
+
+func (s *store) AddViews(views map[int64]int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	for id, n := range views {
+		s.views[id] += n
+	}
+
+	return nil
+}
+
+func (s *store) get(id int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.views[id]
+}
+
+func TestCounter(t *testing.T) {
+	t.Run("Should flush buffered views", func(t *testing.T) {
+		s := &store{views: make(map[int64]int64)}
+		c := views.NewCounter(s, time.Hour, 100)
+
+		c.Add(1)
+		c.Add(1)
+		c.Add(2)
+		if s.get(1) != 0 {
+			t.Fatal("Expected views to be buffered")
+		}
+
+		if err := c.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if s.get(1) != 2 || s.get(2) != 1 {
+			t.Fatalf("Unexpected views %v", s.views)
+		}
+	})
+
+	t.Run("Should keep views when flush fails", func(t *testing.T) {
+		s := &store{views: make(map[int64]int64), err: errors.New("db down")}
+		c := views.NewCounter(s, time.Hour, 100)
+
+		c.Add(1)
+		if err := c.Flush(); err == nil {
+			t.Fatal("Expected flush to fail")
+		}
+
+		s.err = nil
+		c.Add(1)
+		if err := c.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if s.get(1) != 2 {
+			t.Fatalf("Expected %d views got %d", 2, s.get(1))
+		}
+	})
+
+	t.Run("Should flush early when the buffer is full", func(t *testing.T) {
+		s := &store{views: make(map[int64]int64)}
+		c := views.NewCounter(s, time.Hour, 2)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.Run(ctx, nil)
+
+		c.Add(1)
+		c.Add(2)
+
+		for i := 0; i < 100 && s.get(2) == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if s.get(1) != 1 || s.get(2) != 1 {
+			t.Fatalf("Expected buffered views to be flushed got %v", s.views)
+		}
+	})
+}